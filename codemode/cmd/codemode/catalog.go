@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/preblog/codemode/internal/app"
+	"github.com/preblog/codemode/internal/catalog"
+	"github.com/preblog/codemode/internal/config"
+)
+
+func runCatalog(args []string) {
+	flags := flag.NewFlagSet("catalog", flag.ExitOnError)
+	var (
+		mcpConfig = flags.String("mcp-config", config.DefaultMCPServersPath, "JSON file listing the MCP servers to connect to; the embedded demo server is used when the default file is missing")
+		dts       = flags.Bool("dts", false, "Print a TypeScript declaration file for all helpers instead of a summary table")
+		outPath   = flags.String("out", "", "Write the output to this file instead of stdout")
+	)
+	if err := flags.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+
+	cfg, err := config.LoadLocal(config.Options{MCPServersPath: *mcpConfig})
+	if err != nil {
+		log.Fatal(err)
+	}
+	runtime, toolset, err := app.NewToolset(context.Background(), cfg, app.ToolsetOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if err := runtime.Close(); err != nil {
+			log.Printf("close runtime: %v", err)
+		}
+	}()
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer func() {
+			if err := file.Close(); err != nil {
+				log.Printf("close %s: %v", *outPath, err)
+			}
+		}()
+		out = file
+	}
+
+	if *dts {
+		fmt.Fprintf(out, "// Helpers available to codemode execute scripts, generated from %d MCP tools.\n\n", len(toolset.Catalog))
+		fmt.Fprint(out, catalog.TypeScriptDeclarations(toolset.Catalog))
+		return
+	}
+	table := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "HELPER\tSERVER\tTOOL\tDESCRIPTION")
+	for _, item := range toolset.Catalog {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", item.Callable, item.Server, item.Name, previewText(item.Description))
+	}
+	if err := table.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/preblog/codemode/internal/catalog"
+	"github.com/preblog/codemode/internal/policy"
+)
+
+// terminalConfirmer prompts on out and reads y/n answers from in. Lines are
+// read by a single goroutine so an unanswered prompt that timed out does not
+// swallow the answer to the next one.
+func terminalConfirmer(in io.Reader, out io.Writer) policy.Confirmer {
+	var (
+		once  sync.Once
+		lines = make(chan string)
+	)
+	start := func() {
+		go func() {
+			defer close(lines)
+			scanner := bufio.NewScanner(in)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+		}()
+	}
+	return func(ctx context.Context, item catalog.ToolInfo, args map[string]any) (bool, error) {
+		once.Do(start)
+		payload, err := json.Marshal(args)
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintf(out, "\nAllow %s (%s.%s) with %s? [y/N] ", item.Callable, item.Server, item.Name, payload)
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return false, io.ErrUnexpectedEOF
+			}
+			answer := strings.ToLower(strings.TrimSpace(line))
+			return answer == "y" || answer == "yes", nil
+		case <-ctx.Done():
+			fmt.Fprintln(out)
+			return false, ctx.Err()
+		}
+	}
+}
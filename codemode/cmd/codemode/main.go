@@ -10,22 +10,44 @@ import (
 	"strings"
 	"time"
 
-	anthropicloop "github.com/preblog/codemode/internal/anthropic"
 	"github.com/preblog/codemode/internal/app"
 	"github.com/preblog/codemode/internal/config"
+	"github.com/preblog/codemode/internal/loop"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "catalog":
+			runCatalog(os.Args[2:])
+			return
+		}
+	}
+
 	var (
-		model      = flag.String("model", "claude-sonnet-4-6", "Anthropic model name")
+		providerID = flag.String("provider", "anthropic", "Model provider: anthropic, or openai for any OpenAI-compatible chat completions server such as llama.cpp or Ollama")
+		baseURL    = flag.String("base-url", "", "Model API base URL, e.g. http://localhost:11434/v1 for Ollama; defaults to the provider's public endpoint")
+		model      = flag.String("model", "", "Model name; defaults to claude-sonnet-4-6 for anthropic and gpt-4o-mini for openai")
 		prompt     = flag.String("prompt", "", "One-shot prompt to run")
-		maxTurns   = flag.Int("max-turns", 6, "Maximum Anthropic tool loop turns")
+		mcpConfig  = flag.String("mcp-config", config.DefaultMCPServersPath, "JSON file listing the MCP servers to connect to; the embedded demo server is used when the default file is missing")
+		maxTurns   = flag.Int("max-turns", 6, "Maximum model tool loop turns")
 		timeoutSec = flag.Int("timeout-seconds", 10, "QuickJS evaluation timeout in seconds")
 		memoryMB   = flag.Int("memory-mb", 32, "QuickJS memory limit in megabytes")
+		parallel   = flag.Int("max-parallel-calls", 4, "Maximum number of helper calls a single execute runs concurrently")
+		persistent = flag.Bool("persistent-sandbox", false, "Keep one QuickJS VM for the whole conversation so state survives across execute calls")
+		policyPath = flag.String("policy", "", "JSON policy file that allows, denies, constrains or asks for confirmation of helper calls")
+		ranker     = flag.String("search-ranker", "bm25", "Ranking used by the search tool: bm25, embedding or hybrid")
+		vectors    = flag.String("search-vectors", "", "Precomputed embedding vector file for the embedding and hybrid search rankers")
 		verbose    = flag.Bool("verbose", false, "Print tool activity")
+		stream     = flag.Bool("stream", false, "Stream the model response and print text and tool activity as it arrives")
 		noColor    = flag.Bool("no-color", false, "Disable ANSI colors in verbose output")
 		saveTrace  = flag.String("save-trace", "", "Write the full conversation trace to a readable Markdown file")
-		debugHTTP  = flag.Bool("debug-http", false, "Print raw Anthropic HTTP requests and responses to stderr, or capture them in -save-trace")
+		debugHTTP  = flag.Bool("debug-http", false, "Print raw model API HTTP requests and responses to stderr, or capture them in -save-trace")
+		record     = flag.String("record", "", "Write every model request and response, tool call and helper call of the run to a JSONL file")
+		replay     = flag.String("replay", "", "Serve model responses, the tool catalog and helper results from a JSONL recording instead of the network, and report where the run diverges")
 	)
 	flag.Parse()
 
@@ -35,18 +57,28 @@ func main() {
 
 	// captureTrace also enables internal verbose logging so traces can be collected for -save-trace
 	cfg, err := config.Load(config.Options{
-		Model:         *model,
-		MaxTurns:      *maxTurns,
-		EvalTimeoutS:  *timeoutSec,
-		MemoryLimitMB: *memoryMB,
-		Verbose:       captureTrace,
-		DebugHTTP:     *debugHTTP,
+		Provider:       *providerID,
+		BaseURL:        *baseURL,
+		Model:          *model,
+		MCPServersPath: *mcpConfig,
+		MaxTurns:       *maxTurns,
+		EvalTimeoutS:   *timeoutSec,
+		MemoryLimitMB:  *memoryMB,
+		MaxParallel:    *parallel,
+		SearchRanker:   *ranker,
+		SearchVectors:  *vectors,
+		Persistent:     *persistent,
+		PolicyPath:     *policyPath,
+		RecordPath:     *record,
+		ReplayPath:     *replay,
+		Verbose:        captureTrace,
+		DebugHTTP:      *debugHTTP,
 	})
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	runner, err := app.New(cfg, captureHTTPInTrace)
+	runner, err := app.New(cfg, captureHTTPInTrace, terminalConfirmer(os.Stdin, os.Stderr))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -65,15 +97,43 @@ func main() {
 		os.Exit(2)
 	}
 
+	var rendered chan struct{}
+	var events chan loop.Event
+	if *stream {
+		events = make(chan loop.Event, 64)
+		rendered = make(chan struct{})
+		runner.Stream(events)
+		go func() {
+			defer close(rendered)
+			renderStreamEvents(os.Stdout, events, !*noColor)
+		}()
+	}
+
 	result, err := runner.Run(context.Background(), input)
-	if artifactErr := persistRunArtifacts(tracePath, input, cfg, result, err, *verbose, !*noColor, captureHTTPInTrace); artifactErr != nil {
+	if *stream {
+		close(events)
+		<-rendered
+	}
+	if artifactErr := persistRunArtifacts(tracePath, input, cfg, runner.Servers(), result, err, *verbose, !*noColor, captureHTTPInTrace); artifactErr != nil {
 		log.Fatal(artifactErr)
 	}
+	divergences := runner.Divergences()
+	if len(divergences) > 0 {
+		fmt.Fprintf(os.Stderr, "replay diverged from %s in %d place(s):\n", cfg.ReplayPath, len(divergences))
+		for _, divergence := range divergences {
+			fmt.Fprintf(os.Stderr, "  - %s\n", divergence)
+		}
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	fmt.Println(result.Text)
+	if !*stream {
+		fmt.Println(result.Text)
+	}
+	if len(divergences) > 0 {
+		os.Exit(1)
+	}
 }
 
 const (
@@ -147,9 +207,9 @@ func styleVerboseLine(line string, color bool) string {
 	}
 }
 
-func persistRunArtifacts(tracePath, prompt string, cfg config.Config, result anthropicloop.Result, runErr error, verbose bool, color bool, captureHTTPInTrace bool) error {
+func persistRunArtifacts(tracePath, prompt string, cfg config.Config, servers []string, result loop.Result, runErr error, verbose bool, color bool, captureHTTPInTrace bool) error {
 	if tracePath != "" {
-		if err := saveTraceFile(tracePath, prompt, cfg, traceFinalText(result, runErr), result.Trace); err != nil {
+		if err := saveTraceFile(tracePath, prompt, cfg, servers, traceFinalText(result, runErr), result.Trace); err != nil {
 			return err
 		}
 	}
@@ -160,7 +220,7 @@ func persistRunArtifacts(tracePath, prompt string, cfg config.Config, result ant
 	return nil
 }
 
-func traceFinalText(result anthropicloop.Result, runErr error) string {
+func traceFinalText(result loop.Result, runErr error) string {
 	if strings.TrimSpace(result.Text) != "" {
 		return result.Text
 	}
@@ -170,26 +230,47 @@ func traceFinalText(result anthropicloop.Result, runErr error) string {
 	return ""
 }
 
-func saveTraceFile(path, prompt string, cfg config.Config, finalText string, trace []string) error {
+func saveTraceFile(path, prompt string, cfg config.Config, servers []string, finalText string, trace []string) error {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return fmt.Errorf("create trace directory: %w", err)
 	}
-	content := buildTraceDocument(prompt, cfg, finalText, trace)
+	content := buildTraceDocument(prompt, cfg, servers, finalText, trace)
 	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
 		return fmt.Errorf("write trace file: %w", err)
 	}
 	return nil
 }
 
-func buildTraceDocument(prompt string, cfg config.Config, finalText string, trace []string) string {
+func buildTraceDocument(prompt string, cfg config.Config, servers []string, finalText string, trace []string) string {
 	var builder strings.Builder
 	builder.WriteString("# CodeMode Trace\n\n")
 	builder.WriteString("## Run Metadata\n\n")
 	fmt.Fprintf(&builder, "- Generated: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&builder, "- Provider: %s\n", cfg.Provider)
+	if cfg.BaseURL != "" {
+		fmt.Fprintf(&builder, "- Base URL: %s\n", cfg.BaseURL)
+	}
 	fmt.Fprintf(&builder, "- Model: %s\n", cfg.Model)
 	fmt.Fprintf(&builder, "- Max turns: %d\n", cfg.MaxTurns)
+	fmt.Fprintf(&builder, "- Max parallel helper calls: %d\n", cfg.MaxParallelCalls)
+	fmt.Fprintf(&builder, "- Search ranker: %s\n", cfg.SearchRanker)
+	fmt.Fprintf(&builder, "- Persistent sandbox: %t\n", cfg.Persistent)
+	if cfg.PolicyPath != "" {
+		fmt.Fprintf(&builder, "- Policy: %s\n", cfg.PolicyPath)
+	}
+	if cfg.RecordPath != "" {
+		fmt.Fprintf(&builder, "- Recording: %s\n", cfg.RecordPath)
+	}
+	if cfg.ReplayPath != "" {
+		fmt.Fprintf(&builder, "- Replayed from: %s\n", cfg.ReplayPath)
+	}
 	fmt.Fprintf(&builder, "- Debug HTTP: %t\n", cfg.DebugHTTP)
-	builder.WriteString("- MCP server: embedded demo\n")
+	if cfg.MCPServersPath == "" {
+		builder.WriteString("- MCP server config: embedded demo\n")
+	} else {
+		fmt.Fprintf(&builder, "- MCP server config: %s\n", cfg.MCPServersPath)
+	}
+	fmt.Fprintf(&builder, "- MCP servers: %s\n", strings.Join(servers, ", "))
 	builder.WriteString("\n## Prompt\n\n```text\n")
 	builder.WriteString(prompt)
 	builder.WriteString("\n```\n\n")
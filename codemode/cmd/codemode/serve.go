@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/preblog/codemode/internal/app"
+	"github.com/preblog/codemode/internal/config"
+	"github.com/preblog/codemode/internal/mcpserve"
+)
+
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	var (
+		transport  = flags.String("transport", "stdio", "MCP transport to serve: stdio or http")
+		addr       = flags.String("addr", ":8080", "Listen address for the http transport")
+		mcpConfig  = flags.String("mcp-config", config.DefaultMCPServersPath, "JSON file listing the downstream MCP servers; the embedded demo server is used when the default file is missing")
+		timeoutSec = flags.Int("timeout-seconds", 10, "QuickJS evaluation timeout in seconds")
+		memoryMB   = flags.Int("memory-mb", 32, "QuickJS memory limit in megabytes")
+		parallel   = flags.Int("max-parallel-calls", 4, "Maximum number of helper calls a single execute runs concurrently")
+		ranker     = flags.String("search-ranker", "bm25", "Ranking used by the search tool: bm25, embedding or hybrid")
+		vectors    = flags.String("search-vectors", "", "Precomputed embedding vector file for the embedding and hybrid search rankers")
+		policyPath = flags.String("policy", "", "JSON policy file for helper calls; calls that require confirmation are denied and budgets span the server lifetime")
+	)
+	if err := flags.Parse(args); err != nil {
+		log.Fatal(err)
+	}
+
+	cfg, err := config.LoadLocal(config.Options{
+		MCPServersPath: *mcpConfig,
+		EvalTimeoutS:   *timeoutSec,
+		MemoryLimitMB:  *memoryMB,
+		MaxParallel:    *parallel,
+		SearchRanker:   *ranker,
+		SearchVectors:  *vectors,
+		PolicyPath:     *policyPath,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	runtime, toolset, err := app.NewToolset(ctx, cfg, app.ToolsetOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if err := runtime.Close(); err != nil {
+			log.Printf("close runtime: %v", err)
+		}
+	}()
+	server := mcpserve.New(toolset)
+
+	switch *transport {
+	case "stdio":
+		if err := server.Run(ctx, &mcp.StdioTransport{}); err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, io.EOF) {
+			log.Printf("serve stdio: %v", err)
+		}
+	case "http":
+		if err := serveHTTP(ctx, server, *addr, len(toolset.Catalog)); err != nil {
+			log.Printf("serve http: %v", err)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported transport %q, use stdio or http\n", *transport)
+		os.Exit(2)
+	}
+}
+
+func serveHTTP(ctx context.Context, server *mcp.Server, addr string, helpers int) error {
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server {
+		return server
+	}, nil)
+	srv := &http.Server{
+		Addr:        addr,
+		Handler:     handler,
+		ReadTimeout: 15 * time.Second,
+		IdleTimeout: 120 * time.Second,
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		log.Printf("Serving code mode over MCP on %s with %d downstream helper(s)", addr, helpers)
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/preblog/codemode/internal/loop"
+)
+
+const streamPreviewLimit = 400
+
+func renderStreamEvents(w io.Writer, events <-chan loop.Event, color bool) {
+	atLineStart := true
+	writeLine := func(style, line string) {
+		if !atLineStart {
+			fmt.Fprintln(w)
+		}
+		if color {
+			line = style + line + ansiReset
+		}
+		fmt.Fprintln(w, line)
+		atLineStart = true
+	}
+	for event := range events {
+		switch event.Kind {
+		case loop.EventTurnStart:
+			writeLine(ansiDim, fmt.Sprintf("--- turn %d ---", event.Turn))
+		case loop.EventTextDelta:
+			fmt.Fprint(w, event.Text)
+			atLineStart = strings.HasSuffix(event.Text, "\n")
+		case loop.EventToolUse:
+			writeLine(ansiYellow, fmt.Sprintf("[tool call %s] %s", event.ToolName, previewText(string(event.Input))))
+		case loop.EventToolResult:
+			style := ansiYellow
+			if event.IsError {
+				style = ansiRed
+			}
+			writeLine(style, fmt.Sprintf("[tool result %s] is_error=%t %s", event.ToolName, event.IsError, previewText(event.Output)))
+		}
+	}
+	if !atLineStart {
+		fmt.Fprintln(w)
+	}
+}
+
+func previewText(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= streamPreviewLimit {
+		return text
+	}
+	return text[:streamPreviewLimit] + "..."
+}
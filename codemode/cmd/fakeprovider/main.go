@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/preblog/codemode/internal/provider/providertest"
+)
+
+func main() {
+	var (
+		addr       = flag.String("addr", "127.0.0.1:8089", "Listen address")
+		scriptPath = flag.String("script", "cmd/fakeprovider/script.json", "JSON file with the scripted assistant turns")
+		chunkSize  = flag.Int("chunk-size", 16, "Characters per streamed delta")
+		chunkDelay = flag.Duration("chunk-delay", 30*time.Millisecond, "Delay between streamed events")
+	)
+	flag.Parse()
+
+	turns, err := providertest.LoadScript(*scriptPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	handler := providertest.NewHandler(turns...)
+	handler.ChunkSize = *chunkSize
+	handler.ChunkDelay = *chunkDelay
+
+	log.Printf("serving %d scripted turn(s) on http://%s, set ANTHROPIC_BASE_URL to this address or pass -provider openai -base-url http://%s/v1", len(turns), *addr, *addr)
+	if err := http.ListenAndServe(*addr, handler); err != nil {
+		log.Fatal(err)
+	}
+}
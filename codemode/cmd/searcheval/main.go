@@ -0,0 +1,62 @@
+// Command searcheval reports the recall@k of the catalog search rankers on a
+// corpus of tools and labelled queries.
+//
+// The bundled corpus has 61 tools from 11 servers. Catalogs with hundreds of
+// tools hold many more helpers with overlapping names and descriptions, so
+// its numbers are an upper bound for them; evaluate such a catalog with its
+// own corpus via -corpus.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/preblog/codemode/internal/catalog"
+)
+
+func main() {
+	var (
+		corpusPath  = flag.String("corpus", "cmd/searcheval/corpus.json", "Search corpus with tools and expected helpers per query; the bundled one has 61 tools from 11 servers and 34 queries")
+		vectorsPath = flag.String("vectors", "", "Precomputed embedding vector file; enables the embedding and hybrid rankers")
+		k           = flag.Int("k", 5, "Number of results considered per query")
+		verbose     = flag.Bool("verbose", false, "Print the queries that missed expected helpers")
+	)
+	flag.Parse()
+
+	corpus, err := catalog.LoadSearchCorpus(*corpusPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	kinds := []catalog.RankerKind{catalog.RankerBM25}
+	var vectors *catalog.Vectors
+	if *vectorsPath != "" {
+		if vectors, err = catalog.LoadVectors(*vectorsPath); err != nil {
+			log.Fatal(err)
+		}
+		kinds = append(kinds, catalog.RankerEmbedding, catalog.RankerHybrid)
+	}
+
+	fmt.Printf("corpus: %d tools, %d queries\n", len(corpus.Tools), len(corpus.Cases))
+	for _, kind := range kinds {
+		ranker, err := catalog.NewRanker(kind, corpus.Tools, vectors)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, cutoff := range []int{1, 3, *k} {
+			recall, _ := catalog.RecallAtK(ranker, corpus.Cases, cutoff)
+			fmt.Printf("%-10s recall@%d = %.3f\n", kind, cutoff, recall)
+		}
+		if !*verbose {
+			continue
+		}
+		_, results := catalog.RecallAtK(ranker, corpus.Cases, *k)
+		for _, result := range results {
+			if len(result.Missing) == 0 {
+				continue
+			}
+			fmt.Printf("  miss %q missing=%s got=%s\n", result.Case.Query, strings.Join(result.Missing, ","), strings.Join(result.Returned, ","))
+		}
+	}
+}
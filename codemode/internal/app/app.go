@@ -2,47 +2,179 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
-	anthropicloop "github.com/preblog/codemode/internal/anthropic"
 	"github.com/preblog/codemode/internal/catalog"
 	"github.com/preblog/codemode/internal/codemode"
 	"github.com/preblog/codemode/internal/config"
-	"github.com/preblog/codemode/internal/mcpdemo"
+	"github.com/preblog/codemode/internal/loop"
+	"github.com/preblog/codemode/internal/mcpservers"
+	"github.com/preblog/codemode/internal/policy"
+	"github.com/preblog/codemode/internal/provider"
+	"github.com/preblog/codemode/internal/recording"
 	"github.com/preblog/codemode/internal/sandbox"
 )
 
 type App struct {
-	runtime *mcpdemo.Runtime
-	loop    *anthropicloop.Runner
+	backend  recording.Backend
+	loop     *loop.Runner
+	recorder *recording.Recorder
+	player   *recording.Player
 }
 
-func New(cfg config.Config, captureHTTPInTrace bool) (*App, error) {
-	ctx := context.Background()
-	runtime, err := mcpdemo.NewDemo(ctx)
+func New(cfg config.Config, captureHTTPInTrace bool, confirm policy.Confirmer) (*App, error) {
+	opts := ToolsetOptions{Confirm: confirm}
+	a := &App{}
+	if cfg.ReplayPath != "" {
+		rec, err := recording.Load(cfg.ReplayPath)
+		if err != nil {
+			return nil, err
+		}
+		if a.player, err = recording.NewPlayer(rec); err != nil {
+			return nil, fmt.Errorf("replay %s: %w", cfg.ReplayPath, err)
+		}
+		opts.Replay = rec
+	}
+	if cfg.RecordPath != "" {
+		recorder, err := recording.Create(cfg.RecordPath)
+		if err != nil {
+			return nil, err
+		}
+		a.recorder = recorder
+		opts.Recorder = recorder
+	}
+	backend, toolset, err := NewToolset(context.Background(), cfg, opts)
 	if err != nil {
+		if a.recorder != nil {
+			err = errors.Join(err, a.recorder.Close())
+		}
 		return nil, err
 	}
-	items, err := catalog.Load(ctx, runtime.ServerName(), runtime)
+	a.backend = backend
+	providerConfig := provider.Config{Kind: cfg.Provider, Model: cfg.Model, APIKey: cfg.APIKey, BaseURL: cfg.BaseURL}
+	if a.loop, err = loop.New(providerConfig, cfg.MaxTurns, cfg.Verbose, cfg.DebugHTTP, captureHTTPInTrace, toolset); err != nil {
+		return nil, errors.Join(err, a.Close())
+	}
+	if a.recorder != nil {
+		a.loop.Record(a.recorder)
+	}
+	if a.player != nil {
+		a.loop.Replay(a.player)
+	}
+	return a, nil
+}
+
+type ToolsetOptions struct {
+	// Confirm answers calls that the policy marks for confirmation; when it is
+	// nil those calls are denied.
+	Confirm policy.Confirmer
+	// Recorder, when set, records the tool catalog and every helper call.
+	Recorder *recording.Recorder
+	// Replay, when set, serves the catalog and helper results from a recording
+	// instead of connecting the configured MCP servers.
+	Replay *recording.Recording
+}
+
+// NewToolset connects the configured MCP servers and builds the search and
+// execute tools.
+func NewToolset(ctx context.Context, cfg config.Config, opts ToolsetOptions) (recording.Backend, *codemode.Toolset, error) {
+	var enforcer *policy.Enforcer
+	if cfg.PolicyPath != "" {
+		rules, err := policy.Load(cfg.PolicyPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		enforcer = policy.NewEnforcer(rules, opts.Confirm)
+	}
+	backend, err := connectBackend(ctx, cfg, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	items, err := catalog.LoadAll(ctx, backend)
 	if err != nil {
-		if closeErr := runtime.Close(); closeErr != nil {
-			return nil, fmt.Errorf("load catalog: %w (close runtime: %v)", err, closeErr)
+		return nil, nil, closeAfter(backend, fmt.Errorf("load catalog: %w", err))
+	}
+	var vectors *catalog.Vectors
+	if cfg.SearchVectors != "" {
+		if vectors, err = catalog.LoadVectors(cfg.SearchVectors); err != nil {
+			return nil, nil, closeAfter(backend, err)
 		}
-		return nil, fmt.Errorf("load catalog: %w", err)
 	}
-	sb := sandbox.New(items, runtime, cfg.EvalTimeout, cfg.MemoryLimitBytes)
-	toolset := &codemode.Toolset{Catalog: items, Sandbox: sb}
-	loop := anthropicloop.New(cfg.AnthropicAPIKey, cfg.Model, cfg.MaxTurns, cfg.Verbose, cfg.DebugHTTP, captureHTTPInTrace, toolset)
-	return &App{runtime: runtime, loop: loop}, nil
+	ranker, err := catalog.NewRanker(catalog.RankerKind(cfg.SearchRanker), items, vectors)
+	if err != nil {
+		return nil, nil, closeAfter(backend, err)
+	}
+	sb := sandbox.New(items, backend, cfg.EvalTimeout, cfg.MemoryLimitBytes, cfg.MaxParallelCalls)
+	if enforcer != nil {
+		sb.SetGuard(enforcer)
+	}
+	toolset := &codemode.Toolset{Catalog: items, Ranker: ranker, Sandbox: sb, Persistent: cfg.Persistent, Policy: enforcer}
+	return backend, toolset, nil
 }
 
-func (a *App) Run(ctx context.Context, prompt string) (anthropicloop.Result, error) {
+func connectBackend(ctx context.Context, cfg config.Config, opts ToolsetOptions) (recording.Backend, error) {
+	var backend recording.Backend
+	if opts.Replay != nil {
+		replay, err := recording.NewReplayBackend(opts.Replay)
+		if err != nil {
+			return nil, err
+		}
+		backend = replay
+	} else {
+		servers, err := mcpservers.LoadConfig(cfg.MCPServersPath)
+		if err != nil {
+			return nil, err
+		}
+		runtime, err := mcpservers.Connect(ctx, servers)
+		if err != nil {
+			return nil, err
+		}
+		backend = runtime
+	}
+	if opts.Recorder != nil {
+		backend = recording.RecordBackend(backend, opts.Recorder)
+	}
+	return backend, nil
+}
+
+func (a *App) Run(ctx context.Context, prompt string) (loop.Result, error) {
 	return a.loop.Run(ctx, prompt)
 }
 
-func (a *App) Close() error {
-	if a.runtime == nil {
+func (a *App) Stream(events chan<- loop.Event) {
+	a.loop.Stream(events)
+}
+
+func (a *App) Servers() []string {
+	if a.backend == nil {
 		return nil
 	}
-	return a.runtime.Close()
+	return a.backend.Servers()
+}
+
+// Divergences lists where a replayed run differed from its recording.
+func (a *App) Divergences() []string {
+	if a.player == nil {
+		return nil
+	}
+	return a.player.Divergences()
+}
+
+func (a *App) Close() error {
+	var errs []error
+	if a.backend != nil {
+		errs = append(errs, a.backend.Close())
+	}
+	if a.recorder != nil {
+		errs = append(errs, a.recorder.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func closeAfter(backend recording.Backend, err error) error {
+	if closeErr := backend.Close(); closeErr != nil {
+		return fmt.Errorf("%w (close runtime: %v)", err, closeErr)
+	}
+	return err
 }
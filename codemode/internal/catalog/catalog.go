@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"slices"
-	"strconv"
 	"strings"
 	"unicode"
 
@@ -26,6 +25,11 @@ type Lister interface {
 	ListTools(context.Context) ([]*mcp.Tool, error)
 }
 
+type Source interface {
+	Servers() []string
+	ListTools(ctx context.Context, server string) ([]*mcp.Tool, error)
+}
+
 func Load(ctx context.Context, serverName string, lister Lister) ([]ToolInfo, error) {
 	tools, err := lister.ListTools(ctx)
 	if err != nil {
@@ -37,151 +41,77 @@ func Load(ctx context.Context, serverName string, lister Lister) ([]ToolInfo, er
 			Server:       serverName,
 			Name:         tool.Name,
 			Description:  tool.Description,
-			Callable:     fmt.Sprintf("%s_%s", serverName, tool.Name),
+			Callable:     callableName(serverName, tool.Name),
 			InputSchema:  schemaToMap(tool.InputSchema),
 			OutputSchema: schemaToMap(tool.OutputSchema),
 		})
 	}
-	slices.SortFunc(items, func(a, b ToolInfo) int {
-		return cmp.Compare(a.Name, b.Name)
-	})
+	slices.SortFunc(items, compareToolInfo)
 	return items, nil
 }
 
-func Search(items []ToolInfo, query string, limit int) []ToolInfo {
-	if limit <= 0 {
-		limit = 8
-	}
-	query = strings.TrimSpace(strings.ToLower(query))
-	if query == "" {
-		if len(items) <= limit {
-			return append([]ToolInfo(nil), items...)
+func LoadAll(ctx context.Context, source Source) ([]ToolInfo, error) {
+	var items []ToolInfo
+	callables := map[string]string{}
+	for _, server := range source.Servers() {
+		serverItems, err := Load(ctx, server, serverLister{source: source, server: server})
+		if err != nil {
+			return nil, fmt.Errorf("list tools of %s: %w", server, err)
 		}
-		return append([]ToolInfo(nil), items[:limit]...)
-	}
-
-	terms := expandSearchTerms(searchTerms(query))
-	type scoredMatch struct {
-		item  ToolInfo
-		score int
-	}
-	matches := make([]scoredMatch, 0, len(items))
-	for _, item := range items {
-		score := searchScore(item, terms)
-		if score > 0 {
-			matches = append(matches, scoredMatch{item: item, score: score})
+		for _, item := range serverItems {
+			if previous, ok := callables[item.Callable]; ok {
+				return nil, fmt.Errorf("helper %s from %s/%s collides with %s", item.Callable, server, item.Name, previous)
+			}
+			callables[item.Callable] = server + "/" + item.Name
 		}
+		items = append(items, serverItems...)
 	}
-	slices.SortFunc(matches, func(a, b scoredMatch) int {
-		return cmp.Or(
-			cmp.Compare(b.score, a.score),
-			cmp.Compare(a.item.Name, b.item.Name),
-			cmp.Compare(a.item.Callable, b.item.Callable),
-		)
-	})
-	if len(matches) > limit {
-		matches = matches[:limit]
-	}
-	results := make([]ToolInfo, 0, len(matches))
-	for _, match := range matches {
-		results = append(results, match.item)
-	}
-	return results
+	slices.SortFunc(items, compareToolInfo)
+	return items, nil
 }
 
-func HelperDefinitions(items []ToolInfo) string {
-	var builder strings.Builder
-	if len(items) == 0 {
-		builder.WriteString("// No helper functions matched this search.\n")
-		return builder.String()
-	}
-
-	for _, item := range items {
-		builder.WriteString("/**\n")
-		if desc := sanitizeJSDocText(item.Description); desc != "" {
-			builder.WriteString(" * ")
-			builder.WriteString(desc)
-			builder.WriteString("\n")
-		}
-		builder.WriteString(" * @param ")
-		builder.WriteString(jsDocObjectType(item.InputSchema))
-		builder.WriteString(" args\n")
-		writeSchemaFieldDescriptions(&builder, "Input fields", "args", item.InputSchema)
-		builder.WriteString(" * @returns {")
-		builder.WriteString(jsDocType(item.OutputSchema))
-		builder.WriteString("}\n")
-		writeSchemaFieldDescriptions(&builder, "Output fields", "result", item.OutputSchema)
-		builder.WriteString(" */\n")
-		builder.WriteString("function ")
-		builder.WriteString(item.Callable)
-		builder.WriteString("(args) {}\n\n")
-	}
+type serverLister struct {
+	source Source
+	server string
+}
 
-	return builder.String()
+func (l serverLister) ListTools(ctx context.Context) ([]*mcp.Tool, error) {
+	return l.source.ListTools(ctx, l.server)
 }
 
-func writeSchemaFieldDescriptions(builder *strings.Builder, heading string, root string, schema map[string]any) {
-	lines := schemaFieldDescriptions(root, schema)
-	if len(lines) == 0 {
-		return
-	}
-	builder.WriteString(" * ")
-	builder.WriteString(heading)
-	builder.WriteString(":\n")
-	for _, line := range lines {
-		builder.WriteString(" *   - ")
-		builder.WriteString(line)
-		builder.WriteString("\n")
-	}
+func compareToolInfo(a, b ToolInfo) int {
+	return cmp.Or(
+		cmp.Compare(a.Name, b.Name),
+		cmp.Compare(a.Callable, b.Callable),
+	)
 }
 
-func schemaFieldDescriptions(root string, schema map[string]any) []string {
-	if len(schema) == 0 {
-		return nil
-	}
-	properties, _ := schema["properties"].(map[string]any)
-	if len(properties) == 0 {
-		return nil
+func callableName(serverName, toolName string) string {
+	name := []byte(fmt.Sprintf("%s_%s", serverName, toolName))
+	for i, r := range name {
+		if r != '_' && r != '$' && (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			name[i] = '_'
+		}
 	}
-
-	names := make([]string, 0, len(properties))
-	for name := range properties {
-		names = append(names, name)
+	if len(name) > 0 && name[0] >= '0' && name[0] <= '9' {
+		return "_" + string(name)
 	}
-	slices.Sort(names)
+	return string(name)
+}
 
-	lines := make([]string, 0, len(names))
-	for _, name := range names {
-		propertySchema, _ := properties[name].(map[string]any)
-		path := jsPropertyPath(root, name)
-		if desc := sanitizeJSDocText(schemaDescription(propertySchema)); desc != "" {
-			lines = append(lines, fmt.Sprintf("%s: %s", path, desc))
-		}
-		lines = append(lines, schemaFieldDescriptionsForProperty(path, propertySchema)...)
-	}
-	return lines
+func Search(items []ToolInfo, query string, limit int) []ToolInfo {
+	return NewBM25Ranker(items).Rank(query, limit)
 }
 
-func schemaFieldDescriptionsForProperty(path string, schema map[string]any) []string {
-	if len(schema) == 0 {
-		return nil
+func HelperDefinitions(items []ToolInfo) string {
+	var builder strings.Builder
+	if len(items) == 0 {
+		builder.WriteString("// No helper functions matched this search.\n")
+		return builder.String()
 	}
 
-	typeName, _ := schema["type"].(string)
-	switch typeName {
-	case "object":
-		return schemaFieldDescriptions(path, schema)
-	case "array":
-		itemSchema, _ := schema["items"].(map[string]any)
-		itemPath := path + "[]"
-		lines := make([]string, 0, 1)
-		if desc := sanitizeJSDocText(schemaDescription(itemSchema)); desc != "" {
-			lines = append(lines, fmt.Sprintf("%s: %s", itemPath, desc))
-		}
-		return append(lines, schemaFieldDescriptionsForProperty(itemPath, itemSchema)...)
-	default:
-		return nil
-	}
+	builder.WriteString(TypeScriptDeclarations(items))
+	return builder.String()
 }
 
 func schemaDescription(schema map[string]any) string {
@@ -211,73 +141,6 @@ func searchTerms(query string) []string {
 	return terms
 }
 
-func expandSearchTerms(terms []string) []string {
-	if len(terms) == 0 {
-		return nil
-	}
-	seen := make(map[string]struct{}, len(terms)+8)
-	expanded := make([]string, 0, len(terms)+8)
-	for _, term := range terms {
-		if term == "" {
-			continue
-		}
-		if _, ok := seen[term]; ok {
-			continue
-		}
-		seen[term] = struct{}{}
-		expanded = append(expanded, term)
-	}
-
-	if containsAnyTerm(seen, "shipping", "shipment", "parcel", "carrier", "carriers", "rate", "rates", "quote", "quotes") {
-		for _, related := range []string{"carrier", "quote", "delivery", "surcharge", "shipping"} {
-			if _, ok := seen[related]; ok {
-				continue
-			}
-			seen[related] = struct{}{}
-			expanded = append(expanded, related)
-		}
-	}
-
-	return expanded
-}
-
-func containsAnyTerm(terms map[string]struct{}, candidates ...string) bool {
-	for _, candidate := range candidates {
-		if _, ok := terms[candidate]; ok {
-			return true
-		}
-	}
-	return false
-}
-
-func searchScore(item ToolInfo, terms []string) int {
-	if len(terms) == 0 {
-		return 0
-	}
-	name := strings.ToLower(item.Name)
-	callable := strings.ToLower(item.Callable)
-	description := strings.ToLower(item.Description)
-	schema := strings.ToLower(compactSchema(item.InputSchema))
-	server := strings.ToLower(item.Server)
-
-	score := 0
-	for _, term := range terms {
-		switch {
-		case strings.Contains(callable, term):
-			score += 6
-		case strings.Contains(name, term):
-			score += 5
-		case strings.Contains(description, term):
-			score += 3
-		case strings.Contains(server, term):
-			score += 2
-		case strings.Contains(schema, strconv.Quote(term)) || strings.Contains(schema, term):
-			score += 1
-		}
-	}
-	return score
-}
-
 func schemaToMap(input any) map[string]any {
 	if input == nil {
 		return nil
@@ -293,113 +156,6 @@ func schemaToMap(input any) map[string]any {
 	return out
 }
 
-func compactSchema(schema map[string]any) string {
-	if len(schema) == 0 {
-		return ""
-	}
-	b, err := json.Marshal(schema)
-	if err != nil {
-		return ""
-	}
-	return string(b)
-}
-
-func jsDocObjectType(schema map[string]any) string {
-	if len(schema) == 0 {
-		return "{Record<string, any>}"
-	}
-	return "{" + jsDocType(schema) + "}"
-}
-
-func jsDocObjectLiteral(schema map[string]any) string {
-	if len(schema) == 0 {
-		return "Record<string, any>"
-	}
-	properties, _ := schema["properties"].(map[string]any)
-	if len(properties) == 0 {
-		return "Record<string, any>"
-	}
-
-	required := map[string]struct{}{}
-	if values, ok := schema["required"].([]any); ok {
-		for _, value := range values {
-			if name, ok := value.(string); ok {
-				required[name] = struct{}{}
-			}
-		}
-	}
-
-	names := make([]string, 0, len(properties))
-	for name := range properties {
-		names = append(names, name)
-	}
-	slices.Sort(names)
-
-	parts := make([]string, 0, len(names))
-	for _, name := range names {
-		propertySchema, _ := properties[name].(map[string]any)
-		optional := ""
-		if _, ok := required[name]; !ok {
-			optional = "?"
-		}
-		parts = append(parts, fmt.Sprintf("%s%s: %s", jsPropertyName(name), optional, jsDocType(propertySchema)))
-	}
-	return "{ " + strings.Join(parts, ", ") + " }"
-}
-
-func jsDocType(schema map[string]any) string {
-	if len(schema) == 0 {
-		return "any"
-	}
-	if enumValues, ok := schema["enum"].([]any); ok && len(enumValues) > 0 {
-		literals := make([]string, 0, len(enumValues))
-		for _, value := range enumValues {
-			switch value := value.(type) {
-			case string:
-				literals = append(literals, fmt.Sprintf("%q", value))
-			case float64:
-				literals = append(literals, strings.TrimSuffix(strings.TrimSuffix(fmt.Sprintf("%f", value), "0"), "."))
-			case bool:
-				literals = append(literals, fmt.Sprintf("%t", value))
-			default:
-				literals = append(literals, "any")
-			}
-		}
-		return strings.Join(literals, " | ")
-	}
-
-	if unionTypes, ok := schema["type"].([]any); ok && len(unionTypes) > 0 {
-		parts := make([]string, 0, len(unionTypes))
-		for _, value := range unionTypes {
-			if name, ok := value.(string); ok {
-				parts = append(parts, jsDocType(map[string]any{"type": name}))
-			}
-		}
-		if len(parts) > 0 {
-			return strings.Join(parts, " | ")
-		}
-	}
-
-	typeName, _ := schema["type"].(string)
-	switch typeName {
-	case "string":
-		return "string"
-	case "integer", "number":
-		return "number"
-	case "boolean":
-		return "boolean"
-	case "null":
-		return "null"
-	case "array":
-		itemSchema, _ := schema["items"].(map[string]any)
-		return "Array<" + jsDocType(itemSchema) + ">"
-	case "object":
-		return jsDocObjectLiteral(schema)
-	default:
-		return "any"
-	}
-}
-
 func jsPropertyName(name string) string {
 	if isValidJSIdentifier(name) {
 		return name
@@ -407,16 +163,6 @@ func jsPropertyName(name string) string {
 	return fmt.Sprintf("%q", name)
 }
 
-func jsPropertyPath(root string, name string) string {
-	if root == "" {
-		return jsPropertyName(name)
-	}
-	if isValidJSIdentifier(name) {
-		return root + "." + name
-	}
-	return fmt.Sprintf("%s[%q]", root, name)
-}
-
 func isValidJSIdentifier(name string) bool {
 	if name == "" {
 		return false
@@ -0,0 +1,125 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Vectors is a precomputed embedding file. Terms holds word vectors keyed by
+// lowercase singular tokens and is used to embed queries locally. Tools may hold
+// a vector per helper callable; helpers without one are embedded from the terms
+// of their name, description and schema.
+type Vectors struct {
+	Model      string               `json:"model,omitempty"`
+	Dimensions int                  `json:"dimensions"`
+	Terms      map[string][]float64 `json:"terms"`
+	Tools      map[string][]float64 `json:"tools,omitempty"`
+}
+
+func LoadVectors(path string) (*Vectors, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read vector file: %w", err)
+	}
+	var vectors Vectors
+	if err := json.Unmarshal(b, &vectors); err != nil {
+		return nil, fmt.Errorf("parse vector file %s: %w", path, err)
+	}
+	if vectors.Dimensions <= 0 {
+		return nil, fmt.Errorf("vector file %s: dimensions must be positive", path)
+	}
+	for term, vector := range vectors.Terms {
+		if len(vector) != vectors.Dimensions {
+			return nil, fmt.Errorf("vector file %s: term %q has %d dimensions, want %d", path, term, len(vector), vectors.Dimensions)
+		}
+	}
+	for callable, vector := range vectors.Tools {
+		if len(vector) != vectors.Dimensions {
+			return nil, fmt.Errorf("vector file %s: tool %q has %d dimensions, want %d", path, callable, len(vector), vectors.Dimensions)
+		}
+	}
+	return &vectors, nil
+}
+
+func (v *Vectors) embed(tokens []string) []float64 {
+	sum := make([]float64, v.Dimensions)
+	found := 0
+	for _, token := range tokens {
+		vector, ok := v.Terms[token]
+		if !ok {
+			continue
+		}
+		for i, value := range vector {
+			sum[i] += value
+		}
+		found++
+	}
+	if found == 0 {
+		return nil
+	}
+	return normalize(sum)
+}
+
+type EmbeddingRanker struct {
+	items   []ToolInfo
+	vectors *Vectors
+	docs    [][]float64
+}
+
+func NewEmbeddingRanker(items []ToolInfo, vectors *Vectors) (*EmbeddingRanker, error) {
+	r := &EmbeddingRanker{items: items, vectors: vectors, docs: make([][]float64, len(items))}
+	for i, item := range items {
+		if vector, ok := vectors.Tools[item.Callable]; ok {
+			r.docs[i] = normalize(append([]float64(nil), vector...))
+			continue
+		}
+		var tokens []string
+		for _, field := range toolFields(item) {
+			for _, token := range tokenize(field.text) {
+				for range field.weight {
+					tokens = append(tokens, token)
+				}
+			}
+		}
+		r.docs[i] = vectors.embed(tokens)
+	}
+	return r, nil
+}
+
+func (r *EmbeddingRanker) Rank(query string, limit int) []ToolInfo {
+	queryVector := r.vectors.embed(queryTerms(query))
+	if queryVector == nil {
+		return firstTools(r.items, limit)
+	}
+	scores := make([]scoredTool, 0, len(r.items))
+	for i, doc := range r.docs {
+		if doc == nil {
+			continue
+		}
+		if score := dot(queryVector, doc); score > 0 {
+			scores = append(scores, scoredTool{index: i, score: score})
+		}
+	}
+	return topTools(r.items, scores, limit)
+}
+
+func normalize(vector []float64) []float64 {
+	norm := math.Sqrt(dot(vector, vector))
+	if norm == 0 {
+		return nil
+	}
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
+
+func dot(a, b []float64) float64 {
+	sum := 0.0
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,71 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+)
+
+type SearchCase struct {
+	Query    string   `json:"query"`
+	Expected []string `json:"expected"`
+}
+
+type SearchCorpus struct {
+	Tools []ToolInfo   `json:"tools"`
+	Cases []SearchCase `json:"cases"`
+}
+
+type CaseResult struct {
+	Case     SearchCase
+	Found    []string
+	Missing  []string
+	Returned []string
+}
+
+func LoadSearchCorpus(path string) (SearchCorpus, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return SearchCorpus{}, fmt.Errorf("read search corpus: %w", err)
+	}
+	var corpus SearchCorpus
+	if err := json.Unmarshal(b, &corpus); err != nil {
+		return SearchCorpus{}, fmt.Errorf("parse search corpus %s: %w", path, err)
+	}
+	for i, tool := range corpus.Tools {
+		if tool.Callable == "" {
+			corpus.Tools[i].Callable = callableName(tool.Server, tool.Name)
+		}
+	}
+	slices.SortFunc(corpus.Tools, compareToolInfo)
+	return corpus, nil
+}
+
+// RecallAtK returns the mean fraction of expected callables found in the top k
+// results of each case, together with the per-case outcome.
+func RecallAtK(ranker Ranker, cases []SearchCase, k int) (float64, []CaseResult) {
+	if len(cases) == 0 {
+		return 0, nil
+	}
+	total := 0.0
+	results := make([]CaseResult, 0, len(cases))
+	for _, searchCase := range cases {
+		result := CaseResult{Case: searchCase}
+		for _, item := range ranker.Rank(searchCase.Query, k) {
+			result.Returned = append(result.Returned, item.Callable)
+		}
+		for _, expected := range searchCase.Expected {
+			if slices.Contains(result.Returned, expected) {
+				result.Found = append(result.Found, expected)
+			} else {
+				result.Missing = append(result.Missing, expected)
+			}
+		}
+		if len(searchCase.Expected) > 0 {
+			total += float64(len(result.Found)) / float64(len(searchCase.Expected))
+		}
+		results = append(results, result)
+	}
+	return total / float64(len(cases)), results
+}
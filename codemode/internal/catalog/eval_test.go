@@ -0,0 +1,145 @@
+package catalog
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// The searcheval corpus, shared with the command so that a ranking change that
+// lowers recall fails here before anyone reruns the command.
+const corpusPath = "../../cmd/searcheval/corpus.json"
+
+func loadCorpus(t *testing.T) SearchCorpus {
+	t.Helper()
+	corpus, err := LoadSearchCorpus(corpusPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return corpus
+}
+
+func loadVectors(t *testing.T) *Vectors {
+	t.Helper()
+	vectors, err := LoadVectors("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vectors
+}
+
+// checkRecall fails the test when the recall at k of ranker on cases is below
+// min and lists the cases that missed.
+func checkRecall(t *testing.T, ranker Ranker, cases []SearchCase, k int, min float64) {
+	t.Helper()
+	recall, results := RecallAtK(ranker, cases, k)
+	if recall >= min {
+		return
+	}
+	t.Errorf("recall@%d = %.3f, want at least %.3f", k, recall, min)
+	for _, result := range results {
+		if len(result.Missing) > 0 {
+			t.Logf("  %q missing=%s got=%s", result.Case.Query, strings.Join(result.Missing, ","), strings.Join(result.Returned, ","))
+		}
+	}
+}
+
+func TestBM25Recall(t *testing.T) {
+	corpus := loadCorpus(t)
+	if len(corpus.Tools) < 50 || len(corpus.Cases) < 30 {
+		t.Fatalf("corpus has %d tools and %d queries", len(corpus.Tools), len(corpus.Cases))
+	}
+	ranker := NewBM25Ranker(corpus.Tools)
+
+	checkRecall(t, ranker, corpus.Cases, 1, 0.65)
+	checkRecall(t, ranker, corpus.Cases, 5, 0.80)
+}
+
+// embeddingCases mostly use words that the helpers they expect never
+// mention. BM25 misses half of them; the fixture vectors relate the words.
+var embeddingCases = []SearchCase{
+	{Query: "what does the clock say in Sydney", Expected: []string{"demo_city_time"}},
+	{Query: "courier price for a parcel", Expected: []string{"demo_quote_rate"}},
+	{Query: "report a bug", Expected: []string{"github_create_issue"}},
+	{Query: "do I need an umbrella tomorrow", Expected: []string{"weather_forecast"}},
+	{Query: "reimburse the customer", Expected: []string{"payments_refund_payment"}},
+	{Query: "drop bob a message", Expected: []string{"mail_send_email"}},
+}
+
+func embeddingTools(t *testing.T) []ToolInfo {
+	t.Helper()
+	var tools []ToolInfo
+	for _, item := range loadCorpus(t).Tools {
+		for _, c := range embeddingCases {
+			if slices.Contains(c.Expected, item.Callable) {
+				tools = append(tools, item)
+			}
+		}
+	}
+	if len(tools) != len(embeddingCases) {
+		t.Fatalf("found %d of the %d expected helpers in the corpus", len(tools), len(embeddingCases))
+	}
+	return tools
+}
+
+func TestEmbeddingRecall(t *testing.T) {
+	tools, vectors := embeddingTools(t), loadVectors(t)
+	if recall, _ := RecallAtK(NewBM25Ranker(tools), embeddingCases, 5); recall == 1 {
+		t.Fatal("BM25 finds every case, the fixture does not test the vectors")
+	}
+
+	tests := []struct {
+		kind RankerKind
+		k    int
+		min  float64
+	}{
+		{kind: RankerEmbedding, k: 1, min: 1},
+		{kind: RankerHybrid, k: 3, min: 1},
+	}
+	for _, test := range tests {
+		t.Run(string(test.kind), func(t *testing.T) {
+			ranker, err := NewRanker(test.kind, tools, vectors)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkRecall(t, ranker, embeddingCases, test.k, test.min)
+		})
+	}
+}
+
+func TestEmbeddingRankerUnknownTerms(t *testing.T) {
+	tools := embeddingTools(t)
+	ranker, err := NewEmbeddingRanker(tools, loadVectors(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without a known term the ranker falls back to the catalog order.
+	got := ranker.Rank("zxcv qwerty", 2)
+	if len(got) != 2 || got[0].Callable != tools[0].Callable || got[1].Callable != tools[1].Callable {
+		t.Errorf("Rank() = %v, want the first two helpers", got)
+	}
+}
+
+func TestNewRankerRequiresVectors(t *testing.T) {
+	for _, kind := range []RankerKind{RankerEmbedding, RankerHybrid} {
+		if _, err := NewRanker(kind, nil, nil); err == nil || !strings.Contains(err.Error(), "requires a vector file") {
+			t.Errorf("NewRanker(%s) error = %v", kind, err)
+		}
+	}
+	if _, err := NewRanker("fuzzy", nil, nil); err == nil {
+		t.Error("NewRanker(fuzzy) succeeded")
+	}
+}
+
+func TestBM25RankerMatchesOnlyQueryTerms(t *testing.T) {
+	tools := []ToolInfo{
+		{Server: "shipping", Name: "quote_rate", Callable: "shipping_quote_rate", Description: "Quote a carrier rate for a parcel."},
+		{Server: "shipping", Name: "apply_surcharge", Callable: "shipping_apply_surcharge", Description: "Add a fuel surcharge."},
+	}
+	// A query about one shipping helper does not pull in the others through
+	// domain synonyms.
+	got := NewBM25Ranker(tools).Rank("parcel", 5)
+	if len(got) != 1 || got[0].Callable != "shipping_quote_rate" {
+		t.Errorf("Rank() = %v, want only shipping_quote_rate", got)
+	}
+}
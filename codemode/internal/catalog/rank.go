@@ -0,0 +1,291 @@
+package catalog
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+type Ranker interface {
+	Rank(query string, limit int) []ToolInfo
+}
+
+type RankerKind string
+
+const (
+	RankerBM25      RankerKind = "bm25"
+	RankerEmbedding RankerKind = "embedding"
+	RankerHybrid    RankerKind = "hybrid"
+)
+
+func NewRanker(kind RankerKind, items []ToolInfo, vectors *Vectors) (Ranker, error) {
+	switch kind {
+	case "", RankerBM25:
+		return NewBM25Ranker(items), nil
+	case RankerEmbedding:
+		if vectors == nil {
+			return nil, fmt.Errorf("%s ranker requires a vector file", kind)
+		}
+		return NewEmbeddingRanker(items, vectors)
+	case RankerHybrid:
+		if vectors == nil {
+			return nil, fmt.Errorf("%s ranker requires a vector file", kind)
+		}
+		embedding, err := NewEmbeddingRanker(items, vectors)
+		if err != nil {
+			return nil, err
+		}
+		return NewFusionRanker(items, NewBM25Ranker(items), embedding), nil
+	default:
+		return nil, fmt.Errorf("unknown search ranker %q", kind)
+	}
+}
+
+type scoredTool struct {
+	index int
+	score float64
+}
+
+func topTools(items []ToolInfo, scores []scoredTool, limit int) []ToolInfo {
+	if limit <= 0 {
+		limit = 8
+	}
+	slices.SortFunc(scores, func(a, b scoredTool) int {
+		return cmp.Or(
+			cmp.Compare(b.score, a.score),
+			compareToolInfo(items[a.index], items[b.index]),
+		)
+	})
+	if len(scores) > limit {
+		scores = scores[:limit]
+	}
+	results := make([]ToolInfo, 0, len(scores))
+	for _, scored := range scores {
+		results = append(results, items[scored.index])
+	}
+	return results
+}
+
+func firstTools(items []ToolInfo, limit int) []ToolInfo {
+	if limit <= 0 {
+		limit = 8
+	}
+	if len(items) <= limit {
+		return append([]ToolInfo(nil), items...)
+	}
+	return append([]ToolInfo(nil), items[:limit]...)
+}
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	nameFieldWeight        = 3
+	descriptionFieldWeight = 2
+	schemaFieldWeight      = 1
+)
+
+type BM25Ranker struct {
+	items        []ToolInfo
+	termFreqs    []map[string]int
+	docLengths   []int
+	docFreqs     map[string]int
+	avgDocLength float64
+}
+
+func NewBM25Ranker(items []ToolInfo) *BM25Ranker {
+	r := &BM25Ranker{
+		items:      items,
+		termFreqs:  make([]map[string]int, len(items)),
+		docLengths: make([]int, len(items)),
+		docFreqs:   map[string]int{},
+	}
+	total := 0
+	for i, item := range items {
+		freqs := map[string]int{}
+		for _, field := range toolFields(item) {
+			for _, token := range tokenize(field.text) {
+				freqs[token] += field.weight
+				r.docLengths[i] += field.weight
+			}
+		}
+		for token := range freqs {
+			r.docFreqs[token]++
+		}
+		r.termFreqs[i] = freqs
+		total += r.docLengths[i]
+	}
+	if len(items) > 0 {
+		r.avgDocLength = float64(total) / float64(len(items))
+	}
+	return r
+}
+
+func (r *BM25Ranker) Rank(query string, limit int) []ToolInfo {
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return firstTools(r.items, limit)
+	}
+	scores := make([]scoredTool, 0, len(r.items))
+	for i := range r.items {
+		if score := r.score(i, terms); score > 0 {
+			scores = append(scores, scoredTool{index: i, score: score})
+		}
+	}
+	return topTools(r.items, scores, limit)
+}
+
+func (r *BM25Ranker) score(doc int, terms []string) float64 {
+	n := float64(len(r.items))
+	score := 0.0
+	for _, term := range terms {
+		tf := float64(r.termFreqs[doc][term])
+		if tf == 0 {
+			continue
+		}
+		df := float64(r.docFreqs[term])
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		norm := 1 - bm25B + bm25B*float64(r.docLengths[doc])/r.avgDocLength
+		score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+	}
+	return score
+}
+
+type FusionRanker struct {
+	items   []ToolInfo
+	rankers []Ranker
+}
+
+// NewFusionRanker merges the rankings of several rankers with reciprocal rank fusion.
+func NewFusionRanker(items []ToolInfo, rankers ...Ranker) *FusionRanker {
+	return &FusionRanker{items: items, rankers: rankers}
+}
+
+func (r *FusionRanker) Rank(query string, limit int) []ToolInfo {
+	const rrfK = 60
+	if limit <= 0 {
+		limit = 8
+	}
+	positions := make(map[string]int, len(r.items))
+	for i, item := range r.items {
+		positions[item.Callable] = i
+	}
+	fused := map[int]float64{}
+	for _, ranker := range r.rankers {
+		for rank, item := range ranker.Rank(query, len(r.items)) {
+			fused[positions[item.Callable]] += 1.0 / float64(rrfK+rank+1)
+		}
+	}
+	if len(fused) == 0 {
+		return firstTools(r.items, limit)
+	}
+	scores := make([]scoredTool, 0, len(fused))
+	for index, score := range fused {
+		scores = append(scores, scoredTool{index: index, score: score})
+	}
+	return topTools(r.items, scores, limit)
+}
+
+type toolField struct {
+	text   string
+	weight int
+}
+
+func toolFields(item ToolInfo) []toolField {
+	fields := []toolField{
+		{text: item.Name, weight: nameFieldWeight},
+		{text: item.Callable, weight: nameFieldWeight},
+		{text: item.Description, weight: descriptionFieldWeight},
+		{text: item.Server, weight: schemaFieldWeight},
+	}
+	for _, text := range schemaTexts(item.InputSchema) {
+		fields = append(fields, toolField{text: text, weight: schemaFieldWeight})
+	}
+	return fields
+}
+
+func schemaTexts(schema map[string]any) []string {
+	if len(schema) == 0 {
+		return nil
+	}
+	var texts []string
+	if desc := schemaDescription(schema); desc != "" {
+		texts = append(texts, desc)
+	}
+	if values, ok := schema["enum"].([]any); ok {
+		for _, value := range values {
+			if text, ok := value.(string); ok {
+				texts = append(texts, text)
+			}
+		}
+	}
+	properties, _ := schema["properties"].(map[string]any)
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		texts = append(texts, name)
+		propertySchema, _ := properties[name].(map[string]any)
+		texts = append(texts, schemaTexts(propertySchema)...)
+	}
+	if itemSchema, ok := schema["items"].(map[string]any); ok {
+		texts = append(texts, schemaTexts(itemSchema)...)
+	}
+	return texts
+}
+
+func queryTerms(query string) []string {
+	terms := searchTerms(query)
+	tokens := make([]string, 0, len(terms))
+	seen := make(map[string]struct{}, len(terms))
+	for _, term := range terms {
+		for _, token := range tokenize(term) {
+			if _, ok := seen[token]; ok {
+				continue
+			}
+			seen[token] = struct{}{}
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	tokens := make([]string, 0, len(words))
+	for _, word := range words {
+		parts := strings.FieldsFunc(word, func(r rune) bool { return r == '_' })
+		for _, part := range parts {
+			if _, ok := stopWords[part]; ok {
+				continue
+			}
+			tokens = append(tokens, stem(part))
+		}
+	}
+	return tokens
+}
+
+func stem(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us"):
+		return strings.TrimSuffix(word, "s")
+	default:
+		return word
+	}
+}
+
+var stopWords = map[string]struct{}{
+	"a": {}, "an": {}, "and": {}, "are": {}, "as": {}, "at": {}, "be": {}, "by": {}, "for": {},
+	"from": {}, "get": {}, "how": {}, "i": {}, "in": {}, "is": {}, "it": {}, "me": {}, "my": {},
+	"of": {}, "on": {}, "or": {}, "such": {}, "that": {}, "the": {}, "this": {}, "to": {},
+	"what": {}, "which": {}, "with": {},
+}
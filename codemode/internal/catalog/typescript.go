@@ -0,0 +1,432 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TypeScriptDeclarations renders the helpers as a TypeScript declaration file:
+// an input and output type per helper, one type per $defs entry of its
+// schemas, and a declare function returning a Promise of the output type.
+func TypeScriptDeclarations(items []ToolInfo) string {
+	var builder strings.Builder
+	for i, item := range items {
+		if i > 0 {
+			builder.WriteString("\n")
+		}
+		writeHelperDeclaration(&builder, item)
+	}
+	return builder.String()
+}
+
+func writeHelperDeclaration(builder *strings.Builder, item ToolInfo) {
+	prefix := pascalCase(item.Callable)
+	inputName, outputName := prefix+"Input", prefix+"Output"
+
+	// The $defs of each schema are named after its root type, so an input
+	// and an output definition of the same name, or one called Input or
+	// Output, do not collide.
+	input := newTSSchema(item.InputSchema, inputName, inputName)
+	input.writeDefinitions(builder)
+	input.writeRoot(builder, inputName, "Record<string, any>")
+
+	output := newTSSchema(item.OutputSchema, outputName, outputName)
+	output.writeDefinitions(builder)
+	output.writeRoot(builder, outputName, "any")
+
+	writeDocComment(builder, "", item.Description, fmt.Sprintf("MCP tool %s on server %s.", item.Name, item.Server))
+	fmt.Fprintf(builder, "declare function %s(args: %s): Promise<%s>;\n", item.Callable, inputName, outputName)
+}
+
+// tsSchema converts one tool schema. Local references (#, #/$defs/Name and
+// #/definitions/Name) become named types; anything else degrades to any.
+type tsSchema struct {
+	root     map[string]any
+	rootName string
+	defs     map[string]map[string]any
+	defNames map[string]string
+}
+
+func newTSSchema(root map[string]any, rootName, defPrefix string) *tsSchema {
+	s := &tsSchema{root: root, rootName: rootName, defs: map[string]map[string]any{}, defNames: map[string]string{}}
+	// Names like user-id and user_id, or the same name in $defs and
+	// definitions, would declare one type twice.
+	taken := map[string]bool{rootName: true}
+	for _, key := range []string{"$defs", "definitions"} {
+		defs, _ := root[key].(map[string]any)
+		for _, name := range slices.Sorted(maps.Keys(defs)) {
+			schema, _ := defs[name].(map[string]any)
+			ref := "#/" + key + "/" + name
+			typeName := defPrefix + pascalCase(name)
+			for i := 2; taken[typeName]; i++ {
+				typeName = defPrefix + pascalCase(name) + strconv.Itoa(i)
+			}
+			taken[typeName] = true
+			s.defs[ref] = schema
+			s.defNames[ref] = typeName
+		}
+	}
+	return s
+}
+
+func (s *tsSchema) writeDefinitions(builder *strings.Builder) {
+	refs := make([]string, 0, len(s.defs))
+	for ref := range s.defs {
+		refs = append(refs, ref)
+	}
+	slices.Sort(refs)
+	for _, ref := range refs {
+		s.writeNamed(builder, s.defNames[ref], s.defs[ref], "any")
+	}
+}
+
+func (s *tsSchema) writeRoot(builder *strings.Builder, name, fallback string) {
+	s.writeNamed(builder, name, s.root, fallback)
+}
+
+func (s *tsSchema) writeNamed(builder *strings.Builder, name string, schema map[string]any, fallback string) {
+	writeDocComment(builder, "", schemaDescription(schema))
+	if isPlainObjectSchema(schema) {
+		fmt.Fprintf(builder, "interface %s %s\n\n", name, s.objectLiteral(schema, ""))
+		return
+	}
+	typ := fallback
+	if len(schema) > 0 {
+		typ = s.typeOf(schema, "")
+	}
+	fmt.Fprintf(builder, "type %s = %s;\n\n", name, typ)
+}
+
+func (s *tsSchema) typeOf(schema map[string]any, indent string) string {
+	if len(schema) == 0 {
+		return "any"
+	}
+	typ := s.baseType(schema, indent)
+	if nullable, _ := schema["nullable"].(bool); nullable && !strings.HasSuffix(typ, "| null") && typ != "null" && typ != "any" {
+		typ += " | null"
+	}
+	return typ
+}
+
+func (s *tsSchema) baseType(schema map[string]any, indent string) string {
+	if ref, ok := schema["$ref"].(string); ok {
+		return s.refType(ref)
+	}
+	if value, ok := schema["const"]; ok {
+		return tsLiteral(value)
+	}
+	if values, ok := schema["enum"].([]any); ok && len(values) > 0 {
+		literals := make([]string, 0, len(values))
+		for _, value := range values {
+			literals = appendUnique(literals, tsLiteral(value))
+		}
+		return strings.Join(literals, " | ")
+	}
+
+	var parts []string
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if variants := schemaList(schema[key]); len(variants) > 0 {
+			members := make([]string, 0, len(variants))
+			for _, variant := range variants {
+				members = appendUnique(members, s.typeOf(variant, indent))
+			}
+			parts = append(parts, strings.Join(members, " | "))
+		}
+	}
+	for _, variant := range schemaList(schema["allOf"]) {
+		parts = append(parts, s.typeOf(variant, indent))
+	}
+	if hasOwnType(schema) {
+		// A bare "type": "object" next to combinators adds nothing to the union.
+		if own := s.ownType(schema, indent); len(parts) == 0 || (own != "any" && own != "Record<string, any>") {
+			parts = append([]string{own}, parts...)
+		}
+	}
+	switch len(parts) {
+	case 0:
+		return "any"
+	case 1:
+		return parts[0]
+	}
+	for i, part := range parts {
+		parts[i] = parenthesize(part, strings.Contains(part, " | "))
+	}
+	return strings.Join(parts, " & ")
+}
+
+// ownType renders the schema's type keyword, ignoring combinators.
+func (s *tsSchema) ownType(schema map[string]any, indent string) string {
+	var names []string
+	switch typ := schema["type"].(type) {
+	case string:
+		names = []string{typ}
+	case []any:
+		for _, value := range typ {
+			if name, ok := value.(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		switch {
+		case schema["properties"] != nil || schema["additionalProperties"] != nil:
+			names = []string{"object"}
+		case schema["items"] != nil || schema["prefixItems"] != nil:
+			names = []string{"array"}
+		}
+	}
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		var part string
+		switch name {
+		case "string":
+			part = "string"
+		case "integer", "number":
+			part = "number"
+		case "boolean":
+			part = "boolean"
+		case "null":
+			part = "null"
+		case "array":
+			part = s.arrayType(schema, indent)
+		case "object":
+			part = s.objectLiteral(schema, indent)
+		default:
+			part = "any"
+		}
+		parts = appendUnique(parts, part)
+	}
+	if len(parts) == 0 {
+		return "any"
+	}
+	// Go reflection schemas list "null" first; readers expect it last.
+	if i := slices.Index(parts, "null"); i >= 0 && len(parts) > 1 {
+		parts = append(slices.Delete(parts, i, i+1), "null")
+	}
+	return strings.Join(parts, " | ")
+}
+
+func (s *tsSchema) arrayType(schema map[string]any, indent string) string {
+	tuple := schemaList(schema["prefixItems"])
+	if len(tuple) == 0 {
+		tuple = schemaList(schema["items"])
+	}
+	if len(tuple) > 0 {
+		members := make([]string, 0, len(tuple))
+		for _, item := range tuple {
+			members = append(members, s.typeOf(item, indent))
+		}
+		return "[" + strings.Join(members, ", ") + "]"
+	}
+	items, _ := schema["items"].(map[string]any)
+	return "Array<" + s.typeOf(items, indent) + ">"
+}
+
+func (s *tsSchema) objectLiteral(schema map[string]any, indent string) string {
+	properties, _ := schema["properties"].(map[string]any)
+	additional := s.additionalPropertiesType(schema, indent)
+	if len(properties) == 0 {
+		if additional == "" {
+			return "Record<string, any>"
+		}
+		return "Record<string, " + additional + ">"
+	}
+
+	required := map[string]struct{}{}
+	for _, value := range schemaValues(schema["required"]) {
+		if name, ok := value.(string); ok {
+			required[name] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	inner := indent + "  "
+	// TypeScript requires every declared property to fit the index
+	// signature, so it also lists the property types that differ from the
+	// type of the additional properties, and undefined for optional ones
+	// under strictNullChecks.
+	index := []string{additional}
+	var hasOptional bool
+	var builder strings.Builder
+	builder.WriteString("{\n")
+	for _, name := range names {
+		propertySchema, _ := properties[name].(map[string]any)
+		writeDocComment(&builder, inner, schemaDescription(propertySchema), schemaAnnotations(propertySchema)...)
+		optional := "?"
+		if _, ok := required[name]; ok {
+			optional = ""
+		} else {
+			hasOptional = true
+		}
+		typ := s.typeOf(propertySchema, inner)
+		index = appendUnique(index, typ)
+		fmt.Fprintf(&builder, "%s%s%s: %s;\n", inner, jsPropertyName(name), optional, typ)
+	}
+	if additional != "" {
+		if additional == "any" {
+			index = index[:1]
+		} else if hasOptional {
+			index = appendUnique(index, "undefined")
+		}
+		fmt.Fprintf(&builder, "%s[key: string]: %s;\n", inner, strings.Join(index, " | "))
+	}
+	builder.WriteString(indent + "}")
+	return builder.String()
+}
+
+// additionalPropertiesType returns the value type allowed for undeclared keys,
+// or "" when the schema does not say anything explicit about them.
+func (s *tsSchema) additionalPropertiesType(schema map[string]any, indent string) string {
+	switch additional := schema["additionalProperties"].(type) {
+	case bool:
+		if additional {
+			return "any"
+		}
+	case map[string]any:
+		return s.typeOf(additional, indent)
+	}
+	return ""
+}
+
+func (s *tsSchema) refType(ref string) string {
+	if ref == "#" {
+		return s.rootName
+	}
+	if name, ok := s.defNames[ref]; ok {
+		return name
+	}
+	return "any"
+}
+
+func isPlainObjectSchema(schema map[string]any) bool {
+	if typ, _ := schema["type"].(string); typ != "object" {
+		return false
+	}
+	properties, _ := schema["properties"].(map[string]any)
+	if len(properties) == 0 {
+		return false
+	}
+	for _, key := range []string{"$ref", "oneOf", "anyOf", "allOf", "enum", "const"} {
+		if _, ok := schema[key]; ok {
+			return false
+		}
+	}
+	nullable, _ := schema["nullable"].(bool)
+	return !nullable
+}
+
+func hasOwnType(schema map[string]any) bool {
+	for _, key := range []string{"type", "properties", "additionalProperties", "items", "prefixItems"} {
+		if _, ok := schema[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func schemaAnnotations(schema map[string]any) []string {
+	var notes []string
+	if format, ok := schema["format"].(string); ok && format != "" {
+		notes = append(notes, "@format "+format)
+	}
+	if value, ok := schema["default"]; ok {
+		notes = append(notes, "@default "+tsLiteral(value))
+	}
+	return notes
+}
+
+func writeDocComment(builder *strings.Builder, indent, description string, notes ...string) {
+	var lines []string
+	if description = sanitizeJSDocText(description); description != "" {
+		lines = append(lines, description)
+	}
+	for _, note := range notes {
+		lines = append(lines, sanitizeJSDocText(note))
+	}
+	switch len(lines) {
+	case 0:
+		return
+	case 1:
+		fmt.Fprintf(builder, "%s/** %s */\n", indent, lines[0])
+	default:
+		fmt.Fprintf(builder, "%s/**\n", indent)
+		for _, line := range lines {
+			fmt.Fprintf(builder, "%s * %s\n", indent, line)
+		}
+		fmt.Fprintf(builder, "%s */\n", indent)
+	}
+}
+
+func tsLiteral(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case string, bool:
+		b, _ := json.Marshal(value)
+		return string(b)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	default:
+		return "any"
+	}
+}
+
+func schemaList(value any) []map[string]any {
+	values, ok := value.([]any)
+	if !ok {
+		return nil
+	}
+	schemas := make([]map[string]any, 0, len(values))
+	for _, value := range values {
+		if schema, ok := value.(map[string]any); ok {
+			schemas = append(schemas, schema)
+		} else if value == true {
+			schemas = append(schemas, map[string]any{})
+		}
+	}
+	return schemas
+}
+
+func schemaValues(value any) []any {
+	values, _ := value.([]any)
+	return values
+}
+
+func appendUnique(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+func parenthesize(typ string, wrap bool) string {
+	if !wrap {
+		return typ
+	}
+	return "(" + typ + ")"
+}
+
+func pascalCase(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var builder strings.Builder
+	for _, word := range words {
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		builder.WriteString(string(runes))
+	}
+	if builder.Len() == 0 || unicode.IsDigit([]rune(builder.String())[0]) {
+		return "T" + builder.String()
+	}
+	return builder.String()
+}
@@ -0,0 +1,224 @@
+package catalog
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestObjectTypes(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{
+			name:   "properties only",
+			schema: `{"type": "object", "properties": {"id": {"type": "string"}, "count": {"type": "integer"}}, "required": ["id"]}`,
+			want: `interface Input {
+  count?: number;
+  id: string;
+}`,
+		},
+		{
+			name:   "typed additional properties",
+			schema: `{"type": "object", "properties": {"a": {"type": "number"}}, "required": ["a"], "additionalProperties": {"type": "number"}}`,
+			want: `interface Input {
+  a: number;
+  [key: string]: number;
+}`,
+		},
+		{
+			name:   "additional properties of another type",
+			schema: `{"type": "object", "properties": {"label": {"type": "string"}, "total": {"type": "number"}}, "additionalProperties": {"type": "number"}}`,
+			want: `interface Input {
+  label?: string;
+  total?: number;
+  [key: string]: number | string | undefined;
+}`,
+		},
+		{
+			name: "object additional properties",
+			schema: `{"type": "object", "properties": {"default": {"$ref": "#/$defs/limit"}},
+				"additionalProperties": {"$ref": "#/$defs/limit"},
+				"$defs": {"limit": {"type": "object", "properties": {"max": {"type": "integer"}}}}}`,
+			want: `interface Input {
+  default?: InputLimit;
+  [key: string]: InputLimit | undefined;
+}`,
+		},
+		{
+			name:   "any additional properties",
+			schema: `{"type": "object", "properties": {"id": {"type": "string"}}, "additionalProperties": true}`,
+			want: `interface Input {
+  id?: string;
+  [key: string]: any;
+}`,
+		},
+		{
+			name:   "no additional properties",
+			schema: `{"type": "object", "properties": {"id": {"type": "string"}}, "additionalProperties": false}`,
+			want: `interface Input {
+  id?: string;
+}`,
+		},
+		{
+			name:   "map",
+			schema: `{"type": "object", "additionalProperties": {"type": "array", "items": {"type": "string"}}}`,
+			want:   `type Input = Record<string, Array<string>>;`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var builder strings.Builder
+			newTSSchema(decodeSchema(t, test.schema), "Input", "Input").writeRoot(&builder, "Input", "any")
+			if got := strings.TrimSpace(builder.String()); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSchemaTypes(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{
+			name: "$defs and definitions",
+			schema: `{"type": "object", "properties": {"origin": {"$ref": "#/$defs/address"}, "destination": {"$ref": "#/definitions/address"}}, "required": ["origin"],
+				"$defs": {"address": {"type": "object", "description": "A postal address.", "properties": {"zip": {"type": "string"}}, "required": ["zip"]}},
+				"definitions": {"address": {"type": "string"}}}`,
+			want: `/** A postal address. */
+interface InputAddress {
+  zip: string;
+}
+
+type InputAddress2 = string;
+
+interface Input {
+  destination?: InputAddress2;
+  origin: InputAddress;
+}`,
+		},
+		{
+			name:   "recursive and remote references",
+			schema: `{"type": "object", "properties": {"children": {"type": "array", "items": {"$ref": "#"}}, "parent": {"$ref": "https://example.com/node.json"}}}`,
+			want: `interface Input {
+  children?: Array<Input>;
+  parent?: any;
+}`,
+		},
+		{
+			name:   "oneOf",
+			schema: `{"oneOf": [{"type": "string"}, {"type": "number"}, {"type": "string"}]}`,
+			want:   `type Input = string | number;`,
+		},
+		{
+			name:   "anyOf with null",
+			schema: `{"anyOf": [{"$ref": "#/$defs/point"}, {"type": "null"}], "$defs": {"point": {"type": "array", "prefixItems": [{"type": "number"}, {"type": "number"}]}}}`,
+			want: `type InputPoint = [number, number];
+
+type Input = InputPoint | null;`,
+		},
+		{
+			name:   "object variants",
+			schema: `{"type": "object", "oneOf": [{"properties": {"id": {"type": "string"}}, "required": ["id"]}, {"properties": {"name": {"type": "string"}}, "required": ["name"]}]}`,
+			want: `type Input = {
+  id: string;
+} | {
+  name: string;
+};`,
+		},
+		{
+			name:   "allOf",
+			schema: `{"allOf": [{"$ref": "#/$defs/base"}, {"type": "object", "properties": {"extra": {"type": "boolean"}}}], "$defs": {"base": {"type": "object", "properties": {"id": {"type": "string"}}}}}`,
+			want: `interface InputBase {
+  id?: string;
+}
+
+type Input = InputBase & {
+  extra?: boolean;
+};`,
+		},
+		{
+			name:   "enum",
+			schema: `{"enum": ["ground", "air", null, 2, "air"]}`,
+			want:   `type Input = "ground" | "air" | null | 2;`,
+		},
+		{
+			name:   "const and nullable",
+			schema: `{"type": "object", "properties": {"mode": {"const": "express"}, "note": {"type": "string", "nullable": true}, "weight": {"type": ["null", "number"]}}, "required": ["mode"]}`,
+			want: `interface Input {
+  mode: "express";
+  note?: string | null;
+  weight?: number | null;
+}`,
+		},
+		{
+			name:   "array of objects",
+			schema: `{"type": "array", "items": {"type": "object", "properties": {"sku": {"type": "string", "description": "Stock keeping unit."}, "qty": {"type": "integer", "default": 1}}, "required": ["sku"]}}`,
+			want: `type Input = Array<{
+  /** @default 1 */
+  qty?: number;
+  /** Stock keeping unit. */
+  sku: string;
+}>;`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var builder strings.Builder
+			schema := newTSSchema(decodeSchema(t, test.schema), "Input", "Input")
+			schema.writeDefinitions(&builder)
+			schema.writeRoot(&builder, "Input", "any")
+			if got := strings.TrimSpace(builder.String()); got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTypeScriptDeclarations(t *testing.T) {
+	// Definitions named like the other schema's root type must not collide
+	// with it.
+	got := TypeScriptDeclarations([]ToolInfo{{
+		Server:       "shipping",
+		Name:         "quote_rate",
+		Callable:     "quoteRate",
+		Description:  "Quote a rate.",
+		InputSchema:  decodeSchema(t, `{"type": "object", "properties": {"item": {"$ref": "#/$defs/Output"}}, "$defs": {"Output": {"type": "string"}}}`),
+		OutputSchema: decodeSchema(t, `{"type": "object", "properties": {"rate": {"$ref": "#/$defs/Input"}}, "required": ["rate"], "$defs": {"Input": {"type": "number"}}}`),
+	}})
+	want := `type QuoteRateInputOutput = string;
+
+interface QuoteRateInput {
+  item?: QuoteRateInputOutput;
+}
+
+type QuoteRateOutputInput = number;
+
+interface QuoteRateOutput {
+  rate: QuoteRateOutputInput;
+}
+
+/**
+ * Quote a rate.
+ * MCP tool quote_rate on server shipping.
+ */
+declare function quoteRate(args: QuoteRateInput): Promise<QuoteRateOutput>;
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func decodeSchema(t *testing.T, data string) map[string]any {
+	t.Helper()
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(data), &schema); err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
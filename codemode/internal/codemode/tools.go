@@ -5,56 +5,113 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 
-	anthropic "github.com/anthropics/anthropic-sdk-go"
 	"github.com/preblog/codemode/internal/catalog"
+	"github.com/preblog/codemode/internal/policy"
+	"github.com/preblog/codemode/internal/provider"
 	"github.com/preblog/codemode/internal/sandbox"
 )
 
 type SearchInput struct {
-	Query string `json:"query"`
-	Limit int    `json:"limit,omitempty"`
+	Query string `json:"query" jsonschema:"A concise natural-language summary of the user's task or the helpers you need to find."`
+	Limit int    `json:"limit,omitempty" jsonschema:"Maximum results to return."`
 }
 
 type ExecuteInput struct {
-	Code string `json:"code"`
+	Code string `json:"code" jsonschema:"JavaScript body to execute inside an async IIFE. End by returning a value. Do not write comments."`
 }
 
+const (
+	SearchDescription  = "Call this to discover relevant helpers for the user's task. The result includes TypeScript declarations of the helpers you should call from plain JavaScript in execute(code)."
+	ExecuteDescription = "Execute JavaScript inside an async function. The sandbox supports ECMAScript 14 (ES2023) only and does not support any Web APIs. Every helper returned by search is async and returns a Promise, so await it, for example await demo_add_numbers({...}). Start independent helper calls together and await them with Promise.all so they run in parallel. Keep the code minimal and do not write comments."
+)
+
 type Toolset struct {
-	Catalog []catalog.ToolInfo
-	Sandbox *sandbox.Sandbox
-	Tracef  func(string, ...any)
+	Catalog    []catalog.ToolInfo
+	Ranker     catalog.Ranker
+	Sandbox    *sandbox.Sandbox
+	Persistent bool
+	Policy     *policy.Enforcer
+	Tracef     func(string, ...any)
+
+	// mu guards session and serializes its executions with reset, since
+	// the MCP server handles requests concurrently.
+	mu      sync.Mutex
+	session *sandbox.Session
 }
 
-func (t *Toolset) SearchDefinition() anthropic.ToolUnionParam {
-	return anthropic.ToolUnionParam{OfTool: &anthropic.ToolParam{
+const persistentExecuteNote = " Global state survives between execute calls in this conversation: store values you want to reuse on the global state object, for example state.quotes = quotes, and read them back in later calls. Call reset to start over with a fresh sandbox."
+
+func (t *Toolset) SearchDefinition() provider.Tool {
+	return provider.Tool{
 		Name:        "search",
-		Description: anthropic.String("Call this to discover relevant helpers for the user's task. The result includes the JavaScript helper definitions you should use in execute(code)."),
-		InputSchema: anthropic.ToolInputSchemaParam{
-			Properties: map[string]any{
+		Description: SearchDescription,
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
 				"query": map[string]any{"type": "string", "description": "A concise natural-language summary of the user's task or the helpers you need to find."},
 				"limit": map[string]any{"type": "integer", "description": "Maximum results to return."},
 			},
-			Required: []string{"query"},
+			"required": []string{"query"},
 		},
-	}}
+	}
 }
 
-func (t *Toolset) ExecuteDefinition() anthropic.ToolUnionParam {
-	return anthropic.ToolUnionParam{OfTool: &anthropic.ToolParam{
+func (t *Toolset) ExecuteDefinition() provider.Tool {
+	description := ExecuteDescription
+	if t.Persistent {
+		description += persistentExecuteNote
+	}
+	return provider.Tool{
 		Name:        "execute",
-		Description: anthropic.String("Execute synchronous JavaScript only. The sandbox supports ECMAScript 14 (ES2023) only, and the flow is sync so it does not support async or await. The sandbox does not support any Web APIs. Use the helper definitions returned by search, for example demo_add_numbers({...}). Keep the code minimal and do not write comments."),
-		InputSchema: anthropic.ToolInputSchemaParam{
-			Properties: map[string]any{
-				"code": map[string]any{"type": "string", "description": "JavaScript body to execute inside a synchronous IIFE. End by returning a value. Do not write comments."},
+		Description: description,
+		InputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"code": map[string]any{"type": "string", "description": "JavaScript body to execute inside an async IIFE. End by returning a value. Do not write comments."},
 			},
-			Required: []string{"code"},
+			"required": []string{"code"},
 		},
-	}}
+	}
 }
 
-func (t *Toolset) Definitions() []anthropic.ToolUnionParam {
-	return []anthropic.ToolUnionParam{t.SearchDefinition(), t.ExecuteDefinition()}
+func (t *Toolset) ResetDefinition() provider.Tool {
+	return provider.Tool{
+		Name:        "reset",
+		Description: "Discard all state kept in the execute sandbox and start the next execute call with a fresh environment.",
+		InputSchema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{},
+		},
+	}
+}
+
+func (t *Toolset) Definitions() []provider.Tool {
+	if t.Persistent {
+		return []provider.Tool{t.SearchDefinition(), t.ExecuteDefinition(), t.ResetDefinition()}
+	}
+	return []provider.Tool{t.SearchDefinition(), t.ExecuteDefinition()}
+}
+
+// EndSession releases the persistent sandbox and resets the per-run policy
+// budgets at the end of a conversation.
+func (t *Toolset) EndSession() error {
+	if t.Policy != nil {
+		t.Policy.Reset()
+	}
+	return t.closeSession()
+}
+
+func (t *Toolset) closeSession() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.session == nil {
+		return nil
+	}
+	err := t.session.Close()
+	t.session = nil
+	return err
 }
 
 func (t *Toolset) Description() string {
@@ -68,42 +125,92 @@ func (t *Toolset) Execute(ctx context.Context, name string, input json.RawMessag
 		if err := json.Unmarshal(input, &args); err != nil {
 			return "", true, fmt.Errorf("parse search input: %w", err)
 		}
-		matches := catalog.Search(t.Catalog, args.Query, args.Limit)
-		payload, err := json.Marshal(map[string]any{
-			"api_definition": catalog.HelperDefinitions(matches),
-		})
+		payload, err := t.Search(args.Query, args.Limit)
 		if err != nil {
 			return "", true, err
 		}
-		if t.Tracef != nil {
-			t.Tracef("tool search query=%q results=%d\n%s", args.Query, len(matches), indent(string(payload), "  "))
-		}
-		return string(payload), false, nil
+		return payload, false, nil
 	case "execute":
 		var args ExecuteInput
 		if err := json.Unmarshal(input, &args); err != nil {
 			return "", true, fmt.Errorf("parse execute input: %w", err)
 		}
-		result, err := t.Sandbox.Execute(ctx, args.Code)
+		payload, err := t.RunCode(ctx, args.Code)
 		if err != nil {
-			if t.Tracef != nil {
-				t.Tracef("tool execute error=%q", err.Error())
-			}
 			return "", true, err
 		}
-		payload, err := json.Marshal(result)
-		if err != nil {
+		return payload, false, nil
+	case "reset":
+		if !t.Persistent {
+			return "", true, fmt.Errorf("unknown tool %q", name)
+		}
+		if err := t.closeSession(); err != nil {
 			return "", true, err
 		}
 		if t.Tracef != nil {
-			t.Tracef("tool execute ok logs=%d\n%s", len(result.Logs), indent(string(payload), "  "))
+			t.Tracef("tool reset ok")
 		}
-		return string(payload), false, nil
+		return `{"reset":true}`, false, nil
 	default:
 		return "", true, fmt.Errorf("unknown tool %q", name)
 	}
 }
 
+func (t *Toolset) Search(query string, limit int) (string, error) {
+	matches := t.search(query, limit)
+	payload, err := json.Marshal(map[string]any{
+		"api_definition": catalog.HelperDefinitions(matches),
+	})
+	if err != nil {
+		return "", err
+	}
+	if t.Tracef != nil {
+		t.Tracef("tool search query=%q results=%d\n%s", query, len(matches), indent(string(payload), "  "))
+	}
+	return string(payload), nil
+}
+
+func (t *Toolset) RunCode(ctx context.Context, code string) (string, error) {
+	result, err := t.execute(ctx, code)
+	if err != nil {
+		if t.Tracef != nil {
+			t.Tracef("tool execute error=%q", err.Error())
+		}
+		return "", err
+	}
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	if t.Tracef != nil {
+		t.Tracef("tool execute ok logs=%d\n%s", len(result.Logs), indent(string(payload), "  "))
+	}
+	return string(payload), nil
+}
+
+func (t *Toolset) execute(ctx context.Context, code string) (sandbox.Result, error) {
+	if !t.Persistent {
+		return t.Sandbox.Execute(ctx, code)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.session == nil {
+		session, err := t.Sandbox.NewSession()
+		if err != nil {
+			return sandbox.Result{}, err
+		}
+		t.session = session
+	}
+	return t.session.Execute(ctx, code)
+}
+
+func (t *Toolset) search(query string, limit int) []catalog.ToolInfo {
+	if t.Ranker == nil {
+		return catalog.Search(t.Catalog, query, limit)
+	}
+	return t.Ranker.Rank(query, limit)
+}
+
 func indent(s, prefix string) string {
 	lines := strings.Split(s, "\n")
 	for i, line := range lines {
@@ -0,0 +1,171 @@
+package codemode
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/preblog/codemode/internal/sandbox"
+)
+
+func newTestToolset(persistent bool, timeout time.Duration, memoryLimit uintptr) *Toolset {
+	return &Toolset{Sandbox: sandbox.New(nil, nil, timeout, memoryLimit, 1), Persistent: persistent}
+}
+
+// run calls the execute tool and returns the value of the script.
+func run(t *testing.T, toolset *Toolset, code string) (any, error) {
+	t.Helper()
+	input, err := json.Marshal(ExecuteInput{Code: code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	output, _, err := toolset.Execute(context.Background(), "execute", input)
+	if err != nil {
+		return nil, err
+	}
+	var result sandbox.Result
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result.Value, nil
+}
+
+func mustRun(t *testing.T, toolset *Toolset, code string) any {
+	t.Helper()
+	value, err := run(t, toolset, code)
+	if err != nil {
+		t.Fatalf("%s: %v", code, err)
+	}
+	return value
+}
+
+func TestPersistentState(t *testing.T) {
+	toolset := newTestToolset(true, time.Second, 64<<20)
+	defer toolset.EndSession()
+
+	mustRun(t, toolset, `state.quotes = [12, 30]; globalThis.carrier = "dhl"; return null;`)
+	if got := mustRun(t, toolset, `return carrier + " " + state.quotes.reduce((a, b) => a + b);`); got != "dhl 42" {
+		t.Errorf("second call = %v, want the state of the first", got)
+	}
+
+	// A failing call keeps the state too.
+	if _, err := run(t, toolset, `state.quotes.push(1); throw new Error("oops");`); err == nil {
+		t.Fatal("the failing call succeeded")
+	}
+	if got := mustRun(t, toolset, `return state.quotes.length;`); got != float64(3) {
+		t.Errorf("quotes after the failing call = %v, want 3", got)
+	}
+
+	output, isError, err := toolset.Execute(context.Background(), "reset", json.RawMessage(`{}`))
+	if err != nil || isError || output != `{"reset":true}` {
+		t.Fatalf("reset = %s, %t, %v", output, isError, err)
+	}
+	if got := mustRun(t, toolset, `return [typeof globalThis.carrier, Object.keys(state).length];`); !equalJSON(got, []any{"undefined", float64(0)}) {
+		t.Errorf("after reset = %v, want a fresh sandbox", got)
+	}
+
+	// EndSession drops the state like reset.
+	mustRun(t, toolset, `state.n = 1; return null;`)
+	if err := toolset.EndSession(); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustRun(t, toolset, `return state.n ?? "gone";`); got != "gone" {
+		t.Errorf("after EndSession = %v, want a fresh sandbox", got)
+	}
+}
+
+func TestNonPersistent(t *testing.T) {
+	toolset := newTestToolset(false, time.Second, 64<<20)
+	mustRun(t, toolset, `state.n = 1; return null;`)
+	if got := mustRun(t, toolset, `return state.n ?? "gone";`); got != "gone" {
+		t.Errorf("second call = %v, want a fresh sandbox", got)
+	}
+	if _, isError, err := toolset.Execute(context.Background(), "reset", json.RawMessage(`{}`)); err == nil || !isError {
+		t.Error("reset is available without a persistent sandbox")
+	}
+	if names := len(toolset.Definitions()); names != 2 {
+		t.Errorf("%d tool definitions, want search and execute", names)
+	}
+}
+
+func TestPersistentLimits(t *testing.T) {
+	t.Run("timeout per call", func(t *testing.T) {
+		toolset := newTestToolset(true, 100*time.Millisecond, 64<<20)
+		defer toolset.EndSession()
+
+		mustRun(t, toolset, `state.n = 1; return null;`)
+		if _, err := run(t, toolset, `while (true) {}`); err == nil {
+			t.Fatal("the endless loop was not stopped")
+		}
+		// Each call gets the full timeout again, and the session survives.
+		for range 3 {
+			if got := mustRun(t, toolset, `const end = Date.now() + 60; while (Date.now() < end) {} return state.n;`); got != float64(1) {
+				t.Fatalf("state after the timeout = %v, want 1", got)
+			}
+		}
+	})
+
+	t.Run("memory across calls", func(t *testing.T) {
+		const grow = `state.chunks = state.chunks || []; state.chunks.push(new Array(200000).fill(state.chunks.length)); return state.chunks.length;`
+		persistent := newTestToolset(true, 5*time.Second, 16<<20)
+		defer persistent.EndSession()
+		fresh := newTestToolset(false, 5*time.Second, 16<<20)
+
+		// The memory limit covers everything the session keeps, not each
+		// call on its own.
+		var err error
+		for i := 0; i < 50 && err == nil; i++ {
+			mustRun(t, fresh, grow)
+			_, err = run(t, persistent, grow)
+		}
+		if err == nil || !strings.Contains(err.Error(), "memory") {
+			t.Fatalf("growing the session state = %v, want an out of memory error", err)
+		}
+	})
+}
+
+func TestConcurrentExecute(t *testing.T) {
+	toolset := newTestToolset(true, 5*time.Second, 64<<20)
+	defer toolset.EndSession()
+	mustRun(t, toolset, `state.n = 0; return null;`)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			if _, err := run(t, toolset, `state.n++; return state.n;`); err != nil {
+				t.Error(err)
+			}
+		})
+		wg.Go(func() {
+			toolset.Execute(context.Background(), "search", json.RawMessage(`{"query": "state"}`))
+		})
+	}
+	wg.Wait()
+	if got := mustRun(t, toolset, `return state.n;`); got != float64(8) {
+		t.Errorf("state.n = %v, want 8", got)
+	}
+
+	// A reset waits for a running call instead of closing its session.
+	for range 8 {
+		wg.Go(func() {
+			if _, err := run(t, toolset, `state.n = (state.n ?? 0) + 1; return state.n;`); err != nil {
+				t.Error(err)
+			}
+		})
+		wg.Go(func() {
+			if _, _, err := toolset.Execute(context.Background(), "reset", json.RawMessage(`{}`)); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func equalJSON(a, b any) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
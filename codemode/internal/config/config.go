@@ -1,7 +1,9 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"strings"
 	"time"
@@ -9,31 +11,86 @@ import (
 	"github.com/joho/godotenv"
 )
 
+const DefaultMCPServersPath = "mcp_servers.json"
+
+const (
+	ProviderAnthropic = "anthropic"
+	ProviderOpenAI    = "openai"
+)
+
+var defaultModels = map[string]string{
+	ProviderAnthropic: "claude-sonnet-4-6",
+	ProviderOpenAI:    "gpt-4o-mini",
+}
+
 type Options struct {
-	Model         string
-	MaxTurns      int
-	EvalTimeoutS  int
-	MemoryLimitMB int
-	Verbose       bool
-	DebugHTTP     bool
+	Provider       string
+	BaseURL        string
+	Model          string
+	MCPServersPath string
+	MaxTurns       int
+	EvalTimeoutS   int
+	MemoryLimitMB  int
+	MaxParallel    int
+	SearchRanker   string
+	SearchVectors  string
+	Persistent     bool
+	PolicyPath     string
+	RecordPath     string
+	ReplayPath     string
+	Verbose        bool
+	DebugHTTP      bool
 }
 
 type Config struct {
-	AnthropicAPIKey  string
+	Provider         string
+	APIKey           string
+	BaseURL          string
 	Model            string
+	MCPServersPath   string
 	MaxTurns         int
 	EvalTimeout      time.Duration
 	MemoryLimitBytes uintptr
+	MaxParallelCalls int
+	SearchRanker     string
+	SearchVectors    string
+	Persistent       bool
+	PolicyPath       string
+	RecordPath       string
+	ReplayPath       string
 	Verbose          bool
 	DebugHTTP        bool
 }
 
 func Load(opts Options) (Config, error) {
+	cfg, err := LoadLocal(opts)
+	if err != nil {
+		return Config{}, err
+	}
+	if cfg.Provider == ProviderAnthropic && cfg.APIKey == "" && cfg.ReplayPath == "" {
+		return Config{}, fmt.Errorf("ANTHROPIC_API_KEY is required")
+	}
+	return cfg, nil
+}
+
+// LoadLocal resolves the configuration without requiring an API key, for
+// commands that only use the catalog and the sandbox. The OpenAI-compatible
+// provider reads OPENAI_API_KEY, which local servers usually do not need.
+func LoadLocal(opts Options) (Config, error) {
 	_ = godotenv.Load()
 
-	apiKey := strings.TrimSpace(os.Getenv("ANTHROPIC_API_KEY"))
-	if apiKey == "" {
-		return Config{}, fmt.Errorf("ANTHROPIC_API_KEY is required")
+	providerName := strings.ToLower(strings.TrimSpace(opts.Provider))
+	if providerName == "" {
+		providerName = ProviderAnthropic
+	}
+	var apiKey string
+	switch providerName {
+	case ProviderAnthropic:
+		apiKey = strings.TrimSpace(os.Getenv("ANTHROPIC_API_KEY"))
+	case ProviderOpenAI:
+		apiKey = strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
+	default:
+		return Config{}, fmt.Errorf("unknown provider %q, use %s or %s", opts.Provider, ProviderAnthropic, ProviderOpenAI)
 	}
 	if opts.MaxTurns <= 0 {
 		opts.MaxTurns = 6
@@ -44,17 +101,52 @@ func Load(opts Options) (Config, error) {
 	if opts.MemoryLimitMB <= 0 {
 		opts.MemoryLimitMB = 32
 	}
+	if opts.MaxParallel <= 0 {
+		opts.MaxParallel = 4
+	}
 	model := strings.TrimSpace(opts.Model)
 	if model == "" {
-		model = "claude-sonnet-4-6"
+		model = defaultModels[providerName]
+	}
+	searchRanker := strings.TrimSpace(opts.SearchRanker)
+	if searchRanker == "" {
+		searchRanker = "bm25"
+	}
+	serversPath, err := resolveMCPServersPath(opts.MCPServersPath)
+	if err != nil {
+		return Config{}, err
 	}
 	return Config{
-		AnthropicAPIKey:  apiKey,
+		Provider:         providerName,
+		APIKey:           apiKey,
+		BaseURL:          strings.TrimSpace(opts.BaseURL),
 		Model:            model,
+		MCPServersPath:   serversPath,
 		MaxTurns:         opts.MaxTurns,
 		EvalTimeout:      time.Duration(opts.EvalTimeoutS) * time.Second,
 		MemoryLimitBytes: uintptr(opts.MemoryLimitMB) * 1024 * 1024,
+		MaxParallelCalls: opts.MaxParallel,
+		SearchRanker:     searchRanker,
+		SearchVectors:    strings.TrimSpace(opts.SearchVectors),
+		Persistent:       opts.Persistent,
+		PolicyPath:       strings.TrimSpace(opts.PolicyPath),
+		RecordPath:       strings.TrimSpace(opts.RecordPath),
+		ReplayPath:       strings.TrimSpace(opts.ReplayPath),
 		Verbose:          opts.Verbose,
 		DebugHTTP:        opts.DebugHTTP,
 	}, nil
 }
+
+func resolveMCPServersPath(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) && path == DefaultMCPServersPath {
+			return "", nil
+		}
+		return "", fmt.Errorf("mcp server config: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,43 @@
+package loop
+
+import (
+	"context"
+	"encoding/json"
+)
+
+type EventKind string
+
+const (
+	EventTurnStart  EventKind = "turn_start"
+	EventTextDelta  EventKind = "text_delta"
+	EventToolUse    EventKind = "tool_use"
+	EventToolResult EventKind = "tool_result"
+)
+
+type Event struct {
+	Kind     EventKind
+	Turn     int
+	Text     string
+	ToolID   string
+	ToolName string
+	Input    json.RawMessage
+	Output   string
+	IsError  bool
+}
+
+// Stream switches the runner to streamed model responses and publishes text
+// deltas, tool calls and tool results to events as they arrive. The runner
+// never closes events.
+func (r *Runner) Stream(events chan<- Event) {
+	r.events = events
+}
+
+func (r *Runner) emit(ctx context.Context, event Event) {
+	if r.events == nil {
+		return
+	}
+	select {
+	case r.events <- event:
+	case <-ctx.Done():
+	}
+}
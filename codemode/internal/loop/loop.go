@@ -1,6 +1,7 @@
-package anthropicloop
+package loop
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -9,117 +10,142 @@ import (
 	"os"
 	"strings"
 
-	anthropic "github.com/anthropics/anthropic-sdk-go"
-	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/preblog/codemode/internal/codemode"
+	"github.com/preblog/codemode/internal/provider"
+	"github.com/preblog/codemode/internal/recording"
 )
 
+const maxTokens = 1600
+
 type Result struct {
 	Text  string
 	Trace []string
 }
 
 type Runner struct {
-	client    anthropic.Client
-	model     string
+	provider  provider.Provider
 	maxTurns  int
 	tools     *codemode.Toolset
 	verbose   bool
 	debugHTTP bool
 	trace     []string
+	events    chan<- Event
+	recorder  *recording.Recorder
+	player    *recording.Player
 }
 
 func (r *Runner) snapshotResult(text string) Result {
 	return Result{Text: text, Trace: append([]string(nil), r.trace...)}
 }
 
-func New(apiKey, model string, maxTurns int, verbose bool, debugHTTP bool, captureHTTPInTrace bool, tools *codemode.Toolset) *Runner {
+func New(providerConfig provider.Config, maxTurns int, verbose bool, debugHTTP bool, captureHTTPInTrace bool, tools *codemode.Toolset) (*Runner, error) {
 	r := &Runner{
-		model:     model,
 		maxTurns:  maxTurns,
 		tools:     tools,
 		verbose:   verbose,
 		debugHTTP: debugHTTP,
 	}
-	options := []option.RequestOption{option.WithAPIKey(apiKey)}
 	if debugHTTP {
-		logger := log.New(os.Stderr, "[anthropic-http] ", 0)
+		logger := log.New(os.Stderr, fmt.Sprintf("[%s-http] ", providerName(providerConfig.Kind)), 0)
 		if captureHTTPInTrace {
 			logger = log.New(&httpTraceWriter{runner: r}, "", 0)
 		}
-		options = append(options, option.WithDebugLog(logger))
+		providerConfig.DebugLog = logger
+	}
+	p, err := provider.New(providerConfig)
+	if err != nil {
+		return nil, err
 	}
-	r.client = anthropic.NewClient(options...)
+	r.provider = p
 	tools.Tracef = r.tracef
-	return r
+	return r, nil
+}
+
+func providerName(kind string) string {
+	if kind == "" {
+		return provider.KindAnthropic
+	}
+	return kind
 }
 
-func (r *Runner) Run(ctx context.Context, prompt string) (Result, error) {
+func (r *Runner) Run(ctx context.Context, prompt string) (result Result, err error) {
+	defer func() {
+		if endErr := r.tools.EndSession(); endErr != nil && err == nil {
+			err = fmt.Errorf("close sandbox session: %w", endErr)
+		}
+	}()
+	defer func() {
+		errText := ""
+		if err != nil {
+			errText = err.Error()
+		}
+		r.record(recording.Entry{Kind: recording.KindResult, Output: result.Text, Error: errText})
+		if r.player != nil {
+			r.player.CompareResult(result.Text, errText)
+		}
+	}()
 	r.trace = nil
 	r.traceConversationHeader(prompt)
-	messages := []anthropic.MessageParam{
-		anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
-	}
+	r.record(recording.Entry{Kind: recording.KindRun, Provider: r.provider.Name(), Model: r.provider.Model(), Prompt: prompt})
+	messages := []provider.Message{{Role: provider.RoleUser, Text: prompt}}
 
 	for turn := 0; turn < r.maxTurns; turn++ {
-		params := r.messageParams(messages, turn)
+		request := r.request(messages, turn)
 		if turn == 0 {
-			r.tracef("[turn %d request] sending %d conversation message(s) to Anthropic with tool_choice=search", turn+1, len(messages))
+			r.tracef("[turn %d request] sending %d conversation message(s) to %s with tool_choice=search", turn+1, len(messages), r.provider.Name())
 		} else {
-			r.tracef("[turn %d request] sending %d conversation message(s) to Anthropic", turn+1, len(messages))
+			r.tracef("[turn %d request] sending %d conversation message(s) to %s", turn+1, len(messages), r.provider.Name())
 		}
-		message, err := r.client.Messages.New(ctx, params)
+		r.emit(ctx, Event{Kind: EventTurnStart, Turn: turn + 1})
+		response, err := r.complete(ctx, request, turn+1)
 		if err != nil {
-			r.tracef("[runner error] anthropic request failed: %v", err)
+			r.tracef("[runner error] %s request failed: %v", r.provider.Name(), err)
 			return r.snapshotResult(""), err
 		}
 
-		messages = append(messages, message.ToParam())
+		message := response.Message
+		messages = append(messages, message)
 		r.traceAssistantMessage(turn+1, message)
-		toolResults := []anthropic.ContentBlockParamUnion{}
-		for _, block := range message.Content {
-			switch block := block.AsAny().(type) {
-			case anthropic.ToolUseBlock:
-				inputJSON, err := json.Marshal(block.Input)
-				if err != nil {
-					r.tracef("[runner error] marshal tool input for %s: %v", block.Name, err)
-					return r.snapshotResult(""), err
-				}
-				r.tracef("[turn %d tool call %s] input\n%s", turn+1, block.Name, indentJSON(string(inputJSON)))
-				output, isError, err := r.tools.Execute(ctx, block.Name, inputJSON)
-				if err != nil {
-					output = err.Error()
-					isError = true
-				}
-				r.tracef("[turn %d tool result %s] is_error=%t\n%s", turn+1, block.Name, isError, indentJSON(output))
-				toolResults = append(toolResults, anthropic.NewToolResultBlock(block.ID, output, isError))
-			}
+		if len(message.ToolCalls) == 0 {
+			return r.snapshotResult(strings.TrimSpace(message.Text)), nil
 		}
 
-		if len(toolResults) == 0 {
-			return r.snapshotResult(extractText(message)), nil
+		toolResults := make([]provider.ToolResult, 0, len(message.ToolCalls))
+		for _, call := range message.ToolCalls {
+			r.tracef("[turn %d tool call %s] input\n%s", turn+1, call.Name, indentJSON(string(call.Input)))
+			r.record(recording.Entry{Kind: recording.KindToolCall, Turn: turn + 1, ToolID: call.ID, Name: call.Name, Input: call.Input})
+			output, isError, err := r.tools.Execute(ctx, call.Name, call.Input)
+			if err != nil {
+				output = err.Error()
+				isError = true
+			}
+			r.tracef("[turn %d tool result %s] is_error=%t\n%s", turn+1, call.Name, isError, indentJSON(output))
+			r.record(recording.Entry{Kind: recording.KindToolResult, Turn: turn + 1, ToolID: call.ID, Name: call.Name, Output: output, IsError: isError})
+			if r.player != nil {
+				r.player.CompareToolResult(turn+1, call.ID, call.Name, output, isError)
+			}
+			r.emit(ctx, Event{Kind: EventToolResult, Turn: turn + 1, ToolID: call.ID, ToolName: call.Name, Output: output, IsError: isError})
+			toolResults = append(toolResults, provider.ToolResult{CallID: call.ID, Output: output, IsError: isError})
 		}
-
-		messages = append(messages, anthropic.NewUserMessage(toolResults...))
+		messages = append(messages, provider.Message{Role: provider.RoleUser, ToolResults: toolResults})
 	}
 
-	err := fmt.Errorf("tool loop reached max turns (%d)", r.maxTurns)
+	err = fmt.Errorf("tool loop reached max turns (%d)", r.maxTurns)
 	r.tracef("[runner error] %v", err)
 	return r.snapshotResult(""), err
 }
 
-func (r *Runner) messageParams(messages []anthropic.MessageParam, turn int) anthropic.MessageNewParams {
-	params := anthropic.MessageNewParams{
-		Model:     anthropic.Model(r.model),
-		MaxTokens: 1600,
-		System:    r.systemBlocks(turn),
-		Messages:  messages,
+func (r *Runner) request(messages []provider.Message, turn int) provider.Request {
+	request := provider.Request{
+		System:    r.systemPromptForTurn(turn),
+		Messages:  append([]provider.Message(nil), messages...),
 		Tools:     r.toolDefinitions(turn),
+		MaxTokens: maxTokens,
 	}
 	if turn == 0 {
-		params.ToolChoice = anthropic.ToolChoiceParamOfTool("search")
+		request.ForceTool = "search"
 	}
-	return params
+	return request
 }
 
 func (r *Runner) traceConversationHeader(prompt string) {
@@ -131,20 +157,14 @@ func (r *Runner) traceConversationHeader(prompt string) {
 		"[initial system prompt]\n"+searchOnlySystemPrompt(),
 		"[follow-up system prompt]\n"+fullSystemPrompt(),
 		"[catalog snapshot]\n"+r.tools.Description(),
-		fmt.Sprintf("[runner]\nmodel=%s max_turns=%d debug_http=%t", r.model, r.maxTurns, r.debugHTTP),
+		fmt.Sprintf("[runner]\nprovider=%s model=%s max_turns=%d debug_http=%t persistent_sandbox=%t", r.provider.Name(), r.provider.Model(), r.maxTurns, r.debugHTTP, r.tools.Persistent),
 		"[user]\n"+prompt,
 	)
 }
 
-func (r *Runner) systemBlocks(turn int) []anthropic.TextBlockParam {
-	return []anthropic.TextBlockParam{
-		{Text: r.systemPromptForTurn(turn)},
-	}
-}
-
-func (r *Runner) toolDefinitions(turn int) []anthropic.ToolUnionParam {
+func (r *Runner) toolDefinitions(turn int) []provider.Tool {
 	if turn == 0 {
-		return []anthropic.ToolUnionParam{r.tools.SearchDefinition()}
+		return []provider.Tool{r.tools.SearchDefinition()}
 	}
 	return r.tools.Definitions()
 }
@@ -170,33 +190,23 @@ func (r *Runner) traceHTTP(label, dump string) {
 	r.trace = append(r.trace, formatHTTPTrace(label, dump))
 }
 
-func (r *Runner) traceAssistantMessage(turn int, message *anthropic.Message) {
+func (r *Runner) traceAssistantMessage(turn int, message provider.Message) {
 	if !r.verbose {
 		return
 	}
 	var parts []string
-	for _, block := range message.Content {
-		switch block := block.AsAny().(type) {
-		case anthropic.TextBlock:
-			parts = append(parts, block.Text)
-		case anthropic.ToolUseBlock:
-			inputJSON, _ := json.MarshalIndent(block.Input, "", "  ")
-			parts = append(parts, fmt.Sprintf("tool_use %s\n%s", block.Name, string(inputJSON)))
-		default:
-			parts = append(parts, fmt.Sprintf("unsupported block %T", block))
-		}
+	if message.Text != "" {
+		parts = append(parts, message.Text)
 	}
-	r.trace = append(r.trace, fmt.Sprintf("[assistant turn %d]\n%s", turn, strings.Join(parts, "\n\n")))
-}
-
-func extractText(message *anthropic.Message) string {
-	parts := make([]string, 0, len(message.Content))
-	for _, block := range message.Content {
-		if text, ok := block.AsAny().(anthropic.TextBlock); ok {
-			parts = append(parts, text.Text)
+	for _, call := range message.ToolCalls {
+		var input bytes.Buffer
+		if err := json.Indent(&input, call.Input, "", "  "); err != nil {
+			input.Reset()
+			input.Write(call.Input)
 		}
+		parts = append(parts, fmt.Sprintf("tool_use %s\n%s", call.Name, input.String()))
 	}
-	return strings.TrimSpace(strings.Join(parts, "\n"))
+	r.trace = append(r.trace, fmt.Sprintf("[assistant turn %d]\n%s", turn, strings.Join(parts, "\n\n")))
 }
 
 func searchOnlySystemPrompt() string {
@@ -218,6 +228,9 @@ it's better to do that orchestration in a single execute call with JavaScript, r
 tool calls. For example if the helper returns a list of items and you want to call another helper on each item, it's better to do that 
 iteration within the same JavaScript snippet. 
 
+Every helper is async and returns a Promise. When helper calls do not depend on each other, 
+start them together and await them with Promise.all so they run in parallel.
+
 You have a ECMAScript 2023 environment to your disposal in the execute(code) helper, and you can use it to orchestrate 
 calls to other helpers as needed.
 `)
@@ -284,7 +297,7 @@ func sanitizeHTTPTraceHeaders(headers string) string {
 		if !ok {
 			continue
 		}
-		if strings.EqualFold(strings.TrimSpace(name), "X-Api-Key") {
+		if key := strings.TrimSpace(name); strings.EqualFold(key, "X-Api-Key") || strings.EqualFold(key, "Authorization") {
 			lines[i] = name + ": <secure>"
 		}
 	}
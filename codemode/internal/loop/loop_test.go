@@ -0,0 +1,112 @@
+package loop
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/preblog/codemode/internal/catalog"
+	"github.com/preblog/codemode/internal/codemode"
+	"github.com/preblog/codemode/internal/provider"
+	"github.com/preblog/codemode/internal/provider/providertest"
+)
+
+var testCatalog = []catalog.ToolInfo{
+	{Server: "demo", Name: "city_time", Callable: "demo_city_time", Description: "Get the current time for a supported city."},
+	{Server: "demo", Name: "add_numbers", Callable: "demo_add_numbers", Description: "Add two numbers together."},
+}
+
+// script searches for helpers in the first turn and answers in the second.
+func script() []providertest.Turn {
+	return []providertest.Turn{
+		{ToolUses: []providertest.ToolUse{{ID: "call_1", Name: "search", Input: json.RawMessage(`{"query":"time in a city"}`)}}},
+		{Text: "It is noon in Tokyo right now."},
+	}
+}
+
+// compact joins consecutive text deltas of a turn, which arrive in chunks.
+func compact(events []Event) []Event {
+	var out []Event
+	for _, event := range events {
+		if last := len(out) - 1; event.Kind == EventTextDelta && last >= 0 && out[last].Kind == EventTextDelta && out[last].Turn == event.Turn {
+			out[last].Text += event.Text
+			continue
+		}
+		out = append(out, event)
+	}
+	return out
+}
+
+func TestRunnerStream(t *testing.T) {
+	tests := []struct {
+		kind    string
+		baseURL func(url string) string
+	}{
+		{kind: provider.KindAnthropic, baseURL: func(url string) string { return url }},
+		{kind: provider.KindOpenAI, baseURL: func(url string) string { return url + "/v1" }},
+	}
+	for _, test := range tests {
+		t.Run(test.kind, func(t *testing.T) {
+			handler := providertest.NewHandler(script()...)
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			tools := &codemode.Toolset{Catalog: testCatalog, Ranker: catalog.NewBM25Ranker(testCatalog)}
+			runner, err := New(provider.Config{Kind: test.kind, Model: "test-model", APIKey: "test", BaseURL: test.baseURL(server.URL)}, 4, false, false, false, tools)
+			if err != nil {
+				t.Fatal(err)
+			}
+			events := make(chan Event, 256)
+			runner.Stream(events)
+
+			result, err := runner.Run(context.Background(), "What time is it in Tokyo?")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Text != "It is noon in Tokyo right now." {
+				t.Errorf("Text = %q", result.Text)
+			}
+
+			var got []Event
+			for len(events) > 0 {
+				got = append(got, <-events)
+			}
+			got = compact(got)
+			want := []EventKind{EventTurnStart, EventToolUse, EventToolResult, EventTurnStart, EventTextDelta}
+			kinds := make([]EventKind, len(got))
+			for i, event := range got {
+				kinds[i] = event.Kind
+			}
+			if !slices.Equal(kinds, want) {
+				t.Fatalf("events = %v, want %v", kinds, want)
+			}
+
+			toolUse, toolResult, text := got[1], got[2], got[4]
+			if toolUse.Turn != 1 || toolUse.ToolID != "call_1" || toolUse.ToolName != "search" {
+				t.Errorf("tool use = %+v", toolUse)
+			}
+			var input codemode.SearchInput
+			if err := json.Unmarshal(toolUse.Input, &input); err != nil || input.Query != "time in a city" {
+				t.Errorf("tool use input = %s (%v)", toolUse.Input, err)
+			}
+			if toolResult.ToolID != "call_1" || toolResult.IsError || !strings.Contains(toolResult.Output, "demo_city_time") {
+				t.Errorf("tool result = %+v", toolResult)
+			}
+			if text.Turn != 2 || text.Text != "It is noon in Tokyo right now." {
+				t.Errorf("text = %+v", text)
+			}
+
+			// The search result went back to the model in the second request.
+			requests := handler.Requests()
+			if len(requests) != 2 {
+				t.Fatalf("model got %d requests, want 2", len(requests))
+			}
+			if second := string(requests[1]); !strings.Contains(second, "call_1") || !strings.Contains(second, "demo_city_time") {
+				t.Errorf("second request lacks the tool result:\n%s", second)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package loop
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/preblog/codemode/internal/provider"
+	"github.com/preblog/codemode/internal/recording"
+)
+
+// Record writes every model request and response and every tool call and
+// result of the following runs to recorder.
+func (r *Runner) Record(recorder *recording.Recorder) {
+	r.recorder = recorder
+}
+
+// Replay serves model responses from player instead of calling the provider.
+// Tool calls still run locally so their results can be compared with the
+// recording.
+func (r *Runner) Replay(player *recording.Player) {
+	r.player = player
+}
+
+func (r *Runner) record(entry recording.Entry) {
+	if r.recorder != nil {
+		r.recorder.Record(entry)
+	}
+}
+
+func (r *Runner) complete(ctx context.Context, request provider.Request, turn int) (provider.Response, error) {
+	var onDelta func(provider.Delta)
+	if r.events != nil {
+		onDelta = func(delta provider.Delta) {
+			if delta.Text != "" {
+				r.emit(ctx, Event{Kind: EventTextDelta, Turn: turn, Text: delta.Text})
+			}
+			if call := delta.ToolCall; call != nil {
+				r.emit(ctx, Event{Kind: EventToolUse, Turn: turn, ToolID: call.ID, ToolName: call.Name, Input: call.Input})
+			}
+		}
+	}
+	if r.recorder == nil && r.player == nil {
+		return r.provider.Complete(ctx, request, onDelta)
+	}
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return provider.Response{}, fmt.Errorf("marshal model request: %w", err)
+	}
+	r.record(recording.Entry{Kind: recording.KindModelRequest, Turn: turn, Request: requestJSON})
+
+	var response provider.Response
+	if r.player != nil {
+		response, err = r.replayResponse(requestJSON, turn, onDelta)
+	} else {
+		response, err = r.provider.Complete(ctx, request, onDelta)
+	}
+	if err != nil {
+		r.record(recording.Entry{Kind: recording.KindModelResponse, Turn: turn, Error: err.Error()})
+		return provider.Response{}, err
+	}
+	responseJSON, err := json.Marshal(response)
+	if err != nil {
+		return provider.Response{}, fmt.Errorf("marshal model response: %w", err)
+	}
+	r.record(recording.Entry{Kind: recording.KindModelResponse, Turn: turn, Response: responseJSON})
+	return response, nil
+}
+
+func (r *Runner) replayResponse(request json.RawMessage, turn int, onDelta func(provider.Delta)) (provider.Response, error) {
+	raw, err := r.player.Next(turn, request)
+	if err != nil {
+		return provider.Response{}, err
+	}
+	var response provider.Response
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return provider.Response{}, fmt.Errorf("replay: parse model response for turn %d: %w", turn, err)
+	}
+	if onDelta != nil {
+		if response.Message.Text != "" {
+			onDelta(provider.Delta{Text: response.Message.Text})
+		}
+		for i := range response.Message.ToolCalls {
+			onDelta(provider.Delta{ToolCall: &response.Message.ToolCalls[i]})
+		}
+	}
+	return response, nil
+}
@@ -5,19 +5,11 @@ import (
 	"fmt"
 	"math"
 	"sort"
-	"sync"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
-type Runtime struct {
-	clientSession *mcp.ClientSession
-	serverName    string
-	closeFn       func() error
-	mu            sync.Mutex
-}
-
 type AddNumbersInput struct {
 	A float64 `json:"a" jsonschema:"first number"`
 	B float64 `json:"b" jsonschema:"second number"`
@@ -193,7 +185,9 @@ var shippingProfiles = map[string]carrierQuoteProfile{
 	},
 }
 
-func NewDemo(ctx context.Context) (*Runtime, error) {
+const ServerName = "demo"
+
+func NewServer() *mcp.Server {
 	server := mcp.NewServer(&mcp.Implementation{Name: "demo-mcp", Version: "0.1.0"}, nil)
 	mcp.AddTool(server, &mcp.Tool{Name: "add_numbers", Description: "Add two numbers together."}, addNumbers)
 	mcp.AddTool(server, &mcp.Tool{Name: "city_time", Description: "Get the current time for a supported city."}, cityTime)
@@ -203,68 +197,7 @@ func NewDemo(ctx context.Context) (*Runtime, error) {
 	mcp.AddTool(server, &mcp.Tool{Name: "estimate_delivery", Description: "Get a deterministic delivery window for a carrier and route."}, estimateDelivery)
 	mcp.AddTool(server, &mcp.Tool{Name: "apply_surcharge", Description: "Calculate deterministic surcharges for package traits such as weight, remote areas, and fragile handling."}, applySurcharge)
 	mcp.AddTool(server, &mcp.Tool{Name: "quote_summary", Description: "Normalize a shipping quote into a sortable final summary."}, quoteSummary)
-
-	client := mcp.NewClient(&mcp.Implementation{Name: "demo-client", Version: "0.1.0"}, nil)
-	serverTransport, clientTransport := mcp.NewInMemoryTransports()
-
-	serverSession, err := server.Connect(ctx, serverTransport, nil)
-	if err != nil {
-		return nil, fmt.Errorf("connect server: %w", err)
-	}
-	clientSession, err := client.Connect(ctx, clientTransport, nil)
-	if err != nil {
-		if closeErr := serverSession.Close(); closeErr != nil {
-			return nil, fmt.Errorf("connect client: %w (close server session: %v)", err, closeErr)
-		}
-		return nil, fmt.Errorf("connect client: %w", err)
-	}
-
-	return &Runtime{
-		clientSession: clientSession,
-		serverName:    "demo",
-		closeFn: func() error {
-			return firstError(clientSession.Close(), serverSession.Close())
-		},
-	}, nil
-}
-
-func (r *Runtime) Close() error {
-	if r.closeFn == nil {
-		return nil
-	}
-	return r.closeFn()
-}
-
-func (r *Runtime) ListTools(ctx context.Context) ([]*mcp.Tool, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	result, err := r.clientSession.ListTools(ctx, nil)
-	if err != nil {
-		return nil, err
-	}
-	return result.Tools, nil
-}
-
-func (r *Runtime) CallTool(ctx context.Context, name string, args map[string]any) (*mcp.CallToolResult, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	return r.clientSession.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
-}
-
-func (r *Runtime) ServerName() string {
-	if r == nil || r.serverName == "" {
-		return "mcp"
-	}
-	return r.serverName
-}
-
-func firstError(errs ...error) error {
-	for _, err := range errs {
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return server
 }
 
 func addNumbers(_ context.Context, _ *mcp.CallToolRequest, input AddNumbersInput) (*mcp.CallToolResult, AddNumbersOutput, error) {
@@ -0,0 +1,34 @@
+package mcpserve
+
+import (
+	"context"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/preblog/codemode/internal/codemode"
+)
+
+func New(toolset *codemode.Toolset) *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{Name: "codemode", Version: "0.1.0"}, &mcp.ServerOptions{
+		Instructions: "Code mode gateway. Call search to discover JavaScript helper definitions for the connected tool servers, then call execute with a script that awaits those helpers.",
+	})
+
+	mcp.AddTool(server, &mcp.Tool{Name: "search", Description: codemode.SearchDescription}, func(_ context.Context, _ *mcp.CallToolRequest, input codemode.SearchInput) (*mcp.CallToolResult, any, error) {
+		payload, err := toolset.Search(input.Query, input.Limit)
+		if err != nil {
+			return nil, nil, err
+		}
+		return textResult(payload), nil, nil
+	})
+	mcp.AddTool(server, &mcp.Tool{Name: "execute", Description: codemode.ExecuteDescription}, func(ctx context.Context, _ *mcp.CallToolRequest, input codemode.ExecuteInput) (*mcp.CallToolResult, any, error) {
+		payload, err := toolset.RunCode(ctx, input.Code)
+		if err != nil {
+			return nil, nil, err
+		}
+		return textResult(payload), nil, nil
+	})
+	return server
+}
+
+func textResult(text string) *mcp.CallToolResult {
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: text}}}
+}
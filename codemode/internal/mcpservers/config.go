@@ -0,0 +1,95 @@
+package mcpservers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/preblog/codemode/internal/mcpdemo"
+)
+
+const (
+	TransportDemo  = "demo"
+	TransportStdio = "stdio"
+	TransportHTTP  = "http"
+
+	DefaultMaxConcurrency = 8
+)
+
+type ServerConfig struct {
+	Name           string            `json:"name"`
+	Transport      string            `json:"transport"`
+	Command        string            `json:"command,omitempty"`
+	Args           []string          `json:"args,omitempty"`
+	Env            map[string]string `json:"env,omitempty"`
+	Dir            string            `json:"dir,omitempty"`
+	URL            string            `json:"url,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	MaxConcurrency int               `json:"max_concurrency,omitempty"`
+}
+
+type FileConfig struct {
+	Servers []ServerConfig `json:"servers"`
+}
+
+func DefaultConfig() FileConfig {
+	return FileConfig{Servers: []ServerConfig{{Name: mcpdemo.ServerName, Transport: TransportDemo}}}
+}
+
+func LoadConfig(path string) (FileConfig, error) {
+	if strings.TrimSpace(path) == "" {
+		return DefaultConfig(), nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return FileConfig{}, fmt.Errorf("read mcp server config: %w", err)
+	}
+	var cfg FileConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return FileConfig{}, fmt.Errorf("parse mcp server config %s: %w", path, err)
+	}
+	// Names are trimmed once here, so the duplicate check and the runtime,
+	// which keys its sessions by name, see the same name.
+	for i := range cfg.Servers {
+		cfg.Servers[i].Name = strings.TrimSpace(cfg.Servers[i].Name)
+	}
+	if err := cfg.Validate(); err != nil {
+		return FileConfig{}, fmt.Errorf("invalid mcp server config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (c FileConfig) Validate() error {
+	if len(c.Servers) == 0 {
+		return fmt.Errorf("at least one server is required")
+	}
+	seen := make(map[string]struct{}, len(c.Servers))
+	for i, server := range c.Servers {
+		name := server.Name
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("servers[%d]: name is required", i)
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("servers[%d]: duplicate server name %q", i, name)
+		}
+		seen[name] = struct{}{}
+		if server.MaxConcurrency < 0 {
+			return fmt.Errorf("server %q: max_concurrency must not be negative", name)
+		}
+		switch server.Transport {
+		case TransportDemo:
+		case TransportStdio:
+			if strings.TrimSpace(server.Command) == "" {
+				return fmt.Errorf("server %q: command is required for stdio transport", name)
+			}
+		case TransportHTTP:
+			if strings.TrimSpace(server.URL) == "" {
+				return fmt.Errorf("server %q: url is required for http transport", name)
+			}
+		default:
+			return fmt.Errorf("server %q: unsupported transport %q", name, server.Transport)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package mcpservers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []ServerConfig
+		err     string
+	}{
+		{
+			name: "every transport",
+			servers: []ServerConfig{
+				{Name: "demo", Transport: TransportDemo},
+				{Name: "files", Transport: TransportStdio, Command: "mcp-files"},
+				{Name: "search", Transport: TransportHTTP, URL: "http://localhost:8080/mcp", MaxConcurrency: 2},
+			},
+		},
+		{name: "no servers", err: "at least one server is required"},
+		{name: "missing name", servers: []ServerConfig{{Transport: TransportDemo}}, err: "servers[0]: name is required"},
+		{name: "blank name", servers: []ServerConfig{{Name: "  ", Transport: TransportDemo}}, err: "servers[0]: name is required"},
+		{
+			name:    "duplicate name",
+			servers: []ServerConfig{{Name: "demo", Transport: TransportDemo}, {Name: "demo", Transport: TransportHTTP, URL: "http://localhost"}},
+			err:     `servers[1]: duplicate server name "demo"`,
+		},
+		{name: "stdio without command", servers: []ServerConfig{{Name: "files", Transport: TransportStdio, Command: " "}}, err: `server "files": command is required for stdio transport`},
+		{name: "http without url", servers: []ServerConfig{{Name: "search", Transport: TransportHTTP, Command: "mcp-search"}}, err: `server "search": url is required for http transport`},
+		{name: "unknown transport", servers: []ServerConfig{{Name: "files", Transport: "sse"}}, err: `server "files": unsupported transport "sse"`},
+		{name: "missing transport", servers: []ServerConfig{{Name: "files", Command: "mcp-files"}}, err: `server "files": unsupported transport ""`},
+		{name: "negative concurrency", servers: []ServerConfig{{Name: "demo", Transport: TransportDemo, MaxConcurrency: -1}}, err: `server "demo": max_concurrency must not be negative`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := FileConfig{Servers: test.servers}.Validate()
+			if test.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != test.err {
+				t.Errorf("Validate() error = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "servers.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, `{"servers": [{"name": " files ", "transport": "stdio", "command": "mcp-files"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := cfg.Servers[0].Name; name != "files" {
+		t.Errorf("server name = %q, want it trimmed", name)
+	}
+
+	// Names that differ only in surrounding space are the same server.
+	_, err = LoadConfig(writeConfig(t, `{"servers": [{"name": "demo", "transport": "demo"}, {"name": "demo ", "transport": "demo"}]}`))
+	if err == nil || !strings.Contains(err.Error(), `servers[1]: duplicate server name "demo"`) {
+		t.Errorf("LoadConfig() of duplicate names error = %v", err)
+	}
+
+	if _, err := LoadConfig(writeConfig(t, `{"servers": [{"name": "demo", "transport": "demo"`)); err == nil || !strings.Contains(err.Error(), "parse mcp server config") {
+		t.Errorf("LoadConfig() of malformed JSON error = %v", err)
+	}
+	if _, err := LoadConfig(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadConfig() of a missing file succeeded")
+	}
+	if cfg, err := LoadConfig(" "); err != nil || len(cfg.Servers) != 1 || cfg.Servers[0].Transport != TransportDemo {
+		t.Errorf("LoadConfig() without a path = %+v, %v, want the demo server", cfg, err)
+	}
+}
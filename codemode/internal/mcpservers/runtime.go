@@ -0,0 +1,185 @@
+package mcpservers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/preblog/codemode/internal/mcpdemo"
+)
+
+type Runtime struct {
+	sessions map[string]*session
+	order    []string
+}
+
+type session struct {
+	clientSession *mcp.ClientSession
+	closeFn       func() error
+	slots         chan struct{}
+}
+
+func Connect(ctx context.Context, cfg FileConfig) (*Runtime, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	r := &Runtime{sessions: make(map[string]*session, len(cfg.Servers))}
+	for _, server := range cfg.Servers {
+		s, err := connectServer(ctx, server)
+		if err != nil {
+			err = fmt.Errorf("connect mcp server %q: %w", server.Name, err)
+			if closeErr := r.Close(); closeErr != nil {
+				return nil, fmt.Errorf("%w (close runtime: %v)", err, closeErr)
+			}
+			return nil, err
+		}
+		maxConcurrency := server.MaxConcurrency
+		if maxConcurrency <= 0 {
+			maxConcurrency = DefaultMaxConcurrency
+		}
+		s.slots = make(chan struct{}, maxConcurrency)
+		r.sessions[server.Name] = s
+		r.order = append(r.order, server.Name)
+	}
+	return r, nil
+}
+
+func connectServer(ctx context.Context, cfg ServerConfig) (*session, error) {
+	client := mcp.NewClient(&mcp.Implementation{Name: "codemode", Version: "0.1.0"}, nil)
+	switch cfg.Transport {
+	case TransportDemo:
+		serverTransport, clientTransport := mcp.NewInMemoryTransports()
+		serverSession, err := mcpdemo.NewServer().Connect(ctx, serverTransport, nil)
+		if err != nil {
+			return nil, fmt.Errorf("connect server: %w", err)
+		}
+		clientSession, err := client.Connect(ctx, clientTransport, nil)
+		if err != nil {
+			if closeErr := serverSession.Close(); closeErr != nil {
+				return nil, fmt.Errorf("connect client: %w (close server session: %v)", err, closeErr)
+			}
+			return nil, fmt.Errorf("connect client: %w", err)
+		}
+		return &session{
+			clientSession: clientSession,
+			closeFn: func() error {
+				return errors.Join(clientSession.Close(), serverSession.Close())
+			},
+		}, nil
+	case TransportStdio:
+		cmd := exec.Command(cfg.Command, cfg.Args...)
+		cmd.Dir = cfg.Dir
+		cmd.Stderr = os.Stderr
+		cmd.Env = os.Environ()
+		for key, value := range cfg.Env {
+			cmd.Env = append(cmd.Env, key+"="+os.ExpandEnv(value))
+		}
+		clientSession, err := client.Connect(ctx, &mcp.CommandTransport{Command: cmd}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("start %s: %w", cfg.Command, err)
+		}
+		return &session{clientSession: clientSession, closeFn: clientSession.Close}, nil
+	case TransportHTTP:
+		transport := &mcp.StreamableClientTransport{Endpoint: cfg.URL}
+		if len(cfg.Headers) > 0 {
+			headers := make(map[string]string, len(cfg.Headers))
+			for key, value := range cfg.Headers {
+				headers[key] = os.ExpandEnv(value)
+			}
+			transport.HTTPClient = &http.Client{Transport: &headerTransport{headers: headers, next: http.DefaultTransport}}
+		}
+		clientSession, err := client.Connect(ctx, transport, nil)
+		if err != nil {
+			return nil, fmt.Errorf("connect %s: %w", cfg.URL, err)
+		}
+		return &session{clientSession: clientSession, closeFn: clientSession.Close}, nil
+	default:
+		return nil, fmt.Errorf("unsupported transport %q", cfg.Transport)
+	}
+}
+
+func (r *Runtime) Servers() []string {
+	return slices.Clone(r.order)
+}
+
+func (r *Runtime) Close() error {
+	var errs []error
+	for _, name := range slices.Backward(r.order) {
+		if s := r.sessions[name]; s != nil && s.closeFn != nil {
+			if err := s.closeFn(); err != nil {
+				errs = append(errs, fmt.Errorf("close %s: %w", name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Runtime) ListTools(ctx context.Context, server string) ([]*mcp.Tool, error) {
+	s, err := r.session(server)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	var tools []*mcp.Tool
+	for tool, err := range s.clientSession.Tools(ctx, nil) {
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+func (r *Runtime) CallTool(ctx context.Context, server, name string, args map[string]any) (*mcp.CallToolResult, error) {
+	s, err := r.session(server)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	return s.clientSession.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+}
+
+func (r *Runtime) session(server string) (*session, error) {
+	s, ok := r.sessions[server]
+	if !ok {
+		return nil, fmt.Errorf("unknown mcp server %q", server)
+	}
+	return s, nil
+}
+
+func (s *session) acquire(ctx context.Context) error {
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *session) release() {
+	<-s.slots
+}
+
+type headerTransport struct {
+	headers map[string]string
+	next    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	return t.next.RoundTrip(req)
+}
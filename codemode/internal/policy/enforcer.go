@@ -0,0 +1,144 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/preblog/codemode/internal/catalog"
+	"github.com/preblog/codemode/internal/sandbox"
+)
+
+// Confirmer asks a human whether a helper call may proceed.
+type Confirmer func(ctx context.Context, item catalog.ToolInfo, args map[string]any) (bool, error)
+
+type DeniedError struct {
+	Tool   string
+	Reason string
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("policy denied %s: %s", e.Tool, e.Reason)
+}
+
+// Enforcer applies a policy to helper calls and tracks the call budgets of the
+// current run. Budgets only count calls that were allowed; call Reset between
+// runs.
+type Enforcer struct {
+	policy  *Policy
+	confirm Confirmer
+
+	mu        sync.Mutex
+	total     int
+	ruleCalls map[int]int
+
+	confirmMu sync.Mutex
+}
+
+func NewEnforcer(policy *Policy, confirm Confirmer) *Enforcer {
+	return &Enforcer{policy: policy, confirm: confirm, ruleCalls: map[int]int{}}
+}
+
+func (e *Enforcer) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.total = 0
+	clear(e.ruleCalls)
+}
+
+func (e *Enforcer) Check(ctx context.Context, item catalog.ToolInfo, args map[string]any) error {
+	rule, matched := e.match(item)
+	action := e.policy.defaultAction
+	if matched {
+		action = rule.action
+		reason, err := rule.check(item, args)
+		if err != nil {
+			return &DeniedError{Tool: item.Callable, Reason: err.Error()}
+		}
+		if reason != "" {
+			return &DeniedError{Tool: item.Callable, Reason: withRuleReason(reason, rule.reason)}
+		}
+	}
+	if action == ActionDeny {
+		reason := "no rule allows this helper"
+		if matched {
+			reason = withRuleReason(fmt.Sprintf("denied by rule %q", rule.tool), rule.reason)
+		}
+		return &DeniedError{Tool: item.Callable, Reason: reason}
+	}
+	if err := e.withinBudget(item, rule, matched); err != nil {
+		return err
+	}
+	if action == ActionConfirm {
+		if err := e.confirmCall(ctx, item, args); err != nil {
+			return err
+		}
+	}
+	return e.spend(item, rule, matched)
+}
+
+func (e *Enforcer) match(item catalog.ToolInfo) (compiledRule, bool) {
+	for _, rule := range e.policy.rules {
+		if rule.matches(item) {
+			return rule, true
+		}
+	}
+	return compiledRule{}, false
+}
+
+func (e *Enforcer) withinBudget(item catalog.ToolInfo, rule compiledRule, matched bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.budgetErrorLocked(item, rule, matched)
+}
+
+// spend re-checks the budget after a confirmation prompt, since concurrent
+// calls may have used it up in the meantime.
+func (e *Enforcer) spend(item catalog.ToolInfo, rule compiledRule, matched bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.budgetErrorLocked(item, rule, matched); err != nil {
+		return err
+	}
+	e.total++
+	if matched {
+		e.ruleCalls[rule.index]++
+	}
+	return nil
+}
+
+func (e *Enforcer) budgetErrorLocked(item catalog.ToolInfo, rule compiledRule, matched bool) error {
+	if e.policy.maxCalls > 0 && e.total >= e.policy.maxCalls {
+		return &DeniedError{Tool: item.Callable, Reason: fmt.Sprintf("run budget of %d helper calls is exhausted", e.policy.maxCalls)}
+	}
+	if matched && rule.maxCalls > 0 && e.ruleCalls[rule.index] >= rule.maxCalls {
+		return &DeniedError{Tool: item.Callable, Reason: fmt.Sprintf("budget of %d calls for %q is exhausted", rule.maxCalls, rule.tool)}
+	}
+	return nil
+}
+
+func (e *Enforcer) confirmCall(ctx context.Context, item catalog.ToolInfo, args map[string]any) error {
+	if e.confirm == nil {
+		return &DeniedError{Tool: item.Callable, Reason: "confirmation required but no confirmation prompt is available"}
+	}
+	// Waiting for the prompt, or for the prompts of other calls, does not
+	// count against the script's evaluation timeout.
+	defer sandbox.PauseDeadline(ctx)()
+	e.confirmMu.Lock()
+	defer e.confirmMu.Unlock()
+	ok, err := e.confirm(ctx, item, args)
+	if err != nil {
+		return &DeniedError{Tool: item.Callable, Reason: fmt.Sprintf("confirmation failed: %v", err)}
+	}
+	if !ok {
+		return &DeniedError{Tool: item.Callable, Reason: "declined by user"}
+	}
+	return nil
+}
+
+func withRuleReason(reason, ruleReason string) string {
+	if ruleReason == "" {
+		return reason
+	}
+	return reason + " (" + ruleReason + ")"
+}
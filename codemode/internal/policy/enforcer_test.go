@@ -0,0 +1,185 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/preblog/codemode/internal/catalog"
+)
+
+func tool(name string) catalog.ToolInfo {
+	return catalog.ToolInfo{Server: "demo", Name: name, Callable: "demo_" + name}
+}
+
+func compile(t *testing.T, file File) *Policy {
+	t.Helper()
+	policy, err := Compile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policy
+}
+
+func TestEnforcerDecisions(t *testing.T) {
+	policy := compile(t, File{
+		Default: ActionDeny,
+		Rules: []Rule{
+			{Tool: "demo_*time*"},
+			{
+				Tool: "demo_quote_rate",
+				Schema: &jsonschema.Schema{
+					Type:     "object",
+					Required: []string{"carrier"},
+				},
+				When:   []string{"args.weight_kg > 0 && args.weight_kg <= 70"},
+				Reason: "up to 70 kg",
+			},
+			{Tool: "demo.delete_*", Action: ActionDeny, Reason: "read-only"},
+			{Tool: "demo_*", Action: ActionAllow},
+		},
+	})
+
+	tests := []struct {
+		name   string
+		tool   catalog.ToolInfo
+		args   map[string]any
+		denied string
+	}{
+		{name: "allowed by pattern", tool: tool("current_time")},
+		{name: "constraints met", tool: tool("quote_rate"), args: map[string]any{"carrier": "ups", "weight_kg": 5}},
+		{name: "constraint violated", tool: tool("quote_rate"), args: map[string]any{"carrier": "ups", "weight_kg": 90},
+			denied: "constraint args.weight_kg > 0 && args.weight_kg <= 70 is not satisfied (up to 70 kg)"},
+		{name: "schema violated", tool: tool("quote_rate"), args: map[string]any{"weight_kg": 5},
+			denied: "arguments do not match schema"},
+		{name: "constraint error", tool: tool("quote_rate"), args: map[string]any{"carrier": "ups", "weight_kg": "heavy"},
+			denied: "cannot compare string with float64"},
+		{name: "denied by server.tool rule", tool: tool("delete_order"),
+			denied: `denied by rule "demo.delete_*" (read-only)`},
+		{name: "later rule", tool: tool("list_orders")},
+		{name: "default", tool: catalog.ToolInfo{Server: "files", Name: "read", Callable: "files_read"},
+			denied: "no rule allows this helper"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := NewEnforcer(policy, nil).Check(context.Background(), test.tool, test.args)
+			if test.denied == "" {
+				if err != nil {
+					t.Fatalf("Check() = %v, want allowed", err)
+				}
+				return
+			}
+			var denied *DeniedError
+			if !errors.As(err, &denied) {
+				t.Fatalf("Check() = %v, want a *DeniedError", err)
+			}
+			if denied.Tool != test.tool.Callable || !strings.Contains(denied.Reason, test.denied) {
+				t.Errorf("denied %s: %q, want %s: %q", denied.Tool, denied.Reason, test.tool.Callable, test.denied)
+			}
+		})
+	}
+}
+
+func TestEnforcerBudgets(t *testing.T) {
+	policy := compile(t, File{
+		MaxCalls: 4,
+		Rules: []Rule{
+			{Tool: "demo_quote_rate", MaxCalls: 2},
+			{Tool: "demo_blocked", Action: ActionDeny},
+		},
+	})
+	enforcer := NewEnforcer(policy, nil)
+	check := func(item catalog.ToolInfo) error {
+		return enforcer.Check(context.Background(), item, nil)
+	}
+
+	for range 2 {
+		if err := check(tool("quote_rate")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := check(tool("quote_rate")); err == nil || !strings.Contains(err.Error(), `budget of 2 calls for "demo_quote_rate" is exhausted`) {
+		t.Errorf("third rule call: %v", err)
+	}
+
+	// Denied calls do not use up the run budget.
+	if err := check(tool("blocked")); err == nil {
+		t.Error("denied helper was allowed")
+	}
+	for range 2 {
+		if err := check(tool("current_time")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := check(tool("current_time")); err == nil || !strings.Contains(err.Error(), "run budget of 4 helper calls is exhausted") {
+		t.Errorf("fifth call: %v", err)
+	}
+
+	enforcer.Reset()
+	if err := check(tool("quote_rate")); err != nil {
+		t.Errorf("after Reset: %v", err)
+	}
+}
+
+func TestEnforcerConfirm(t *testing.T) {
+	policy := compile(t, File{
+		Rules: []Rule{{Tool: "demo_apply_surcharge", Action: ActionConfirm, MaxCalls: 1}},
+	})
+	item := tool("apply_surcharge")
+	args := map[string]any{"percent": 5}
+
+	tests := []struct {
+		name    string
+		confirm Confirmer
+		denied  string
+	}{
+		{
+			name: "approved",
+			confirm: func(ctx context.Context, got catalog.ToolInfo, gotArgs map[string]any) (bool, error) {
+				if got.Callable != item.Callable || gotArgs["percent"] != 5 {
+					t.Errorf("confirm got %s %v", got.Callable, gotArgs)
+				}
+				return true, nil
+			},
+		},
+		{
+			name: "declined",
+			confirm: func(context.Context, catalog.ToolInfo, map[string]any) (bool, error) {
+				return false, nil
+			},
+			denied: "declined by user",
+		},
+		{
+			name: "prompt failed",
+			confirm: func(context.Context, catalog.ToolInfo, map[string]any) (bool, error) {
+				return false, errors.New("stdin closed")
+			},
+			denied: "confirmation failed: stdin closed",
+		},
+		{
+			name:   "no prompt",
+			denied: "no confirmation prompt is available",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			enforcer := NewEnforcer(policy, test.confirm)
+			err := enforcer.Check(context.Background(), item, args)
+			if test.denied == "" {
+				if err != nil {
+					t.Fatalf("Check() = %v, want allowed", err)
+				}
+				// The approved call used up the rule's budget.
+				if err := enforcer.Check(context.Background(), item, args); err == nil {
+					t.Error("second call was allowed beyond the budget")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.denied) {
+				t.Errorf("Check() = %v, want %q", err, test.denied)
+			}
+		})
+	}
+}
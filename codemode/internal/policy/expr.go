@@ -0,0 +1,612 @@
+package policy
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a compiled argument constraint written in a small CEL-like language:
+// field access (args.carrier, args.items[0]), literals, lists, unary minus,
+// comparison and `in` operators, && || !, and the functions size, has,
+// matches, startsWith and endsWith.
+type Expr struct {
+	source string
+	root   node
+}
+
+func CompileExpr(source string) (*Expr, error) {
+	p := &parser{source: source}
+	if err := p.tokenize(); err != nil {
+		return nil, fmt.Errorf("compile %q: %w", source, err)
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("compile %q: %w", source, err)
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("compile %q: unexpected %q", source, p.peek().text)
+	}
+	return &Expr{source: source, root: root}, nil
+}
+
+func (e *Expr) String() string {
+	return e.source
+}
+
+func (e *Expr) Eval(vars map[string]any) (bool, error) {
+	value, err := e.root.eval(vars)
+	if err != nil {
+		return false, fmt.Errorf("evaluate %q: %w", e.source, err)
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("evaluate %q: result is %T, want bool", e.source, value)
+	}
+	return result, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+type parser struct {
+	source string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) tokenize() error {
+	src := p.source
+	for i := 0; i < len(src); {
+		r := rune(src[i])
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"' || r == '\'':
+			end := i + 1
+			for end < len(src) && src[end] != src[i] {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return fmt.Errorf("unterminated string at offset %d", i)
+			}
+			text := src[i : end+1]
+			if r == '\'' {
+				text = `"` + strings.ReplaceAll(text[1:len(text)-1], `"`, `\"`) + `"`
+			}
+			value, err := strconv.Unquote(text)
+			if err != nil {
+				return fmt.Errorf("invalid string at offset %d: %w", i, err)
+			}
+			p.tokens = append(p.tokens, token{kind: tokenString, text: value})
+			i = end + 1
+		case unicode.IsDigit(r):
+			end := i
+			for end < len(src) && (unicode.IsDigit(rune(src[end])) || src[end] == '.') {
+				end++
+			}
+			p.tokens = append(p.tokens, token{kind: tokenNumber, text: src[i:end]})
+			i = end
+		case unicode.IsLetter(r) || r == '_':
+			end := i
+			for end < len(src) && (unicode.IsLetter(rune(src[end])) || unicode.IsDigit(rune(src[end])) || src[end] == '_') {
+				end++
+			}
+			p.tokens = append(p.tokens, token{kind: tokenIdent, text: src[i:end]})
+			i = end
+		default:
+			matched := false
+			for _, op := range []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "-", "(", ")", "[", "]", ",", "."} {
+				if strings.HasPrefix(src[i:], op) {
+					p.tokens = append(p.tokens, token{kind: tokenOperator, text: op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return fmt.Errorf("unexpected character %q at offset %d", r, i)
+			}
+		}
+	}
+	p.tokens = append(p.tokens, token{kind: tokenEOF})
+	return nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tokenOperator && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		return fmt.Errorf("expected %q, found %q", op, p.peek().text)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept("!") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+	switch {
+	case t.kind == tokenOperator && slices.Contains([]string{"==", "!=", "<", "<=", ">", ">="}, t.text):
+		p.next()
+	case t.kind == tokenIdent && t.text == "in":
+		p.next()
+	default:
+		return left, nil
+	}
+	right, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return compareNode{op: t.text, left: left, right: right}, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept("-") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negNode{operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	value, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind != tokenIdent {
+				return nil, fmt.Errorf("expected field name after '.', found %q", t.text)
+			}
+			value = indexNode{target: value, index: literalNode{value: t.text}}
+		case p.accept("["):
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			value = indexNode{target: value, index: index}
+		default:
+			return value, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return literalNode{value: value}, nil
+	case tokenString:
+		return literalNode{value: t.text}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null":
+			return literalNode{value: nil}, nil
+		}
+		if p.accept("(") {
+			fn, ok := functions[t.text]
+			if !ok {
+				return nil, fmt.Errorf("unknown function %q", t.text)
+			}
+			var args []node
+			for !p.accept(")") {
+				if len(args) > 0 {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+				}
+				arg, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+			}
+			if len(args) != fn.arity {
+				return nil, fmt.Errorf("%s expects %d argument(s), got %d", t.text, fn.arity, len(args))
+			}
+			return callNode{name: t.text, fn: fn.call, args: args}, nil
+		}
+		return identNode{name: t.text}, nil
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			inner, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			var items []node
+			for !p.accept("]") {
+				if len(items) > 0 {
+					if err := p.expect(","); err != nil {
+						return nil, err
+					}
+				}
+				item, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+			return listNode{items: items}, nil
+		}
+	}
+	if t.kind == tokenEOF {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+type node interface {
+	eval(vars map[string]any) (any, error)
+}
+
+type literalNode struct{ value any }
+
+func (n literalNode) eval(map[string]any) (any, error) { return n.value, nil }
+
+type identNode struct{ name string }
+
+func (n identNode) eval(vars map[string]any) (any, error) {
+	value, ok := vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown variable %q", n.name)
+	}
+	return normalizeValue(value), nil
+}
+
+type listNode struct{ items []node }
+
+func (n listNode) eval(vars map[string]any) (any, error) {
+	values := make([]any, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+type indexNode struct {
+	target node
+	index  node
+}
+
+func (n indexNode) eval(vars map[string]any) (any, error) {
+	target, err := n.target.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch target := target.(type) {
+	case map[string]any:
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("object key must be a string, got %T", index)
+		}
+		return normalizeValue(target[key]), nil
+	case []any:
+		position, ok := index.(float64)
+		if !ok || position != float64(int(position)) {
+			return nil, fmt.Errorf("list index must be an integer, got %v", index)
+		}
+		if position < 0 || int(position) >= len(target) {
+			return nil, nil
+		}
+		return normalizeValue(target[int(position)]), nil
+	case nil:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("cannot index %T", target)
+	}
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(vars map[string]any) (any, error) {
+	value, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return nil, fmt.Errorf("operand of ! is %T, want bool", value)
+	}
+	return !b, nil
+}
+
+type negNode struct{ operand node }
+
+func (n negNode) eval(vars map[string]any) (any, error) {
+	value, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	number, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("operand of - is %T, want number", value)
+	}
+	return -number, nil
+}
+
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n logicalNode) eval(vars map[string]any) (any, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	l, ok := left.(bool)
+	if !ok {
+		return nil, fmt.Errorf("left operand of %s is %T, want bool", n.op, left)
+	}
+	if (n.op == "&&" && !l) || (n.op == "||" && l) {
+		return l, nil
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := right.(bool)
+	if !ok {
+		return nil, fmt.Errorf("right operand of %s is %T, want bool", n.op, right)
+	}
+	return r, nil
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(vars map[string]any) (any, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return reflect.DeepEqual(left, right), nil
+	case "!=":
+		return !reflect.DeepEqual(left, right), nil
+	case "in":
+		switch container := right.(type) {
+		case []any:
+			for _, item := range container {
+				if reflect.DeepEqual(left, item) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]any:
+			key, ok := left.(string)
+			if !ok {
+				return false, nil
+			}
+			_, found := container[key]
+			return found, nil
+		case string:
+			sub, ok := left.(string)
+			return ok && strings.Contains(container, sub), nil
+		default:
+			return nil, fmt.Errorf("right operand of in is %T, want list, object or string", right)
+		}
+	}
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare number with %T", right)
+		}
+		return compareOrdered(n.op, l, r), nil
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare string with %T", right)
+		}
+		return compareOrdered(n.op, l, r), nil
+	default:
+		return nil, fmt.Errorf("operator %s does not support %T", n.op, left)
+	}
+}
+
+func compareOrdered[T float64 | string](op string, l, r T) bool {
+	switch op {
+	case "<":
+		return l < r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	default:
+		return l >= r
+	}
+}
+
+type function struct {
+	arity int
+	call  func(args []any) (any, error)
+}
+
+type callNode struct {
+	name string
+	fn   func(args []any) (any, error)
+	args []node
+}
+
+func (n callNode) eval(vars map[string]any) (any, error) {
+	args := make([]any, 0, len(n.args))
+	for _, arg := range n.args {
+		value, err := arg.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+	value, err := n.fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return value, nil
+}
+
+var functions = map[string]function{
+	"size": {arity: 1, call: func(args []any) (any, error) {
+		switch value := args[0].(type) {
+		case string:
+			return float64(len([]rune(value))), nil
+		case []any:
+			return float64(len(value)), nil
+		case map[string]any:
+			return float64(len(value)), nil
+		case nil:
+			return float64(0), nil
+		default:
+			return nil, fmt.Errorf("unsupported argument %T", value)
+		}
+	}},
+	"has": {arity: 1, call: func(args []any) (any, error) {
+		return args[0] != nil, nil
+	}},
+	"matches": {arity: 2, call: func(args []any) (any, error) {
+		text, ok1 := args[0].(string)
+		pattern, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return false, nil
+		}
+		return regexp.MatchString(pattern, text)
+	}},
+	"startsWith": {arity: 2, call: func(args []any) (any, error) {
+		text, ok1 := args[0].(string)
+		prefix, ok2 := args[1].(string)
+		return ok1 && ok2 && strings.HasPrefix(text, prefix), nil
+	}},
+	"endsWith": {arity: 2, call: func(args []any) (any, error) {
+		text, ok1 := args[0].(string)
+		suffix, ok2 := args[1].(string)
+		return ok1 && ok2 && strings.HasSuffix(text, suffix), nil
+	}},
+}
+
+func normalizeValue(value any) any {
+	switch value := value.(type) {
+	case int:
+		return float64(value)
+	case int64:
+		return float64(value)
+	case float32:
+		return float64(value)
+	default:
+		return value
+	}
+}
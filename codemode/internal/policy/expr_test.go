@@ -0,0 +1,182 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileExprErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{source: "", err: "unexpected end of expression"},
+		{source: "args.x >", err: "unexpected end of expression"},
+		{source: "args.x == 1 )", err: `unexpected ")"`},
+		{source: "(args.x == 1", err: `expected ")"`},
+		{source: "args.", err: "expected field name"},
+		{source: "args.x # 1", err: "unexpected character '#'"},
+		{source: "'open", err: "unterminated string"},
+		{source: "1.2.3 == 1", err: `invalid number "1.2.3"`},
+		{source: "nosuch(args)", err: `unknown function "nosuch"`},
+		{source: "size(args, 1)", err: "size expects 1 argument(s), got 2"},
+		{source: "[1, 2", err: `expected ","`},
+	}
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			_, err := CompileExpr(test.source)
+			if err == nil {
+				t.Fatal("CompileExpr() succeeded, want an error")
+			}
+			if !strings.Contains(err.Error(), test.err) {
+				t.Errorf("error %q does not contain %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestExprEval(t *testing.T) {
+	vars := map[string]any{
+		"tool": "demo_quote_rate",
+		"args": map[string]any{
+			"carrier":   "ups_ground",
+			"weight_kg": 12.5,
+			"count":     3,
+			"offset":    -2,
+			"express":   true,
+			"tags":      []any{"fragile", "gift"},
+			"address":   map[string]any{"country": "ES", "zip": "28001"},
+			"note":      nil,
+		},
+	}
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		// Comparisons.
+		{source: "args.weight_kg <= 70", want: true},
+		{source: "args.weight_kg > 12.5", want: false},
+		{source: "args.weight_kg >= 12.5 && args.weight_kg < 13", want: true},
+		{source: "args.count == 3", want: true},
+		{source: "args.count != 3", want: false},
+		{source: "args.carrier == 'ups_ground'", want: true},
+		{source: `args.carrier == "dhl"`, want: false},
+		{source: "args.carrier < 'v'", want: true},
+		{source: "args.express == true", want: true},
+		{source: "args.note == null", want: true},
+		{source: "args.tags == ['fragile', 'gift']", want: true},
+
+		// Unary minus.
+		{source: "args.offset > -3", want: true},
+		{source: "args.offset == -2", want: true},
+		{source: "-args.offset == 2", want: true},
+		{source: "- -1 == 1", want: true},
+		{source: "args.weight_kg > -1 && args.weight_kg < 70", want: true},
+
+		// Logical operators.
+		{source: "!args.express", want: false},
+		{source: "!(args.count > 5)", want: true},
+		{source: "args.count > 5 || args.express", want: true},
+		{source: "args.count > 5 && args.express", want: false},
+
+		// in.
+		{source: "args.carrier in ['ups_ground', 'dhl_economy']", want: true},
+		{source: "args.carrier in ['correos']", want: false},
+		{source: "'fragile' in args.tags", want: true},
+		{source: "'country' in args.address", want: true},
+		{source: "'street' in args.address", want: false},
+		{source: "1 in args.address", want: false},
+		{source: "'ground' in args.carrier", want: true},
+		{source: "3 in [1, 2, 3]", want: true},
+
+		// Functions.
+		{source: "size(args.tags) == 2", want: true},
+		{source: "size(args.carrier) == 10", want: true},
+		{source: "size(args.address) == 2", want: true},
+		{source: "size(args.missing) == 0", want: true},
+		{source: "has(args.carrier)", want: true},
+		{source: "has(args.note)", want: false},
+		{source: "matches(args.address.zip, '^[0-9]{5}$')", want: true},
+		{source: "matches(args.count, '3')", want: false},
+		{source: "startsWith(args.carrier, 'ups_')", want: true},
+		{source: "startsWith(tool, 'files_')", want: false},
+		{source: "endsWith(args.carrier, '_ground')", want: true},
+		{source: "endsWith(args.count, '3')", want: false},
+
+		// Missing fields and indexes are null.
+		{source: "args.missing == null", want: true},
+		{source: "args.missing.deeper == null", want: true},
+		{source: "!has(args.address.street)", want: true},
+		{source: "args.tags[0] == 'fragile'", want: true},
+		{source: "args.tags[5] == null", want: true},
+		{source: "args['carrier'] == 'ups_ground'", want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			expr, err := CompileExpr(test.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := expr.Eval(vars)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("Eval() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestExprEvalErrors(t *testing.T) {
+	vars := map[string]any{
+		"args": map[string]any{
+			"carrier": "ups_ground",
+			"count":   3,
+			"tags":    []any{"fragile"},
+		},
+	}
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{source: "nosuch == 1", err: `unknown variable "nosuch"`},
+		{source: "args.count", err: "result is float64, want bool"},
+		{source: "args.count < 'a'", err: "cannot compare number with string"},
+		{source: "args.carrier > 1", err: "cannot compare string with float64"},
+		{source: "args.tags < 1", err: "operator < does not support []interface {}"},
+		{source: "!args.count", err: "operand of ! is float64, want bool"},
+		{source: "-args.carrier == 1", err: "operand of - is string, want number"},
+		{source: "args.count && true", err: "left operand of && is float64"},
+		{source: "true || args.count", err: ""},
+		{source: "false || args.count", err: "right operand of || is float64"},
+		{source: "args.carrier in 1", err: "right operand of in is float64"},
+		{source: "args.tags[0.5] == null", err: "list index must be an integer"},
+		{source: "args[1] == null", err: "object key must be a string"},
+		{source: "args.count.x == null", err: "cannot index float64"},
+		{source: "size(args.count) == 1", err: "size: unsupported argument float64"},
+		{source: "matches(args.carrier, '(')", err: "matches: error parsing regexp"},
+	}
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			expr, err := CompileExpr(test.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = expr.Eval(vars)
+			if test.err == "" {
+				// Short-circuiting skips the invalid operand.
+				if err != nil {
+					t.Fatalf("Eval() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Eval() succeeded, want an error")
+			}
+			if !strings.Contains(err.Error(), test.err) {
+				t.Errorf("error %q does not contain %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/preblog/codemode/internal/catalog"
+)
+
+type Action string
+
+const (
+	ActionAllow   Action = "allow"
+	ActionDeny    Action = "deny"
+	ActionConfirm Action = "confirm"
+)
+
+// File is the on-disk policy. Rules are matched in order against the helper
+// callable (or server.tool) and the first matching rule decides; calls that
+// match no rule fall back to Default.
+type File struct {
+	Default  Action `json:"default,omitempty"`
+	MaxCalls int    `json:"max_calls,omitempty"`
+	Rules    []Rule `json:"rules"`
+}
+
+type Rule struct {
+	Tool     string             `json:"tool"`
+	Action   Action             `json:"action,omitempty"`
+	MaxCalls int                `json:"max_calls,omitempty"`
+	Schema   *jsonschema.Schema `json:"schema,omitempty"`
+	When     []string           `json:"when,omitempty"`
+	Reason   string             `json:"reason,omitempty"`
+}
+
+type Policy struct {
+	defaultAction Action
+	maxCalls      int
+	rules         []compiledRule
+}
+
+type compiledRule struct {
+	index    int
+	tool     string
+	action   Action
+	maxCalls int
+	schema   *jsonschema.Resolved
+	when     []*Expr
+	reason   string
+}
+
+func Load(path string) (*Policy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read policy file: %w", err)
+	}
+	var file File
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("parse policy file %s: %w", path, err)
+	}
+	policy, err := Compile(file)
+	if err != nil {
+		return nil, fmt.Errorf("policy file %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+func Compile(file File) (*Policy, error) {
+	policy := &Policy{defaultAction: file.Default, maxCalls: file.MaxCalls}
+	if policy.defaultAction == "" {
+		policy.defaultAction = ActionAllow
+	}
+	if err := validateAction(policy.defaultAction); err != nil {
+		return nil, fmt.Errorf("default: %w", err)
+	}
+	if file.MaxCalls < 0 {
+		return nil, errors.New("max_calls must not be negative")
+	}
+	for i, rule := range file.Rules {
+		compiled, err := compileRule(i, rule)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i, rule.Tool, err)
+		}
+		policy.rules = append(policy.rules, compiled)
+	}
+	return policy, nil
+}
+
+func compileRule(index int, rule Rule) (compiledRule, error) {
+	compiled := compiledRule{index: index, tool: rule.Tool, action: rule.Action, maxCalls: rule.MaxCalls, reason: rule.Reason}
+	if compiled.tool == "" {
+		return compiledRule{}, errors.New("tool pattern is required")
+	}
+	if _, err := path.Match(compiled.tool, ""); err != nil {
+		return compiledRule{}, fmt.Errorf("invalid tool pattern: %w", err)
+	}
+	if compiled.action == "" {
+		compiled.action = ActionAllow
+	}
+	if err := validateAction(compiled.action); err != nil {
+		return compiledRule{}, err
+	}
+	if rule.MaxCalls < 0 {
+		return compiledRule{}, errors.New("max_calls must not be negative")
+	}
+	if rule.Schema != nil {
+		resolved, err := rule.Schema.Resolve(nil)
+		if err != nil {
+			return compiledRule{}, fmt.Errorf("resolve schema: %w", err)
+		}
+		compiled.schema = resolved
+	}
+	for _, source := range rule.When {
+		expr, err := CompileExpr(source)
+		if err != nil {
+			return compiledRule{}, err
+		}
+		compiled.when = append(compiled.when, expr)
+	}
+	return compiled, nil
+}
+
+func validateAction(action Action) error {
+	switch action {
+	case ActionAllow, ActionDeny, ActionConfirm:
+		return nil
+	default:
+		return fmt.Errorf("unknown action %q", action)
+	}
+}
+
+func (r compiledRule) matches(item catalog.ToolInfo) bool {
+	for _, name := range []string{item.Callable, item.Server + "." + item.Name} {
+		if ok, _ := path.Match(r.tool, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// check validates the arguments against the rule's schema and expressions.
+// It returns a description of the first violated constraint, or "" when the
+// arguments satisfy the rule.
+func (r compiledRule) check(item catalog.ToolInfo, args map[string]any) (string, error) {
+	if r.schema != nil {
+		if err := r.schema.Validate(args); err != nil {
+			return fmt.Sprintf("arguments do not match schema: %v", err), nil
+		}
+	}
+	vars := map[string]any{
+		"args":   args,
+		"tool":   item.Callable,
+		"server": item.Server,
+		"name":   item.Name,
+	}
+	for _, expr := range r.when {
+		ok, err := expr.Eval(vars)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return fmt.Sprintf("constraint %s is not satisfied", expr), nil
+		}
+	}
+	return "", nil
+}
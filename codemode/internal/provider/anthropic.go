@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+type Anthropic struct {
+	client anthropic.Client
+	model  string
+}
+
+// NewAnthropic talks to the Anthropic Messages API. The SDK also honors
+// ANTHROPIC_BASE_URL when cfg.BaseURL is empty.
+func NewAnthropic(cfg Config) *Anthropic {
+	options := []option.RequestOption{option.WithAPIKey(cfg.APIKey)}
+	if cfg.BaseURL != "" {
+		options = append(options, option.WithBaseURL(cfg.BaseURL))
+	}
+	if cfg.DebugLog != nil {
+		options = append(options, option.WithDebugLog(cfg.DebugLog))
+	}
+	return &Anthropic{client: anthropic.NewClient(options...), model: cfg.Model}
+}
+
+func (p *Anthropic) Name() string {
+	return KindAnthropic
+}
+
+func (p *Anthropic) Model() string {
+	return p.model
+}
+
+func (p *Anthropic) Complete(ctx context.Context, req Request, onDelta func(Delta)) (Response, error) {
+	params := p.params(req)
+	var (
+		message *anthropic.Message
+		err     error
+	)
+	if onDelta != nil {
+		message, err = p.stream(ctx, params, onDelta)
+	} else {
+		message, err = p.client.Messages.New(ctx, params)
+	}
+	if err != nil {
+		return Response{}, err
+	}
+	response := Response{StopReason: string(message.StopReason), Raw: json.RawMessage(message.RawJSON())}
+	response.Message.Role = RoleAssistant
+	var texts []string
+	for _, block := range message.Content {
+		switch block := block.AsAny().(type) {
+		case anthropic.TextBlock:
+			texts = append(texts, block.Text)
+		case anthropic.ToolUseBlock:
+			response.Message.ToolCalls = append(response.Message.ToolCalls, ToolCall{ID: block.ID, Name: block.Name, Input: block.Input})
+		}
+	}
+	response.Message.Text = strings.Join(texts, "\n")
+	return response, nil
+}
+
+func (p *Anthropic) params(req Request) anthropic.MessageNewParams {
+	params := anthropic.MessageNewParams{
+		Model:     anthropic.Model(p.model),
+		MaxTokens: int64(req.MaxTokens),
+		System:    []anthropic.TextBlockParam{{Text: req.System}},
+	}
+	for _, message := range req.Messages {
+		params.Messages = append(params.Messages, anthropicMessage(message))
+	}
+	for _, tool := range req.Tools {
+		schema := anthropic.ToolInputSchemaParam{
+			Properties: tool.InputSchema["properties"],
+			Required:   stringList(tool.InputSchema["required"]),
+		}
+		params.Tools = append(params.Tools, anthropic.ToolUnionParam{OfTool: &anthropic.ToolParam{
+			Name:        tool.Name,
+			Description: anthropic.String(tool.Description),
+			InputSchema: schema,
+		}})
+	}
+	if req.ForceTool != "" {
+		params.ToolChoice = anthropic.ToolChoiceParamOfTool(req.ForceTool)
+	}
+	return params
+}
+
+func anthropicMessage(message Message) anthropic.MessageParam {
+	var blocks []anthropic.ContentBlockParamUnion
+	if message.Text != "" {
+		blocks = append(blocks, anthropic.NewTextBlock(message.Text))
+	}
+	for _, call := range message.ToolCalls {
+		blocks = append(blocks, anthropic.NewToolUseBlock(call.ID, call.Input, call.Name))
+	}
+	for _, result := range message.ToolResults {
+		blocks = append(blocks, anthropic.NewToolResultBlock(result.CallID, result.Output, result.IsError))
+	}
+	if message.Role == RoleAssistant {
+		return anthropic.NewAssistantMessage(blocks...)
+	}
+	return anthropic.NewUserMessage(blocks...)
+}
+
+func (p *Anthropic) stream(ctx context.Context, params anthropic.MessageNewParams, onDelta func(Delta)) (*anthropic.Message, error) {
+	stream := p.client.Messages.NewStreaming(ctx, params)
+	defer stream.Close()
+
+	message := &anthropic.Message{}
+	for stream.Next() {
+		event := stream.Current()
+		// A stop event is looked up in the accumulated content below, so an
+		// index past the started blocks is rejected before it is used.
+		if event.Type == "content_block_stop" && (event.Index < 0 || int(event.Index) >= len(message.Content)) {
+			return nil, fmt.Errorf("stream stopped content block %d, but only %d started", event.Index, len(message.Content))
+		}
+		if err := message.Accumulate(event); err != nil {
+			return nil, fmt.Errorf("accumulate stream event: %w", err)
+		}
+		switch event := event.AsAny().(type) {
+		case anthropic.ContentBlockDeltaEvent:
+			if delta, ok := event.Delta.AsAny().(anthropic.TextDelta); ok && delta.Text != "" {
+				onDelta(Delta{Text: delta.Text})
+			}
+		case anthropic.ContentBlockStopEvent:
+			if block, ok := message.Content[event.Index].AsAny().(anthropic.ToolUseBlock); ok {
+				onDelta(Delta{ToolCall: &ToolCall{ID: block.ID, Name: block.Name, Input: block.Input}})
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// streamEvents serves a Messages API stream made of the given event data.
+func streamEvents(events ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range events {
+			event := data[strings.Index(data, `"type":"`)+len(`"type":"`):]
+			event = event[:strings.Index(event, `"`)]
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		}
+	}
+}
+
+const messageStart = `{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"test-model","content":[],"stop_reason":null,"usage":{"input_tokens":0,"output_tokens":0}}}`
+
+func TestAnthropicStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []string
+		calls  []string
+		err    string
+	}{
+		{
+			name: "tool call",
+			events: []string{
+				messageStart,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"call_1","name":"search","input":{}}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"query\":\"time\"}"}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":1}}`,
+				`{"type":"message_stop"}`,
+			},
+			calls: []string{`call_1 search {"query":"time"}`},
+		},
+		{
+			name: "stop of a block that never started",
+			events: []string{
+				messageStart,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"content_block_stop","index":3}`,
+			},
+			err: "stream stopped content block 3, but only 1 started",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(streamEvents(test.events...))
+			defer server.Close()
+
+			provider := NewAnthropic(Config{Model: "test-model", APIKey: "test", BaseURL: server.URL})
+			var calls []string
+			_, err := provider.Complete(context.Background(), Request{MaxTokens: 16}, func(delta Delta) {
+				if delta.ToolCall != nil {
+					calls = append(calls, fmt.Sprintf("%s %s %s", delta.ToolCall.ID, delta.ToolCall.Name, delta.ToolCall.Input))
+				}
+			})
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("Complete() error = %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(calls, "\n") != strings.Join(test.calls, "\n") {
+				t.Errorf("tool calls = %q, want %q", calls, test.calls)
+			}
+		})
+	}
+}
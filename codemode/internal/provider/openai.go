@@ -0,0 +1,322 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"sort"
+	"strings"
+)
+
+const DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// OpenAI speaks the chat completions API, which OpenAI and most local servers
+// such as llama.cpp and Ollama implement.
+type OpenAI struct {
+	httpClient *http.Client
+	baseURL    string
+	apiKey     string
+	model      string
+}
+
+// NewOpenAI falls back to OPENAI_BASE_URL and then the OpenAI endpoint when
+// cfg.BaseURL is empty. The API key is optional for local servers.
+func NewOpenAI(cfg Config) *OpenAI {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = os.Getenv("OPENAI_BASE_URL")
+	}
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	client := &http.Client{}
+	if cfg.DebugLog != nil {
+		client.Transport = &debugTransport{next: http.DefaultTransport, logger: cfg.DebugLog}
+	}
+	return &OpenAI{httpClient: client, baseURL: strings.TrimRight(baseURL, "/"), apiKey: cfg.APIKey, model: cfg.Model}
+}
+
+func (p *OpenAI) Name() string {
+	return KindOpenAI
+}
+
+func (p *OpenAI) Model() string {
+	return p.model
+}
+
+type chatMessage struct {
+	Role       string         `json:"role"`
+	Content    *string        `json:"content"`
+	ToolCalls  []chatToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string         `json:"tool_call_id,omitempty"`
+}
+
+type chatToolCall struct {
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatChoice struct {
+	Message      chatMessage `json:"message"`
+	Delta        chatMessage `json:"delta"`
+	FinishReason string      `json:"finish_reason"`
+}
+
+type chatCompletion struct {
+	Choices []chatChoice `json:"choices"`
+	Error   *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (p *OpenAI) Complete(ctx context.Context, req Request, onDelta func(Delta)) (Response, error) {
+	body, err := json.Marshal(p.requestBody(req, onDelta != nil))
+	if err != nil {
+		return Response{}, fmt.Errorf("marshal chat completion request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return Response{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	httpResp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return Response{}, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(httpResp.Body, 64*1024))
+		return Response{}, fmt.Errorf("chat completion: %s: %s", httpResp.Status, strings.TrimSpace(string(b)))
+	}
+	if onDelta != nil {
+		return p.readStream(httpResp.Body, onDelta)
+	}
+
+	raw, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("read chat completion: %w", err)
+	}
+	var completion chatCompletion
+	if err := json.Unmarshal(raw, &completion); err != nil {
+		return Response{}, fmt.Errorf("parse chat completion: %w", err)
+	}
+	if completion.Error != nil {
+		return Response{}, fmt.Errorf("chat completion: %s", completion.Error.Message)
+	}
+	if len(completion.Choices) == 0 {
+		return Response{}, fmt.Errorf("chat completion returned no choices")
+	}
+	choice := completion.Choices[0]
+	response := Response{StopReason: choice.FinishReason, Raw: raw}
+	response.Message = assistantMessage(choice.Message.Content, choice.Message.ToolCalls)
+	return response, nil
+}
+
+func (p *OpenAI) requestBody(req Request, stream bool) map[string]any {
+	system := req.System
+	messages := []chatMessage{{Role: "system", Content: &system}}
+	for _, message := range req.Messages {
+		messages = append(messages, chatMessages(message)...)
+	}
+	body := map[string]any{
+		"model":      p.model,
+		"messages":   messages,
+		"max_tokens": req.MaxTokens,
+	}
+	if stream {
+		body["stream"] = true
+	}
+	if len(req.Tools) > 0 {
+		tools := make([]map[string]any, 0, len(req.Tools))
+		for _, tool := range req.Tools {
+			tools = append(tools, map[string]any{
+				"type": "function",
+				"function": map[string]any{
+					"name":        tool.Name,
+					"description": tool.Description,
+					"parameters":  tool.InputSchema,
+				},
+			})
+		}
+		body["tools"] = tools
+	}
+	if req.ForceTool != "" {
+		body["tool_choice"] = map[string]any{"type": "function", "function": map[string]any{"name": req.ForceTool}}
+	}
+	return body
+}
+
+func chatMessages(message Message) []chatMessage {
+	if len(message.ToolResults) > 0 {
+		messages := make([]chatMessage, 0, len(message.ToolResults))
+		for _, result := range message.ToolResults {
+			output := result.Output
+			if result.IsError {
+				output = "Error: " + output
+			}
+			messages = append(messages, chatMessage{Role: "tool", Content: &output, ToolCallID: result.CallID})
+		}
+		return messages
+	}
+	chat := chatMessage{Role: string(message.Role)}
+	if message.Text != "" || len(message.ToolCalls) == 0 {
+		text := message.Text
+		chat.Content = &text
+	}
+	for _, call := range message.ToolCalls {
+		toolCall := chatToolCall{ID: call.ID, Type: "function"}
+		toolCall.Function.Name = call.Name
+		toolCall.Function.Arguments = string(call.Input)
+		chat.ToolCalls = append(chat.ToolCalls, toolCall)
+	}
+	return []chatMessage{chat}
+}
+
+func assistantMessage(content *string, calls []chatToolCall) Message {
+	message := Message{Role: RoleAssistant}
+	if content != nil {
+		message.Text = strings.TrimSpace(*content)
+	}
+	for i, call := range calls {
+		id := call.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", i+1)
+		}
+		message.ToolCalls = append(message.ToolCalls, ToolCall{ID: id, Name: call.Function.Name, Input: toolInput(call.Function.Arguments)})
+	}
+	return message
+}
+
+// toolInput keeps malformed arguments from local models as a JSON string, so
+// the tool reports the parse error back to the model instead of the loop
+// failing.
+func toolInput(arguments string) json.RawMessage {
+	arguments = strings.TrimSpace(arguments)
+	if arguments == "" {
+		return json.RawMessage(`{}`)
+	}
+	if json.Valid([]byte(arguments)) {
+		return json.RawMessage(arguments)
+	}
+	b, _ := json.Marshal(arguments)
+	return b
+}
+
+func (p *OpenAI) readStream(body io.Reader, onDelta func(Delta)) (Response, error) {
+	var (
+		text         strings.Builder
+		finishReason string
+		calls        = map[int]*chatToolCall{}
+	)
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 8*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+		var chunk chatCompletion
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return Response{}, fmt.Errorf("parse chat completion chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return Response{}, fmt.Errorf("chat completion: %s", chunk.Error.Message)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != nil && *choice.Delta.Content != "" {
+				text.WriteString(*choice.Delta.Content)
+				onDelta(Delta{Text: *choice.Delta.Content})
+			}
+			for _, delta := range choice.Delta.ToolCalls {
+				index := len(calls)
+				if delta.Index != nil {
+					index = *delta.Index
+				}
+				call, ok := calls[index]
+				if !ok {
+					call = &chatToolCall{}
+					calls[index] = call
+				}
+				if delta.ID != "" {
+					call.ID = delta.ID
+				}
+				if delta.Function.Name != "" {
+					call.Function.Name = delta.Function.Name
+				}
+				call.Function.Arguments += delta.Function.Arguments
+			}
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Response{}, fmt.Errorf("read chat completion stream: %w", err)
+	}
+
+	indexes := make([]int, 0, len(calls))
+	for index := range calls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	ordered := make([]chatToolCall, 0, len(indexes))
+	for _, index := range indexes {
+		ordered = append(ordered, *calls[index])
+	}
+	content := text.String()
+	message := assistantMessage(&content, ordered)
+	for i := range message.ToolCalls {
+		onDelta(Delta{ToolCall: &message.ToolCalls[i]})
+	}
+	return Response{Message: message, StopReason: finishReason}, nil
+}
+
+// debugTransport logs requests and responses in the same format as the
+// Anthropic SDK's debug logger so both end up in -save-trace the same way.
+type debugTransport struct {
+	next   http.RoundTripper
+	logger *log.Logger
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	redacted := req.Clone(req.Context())
+	if redacted.Header.Get("Authorization") != "" {
+		redacted.Header.Set("Authorization", "<secure>")
+	}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			redacted.Body = body
+		}
+	}
+	if dump, err := httputil.DumpRequestOut(redacted, true); err == nil {
+		t.logger.Printf("Request Content:\n%s\n", dump)
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	streaming := strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+	if dump, err := httputil.DumpResponse(resp, !streaming); err == nil {
+		t.logger.Printf("Response Content:\n%s\n", dump)
+	}
+	return resp, nil
+}
@@ -0,0 +1,115 @@
+// Package provider adapts chat model APIs to the provider-neutral messages the
+// codemode tool loop works with.
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+type Tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type ToolCall struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+type ToolResult struct {
+	CallID  string `json:"call_id"`
+	Output  string `json:"output"`
+	IsError bool   `json:"is_error,omitempty"`
+}
+
+// Message is one conversation entry. User messages carry Text or ToolResults,
+// assistant messages Text and ToolCalls.
+type Message struct {
+	Role        Role         `json:"role"`
+	Text        string       `json:"text,omitempty"`
+	ToolCalls   []ToolCall   `json:"tool_calls,omitempty"`
+	ToolResults []ToolResult `json:"tool_results,omitempty"`
+}
+
+type Request struct {
+	System    string    `json:"system"`
+	Messages  []Message `json:"messages"`
+	Tools     []Tool    `json:"tools"`
+	ForceTool string    `json:"force_tool,omitempty"`
+	MaxTokens int       `json:"max_tokens"`
+}
+
+type Response struct {
+	Message    Message         `json:"message"`
+	StopReason string          `json:"stop_reason,omitempty"`
+	Raw        json.RawMessage `json:"raw,omitempty"`
+}
+
+// Delta is a piece of a streamed response: either text or a finished tool call.
+type Delta struct {
+	Text     string
+	ToolCall *ToolCall
+}
+
+type Provider interface {
+	Name() string
+	Model() string
+	// Complete sends the request and returns the assistant reply. When onDelta
+	// is non-nil the response is streamed and onDelta sees each piece as it
+	// arrives.
+	Complete(ctx context.Context, req Request, onDelta func(Delta)) (Response, error)
+}
+
+const (
+	KindAnthropic = "anthropic"
+	KindOpenAI    = "openai"
+)
+
+type Config struct {
+	Kind    string
+	Model   string
+	APIKey  string
+	BaseURL string
+	// DebugLog receives the raw HTTP requests and responses when set.
+	DebugLog *log.Logger
+}
+
+func New(cfg Config) (Provider, error) {
+	switch cfg.Kind {
+	case "", KindAnthropic:
+		return NewAnthropic(cfg), nil
+	case KindOpenAI:
+		return NewOpenAI(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown model provider %q", cfg.Kind)
+	}
+}
+
+func stringList(value any) []string {
+	switch value := value.(type) {
+	case []string:
+		return value
+	case []any:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			if text, ok := item.(string); ok {
+				list = append(list, text)
+			}
+		}
+		return list
+	default:
+		return nil
+	}
+}
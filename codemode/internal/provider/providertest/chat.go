@@ -0,0 +1,90 @@
+package providertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+func buildCompletion(index int, model string, turn Turn) map[string]any {
+	message := map[string]any{"role": "assistant", "content": turn.Text}
+	var toolCalls []map[string]any
+	for i, toolUse := range turn.ToolUses {
+		id := toolUse.ID
+		if id == "" {
+			id = fmt.Sprintf("call_fake_%d_%d", index+1, i+1)
+		}
+		input := toolUse.Input
+		if len(input) == 0 {
+			input = json.RawMessage(`{}`)
+		}
+		toolCalls = append(toolCalls, map[string]any{
+			"id":       id,
+			"type":     "function",
+			"function": map[string]any{"name": toolUse.Name, "arguments": string(input)},
+		})
+	}
+	finishReason := "stop"
+	if len(toolCalls) > 0 {
+		message["tool_calls"] = toolCalls
+		finishReason = "tool_calls"
+	}
+	return map[string]any{
+		"id":      fmt.Sprintf("chatcmpl_fake_%d", index+1),
+		"object":  "chat.completion",
+		"model":   model,
+		"choices": []map[string]any{{"index": 0, "message": message, "finish_reason": finishReason}},
+	}
+}
+
+// streamCompletion splits the content and each tool call's arguments into
+// chunks the way llama.cpp and Ollama stream them.
+func (h *Handler) streamCompletion(w http.ResponseWriter, completion map[string]any) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	send := func(delta map[string]any, finishReason any) {
+		chunk := map[string]any{
+			"id":      completion["id"],
+			"object":  "chat.completion.chunk",
+			"model":   completion["model"],
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		}
+		b, _ := json.Marshal(chunk)
+		fmt.Fprintf(w, "data: %s\n\n", b)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if h.ChunkDelay > 0 {
+			time.Sleep(h.ChunkDelay)
+		}
+	}
+
+	choice := completion["choices"].([]map[string]any)[0]
+	message := choice["message"].(map[string]any)
+	send(map[string]any{"role": "assistant"}, nil)
+	if text, _ := message["content"].(string); text != "" {
+		for _, chunk := range chunks(text, h.ChunkSize) {
+			send(map[string]any{"content": chunk}, nil)
+		}
+	}
+	toolCalls, _ := message["tool_calls"].([]map[string]any)
+	for index, call := range toolCalls {
+		function := call["function"].(map[string]any)
+		send(map[string]any{"tool_calls": []map[string]any{{
+			"index":    index,
+			"id":       call["id"],
+			"type":     "function",
+			"function": map[string]any{"name": function["name"], "arguments": ""},
+		}}}, nil)
+		for _, chunk := range chunks(function["arguments"].(string), h.ChunkSize) {
+			send(map[string]any{"tool_calls": []map[string]any{{"index": index, "function": map[string]any{"arguments": chunk}}}}, nil)
+		}
+	}
+	send(map[string]any{}, choice["finish_reason"])
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,229 @@
+// Package providertest serves scripted model turns to every provider, so the
+// tool loop can run without network access. The script is served as Anthropic
+// Messages API responses on /v1/messages and as OpenAI chat completions on
+// /v1/chat/completions, streamed as server-sent events or as plain JSON.
+package providertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"time"
+)
+
+type Turn struct {
+	Text     string    `json:"text,omitempty"`
+	ToolUses []ToolUse `json:"tool_uses,omitempty"`
+}
+
+type ToolUse struct {
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input,omitempty"`
+}
+
+type Handler struct {
+	ChunkSize  int
+	ChunkDelay time.Duration
+
+	mu       sync.Mutex
+	turns    []Turn
+	next     int
+	requests []json.RawMessage
+}
+
+type Server struct {
+	*Handler
+	URL    string
+	server *httptest.Server
+}
+
+func NewHandler(turns ...Turn) *Handler {
+	return &Handler{ChunkSize: 16, turns: turns}
+}
+
+func NewServer(turns ...Turn) *Server {
+	handler := NewHandler(turns...)
+	server := httptest.NewServer(handler)
+	return &Server{Handler: handler, URL: server.URL, server: server}
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+func LoadScript(path string) ([]Turn, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read script: %w", err)
+	}
+	var turns []Turn
+	if err := json.Unmarshal(b, &turns); err != nil {
+		return nil, fmt.Errorf("parse script %s: %w", path, err)
+	}
+	return turns, nil
+}
+
+func (h *Handler) Requests() []json.RawMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]json.RawMessage(nil), h.requests...)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	chat := r.URL.Path == "/v1/chat/completions"
+	if r.Method != http.MethodPost || (r.URL.Path != "/v1/messages" && !chat) {
+		writeError(w, http.StatusNotFound, "not_found_error", fmt.Sprintf("unsupported endpoint %s %s", r.Method, r.URL.Path))
+		return
+	}
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	var request struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	h.mu.Lock()
+	h.requests = append(h.requests, body)
+	index := h.next
+	if index < len(h.turns) {
+		h.next++
+	}
+	h.mu.Unlock()
+	if index >= len(h.turns) {
+		writeError(w, http.StatusInternalServerError, "api_error", fmt.Sprintf("script exhausted after %d turn(s)", len(h.turns)))
+		return
+	}
+
+	if chat {
+		completion := buildCompletion(index, request.Model, h.turns[index])
+		if !request.Stream {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(completion)
+			return
+		}
+		h.streamCompletion(w, completion)
+		return
+	}
+	message := buildMessage(index, request.Model, h.turns[index])
+	if !request.Stream {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(message)
+		return
+	}
+	h.streamMessage(w, message)
+}
+
+func (h *Handler) streamMessage(w http.ResponseWriter, message map[string]any) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	send := func(event string, data map[string]any) {
+		data["type"] = event
+		b, _ := json.Marshal(data)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if h.ChunkDelay > 0 {
+			time.Sleep(h.ChunkDelay)
+		}
+	}
+
+	content, _ := message["content"].([]map[string]any)
+	start := map[string]any{}
+	for key, value := range message {
+		start[key] = value
+	}
+	start["content"] = []any{}
+	start["stop_reason"] = nil
+	send("message_start", map[string]any{"message": start})
+
+	for index, block := range content {
+		switch block["type"] {
+		case "text":
+			text, _ := block["text"].(string)
+			send("content_block_start", map[string]any{"index": index, "content_block": map[string]any{"type": "text", "text": ""}})
+			for _, chunk := range chunks(text, h.ChunkSize) {
+				send("content_block_delta", map[string]any{"index": index, "delta": map[string]any{"type": "text_delta", "text": chunk}})
+			}
+		case "tool_use":
+			input, _ := block["input"].(json.RawMessage)
+			send("content_block_start", map[string]any{"index": index, "content_block": map[string]any{"type": "tool_use", "id": block["id"], "name": block["name"], "input": map[string]any{}}})
+			for _, chunk := range chunks(string(input), h.ChunkSize) {
+				send("content_block_delta", map[string]any{"index": index, "delta": map[string]any{"type": "input_json_delta", "partial_json": chunk}})
+			}
+		}
+		send("content_block_stop", map[string]any{"index": index})
+	}
+
+	send("message_delta", map[string]any{
+		"delta": map[string]any{"stop_reason": message["stop_reason"], "stop_sequence": nil},
+		"usage": map[string]any{"output_tokens": 0},
+	})
+	send("message_stop", map[string]any{})
+}
+
+func buildMessage(index int, model string, turn Turn) map[string]any {
+	content := []map[string]any{}
+	if turn.Text != "" {
+		content = append(content, map[string]any{"type": "text", "text": turn.Text})
+	}
+	for i, toolUse := range turn.ToolUses {
+		id := toolUse.ID
+		if id == "" {
+			id = fmt.Sprintf("toolu_fake_%d_%d", index+1, i+1)
+		}
+		input := toolUse.Input
+		if len(input) == 0 {
+			input = json.RawMessage(`{}`)
+		}
+		content = append(content, map[string]any{"type": "tool_use", "id": id, "name": toolUse.Name, "input": input})
+	}
+	stopReason := "end_turn"
+	if len(turn.ToolUses) > 0 {
+		stopReason = "tool_use"
+	}
+	return map[string]any{
+		"id":            fmt.Sprintf("msg_fake_%d", index+1),
+		"type":          "message",
+		"role":          "assistant",
+		"model":         model,
+		"content":       content,
+		"stop_reason":   stopReason,
+		"stop_sequence": nil,
+		"usage":         map[string]any{"input_tokens": 0, "output_tokens": 0},
+	}
+}
+
+func chunks(text string, size int) []string {
+	if size <= 0 || len(text) <= size {
+		return []string{text}
+	}
+	runes := []rune(text)
+	parts := make([]string, 0, len(runes)/size+1)
+	for len(runes) > size {
+		parts = append(parts, string(runes[:size]))
+		runes = runes[size:]
+	}
+	return append(parts, string(runes))
+}
+
+func writeError(w http.ResponseWriter, status int, errorType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"type":  "error",
+		"error": map[string]any{"type": errorType, "message": message},
+	})
+}
@@ -0,0 +1,128 @@
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Backend is the MCP side of a run: the servers the catalog is loaded from and
+// the tool calls the sandbox makes.
+type Backend interface {
+	Servers() []string
+	ListTools(ctx context.Context, server string) ([]*mcp.Tool, error)
+	CallTool(ctx context.Context, server, name string, args map[string]any) (*mcp.CallToolResult, error)
+	Close() error
+}
+
+type recordingBackend struct {
+	Backend
+	recorder *Recorder
+}
+
+// RecordBackend records the tool lists and every helper call made through next.
+func RecordBackend(next Backend, recorder *Recorder) Backend {
+	return &recordingBackend{Backend: next, recorder: recorder}
+}
+
+func (b *recordingBackend) ListTools(ctx context.Context, server string) ([]*mcp.Tool, error) {
+	tools, err := b.Backend.ListTools(ctx, server)
+	if err == nil {
+		b.recorder.Record(Entry{Kind: KindTools, Server: server, Tools: tools})
+	}
+	return tools, err
+}
+
+func (b *recordingBackend) CallTool(ctx context.Context, server, name string, args map[string]any) (*mcp.CallToolResult, error) {
+	result, err := b.Backend.CallTool(ctx, server, name, args)
+	entry := Entry{Kind: KindHelperCall, Server: server, Name: name, Args: args, Result: result}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	b.recorder.Record(entry)
+	return result, err
+}
+
+// ReplayBackend serves tool lists and helper results from a recording. Helper
+// calls are matched by server, tool and arguments; repeated identical calls
+// get the recorded results in order, and the last one once they run out.
+type ReplayBackend struct {
+	servers []string
+	tools   map[string][]*mcp.Tool
+
+	mu    sync.Mutex
+	calls map[string][]Entry
+}
+
+func NewReplayBackend(recording *Recording) (*ReplayBackend, error) {
+	b := &ReplayBackend{tools: map[string][]*mcp.Tool{}, calls: map[string][]Entry{}}
+	for _, entry := range recording.filter(KindTools) {
+		if _, ok := b.tools[entry.Server]; !ok {
+			b.servers = append(b.servers, entry.Server)
+		}
+		b.tools[entry.Server] = entry.Tools
+	}
+	if len(b.servers) == 0 {
+		return nil, errors.New("recording has no tool catalog to replay")
+	}
+	for _, entry := range recording.filter(KindHelperCall) {
+		key, err := helperKey(entry.Server, entry.Name, entry.Args)
+		if err != nil {
+			return nil, err
+		}
+		b.calls[key] = append(b.calls[key], entry)
+	}
+	return b, nil
+}
+
+func (b *ReplayBackend) Servers() []string {
+	return append([]string(nil), b.servers...)
+}
+
+func (b *ReplayBackend) ListTools(ctx context.Context, server string) ([]*mcp.Tool, error) {
+	tools, ok := b.tools[server]
+	if !ok {
+		return nil, fmt.Errorf("recording has no tools for server %q", server)
+	}
+	return tools, nil
+}
+
+func (b *ReplayBackend) CallTool(ctx context.Context, server, name string, args map[string]any) (*mcp.CallToolResult, error) {
+	key, err := helperKey(server, name, args)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	queue := b.calls[key]
+	if len(queue) == 0 {
+		return nil, fmt.Errorf("replay: no recorded result for %s.%s with these arguments", server, name)
+	}
+	entry := queue[0]
+	if len(queue) > 1 {
+		b.calls[key] = queue[1:]
+	}
+	if entry.Error != "" {
+		return nil, errors.New(entry.Error)
+	}
+	return entry.Result, nil
+}
+
+func (b *ReplayBackend) Close() error {
+	return nil
+}
+
+func helperKey(server, name string, args map[string]any) (string, error) {
+	if args == nil {
+		args = map[string]any{}
+	}
+	b, err := json.Marshal(args)
+	if err != nil {
+		return "", fmt.Errorf("replay: marshal %s.%s arguments: %w", server, name, err)
+	}
+	return server + "\x00" + name + "\x00" + string(b), nil
+}
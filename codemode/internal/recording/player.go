@@ -0,0 +1,128 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Player hands out the recorded model responses turn by turn and notes every
+// place where the replayed run diverges from the recording: a different model
+// request, a different tool result or a different final answer.
+type Player struct {
+	responses []Entry
+	requests  map[int]json.RawMessage
+	results   map[string]Entry
+	final     *Entry
+
+	mu          sync.Mutex
+	next        int
+	divergences []string
+}
+
+func NewPlayer(recording *Recording) (*Player, error) {
+	p := &Player{
+		responses: recording.filter(KindModelResponse),
+		requests:  map[int]json.RawMessage{},
+		results:   map[string]Entry{},
+	}
+	if len(p.responses) == 0 {
+		return nil, fmt.Errorf("recording has no model responses to replay")
+	}
+	for _, entry := range recording.filter(KindModelRequest) {
+		p.requests[entry.Turn] = entry.Request
+	}
+	for _, entry := range recording.filter(KindToolResult) {
+		p.results[entry.ToolID] = entry
+	}
+	if results := recording.filter(KindResult); len(results) > 0 {
+		p.final = &results[len(results)-1]
+	}
+	return p, nil
+}
+
+// Next returns the recorded response for turn after comparing request with
+// the request recorded for the same turn.
+func (p *Player) Next(turn int, request json.RawMessage) (json.RawMessage, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if recorded, ok := p.requests[turn]; ok {
+		if diff := jsonDifference(recorded, request); diff != "" {
+			p.divergences = append(p.divergences, fmt.Sprintf("turn %d model request: %s", turn, diff))
+		}
+	}
+	if p.next >= len(p.responses) {
+		return nil, fmt.Errorf("replay: recording has no model response for turn %d", turn)
+	}
+	response := p.responses[p.next]
+	p.next++
+	return response.Response, nil
+}
+
+func (p *Player) CompareToolResult(turn int, toolID, name, output string, isError bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	recorded, ok := p.results[toolID]
+	if !ok {
+		p.divergences = append(p.divergences, fmt.Sprintf("turn %d tool %s (%s): not in the recording", turn, name, toolID))
+		return
+	}
+	if recorded.IsError != isError {
+		p.divergences = append(p.divergences, fmt.Sprintf("turn %d tool %s (%s): is_error %t, recorded %t", turn, name, toolID, isError, recorded.IsError))
+		return
+	}
+	if diff := textDifference(recorded.Output, output); diff != "" {
+		p.divergences = append(p.divergences, fmt.Sprintf("turn %d tool %s (%s): %s", turn, name, toolID, diff))
+	}
+}
+
+func (p *Player) CompareResult(text, errText string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.final == nil {
+		return
+	}
+	if p.final.Error != errText {
+		p.divergences = append(p.divergences, fmt.Sprintf("run error %q, recorded %q", errText, p.final.Error))
+	}
+	if diff := textDifference(p.final.Output, text); diff != "" {
+		p.divergences = append(p.divergences, "final answer: "+diff)
+	}
+}
+
+func (p *Player) Divergences() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.divergences...)
+}
+
+func jsonDifference(recorded, replayed json.RawMessage) string {
+	var a, b bytes.Buffer
+	if json.Compact(&a, recorded) != nil || json.Compact(&b, replayed) != nil {
+		return textDifference(string(recorded), string(replayed))
+	}
+	return textDifference(a.String(), b.String())
+}
+
+// textDifference describes the first difference between two strings with a
+// little context on both sides, or returns "" when they are equal.
+func textDifference(recorded, replayed string) string {
+	if recorded == replayed {
+		return ""
+	}
+	const window = 40
+	i := 0
+	for i < len(recorded) && i < len(replayed) && recorded[i] == replayed[i] {
+		i++
+	}
+	start := max(0, i-window)
+	return fmt.Sprintf("differs at byte %d: recorded %q, replayed %q", i, excerpt(recorded, start, i+window), excerpt(replayed, start, i+window))
+}
+
+func excerpt(s string, start, end int) string {
+	if start >= len(s) {
+		return ""
+	}
+	return s[start:min(end, len(s))]
+}
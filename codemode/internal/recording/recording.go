@@ -0,0 +1,141 @@
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type Kind string
+
+const (
+	KindRun           Kind = "run"
+	KindTools         Kind = "tools"
+	KindModelRequest  Kind = "model_request"
+	KindModelResponse Kind = "model_response"
+	KindToolCall      Kind = "tool_call"
+	KindToolResult    Kind = "tool_result"
+	KindHelperCall    Kind = "helper_call"
+	KindResult        Kind = "result"
+)
+
+// Entry is one line of a JSONL recording. Which fields are set depends on Kind:
+// model requests and responses carry the provider-neutral request and response
+// (the response keeps the provider's raw JSON when there is one), tool calls and
+// results the search/execute exchange, helper calls one MCP tool call made
+// from the sandbox together with its result.
+type Entry struct {
+	Kind     Kind                `json:"kind"`
+	Time     time.Time           `json:"time"`
+	Turn     int                 `json:"turn,omitempty"`
+	Provider string              `json:"provider,omitempty"`
+	Model    string              `json:"model,omitempty"`
+	Prompt   string              `json:"prompt,omitempty"`
+	Server   string              `json:"server,omitempty"`
+	Tools    []*mcp.Tool         `json:"tools,omitempty"`
+	Request  json.RawMessage     `json:"request,omitempty"`
+	Response json.RawMessage     `json:"response,omitempty"`
+	ToolID   string              `json:"tool_id,omitempty"`
+	Name     string              `json:"name,omitempty"`
+	Input    json.RawMessage     `json:"input,omitempty"`
+	Output   string              `json:"output,omitempty"`
+	IsError  bool                `json:"is_error,omitempty"`
+	Args     map[string]any      `json:"args,omitempty"`
+	Result   *mcp.CallToolResult `json:"result,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// Recorder appends entries to a JSONL file. It is safe for concurrent use
+// because helper calls are recorded from the sandbox worker goroutines.
+type Recorder struct {
+	mu      sync.Mutex
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	err     error
+}
+
+func Create(path string) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create recording: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	return &Recorder{file: file, writer: writer, encoder: json.NewEncoder(writer)}, nil
+}
+
+func (r *Recorder) Record(entry Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	if err := r.encoder.Encode(entry); err != nil {
+		r.err = fmt.Errorf("write recording: %w", err)
+		return
+	}
+	if err := r.writer.Flush(); err != nil {
+		r.err = fmt.Errorf("write recording: %w", err)
+	}
+}
+
+// Close flushes the file and reports the first write error, if any.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return r.err
+	}
+	err := errors.Join(r.err, r.writer.Flush(), r.file.Close())
+	r.file = nil
+	return err
+}
+
+type Recording struct {
+	Entries []Entry
+}
+
+func Load(path string) (*Recording, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open recording: %w", err)
+	}
+	defer file.Close()
+
+	recording := &Recording{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("parse recording %s line %d: %w", path, line, err)
+		}
+		recording.Entries = append(recording.Entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read recording %s: %w", path, err)
+	}
+	return recording, nil
+}
+
+func (r *Recording) filter(kind Kind) []Entry {
+	var entries []Entry
+	for _, entry := range r.Entries {
+		if entry.Kind == kind {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,203 @@
+package recording
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// sample is a two turn run: a search call, then the final answer. The first
+// request is recorded with extra whitespace, which replay ignores.
+func sample() *Recording {
+	return &Recording{Entries: []Entry{
+		{Kind: KindRun, Provider: "anthropic", Model: "test-model", Prompt: "time in Tokyo?"},
+		{Kind: KindTools, Server: "demo", Tools: []*mcp.Tool{{Name: "city_time"}}},
+		{Kind: KindModelRequest, Turn: 1, Request: json.RawMessage(`{"messages": ["time in Tokyo?"]}`)},
+		{Kind: KindModelResponse, Turn: 1, Response: json.RawMessage(`{"tool_calls":[{"id":"call_1","name":"search"}]}`)},
+		{Kind: KindToolCall, Turn: 1, ToolID: "call_1", Name: "search", Input: json.RawMessage(`{"query":"time"}`)},
+		{Kind: KindToolResult, Turn: 1, ToolID: "call_1", Name: "search", Output: `{"api_definition":"demo_city_time"}`},
+		{Kind: KindModelRequest, Turn: 2, Request: json.RawMessage(`{"messages":["time in Tokyo?","search"]}`)},
+		{Kind: KindModelResponse, Turn: 2, Response: json.RawMessage(`{"text":"It is noon."}`)},
+		{Kind: KindResult, Output: "It is noon."},
+	}}
+}
+
+func TestRecordAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.jsonl")
+	recorder, err := Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range sample().Entries {
+		recorder.Record(entry)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sample().Entries
+	if len(loaded.Entries) != len(want) {
+		t.Fatalf("loaded %d entries, want %d", len(loaded.Entries), len(want))
+	}
+	for i, entry := range loaded.Entries {
+		if entry.Kind != want[i].Kind || entry.Turn != want[i].Turn || entry.Output != want[i].Output || entry.Time.IsZero() {
+			t.Errorf("entry %d = %+v, want %+v", i, entry, want[i])
+		}
+	}
+}
+
+func TestPlayer(t *testing.T) {
+	const (
+		request1 = `{"messages":["time in Tokyo?"]}`
+		request2 = `{"messages":["time in Tokyo?","search"]}`
+		output   = `{"api_definition":"demo_city_time"}`
+	)
+	type toolResult struct {
+		id, output string
+		isError    bool
+	}
+	tests := []struct {
+		name     string
+		requests []string
+		result   toolResult
+		text     string
+		errText  string
+		want     []string
+	}{
+		{
+			name:     "identical",
+			requests: []string{request1, request2},
+			result:   toolResult{id: "call_1", output: output},
+			text:     "It is noon.",
+		},
+		{
+			name:     "model request",
+			requests: []string{request1, `{"messages":["time in Tokyo?","other"]}`},
+			result:   toolResult{id: "call_1", output: output},
+			text:     "It is noon.",
+			want:     []string{`turn 2 model request: differs at byte 31: recorded "{\"messages\":[\"time in Tokyo?\",\"search\"]}", replayed "{\"messages\":[\"time in Tokyo?\",\"other\"]}"`},
+		},
+		{
+			name:     "tool output",
+			requests: []string{request1, request2},
+			result:   toolResult{id: "call_1", output: `{"api_definition":"demo_add_numbers"}`},
+			text:     "It is noon.",
+			want:     []string{`turn 1 tool search (call_1): differs at byte 24: recorded "{\"api_definition\":\"demo_city_time\"}", replayed "{\"api_definition\":\"demo_add_numbers\"}"`},
+		},
+		{
+			name:     "tool error",
+			requests: []string{request1, request2},
+			result:   toolResult{id: "call_1", output: output, isError: true},
+			text:     "It is noon.",
+			want:     []string{"turn 1 tool search (call_1): is_error true, recorded false"},
+		},
+		{
+			name:     "unknown tool call",
+			requests: []string{request1, request2},
+			result:   toolResult{id: "call_9", output: output},
+			text:     "It is noon.",
+			want:     []string{"turn 1 tool search (call_9): not in the recording"},
+		},
+		{
+			name:     "final answer",
+			requests: []string{request1, request2},
+			result:   toolResult{id: "call_1", output: output},
+			text:     "It is one.",
+			errText:  "tool loop reached max turns (2)",
+			want: []string{
+				`run error "tool loop reached max turns (2)", recorded ""`,
+				`final answer: differs at byte 6: recorded "It is noon.", replayed "It is one."`,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			player, err := NewPlayer(sample())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, request := range test.requests {
+				response, err := player.Next(i+1, json.RawMessage(request))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := sample().filter(KindModelResponse)[i].Response; string(response) != string(want) {
+					t.Errorf("turn %d response = %s, want %s", i+1, response, want)
+				}
+				if i == 0 {
+					player.CompareToolResult(1, test.result.id, "search", test.result.output, test.result.isError)
+				}
+			}
+			player.CompareResult(test.text, test.errText)
+
+			if got := player.Divergences(); !slices.Equal(got, test.want) {
+				t.Errorf("Divergences() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+
+			// The run asks for more turns than were recorded.
+			if _, err := player.Next(3, nil); err == nil || !strings.Contains(err.Error(), "no model response for turn 3") {
+				t.Errorf("Next(3) error = %v", err)
+			}
+		})
+	}
+}
+
+func TestNewPlayerWithoutResponses(t *testing.T) {
+	if _, err := NewPlayer(&Recording{Entries: sample().Entries[:3]}); err == nil {
+		t.Error("NewPlayer() succeeded without model responses")
+	}
+}
+
+func TestReplayBackend(t *testing.T) {
+	text := func(s string) *mcp.CallToolResult {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: s}}}
+	}
+	recording := &Recording{Entries: []Entry{
+		{Kind: KindTools, Server: "demo", Tools: []*mcp.Tool{{Name: "city_time"}}},
+		{Kind: KindHelperCall, Server: "demo", Name: "city_time", Args: map[string]any{"city": "tokyo"}, Result: text("12:00")},
+		{Kind: KindHelperCall, Server: "demo", Name: "city_time", Args: map[string]any{"city": "tokyo"}, Result: text("12:01")},
+		{Kind: KindHelperCall, Server: "demo", Name: "city_time", Args: map[string]any{"city": "mars"}, Error: "unsupported city"},
+	}}
+	backend, err := NewReplayBackend(recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if servers := backend.Servers(); !slices.Equal(servers, []string{"demo"}) {
+		t.Errorf("Servers() = %v", servers)
+	}
+	if _, err := backend.ListTools(ctx, "other"); err == nil {
+		t.Error("ListTools(other) succeeded")
+	}
+
+	// Repeated calls get the recorded results in order, then the last one.
+	for _, want := range []string{"12:00", "12:01", "12:01"} {
+		result, err := backend.CallTool(ctx, "demo", "city_time", map[string]any{"city": "tokyo"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := result.Content[0].(*mcp.TextContent).Text; got != want {
+			t.Errorf("CallTool() = %q, want %q", got, want)
+		}
+	}
+	if _, err := backend.CallTool(ctx, "demo", "city_time", map[string]any{"city": "mars"}); err == nil || err.Error() != "unsupported city" {
+		t.Errorf("recorded error = %v", err)
+	}
+	if _, err := backend.CallTool(ctx, "demo", "city_time", map[string]any{"city": "paris"}); err == nil || !strings.Contains(err.Error(), "no recorded result") {
+		t.Errorf("unrecorded call error = %v", err)
+	}
+
+	if _, err := NewReplayBackend(&Recording{}); err == nil {
+		t.Error("NewReplayBackend() succeeded without a tool catalog")
+	}
+}
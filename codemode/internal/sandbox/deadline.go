@@ -0,0 +1,72 @@
+package sandbox
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// runDeadline cancels a run once it has used up its evaluation timeout. The
+// clock stops while a guard waits for a human, see PauseDeadline.
+type runDeadline struct {
+	cancel context.CancelCauseFunc
+
+	mu        sync.Mutex
+	timer     *time.Timer
+	remaining time.Duration
+	started   time.Time
+	pauses    int
+	expired   bool
+}
+
+type deadlineKey struct{}
+
+// withRunDeadline returns a context that is cancelled with cause
+// context.DeadlineExceeded after timeout, not counting paused time.
+func withRunDeadline(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	d := &runDeadline{cancel: cancel, remaining: timeout, started: time.Now()}
+	d.timer = time.AfterFunc(timeout, d.expire)
+	return context.WithValue(ctx, deadlineKey{}, d), func() {
+		d.timer.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+func (d *runDeadline) expire() {
+	d.mu.Lock()
+	d.expired = true
+	d.mu.Unlock()
+	d.cancel(context.DeadlineExceeded)
+}
+
+// PauseDeadline stops the evaluation timeout of the run ctx belongs to until
+// the returned function is called, so time spent waiting for a human does not
+// count against the script. Pauses may overlap. It does nothing for contexts
+// that do not belong to a run.
+func PauseDeadline(ctx context.Context) (resume func()) {
+	d, ok := ctx.Value(deadlineKey{}).(*runDeadline)
+	if !ok {
+		return func() {}
+	}
+
+	d.mu.Lock()
+	if d.pauses == 0 && !d.expired && d.timer.Stop() {
+		d.remaining -= time.Since(d.started)
+	}
+	d.pauses++
+	d.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.pauses--
+			if d.pauses == 0 && !d.expired {
+				d.started = time.Now()
+				d.timer.Reset(max(d.remaining, 0))
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/preblog/codemode/internal/catalog"
+)
+
+type fakeCaller struct{}
+
+func (fakeCaller) CallTool(ctx context.Context, server, name string, args map[string]any) (*mcp.CallToolResult, error) {
+	return &mcp.CallToolResult{StructuredContent: map[string]any{"ok": true}}, nil
+}
+
+// slowGuard waits like a human answering a confirmation prompt.
+type slowGuard struct {
+	wait  time.Duration
+	pause bool
+}
+
+func (g slowGuard) Check(ctx context.Context, item catalog.ToolInfo, args map[string]any) error {
+	if g.pause {
+		defer PauseDeadline(ctx)()
+	}
+	select {
+	case <-time.After(g.wait):
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+func TestPausedDeadline(t *testing.T) {
+	items := []catalog.ToolInfo{{Server: "demo", Name: "apply", Callable: "demo_apply"}}
+	const timeout = 100 * time.Millisecond
+
+	tests := []struct {
+		name    string
+		guard   slowGuard
+		wantErr error
+	}{
+		{name: "paused", guard: slowGuard{wait: 3 * timeout, pause: true}},
+		{name: "not paused", guard: slowGuard{wait: 3 * timeout}, wantErr: context.DeadlineExceeded},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sandbox := New(items, fakeCaller{}, timeout, 64<<20, 2)
+			sandbox.SetGuard(test.guard)
+
+			result, err := sandbox.Execute(context.Background(), "return (await demo_apply({})).ok;")
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("Execute() error = %v, want %v", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Value != true {
+				t.Errorf("result = %v, want true", result.Value)
+			}
+		})
+	}
+}
+
+func TestPauseDeadlineKeepsRemainingTime(t *testing.T) {
+	ctx, cancel := withRunDeadline(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	resume := PauseDeadline(ctx)
+	// Overlapping pauses resume the clock when the last one ends.
+	resumeOther := PauseDeadline(ctx)
+	time.Sleep(100 * time.Millisecond)
+	resume()
+	resume()
+	if ctx.Err() != nil {
+		t.Fatal("deadline expired while paused")
+	}
+	resumeOther()
+
+	select {
+	case <-ctx.Done():
+		if cause := context.Cause(ctx); !errors.Is(cause, context.DeadlineExceeded) {
+			t.Errorf("cause = %v, want %v", cause, context.DeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("deadline did not expire after resuming")
+	}
+
+	// Contexts outside a run are not affected.
+	PauseDeadline(context.Background())()
+}
@@ -3,13 +3,14 @@ package sandbox
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/preblog/codemode/internal/catalog"
-	"github.com/preblog/codemode/internal/mcpdemo"
 	"modernc.org/quickjs"
 )
 
@@ -18,77 +19,244 @@ type Result struct {
 	Value any      `json:"value"`
 }
 
+type ToolCaller interface {
+	CallTool(ctx context.Context, server, name string, args map[string]any) (*mcp.CallToolResult, error)
+}
+
+// Guard decides whether a helper call may reach its MCP server. A non-nil
+// error rejects the helper's promise with the error text.
+type Guard interface {
+	Check(ctx context.Context, item catalog.ToolInfo, args map[string]any) error
+}
+
 type Sandbox struct {
 	catalog         []catalog.ToolInfo
-	runtime         *mcpdemo.Runtime
+	runtime         ToolCaller
+	guard           Guard
 	evalTimeout     time.Duration
 	memoryLimitByte uintptr
+	maxParallel     int
+}
+
+type bridgeCall struct {
+	id       int
+	callable string
+	payload  string
+}
+
+type bridgeResult struct {
+	id      int
+	payload string
 }
 
-func New(items []catalog.ToolInfo, runtime *mcpdemo.Runtime, evalTimeout time.Duration, memoryLimit uintptr) *Sandbox {
-	return &Sandbox{catalog: items, runtime: runtime, evalTimeout: evalTimeout, memoryLimitByte: memoryLimit}
+func New(items []catalog.ToolInfo, runtime ToolCaller, evalTimeout time.Duration, memoryLimit uintptr, maxParallel int) *Sandbox {
+	if maxParallel <= 0 {
+		maxParallel = 1
+	}
+	return &Sandbox{catalog: items, runtime: runtime, evalTimeout: evalTimeout, memoryLimitByte: memoryLimit, maxParallel: maxParallel}
+}
+
+func (s *Sandbox) SetGuard(guard Guard) {
+	s.guard = guard
+}
+
+type Session struct {
+	sandbox  *Sandbox
+	vm       *quickjs.VM
+	tools    map[string]catalog.ToolInfo
+	results  chan bridgeResult
+	slots    chan struct{}
+	mu       sync.Mutex
+	logs     []string
+	inFlight int
+	runCtx   context.Context
+	workers  sync.WaitGroup
 }
 
 func (s *Sandbox) Execute(ctx context.Context, code string) (result Result, err error) {
-	vm, err := quickjs.NewVM()
+	session, err := s.NewSession()
 	if err != nil {
-		return Result{}, fmt.Errorf("create quickjs vm: %w", err)
+		return Result{}, err
 	}
 	defer func() {
-		if closeErr := vm.Close(); closeErr != nil && err == nil {
-			err = fmt.Errorf("close quickjs vm: %w", closeErr)
+		if closeErr := session.Close(); closeErr != nil && err == nil {
+			err = closeErr
 		}
 	}()
+	return session.Execute(ctx, code)
+}
 
-	vm.SetMemoryLimit(s.memoryLimitByte)
-	if err := vm.SetEvalTimeout(s.evalTimeout); err != nil {
-		return Result{}, fmt.Errorf("set eval timeout: %w", err)
+// NewSession creates a VM that keeps its global state across Execute calls.
+// The memory limit applies to the whole session, the evaluation timeout to
+// each Execute call.
+func (s *Sandbox) NewSession() (*Session, error) {
+	vm, err := quickjs.NewVM()
+	if err != nil {
+		return nil, fmt.Errorf("create quickjs vm: %w", err)
+	}
+	session := &Session{
+		sandbox: s,
+		vm:      vm,
+		tools:   make(map[string]catalog.ToolInfo, len(s.catalog)),
+		results: make(chan bridgeResult),
+		slots:   make(chan struct{}, s.maxParallel),
+	}
+	for _, item := range s.catalog {
+		session.tools[item.Callable] = item
 	}
+	if err := session.init(); err != nil {
+		if closeErr := vm.Close(); closeErr != nil {
+			return nil, fmt.Errorf("%w (close quickjs vm: %v)", err, closeErr)
+		}
+		return nil, err
+	}
+	return session, nil
+}
 
-	logs := []string{}
-	if err := vm.RegisterFunc("__host_log", func(payload string) {
-		logs = append(logs, payload)
+func (s *Session) init() error {
+	s.vm.SetMemoryLimit(s.sandbox.memoryLimitByte)
+	if err := s.vm.SetEvalTimeout(s.sandbox.evalTimeout); err != nil {
+		return fmt.Errorf("set eval timeout: %w", err)
+	}
+	if err := s.vm.RegisterFunc("__host_log", func(payload string) {
+		s.logs = append(s.logs, payload)
 	}, false); err != nil {
-		return Result{}, fmt.Errorf("register logger: %w", err)
+		return fmt.Errorf("register logger: %w", err)
 	}
-
-	for _, item := range s.catalog {
-		toolName := item.Name
-		bridgeName := "__bridge_" + item.Callable
-		if err := vm.RegisterFunc(bridgeName, func(payload string) string {
-			var args map[string]any
-			if err := json.Unmarshal([]byte(payload), &args); err != nil {
-				return marshalBridgeResponse(nil, fmt.Sprintf("parse tool args: %v", err))
+	if err := s.vm.RegisterFunc("__bridge_call", func(id int, callable string, payload string) {
+		s.inFlight++
+		runCtx := s.runCtx
+		s.workers.Go(func() {
+			response := s.sandbox.callTool(runCtx, s.slots, s.tools, bridgeCall{id: id, callable: callable, payload: payload})
+			select {
+			case s.results <- bridgeResult{id: id, payload: response}:
+			case <-runCtx.Done():
 			}
-			toolCtx, cancel := context.WithTimeout(ctx, s.evalTimeout)
-			defer cancel()
-			result, err := s.runtime.CallTool(toolCtx, toolName, args)
-			if err != nil {
-				return marshalBridgeResponse(nil, err.Error())
-			}
-			if result.IsError {
-				return marshalBridgeResponse(nil, flattenContent(result))
+		})
+	}, false); err != nil {
+		return fmt.Errorf("register bridge: %w", err)
+	}
+	if _, err := s.vm.Eval(s.sandbox.prelude(), quickjs.EvalGlobal); err != nil {
+		return fmt.Errorf("load prelude: %w", err)
+	}
+	return nil
+}
+
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.vm == nil {
+		return nil
+	}
+	err := s.vm.Close()
+	s.vm = nil
+	if err != nil {
+		return fmt.Errorf("close quickjs vm: %w", err)
+	}
+	return nil
+}
+
+func (s *Session) Execute(ctx context.Context, code string) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.vm == nil {
+		return Result{}, errors.New("sandbox session is closed")
+	}
+
+	runCtx, cancel := withRunDeadline(ctx, s.sandbox.evalTimeout)
+	defer func() {
+		cancel()
+		s.workers.Wait()
+	}()
+	s.runCtx = runCtx
+	s.logs = []string{}
+	s.inFlight = 0
+
+	wrapped := "__codemode_run(async () => {\n" + code + "\n});"
+	if _, err := s.vm.Eval(wrapped, quickjs.EvalGlobal); err != nil {
+		return Result{}, fmt.Errorf("execute javascript: %w", err)
+	}
+
+	for {
+		if _, err := s.vm.ExecutePendingJobs(); err != nil {
+			return Result{}, fmt.Errorf("execute javascript: %w", err)
+		}
+		state, err := s.vm.Eval("__codemode.state", quickjs.EvalGlobal)
+		if err != nil {
+			return Result{}, fmt.Errorf("read script state: %w", err)
+		}
+		// A script that returns without awaiting all of its helper calls
+		// still gets them settled within the run, so their callbacks run
+		// instead of the calls being cancelled halfway.
+		settled := state == "fulfilled" || state == "rejected"
+		if settled && s.inFlight == 0 {
+			return s.outcome(state)
+		}
+		if s.inFlight == 0 {
+			return Result{}, errors.New("execute javascript: script is awaiting a promise that no helper call will settle")
+		}
+		select {
+		case response := <-s.results:
+			s.inFlight--
+			if _, err := s.vm.Call("__codemode_settle", response.id, response.payload); err != nil {
+				return Result{}, fmt.Errorf("settle helper call: %w", err)
 			}
-			if result.StructuredContent != nil {
-				return marshalBridgeResponse(result.StructuredContent, "")
+		case <-runCtx.Done():
+			if settled {
+				return s.outcome(state)
 			}
-			return marshalBridgeResponse(map[string]any{"content": flattenContent(result)}, "")
-		}, false); err != nil {
-			return Result{}, fmt.Errorf("register %s: %w", bridgeName, err)
+			return Result{}, fmt.Errorf("execute javascript: waiting for %d helper call(s): %w", s.inFlight, context.Cause(runCtx))
 		}
 	}
+}
 
-	prelude := s.prelude()
-	wrapped := prelude + "\n(() => {\n" + code + "\n})()"
-	value, err := vm.Eval(wrapped, quickjs.EvalGlobal)
+// outcome returns the result of a script that has settled.
+func (s *Session) outcome(state any) (Result, error) {
+	if state == "rejected" {
+		message, err := s.vm.Eval("__codemode.error", quickjs.EvalGlobal)
+		if err != nil {
+			return Result{}, fmt.Errorf("read script error: %w", err)
+		}
+		return Result{}, fmt.Errorf("execute javascript: %v", message)
+	}
+	value, err := s.vm.Eval("__codemode.value", quickjs.EvalGlobal)
 	if err != nil {
-		return Result{}, fmt.Errorf("execute javascript: %w", err)
+		return Result{}, fmt.Errorf("read script result: %w", err)
 	}
+	return Result{Logs: s.logs, Value: value}, nil
+}
 
-	return Result{
-		Logs:  logs,
-		Value: value,
-	}, nil
+func (s *Sandbox) callTool(ctx context.Context, slots chan struct{}, tools map[string]catalog.ToolInfo, call bridgeCall) string {
+	item, ok := tools[call.callable]
+	if !ok {
+		return marshalBridgeResponse(nil, fmt.Sprintf("unknown helper %q", call.callable))
+	}
+	var args map[string]any
+	if err := json.Unmarshal([]byte(call.payload), &args); err != nil {
+		return marshalBridgeResponse(nil, fmt.Sprintf("parse tool args: %v", err))
+	}
+	if s.guard != nil {
+		if err := s.guard.Check(ctx, item, args); err != nil {
+			return marshalBridgeResponse(nil, err.Error())
+		}
+	}
+	select {
+	case slots <- struct{}{}:
+		defer func() { <-slots }()
+	case <-ctx.Done():
+		return marshalBridgeResponse(nil, context.Cause(ctx).Error())
+	}
+	result, err := s.runtime.CallTool(ctx, item.Server, item.Name, args)
+	if err != nil {
+		return marshalBridgeResponse(nil, err.Error())
+	}
+	if result.IsError {
+		return marshalBridgeResponse(nil, flattenContent(result))
+	}
+	if result.StructuredContent != nil {
+		return marshalBridgeResponse(result.StructuredContent, "")
+	}
+	return marshalBridgeResponse(map[string]any{"content": flattenContent(result)}, "")
 }
 
 func (s *Sandbox) prelude() string {
@@ -96,8 +264,33 @@ func (s *Sandbox) prelude() string {
 	builder.WriteString("const console = {\n")
 	builder.WriteString("  log: (...args) => __host_log(JSON.stringify(args)),\n")
 	builder.WriteString("};\n")
+	builder.WriteString("const state = {};\n")
+	builder.WriteString("const __codemode = { run: 0, nextId: 1, pending: new Map(), state: \"pending\", value: undefined, error: \"\" };\n")
+	builder.WriteString("function __codemode_run(fn) {\n")
+	builder.WriteString("  const run = ++__codemode.run;\n")
+	builder.WriteString("  __codemode.pending.clear();\n")
+	builder.WriteString("  Object.assign(__codemode, { state: \"pending\", value: undefined, error: \"\" });\n")
+	builder.WriteString("  fn().then(\n")
+	builder.WriteString("    (value) => { if (run === __codemode.run) { __codemode.state = \"fulfilled\"; __codemode.value = value; } },\n")
+	builder.WriteString("    (error) => { if (run === __codemode.run) { __codemode.state = \"rejected\"; __codemode.error = String(error); } },\n")
+	builder.WriteString("  );\n")
+	builder.WriteString("}\n")
+	builder.WriteString("function __codemode_call(callable, args) {\n")
+	builder.WriteString("  return new Promise((resolve, reject) => {\n")
+	builder.WriteString("    const id = __codemode.nextId++;\n")
+	builder.WriteString("    __codemode.pending.set(id, { resolve, reject });\n")
+	builder.WriteString("    __bridge_call(id, callable, JSON.stringify(args || {}));\n")
+	builder.WriteString("  });\n")
+	builder.WriteString("}\n")
+	builder.WriteString("function __codemode_settle(id, payload) {\n")
+	builder.WriteString("  const entry = __codemode.pending.get(id);\n")
+	builder.WriteString("  if (!entry) { return; }\n")
+	builder.WriteString("  __codemode.pending.delete(id);\n")
+	builder.WriteString("  const response = JSON.parse(payload);\n")
+	builder.WriteString("  if (response.error) { entry.reject(new Error(response.error)); } else { entry.resolve(response.value); }\n")
+	builder.WriteString("}\n")
 	for _, item := range s.catalog {
-		fmt.Fprintf(&builder, "function %s(args) { const response = JSON.parse(__bridge_%s(JSON.stringify(args || {}))); if (response.error) { throw new Error(response.error); } return response.value; }\n", item.Callable, item.Callable)
+		fmt.Fprintf(&builder, "function %s(args) { return __codemode_call(%q, args); }\n", item.Callable, item.Callable)
 	}
 	return builder.String()
 }
@@ -0,0 +1,168 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/preblog/codemode/internal/catalog"
+)
+
+// countingCaller holds every call for a while and records how many run at
+// the same time.
+type countingCaller struct {
+	delay time.Duration
+
+	mu      sync.Mutex
+	calls   []string
+	running int
+	most    int
+}
+
+func (c *countingCaller) CallTool(ctx context.Context, server, name string, args map[string]any) (*mcp.CallToolResult, error) {
+	c.mu.Lock()
+	c.calls = append(c.calls, fmt.Sprint(args["n"]))
+	c.running++
+	c.most = max(c.most, c.running)
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.running--
+		c.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(c.delay):
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+	switch name {
+	case "fail":
+		return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: "carrier unavailable"}}}, nil
+	case "broken":
+		return nil, errors.New("connection refused")
+	}
+	return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("quote %v", args["n"])}}}, nil
+}
+
+var testItems = []catalog.ToolInfo{
+	{Server: "shipping", Name: "quote", Callable: "shipping_quote"},
+	{Server: "shipping", Name: "fail", Callable: "shipping_fail"},
+	{Server: "shipping", Name: "broken", Callable: "shipping_broken"},
+}
+
+func TestMaxParallel(t *testing.T) {
+	for _, maxParallel := range []int{1, 3} {
+		t.Run(fmt.Sprint(maxParallel), func(t *testing.T) {
+			caller := &countingCaller{delay: 20 * time.Millisecond}
+			sandbox := New(testItems, caller, 5*time.Second, 64<<20, maxParallel)
+
+			result, err := sandbox.Execute(context.Background(), `
+				const quotes = await Promise.all([1, 2, 3, 4, 5, 6, 7, 8].map((n) => shipping_quote({ n })));
+				return quotes.map((q) => q.content).join(",");`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "quote 1,quote 2,quote 3,quote 4,quote 5,quote 6,quote 7,quote 8"; result.Value != want {
+				t.Errorf("result = %v, want %s", result.Value, want)
+			}
+			if len(caller.calls) != 8 || caller.most != maxParallel {
+				t.Errorf("%d calls with up to %d at once, want 8 with up to %d", len(caller.calls), caller.most, maxParallel)
+			}
+		})
+	}
+}
+
+func TestRejectedPromises(t *testing.T) {
+	tests := []struct {
+		name, code string
+		value      any
+		err        string
+	}{
+		{name: "tool error", code: `return await shipping_fail({});`, err: "execute javascript: Error: carrier unavailable"},
+		{name: "call error", code: `return await shipping_broken({});`, err: "execute javascript: Error: connection refused"},
+		{name: "unknown helper", code: `return await __codemode_call("shipping_other", {});`, err: `execute javascript: Error: unknown helper "shipping_other"`},
+		{name: "throw", code: `throw new Error("no carrier");`, err: "execute javascript: Error: no carrier"},
+		{name: "caught", code: `try { await shipping_fail({}); } catch (e) { return "caught " + e.message; }`, value: "caught carrier unavailable"},
+		{name: "never settled", code: `await new Promise(() => {});`, err: "no helper call will settle"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sandbox := New(testItems, &countingCaller{}, 5*time.Second, 64<<20, 2)
+			result, err := sandbox.Execute(context.Background(), test.code)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("Execute() = %+v, %v, want error %q", result, err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Value != test.value {
+				t.Errorf("result = %v, want %v", result.Value, test.value)
+			}
+		})
+	}
+}
+
+func TestUnawaitedPromise(t *testing.T) {
+	caller := &countingCaller{delay: 20 * time.Millisecond}
+	sandbox := New(testItems, caller, 5*time.Second, 64<<20, 2)
+
+	// The script returns before its helper calls settle; the run still waits
+	// for them, so their callbacks run and their failures are not lost.
+	result, err := sandbox.Execute(context.Background(), `
+		shipping_quote({ n: 1 }).then((q) => console.log(q.content));
+		shipping_fail({ n: 2 }).catch((e) => console.log(e.message));
+		return "sent";`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Value != "sent" {
+		t.Errorf("result = %v, want sent", result.Value)
+	}
+	logs := strings.Join(result.Logs, "\n")
+	if !strings.Contains(logs, `["quote 1"]`) || !strings.Contains(logs, `["carrier unavailable"]`) {
+		t.Errorf("logs = %q, want both callbacks", result.Logs)
+	}
+}
+
+func TestExecuteTimeout(t *testing.T) {
+	var cancelled atomic.Bool
+	caller := &countingCaller{delay: time.Minute}
+	sandbox := New(testItems, caller, 100*time.Millisecond, 64<<20, 2)
+	sandbox.SetGuard(guardFunc(func(ctx context.Context) error {
+		context.AfterFunc(ctx, func() { cancelled.Store(true) })
+		return nil
+	}))
+
+	start := time.Now()
+	_, err := sandbox.Execute(context.Background(), `return await shipping_quote({});`)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Execute() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Execute() took %s", elapsed)
+	}
+	if !cancelled.Load() {
+		t.Error("the helper call was not cancelled")
+	}
+
+	// A busy loop is stopped by the evaluation timeout of the VM.
+	if _, err := sandbox.Execute(context.Background(), `while (true) {}`); err == nil {
+		t.Error("Execute() of an endless loop succeeded")
+	}
+}
+
+type guardFunc func(ctx context.Context) error
+
+func (f guardFunc) Check(ctx context.Context, _ catalog.ToolInfo, _ map[string]any) error {
+	return f(ctx)
+}
@@ -3,22 +3,52 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"os"
-	"strings"
 
 	"einoexamples/internal/shared"
 
 	"github.com/cloudwego/eino/adk"
-	"github.com/cloudwego/eino/schema"
 )
 
 func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	resume := flag.String("resume", "", "Run ID of an earlier conversation to continue")
+	checkpointDir := flag.String("checkpoint-dir", shared.DefaultCheckpointDir, "Directory for run checkpoints")
+	sessionFlags := shared.AddSessionFlags()
+	flag.Parse()
+
 	ctx := context.Background()
+	if *sessionFlags.List {
+		return sessionFlags.PrintSessions(ctx)
+	}
+	store, err := sessionFlags.OpenStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	tracer, err := shared.StartTracing("basic-agent")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tracer.Shutdown(context.Background()); err != nil {
+			log.Printf("export trace: %v", err)
+		}
+	}()
+
 	chatModel, err := shared.NewChatModel(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	agent, err := adk.NewChatModelAgent(ctx, &adk.ChatModelAgentConfig{
@@ -28,38 +58,32 @@ func main() {
 		Model:       chatModel,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	runner := adk.NewRunner(ctx, adk.RunnerConfig{
-		Agent:           agent,
-		EnableStreaming: true,
-	})
-
-	history := make([]*schema.Message, 0, 16)
 	scanner := bufio.NewScanner(os.Stdin)
+	run, err := shared.OpenRun(*checkpointDir, *resume, shared.TerminalApprover(scanner, os.Stdout))
+	if err != nil {
+		return err
+	}
+	repl := &shared.REPL{
+		Runner:     run.NewRunner(ctx, agent),
+		Run:        run,
+		Store:      store,
+		Summarizer: sessionFlags.Summarizer(chatModel),
+		Scanner:    scanner,
+	}
 
-	fmt.Println("Ask something. Submit an empty line to exit.")
-	for {
-		fmt.Print("you> ")
-		if !scanner.Scan() {
-			break
-		}
-
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			break
-		}
-
-		history = append(history, schema.UserMessage(line))
-		assistantReply, err := shared.PrintAgentEvents(runner.Run(ctx, history))
-		if err != nil {
-			log.Fatal(err)
-		}
-		history = append(history, schema.AssistantMessage(assistantReply, nil))
+	session, err := repl.Open(ctx, *sessionFlags.Session)
+	if err != nil {
+		return err
 	}
 
-	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
+	fmt.Printf("Run: %s\n", run.ID)
+	err = repl.Start(ctx, session)
+	if errors.Is(err, shared.ErrPaused) {
+		fmt.Println(err)
+		return nil
 	}
+	return err
 }
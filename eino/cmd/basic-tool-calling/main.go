@@ -1,15 +1,18 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
 	"log"
+	"os"
 	"strings"
 
 	"einoexamples/internal/shared"
 
 	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/model"
 	toolcomp "github.com/cloudwego/eino/components/tool"
 	toolutils "github.com/cloudwego/eino/components/tool/utils"
 	"github.com/cloudwego/eino/compose"
@@ -24,18 +27,40 @@ type weatherOutput struct {
 }
 
 func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
 	question := flag.String("question", "Should I take an umbrella in Hangzhou today?", "Question for the tool-using agent")
+	repl := flag.Bool("repl", false, "Hold a conversation on the terminal instead of answering -question")
+	sessionFlags := shared.AddSessionFlags()
 	flag.Parse()
 
 	ctx := context.Background()
+	if *sessionFlags.List {
+		return sessionFlags.PrintSessions(ctx)
+	}
+
+	tracer, err := shared.StartTracing("basic-tool-calling")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tracer.Shutdown(context.Background()); err != nil {
+			log.Printf("export trace: %v", err)
+		}
+	}()
+
 	chatModel, err := shared.NewChatModel(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	weatherTool, err := toolutils.InferTool("lookup_weather", "Look up the current weather for a city.", lookupWeather)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	agent, err := adk.NewChatModelAgent(ctx, &adk.ChatModelAgentConfig{
@@ -50,7 +75,7 @@ func main() {
 		},
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	runner := adk.NewRunner(ctx, adk.RunnerConfig{
@@ -58,10 +83,33 @@ func main() {
 		EnableStreaming: true,
 	})
 
+	if *repl {
+		return converse(ctx, runner, chatModel, sessionFlags)
+	}
+
 	prompt := strings.TrimSpace(*question)
-	if _, err := shared.PrintQueryAgentEvents(prompt, runner.Query(ctx, prompt)); err != nil {
-		log.Fatal(err)
+	_, err = shared.PrintQueryAgentEvents(prompt, runner.Query(ctx, prompt))
+	return err
+}
+
+func converse(ctx context.Context, runner *adk.Runner, chatModel model.BaseChatModel, sessionFlags *shared.SessionFlags) error {
+	store, err := sessionFlags.OpenStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	repl := &shared.REPL{
+		Runner:     runner,
+		Store:      store,
+		Summarizer: sessionFlags.Summarizer(chatModel),
+		Scanner:    bufio.NewScanner(os.Stdin),
+	}
+	session, err := repl.Open(ctx, *sessionFlags.Session)
+	if err != nil {
+		return err
 	}
+	return repl.Start(ctx, session)
 }
 
 func lookupWeather(_ context.Context, input *weatherInput) (*weatherOutput, error) {
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files from the current output")
+
+// TestGolden runs every scenario of testdata/golden. Run it with -update to
+// accept a change of the output.
+func TestGolden(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs the agent commands")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("the go command is needed to build the agent commands")
+	}
+
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "testdata", "golden")
+	scenarios, err := loadScenarios(filepath.Join(dir, "scenarios.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner, err := newRunner(root, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { runner.Close() })
+
+	for _, sc := range scenarios {
+		t.Run(sc.Name, func(t *testing.T) {
+			if err := runner.Check(sc, *update); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+// Command golden runs the agent commands against scripted fake models and
+// compares their output with golden files, so changes to the event printing,
+// the graph routing or the tool flows show up without calling a provider.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	goyaml "gopkg.in/yaml.v3"
+)
+
+// scenario runs one command. Its model replays <name>.script.yaml and its
+// output is compared with <name>.golden. $TMP in args is replaced by a
+// scratch directory for the scenario.
+type scenario struct {
+	Name    string   `yaml:"name"`
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	Stdin   string   `yaml:"stdin"`
+}
+
+var runID = regexp.MustCompile(`\b\d{8}-\d{6}-[0-9a-f]{6}\b`)
+
+func main() {
+	dir := flag.String("dir", filepath.Join("testdata", "golden"), "Directory with scenarios.yaml, the model scripts and the golden files")
+	update := flag.Bool("update", false, "Rewrite the golden files from the current output")
+	filter := flag.String("run", "", "Only run scenarios whose name matches this regular expression")
+	flag.Parse()
+
+	scenarios, err := loadScenarios(filepath.Join(*dir, "scenarios.yaml"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	match, err := regexp.Compile(*filter)
+	if err != nil {
+		log.Fatal(err)
+	}
+	root, err := os.Getwd()
+	if err != nil {
+		log.Fatal(err)
+	}
+	runner, err := newRunner(root, *dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer runner.Close()
+
+	failed := 0
+	for _, sc := range scenarios {
+		if !match.MatchString(sc.Name) {
+			continue
+		}
+		if err := runner.Check(sc, *update); err != nil {
+			fmt.Printf("FAIL %s: %v\n", sc.Name, err)
+			failed++
+			continue
+		}
+		if *update {
+			fmt.Printf("updated %s\n", runner.goldenPath(sc))
+			continue
+		}
+		fmt.Printf("ok   %s\n", sc.Name)
+	}
+
+	if failed > 0 {
+		fmt.Printf("%d scenario(s) failed; rerun with -update if the new output is intended\n", failed)
+		runner.Close()
+		os.Exit(1)
+	}
+}
+
+// runner builds each command once, from the module at root, and runs the
+// scenarios of dir in scratch directories.
+type runner struct {
+	root     string
+	dir      string
+	tmp      string
+	binaries map[string]string
+}
+
+func newRunner(root, dir string) (*runner, error) {
+	tmp, err := os.MkdirTemp("", "eino-golden-")
+	if err != nil {
+		return nil, err
+	}
+	return &runner{root: root, dir: dir, tmp: tmp, binaries: map[string]string{}}, nil
+}
+
+func (r *runner) Close() error {
+	return os.RemoveAll(r.tmp)
+}
+
+// Check runs sc and compares its output with the golden file, or rewrites the
+// golden file when update is set.
+func (r *runner) Check(sc scenario, update bool) error {
+	binary, err := r.build(sc.Command)
+	if err != nil {
+		return err
+	}
+
+	scratch := filepath.Join(r.tmp, sc.Name)
+	output, err := runScenario(sc, binary, r.root, r.dir, scratch)
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, output)
+	}
+	output = normalize(output, r.root, scratch)
+
+	goldenPath := r.goldenPath(sc)
+	if update {
+		return os.WriteFile(goldenPath, []byte(output), 0o644)
+	}
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		return err
+	}
+	if diff := firstDifference(string(want), output); diff != "" {
+		return fmt.Errorf("output differs from %s\n%s", goldenPath, diff)
+	}
+	return nil
+}
+
+func (r *runner) goldenPath(sc scenario) string {
+	return filepath.Join(r.dir, sc.Name+".golden")
+}
+
+func (r *runner) build(command string) (string, error) {
+	if binary, ok := r.binaries[command]; ok {
+		return binary, nil
+	}
+	binary := filepath.Join(r.tmp, "bin", command)
+	build := exec.Command("go", "build", "-o", binary, "./cmd/"+command)
+	build.Dir = r.root
+	if output, err := build.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("build %s: %w\n%s", command, err, output)
+	}
+	r.binaries[command] = binary
+	return binary, nil
+}
+
+func loadScenarios(path string) ([]scenario, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read scenarios: %w", err)
+	}
+
+	var scenarios []scenario
+	if err := goyaml.Unmarshal(content, &scenarios); err != nil {
+		return nil, fmt.Errorf("parse scenarios %s: %w", path, err)
+	}
+
+	return scenarios, nil
+}
+
+// runScenario runs the command from root with only the scripted model
+// configured, so a missing script turn fails instead of reaching a real
+// provider.
+func runScenario(sc scenario, binary, root, dir, scratch string) (string, error) {
+	if err := os.MkdirAll(scratch, 0o755); err != nil {
+		return "", err
+	}
+
+	script, err := filepath.Abs(filepath.Join(dir, sc.Name+".script.yaml"))
+	if err != nil {
+		return "", err
+	}
+	modelsFile := filepath.Join(scratch, "models.yaml")
+	models := fmt.Sprintf("models:\n  default:\n    provider: script\n    script: %q\n", script)
+	if err := os.WriteFile(modelsFile, []byte(models), 0o644); err != nil {
+		return "", err
+	}
+
+	args := make([]string, 0, len(sc.Args))
+	for _, arg := range sc.Args {
+		args = append(args, strings.ReplaceAll(arg, "$TMP", scratch))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(binary, args...)
+	cmd.Dir = root
+	cmd.Env = append(scenarioEnv(), "EINO_MODELS_FILE="+modelsFile)
+	cmd.Stdin = strings.NewReader(sc.Stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String() + stderr.String(), err
+	}
+
+	return stdout.String(), nil
+}
+
+// scenarioEnv drops the variables that would send traces somewhere or pick
+// another model.
+func scenarioEnv() []string {
+	var env []string
+	for _, entry := range os.Environ() {
+		name, _, _ := strings.Cut(entry, "=")
+		if strings.HasPrefix(name, "OPENAI_") || strings.HasPrefix(name, "OTEL_") || strings.HasPrefix(name, "EINO_") {
+			continue
+		}
+		env = append(env, entry)
+	}
+	return env
+}
+
+func normalize(output, root, scratch string) string {
+	output = strings.ReplaceAll(output, scratch, "$TMP")
+	output = strings.ReplaceAll(output, root, "$ROOT")
+	return runID.ReplaceAllString(output, "$$RUN_ID")
+}
+
+func firstDifference(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d:\n  want: %q\n  got:  %q", i+1, w, g)
+		}
+	}
+	return ""
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -32,14 +33,30 @@ type extractedLink struct {
 }
 
 func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
 	pageURL := flag.String("url", "https://example.com", "Page to extract structured data from")
 	image := flag.String("docker-image", "mcr.microsoft.com/playwright/mcp", "Docker image that runs the Playwright MCP server")
 	flag.Parse()
 
 	ctx := context.Background()
+	tracer, err := shared.StartTracing("mcp-playwright-extract")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tracer.Shutdown(context.Background()); err != nil {
+			log.Printf("export trace: %v", err)
+		}
+	}()
+
 	chatModel, err := shared.NewChatModel(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	mcpClient, err := client.NewStdioMCPClient(
@@ -53,7 +70,7 @@ func main() {
 		strings.TrimSpace(*image),
 	)
 	if err != nil {
-		log.Fatalf("create MCP client: %v", err)
+		return fmt.Errorf("create MCP client: %w", err)
 	}
 	defer func() {
 		_ = mcpClient.Close()
@@ -68,17 +85,17 @@ func main() {
 
 	initResult, err := mcpClient.Initialize(ctx, initRequest)
 	if err != nil {
-		log.Fatalf("initialize MCP client: %v", err)
+		return fmt.Errorf("initialize MCP client: %w", err)
 	}
 
 	fmt.Printf("Connected to MCP server: %s %s\n", initResult.ServerInfo.Name, initResult.ServerInfo.Version)
 
 	mcpTools, err := mcpp.GetTools(ctx, &mcpp.Config{Cli: mcpClient})
 	if err != nil {
-		log.Fatalf("load MCP tools: %v", err)
+		return fmt.Errorf("load MCP tools: %w", err)
 	}
 	if len(mcpTools) == 0 {
-		log.Fatal("the Playwright MCP server exposed no tools")
+		return errors.New("the Playwright MCP server exposed no tools")
 	}
 
 	agent, err := adk.NewChatModelAgent(ctx, &adk.ChatModelAgentConfig{
@@ -100,7 +117,7 @@ func main() {
 		},
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	prompt := fmt.Sprintf("Open %s with the Playwright MCP tools and extract the requested structured data.", strings.TrimSpace(*pageURL))
@@ -111,21 +128,22 @@ func main() {
 
 	responseText, err := shared.PrintQueryAgentEvents(prompt, runner.Query(ctx, prompt))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	parsed, err := decodeExtractedPage(responseText)
 	if err != nil {
-		log.Fatalf("parse structured JSON response: %v", err)
+		return fmt.Errorf("parse structured JSON response: %w", err)
 	}
 
 	pretty, err := json.MarshalIndent(parsed, "", "  ")
 	if err != nil {
-		log.Fatalf("format structured JSON response: %v", err)
+		return fmt.Errorf("format structured JSON response: %w", err)
 	}
 
 	fmt.Println("\n[structured result]")
 	fmt.Println(string(pretty))
+	return nil
 }
 
 func decodeExtractedPage(raw string) (*extractedPage, error) {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -17,14 +18,30 @@ import (
 )
 
 func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
 	question := flag.String("question", "Use the Playwright MCP tools to open https://example.com, then tell me the page title and the main heading.", "Question for the Playwright MCP agent")
 	image := flag.String("docker-image", "mcr.microsoft.com/playwright/mcp", "Docker image that runs the Playwright MCP server")
 	flag.Parse()
 
 	ctx := context.Background()
+	tracer, err := shared.StartTracing("mcp-playwright")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tracer.Shutdown(context.Background()); err != nil {
+			log.Printf("export trace: %v", err)
+		}
+	}()
+
 	chatModel, err := shared.NewChatModel(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	mcpClient, err := client.NewStdioMCPClient(
@@ -38,7 +55,7 @@ func main() {
 		strings.TrimSpace(*image),
 	)
 	if err != nil {
-		log.Fatalf("create MCP client: %v", err)
+		return fmt.Errorf("create MCP client: %w", err)
 	}
 	defer func() {
 		_ = mcpClient.Close()
@@ -53,17 +70,17 @@ func main() {
 
 	initResult, err := mcpClient.Initialize(ctx, initRequest)
 	if err != nil {
-		log.Fatalf("initialize MCP client: %v", err)
+		return fmt.Errorf("initialize MCP client: %w", err)
 	}
 
 	fmt.Printf("Connected to MCP server: %s %s\n", initResult.ServerInfo.Name, initResult.ServerInfo.Version)
 
 	mcpTools, err := mcpp.GetTools(ctx, &mcpp.Config{Cli: mcpClient})
 	if err != nil {
-		log.Fatalf("load MCP tools: %v", err)
+		return fmt.Errorf("load MCP tools: %w", err)
 	}
 	if len(mcpTools) == 0 {
-		log.Fatal("the Playwright MCP server exposed no tools")
+		return errors.New("the Playwright MCP server exposed no tools")
 	}
 
 	agent, err := adk.NewChatModelAgent(ctx, &adk.ChatModelAgentConfig{
@@ -82,7 +99,7 @@ func main() {
 		},
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	runner := adk.NewRunner(ctx, adk.RunnerConfig{
@@ -91,7 +108,6 @@ func main() {
 	})
 
 	prompt := strings.TrimSpace(*question)
-	if _, err := shared.PrintQueryAgentEvents(prompt, runner.Query(ctx, prompt)); err != nil {
-		log.Fatal(err)
-	}
+	_, err = shared.PrintQueryAgentEvents(prompt, runner.Query(ctx, prompt))
+	return err
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"flag"
@@ -9,151 +10,187 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"einoexamples/internal/shared"
+	"einoexamples/internal/skills"
 
 	"github.com/cloudwego/eino/adk"
 	"github.com/cloudwego/eino/adk/middlewares/skill"
-	goyaml "gopkg.in/yaml.v3"
+	"github.com/cloudwego/eino/components/model"
+	toolcomp "github.com/cloudwego/eino/components/tool"
+	toolutils "github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
 )
 
+type pageInput struct {
+	Team     string `json:"team" jsonschema:"required" jsonschema_description:"On-call rotation to page, for example checkout or payments"`
+	Severity string `json:"severity" jsonschema:"required" jsonschema_description:"Incident severity such as sev1 or sev2"`
+	Summary  string `json:"summary" jsonschema:"required" jsonschema_description:"One-sentence incident summary for the page"`
+}
+
+type pageOutput struct {
+	Status string `json:"status"`
+}
+
 func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
 	question := flag.String("question", "Use the skill tool with skill=\"incident-triage\" and produce a first-response plan for a p95 latency spike on the checkout API right after today's deploy.", "Question for the skill-enabled agent")
 	skillsDirFlag := flag.String("skills-dir", filepath.Join("skills"), "Directory containing skill subdirectories")
+	resume := flag.String("resume", "", "Run ID of a paused or crashed run to continue")
+	checkpointDir := flag.String("checkpoint-dir", shared.DefaultCheckpointDir, "Directory for run checkpoints")
+	pinsFlag := flag.String("pin", "", "Comma-separated name@version pins for skills that exist in several versions")
+	watch := flag.Duration("watch", 2*time.Second, "How often to check the skills directory for changes; 0 disables hot-reload")
+	repl := flag.Bool("repl", false, "Hold a conversation on the terminal instead of answering -question")
+	sessionFlags := shared.AddSessionFlags()
 	flag.Parse()
 
 	ctx := context.Background()
+	if *sessionFlags.List {
+		return sessionFlags.PrintSessions(ctx)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	run, err := shared.OpenRun(*checkpointDir, *resume, shared.TerminalApprover(scanner, os.Stdout))
+	if err != nil {
+		return err
+	}
+	prompt := strings.TrimSpace(*question)
+	if !*repl {
+		if err := run.Keep("question", &prompt); err != nil {
+			return err
+		}
+	}
+
+	tracer, err := shared.StartTracing("skills-agent")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tracer.Shutdown(context.Background()); err != nil {
+			log.Printf("export trace: %v", err)
+		}
+	}()
+
 	chatModel, err := shared.NewChatModel(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	skillsDir, err := filepath.Abs(strings.TrimSpace(*skillsDirFlag))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	backend := &diskSkillBackend{baseDir: skillsDir}
+	pins, err := skills.ParsePins(*pinsFlag)
+	if err != nil {
+		return err
+	}
+	index, err := skills.Open(skillsDir, pins)
+	if err != nil {
+		return err
+	}
+	printSkillProblems(index.Problems())
+	if *watch > 0 {
+		watchCtx, stopWatching := context.WithCancel(ctx)
+		defer stopWatching()
+		go index.Watch(watchCtx, *watch, func(problems []skills.Problem, err error) {
+			if err != nil {
+				log.Printf("reload skills: %v", err)
+				return
+			}
+			fmt.Printf("[skills] reloaded %d skill(s) from %s\n", len(index.List()), skillsDir)
+			printSkillProblems(problems)
+		})
+	}
+
+	backend := shared.NewSkillBackend(index)
 	skillMiddleware, err := skill.NewMiddleware(ctx, &skill.Config{Backend: backend})
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	resourceTool, err := backend.ResourceTool()
+	if err != nil {
+		return err
+	}
+
+	pageTool, err := toolutils.InferTool("page_oncall", "Page the on-call engineer of a team about an incident. A human has to approve every page.", pageOnCall)
+	if err != nil {
+		return err
 	}
 
 	agent, err := adk.NewChatModelAgent(ctx, &adk.ChatModelAgentConfig{
 		Name:        "skill-agent",
 		Description: "An agent that can discover and load reusable Eino skills from SKILL.md files.",
-		Instruction: "You are a helpful assistant. When a matching skill exists, use it before answering.",
+		Instruction: "You are a helpful assistant. When a matching skill exists, use it before answering. Page the on-call team with page_oncall when an incident needs more hands.",
 		Model:       chatModel,
 		Handlers:    []adk.ChatModelAgentMiddleware{skillMiddleware},
+		ToolsConfig: adk.ToolsConfig{
+			ToolsNodeConfig: compose.ToolsNodeConfig{
+				Tools: []toolcomp.BaseTool{resourceTool, shared.RequireApproval(pageTool)},
+			},
+		},
 	})
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	fmt.Printf("Skills dir: %s\n", skillsDir)
+	fmt.Printf("Skills dir: %s (%d skill(s))\n", skillsDir, len(index.List()))
+	fmt.Printf("Run: %s\n", run.ID)
 
-	runner := adk.NewRunner(ctx, adk.RunnerConfig{
-		Agent:           agent,
-		EnableStreaming: true,
-	})
-
-	prompt := strings.TrimSpace(*question)
-	if _, err := shared.PrintQueryAgentEvents(prompt, runner.Query(ctx, prompt)); err != nil {
-		log.Fatal(err)
+	if *repl {
+		err = converse(ctx, run, agent, chatModel, sessionFlags, scanner)
+	} else {
+		_, err = run.Execute(ctx, run.NewRunner(ctx, agent), "skill-agent", []adk.Message{schema.UserMessage(prompt)}, shared.QueryEventPrinter(prompt))
 	}
-}
-
-type diskSkillBackend struct {
-	baseDir string
-}
-
-func (b *diskSkillBackend) List(ctx context.Context) ([]skill.FrontMatter, error) {
-	_ = ctx
-
-	entries, err := os.ReadDir(b.baseDir)
 	if err != nil {
-		return nil, fmt.Errorf("read skills directory: %w", err)
-	}
-
-	matters := make([]skill.FrontMatter, 0, len(entries))
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
+		if errors.Is(err, shared.ErrPaused) {
+			fmt.Println(err)
+			return nil
 		}
-
-		skillPath := filepath.Join(b.baseDir, entry.Name(), "SKILL.md")
-		sk, err := loadSkillFile(skillPath)
-		if err != nil {
-			return nil, err
-		}
-		matters = append(matters, sk.FrontMatter)
+		return err
 	}
-
-	return matters, nil
+	return nil
 }
 
-func (b *diskSkillBackend) Get(ctx context.Context, name string) (skill.Skill, error) {
-	_ = ctx
-
-	entries, err := os.ReadDir(b.baseDir)
+// converse holds a conversation whose turns are checkpointed in run.
+func converse(ctx context.Context, run *shared.Run, agent adk.Agent, chatModel model.BaseChatModel, sessionFlags *shared.SessionFlags, scanner *bufio.Scanner) error {
+	store, err := sessionFlags.OpenStore()
 	if err != nil {
-		return skill.Skill{}, fmt.Errorf("read skills directory: %w", err)
+		return err
 	}
-
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		skillPath := filepath.Join(b.baseDir, entry.Name(), "SKILL.md")
-		sk, err := loadSkillFile(skillPath)
-		if err != nil {
-			return skill.Skill{}, err
-		}
-		if sk.Name == name {
-			return sk, nil
-		}
-	}
-
-	return skill.Skill{}, fmt.Errorf("skill not found: %s", name)
-}
-
-func loadSkillFile(skillPath string) (skill.Skill, error) {
-	content, err := os.ReadFile(skillPath)
-	if err != nil {
-		return skill.Skill{}, fmt.Errorf("read %s: %w", skillPath, err)
+	defer store.Close()
+
+	repl := &shared.REPL{
+		Runner:     run.NewRunner(ctx, agent),
+		Run:        run,
+		Store:      store,
+		Summarizer: sessionFlags.Summarizer(chatModel),
+		Scanner:    scanner,
 	}
 
-	frontMatter, body, err := splitFrontMatter(string(content))
+	session, err := repl.Open(ctx, *sessionFlags.Session)
 	if err != nil {
-		return skill.Skill{}, fmt.Errorf("parse %s: %w", skillPath, err)
-	}
-
-	var matter skill.FrontMatter
-	if err := goyaml.Unmarshal([]byte(frontMatter), &matter); err != nil {
-		return skill.Skill{}, fmt.Errorf("unmarshal front matter for %s: %w", skillPath, err)
+		return err
 	}
+	return repl.Start(ctx, session)
+}
 
-	return skill.Skill{
-		FrontMatter:   matter,
-		Content:       strings.TrimSpace(body),
-		BaseDirectory: filepath.Dir(skillPath),
+func pageOnCall(_ context.Context, input *pageInput) (*pageOutput, error) {
+	return &pageOutput{
+		Status: fmt.Sprintf("Paged %s on-call (%s): %s", strings.TrimSpace(input.Team), strings.TrimSpace(input.Severity), strings.TrimSpace(input.Summary)),
 	}, nil
 }
 
-func splitFrontMatter(content string) (string, string, error) {
-	content = strings.ReplaceAll(content, "\r\n", "\n")
-	if !strings.HasPrefix(content, "---\n") {
-		return "", "", errors.New("missing YAML front matter")
-	}
-
-	rest := strings.TrimPrefix(content, "---\n")
-	before, after, ok := strings.Cut(rest, "\n---\n")
-	if !ok {
-		return "", "", errors.New("missing closing front matter delimiter")
+func printSkillProblems(problems []skills.Problem) {
+	for _, problem := range problems {
+		fmt.Printf("[skills] %s\n", problem)
 	}
-
-	frontMatter := before
-	body := after
-	return frontMatter, body, nil
 }
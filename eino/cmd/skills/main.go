@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"einoexamples/internal/skills"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "lint":
+		runLint(os.Args[2:])
+	case "list":
+		runList(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: skills lint|list [-skills-dir dir]")
+	os.Exit(2)
+}
+
+func runLint(args []string) {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	skillsDir := flags.String("skills-dir", filepath.Join("skills"), "Directory containing skill subdirectories")
+	strict := flags.Bool("strict", false, "Fail on warnings too")
+	_ = flags.Parse(args)
+
+	index, err := skills.Open(strings.TrimSpace(*skillsDir), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	errorCount, warningCount := 0, 0
+	for _, problem := range index.Problems() {
+		fmt.Println(problem)
+		if problem.Severity == skills.SeverityError {
+			errorCount++
+		} else {
+			warningCount++
+		}
+	}
+
+	skillCount := 0
+	for _, sk := range index.List() {
+		skillCount += len(index.Versions(sk.Name))
+	}
+	fmt.Printf("%d valid skill version(s), %d error(s), %d warning(s)\n", skillCount, errorCount, warningCount)
+	if errorCount > 0 || (*strict && warningCount > 0) {
+		os.Exit(1)
+	}
+}
+
+func runList(args []string) {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	skillsDir := flags.String("skills-dir", filepath.Join("skills"), "Directory containing skill subdirectories")
+	_ = flags.Parse(args)
+
+	index, err := skills.Open(strings.TrimSpace(*skillsDir), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSIONS\tRESOURCES\tDESCRIPTION")
+	for _, sk := range index.List() {
+		versions := strings.Join(index.Versions(sk.Name), ", ")
+		if versions == "" {
+			versions = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", sk.Name, versions, len(sk.Resources), preview(sk.Description, 60))
+	}
+	_ = w.Flush()
+}
+
+func preview(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-3]) + "..."
+}
@@ -0,0 +1,216 @@
+package agentgraph
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+)
+
+// Deps supplies what a spec refers to by name.
+type Deps struct {
+	// Model returns the chat model for an agent and the model name its spec
+	// asks for; the empty name is the default model.
+	Model      func(ctx context.Context, agent, name string) (model.BaseChatModel, error)
+	Tools      map[string]func(ctx context.Context) (tool.BaseTool, error)
+	Middleware map[string]func(ctx context.Context) (adk.ChatModelAgentMiddleware, error)
+	// RunAgent runs the agent or team of one node with its rendered prompt and
+	// returns the answer.
+	RunAgent func(ctx context.Context, node string, agent adk.Agent, prompt string) (string, error)
+}
+
+type Graph struct {
+	Spec     *Spec
+	Runnable compose.Runnable[map[string]string, map[string]string]
+}
+
+// Ask runs the graph for one question and returns the output key's value.
+func (g *Graph) Ask(ctx context.Context, question string) (string, error) {
+	state, err := g.Runnable.Invoke(ctx, map[string]string{g.Spec.Input: question})
+	if err != nil {
+		return "", err
+	}
+
+	return state[g.Spec.Output], nil
+}
+
+type builder struct {
+	spec       *Spec
+	deps       Deps
+	tools      map[string]tool.BaseTool
+	middleware map[string]adk.ChatModelAgentMiddleware
+}
+
+// Build creates the agents and teams of a validated spec and compiles its
+// graph. Every reference to an agent gets its own instance, because an adk
+// agent can only belong to one parent.
+func Build(ctx context.Context, spec *Spec, deps Deps) (*Graph, error) {
+	b := &builder{
+		spec:       spec,
+		deps:       deps,
+		tools:      map[string]tool.BaseTool{},
+		middleware: map[string]adk.ChatModelAgentMiddleware{},
+	}
+
+	graph := compose.NewGraph[map[string]string, map[string]string]()
+	for _, node := range spec.Nodes {
+		agent, err := b.agent(ctx, node.Agent)
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", node.Name, err)
+		}
+
+		node := node
+		lambda := compose.InvokableLambda(func(ctx context.Context, state map[string]string) (map[string]string, error) {
+			prompt, err := node.RenderPrompt(state)
+			if err != nil {
+				return nil, err
+			}
+			answer, err := deps.RunAgent(ctx, node.Name, agent, prompt)
+			if err != nil {
+				return nil, err
+			}
+
+			next := maps.Clone(state)
+			next[node.Output] = answer
+			return next, nil
+		})
+		if err := graph.AddLambdaNode(node.Name, lambda); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, edge := range spec.Edges {
+		if edge.Branch == nil {
+			if err := graph.AddEdge(graphNode(edge.From), graphNode(edge.To)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		branch := edge.Branch
+		targets := map[string]bool{graphNode(branch.Default): true}
+		for _, c := range branch.Cases {
+			targets[graphNode(c.To)] = true
+		}
+		condition := func(_ context.Context, state map[string]string) (string, error) {
+			return graphNode(branch.Next(state)), nil
+		}
+		if err := graph.AddBranch(edge.From, compose.NewGraphBranch(condition, targets)); err != nil {
+			return nil, err
+		}
+	}
+
+	runnable, err := graph.Compile(ctx, compose.WithGraphName(spec.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Graph{Spec: spec, Runnable: runnable}, nil
+}
+
+func graphNode(name string) string {
+	switch name {
+	case Start:
+		return compose.START
+	case End:
+		return compose.END
+	default:
+		return name
+	}
+}
+
+func (b *builder) agent(ctx context.Context, name string) (adk.Agent, error) {
+	if team, ok := b.spec.Teams[name]; ok {
+		return b.team(ctx, name, team)
+	}
+
+	spec := b.spec.Agents[name]
+	if b.deps.Model == nil {
+		return nil, fmt.Errorf("agent %s: no model configured", name)
+	}
+	chatModel, err := b.deps.Model(ctx, name, spec.Model)
+	if err != nil {
+		return nil, fmt.Errorf("agent %s: %w", name, err)
+	}
+
+	config := &adk.ChatModelAgentConfig{
+		Name:        name,
+		Description: spec.Description,
+		Instruction: spec.Instruction,
+		Model:       chatModel,
+	}
+	for _, toolName := range spec.Tools {
+		t, err := b.tool(ctx, toolName)
+		if err != nil {
+			return nil, fmt.Errorf("agent %s: %w", name, err)
+		}
+		config.ToolsConfig.Tools = append(config.ToolsConfig.Tools, t)
+	}
+	for _, middlewareName := range spec.Middleware {
+		handler, err := b.handler(ctx, middlewareName)
+		if err != nil {
+			return nil, fmt.Errorf("agent %s: %w", name, err)
+		}
+		config.Handlers = append(config.Handlers, handler)
+	}
+
+	return adk.NewChatModelAgent(ctx, config)
+}
+
+func (b *builder) team(ctx context.Context, name string, team TeamSpec) (adk.Agent, error) {
+	members := make([]adk.Agent, 0, len(team.Members))
+	for _, member := range team.Members {
+		agent, err := b.agent(ctx, member)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, agent)
+	}
+
+	switch team.Mode {
+	case TeamParallel:
+		return adk.NewParallelAgent(ctx, &adk.ParallelAgentConfig{Name: name, Description: team.Description, SubAgents: members})
+	case TeamLoop:
+		return adk.NewLoopAgent(ctx, &adk.LoopAgentConfig{Name: name, Description: team.Description, SubAgents: members, MaxIterations: team.MaxIterations})
+	default:
+		return adk.NewSequentialAgent(ctx, &adk.SequentialAgentConfig{Name: name, Description: team.Description, SubAgents: members})
+	}
+}
+
+func (b *builder) tool(ctx context.Context, name string) (tool.BaseTool, error) {
+	if t, ok := b.tools[name]; ok {
+		return t, nil
+	}
+	factory, ok := b.deps.Tools[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tool %s", name)
+	}
+
+	t, err := factory(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", name, err)
+	}
+	b.tools[name] = t
+	return t, nil
+}
+
+func (b *builder) handler(ctx context.Context, name string) (adk.ChatModelAgentMiddleware, error) {
+	if handler, ok := b.middleware[name]; ok {
+		return handler, nil
+	}
+	factory, ok := b.deps.Middleware[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown middleware %s", name)
+	}
+
+	handler, err := factory(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("middleware %s: %w", name, err)
+	}
+	b.middleware[name] = handler
+	return handler, nil
+}
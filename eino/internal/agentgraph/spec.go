@@ -0,0 +1,418 @@
+// Package agentgraph loads multi-agent flows from YAML: agents with their
+// instruction, model, tools and middleware, sequential, parallel and loop
+// teams built from them, and a graph of nodes that run an agent or team with
+// edges and branch conditions between them.
+package agentgraph
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	goyaml "gopkg.in/yaml.v3"
+)
+
+const (
+	Start = "start"
+	End   = "end"
+)
+
+type Spec struct {
+	Name string `yaml:"name"`
+	// Input is the state key the question is stored under, "question" by
+	// default. Output is the state key returned as the answer, "answer" by
+	// default.
+	Input  string               `yaml:"input"`
+	Output string               `yaml:"output"`
+	Agents map[string]AgentSpec `yaml:"agents"`
+	Teams  map[string]TeamSpec  `yaml:"teams"`
+	Nodes  []NodeSpec           `yaml:"nodes"`
+	Edges  []EdgeSpec           `yaml:"edges"`
+}
+
+type AgentSpec struct {
+	Description string `yaml:"description"`
+	Instruction string `yaml:"instruction"`
+	// Model names a configured model; empty means the default model.
+	Model      string   `yaml:"model"`
+	Tools      []string `yaml:"tools"`
+	Middleware []string `yaml:"middleware"`
+}
+
+type TeamMode string
+
+const (
+	TeamSequential TeamMode = "sequential"
+	TeamParallel   TeamMode = "parallel"
+	TeamLoop       TeamMode = "loop"
+)
+
+type TeamSpec struct {
+	Description string   `yaml:"description"`
+	Mode        TeamMode `yaml:"mode"`
+	// Members are agent or team names.
+	Members       []string `yaml:"members"`
+	MaxIterations int      `yaml:"max_iterations"`
+}
+
+// NodeSpec runs an agent or team with a prompt rendered from the graph state
+// and stores the answer in the state under Output, or the node name.
+type NodeSpec struct {
+	Name   string `yaml:"name"`
+	Agent  string `yaml:"agent"`
+	Prompt string `yaml:"prompt"`
+	Output string `yaml:"output"`
+
+	prompt *template.Template
+}
+
+// EdgeSpec connects From to To, or to the first matching case of Branch.
+type EdgeSpec struct {
+	From   string      `yaml:"from"`
+	To     string      `yaml:"to"`
+	Branch *BranchSpec `yaml:"branch"`
+}
+
+type BranchSpec struct {
+	Cases   []CaseSpec `yaml:"cases"`
+	Default string     `yaml:"default"`
+}
+
+type CaseSpec struct {
+	When Condition `yaml:"when"`
+	To   string    `yaml:"to"`
+}
+
+// Condition tests one state value. Comparisons ignore case and surrounding
+// whitespace and punctuation; all set tests have to match.
+type Condition struct {
+	Key      string `yaml:"key"`
+	Equals   string `yaml:"equals"`
+	Contains string `yaml:"contains"`
+	Matches  string `yaml:"matches"`
+
+	matches *regexp.Regexp
+}
+
+func Load(path string) (*Spec, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read graph: %w", err)
+	}
+
+	var spec Spec
+	decoder := goyaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("parse graph %s: %w", path, err)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, fmt.Errorf("graph %s: %w", path, err)
+	}
+
+	return &spec, nil
+}
+
+// Validate checks every reference in the spec, compiles the prompt
+// templates and conditions, and fills in defaults.
+func (s *Spec) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if s.Name == "" {
+		s.Name = "agent-graph"
+	}
+	if s.Input == "" {
+		s.Input = "question"
+	}
+	if s.Output == "" {
+		s.Output = "answer"
+	}
+
+	for _, name := range sortedKeys(s.Agents) {
+		agent := s.Agents[name]
+		if _, clash := s.Teams[name]; clash {
+			fail("%s is defined as both an agent and a team", name)
+		}
+		if strings.TrimSpace(agent.Instruction) == "" {
+			fail("agent %s has no instruction", name)
+		}
+	}
+	for _, name := range sortedKeys(s.Teams) {
+		team := s.Teams[name]
+		switch team.Mode {
+		case TeamSequential, TeamParallel, TeamLoop:
+		default:
+			fail("team %s has mode %q, want sequential, parallel or loop", name, team.Mode)
+		}
+		if len(team.Members) == 0 {
+			fail("team %s has no members", name)
+		}
+		for _, member := range team.Members {
+			if !s.isRunnable(member) {
+				fail("team %s references unknown agent or team %s", name, member)
+			}
+		}
+	}
+	if cycle := s.teamCycle(); cycle != "" {
+		fail("teams contain themselves: %s", cycle)
+	}
+
+	nodes := map[string]bool{}
+	outputs := map[string]bool{s.Input: true}
+	for i := range s.Nodes {
+		node := &s.Nodes[i]
+		switch {
+		case node.Name == "":
+			fail("node %d has no name", i+1)
+			continue
+		case node.Name == Start || node.Name == End:
+			fail("node name %s is reserved", node.Name)
+		case nodes[node.Name]:
+			fail("duplicate node %s", node.Name)
+		}
+		nodes[node.Name] = true
+		if !s.isRunnable(node.Agent) {
+			fail("node %s references unknown agent or team %q", node.Name, node.Agent)
+		}
+		if node.Output == "" {
+			node.Output = node.Name
+		}
+		outputs[node.Output] = true
+		if node.Prompt == "" {
+			node.Prompt = "{{." + s.Input + "}}"
+		}
+		tmpl, err := template.New(node.Name).Option("missingkey=zero").Parse(node.Prompt)
+		if err != nil {
+			fail("node %s prompt: %v", node.Name, err)
+			continue
+		}
+		node.prompt = tmpl
+	}
+	if !outputs[s.Output] {
+		fail("no node writes the output key %s", s.Output)
+	}
+
+	outgoing := map[string]int{}
+	incoming := map[string]int{}
+	target := func(from, to string) {
+		if to == End {
+			incoming[End]++
+			return
+		}
+		if !nodes[to] {
+			fail("edge from %s goes to unknown node %q", from, to)
+			return
+		}
+		incoming[to]++
+	}
+	for i := range s.Edges {
+		edge := &s.Edges[i]
+		if edge.From != Start && !nodes[edge.From] {
+			fail("edge %d starts at unknown node %q", i+1, edge.From)
+			continue
+		}
+		outgoing[edge.From]++
+		if (edge.To == "") == (edge.Branch == nil) {
+			fail("edge from %s needs either to or branch", edge.From)
+			continue
+		}
+		if edge.Branch == nil {
+			target(edge.From, edge.To)
+			continue
+		}
+
+		if edge.Branch.Default == "" {
+			fail("branch from %s has no default", edge.From)
+		} else {
+			target(edge.From, edge.Branch.Default)
+		}
+		for j := range edge.Branch.Cases {
+			c := &edge.Branch.Cases[j]
+			target(edge.From, c.To)
+			if err := c.When.compile(outputs); err != nil {
+				fail("branch from %s case %d: %v", edge.From, j+1, err)
+			}
+		}
+	}
+	if outgoing[Start] != 1 {
+		fail("the graph needs exactly one edge from start, found %d", outgoing[Start])
+	}
+	if incoming[End] == 0 {
+		fail("no edge leads to end")
+	}
+	for _, name := range sortedKeys(nodes) {
+		switch {
+		case outgoing[name] == 0:
+			fail("node %s has no outgoing edge", name)
+		case outgoing[name] > 1:
+			fail("node %s has %d outgoing edges; use a branch or a parallel team instead", name, outgoing[name])
+		}
+		if incoming[name] == 0 {
+			fail("node %s is unreachable", name)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (s *Spec) isRunnable(name string) bool {
+	_, isAgent := s.Agents[name]
+	_, isTeam := s.Teams[name]
+	return isAgent || isTeam
+}
+
+func (s *Spec) teamCycle() string {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) string
+	visit = func(name string) string {
+		team, ok := s.Teams[name]
+		if !ok || state[name] == done {
+			return ""
+		}
+		if state[name] == visiting {
+			return strings.Join(append(path, name), " -> ")
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, member := range team.Members {
+			if cycle := visit(member); cycle != "" {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		return ""
+	}
+
+	for _, name := range sortedKeys(s.Teams) {
+		if cycle := visit(name); cycle != "" {
+			return cycle
+		}
+	}
+	return ""
+}
+
+func (c *Condition) compile(keys map[string]bool) error {
+	if c.Key == "" {
+		return errors.New("condition has no key")
+	}
+	if !keys[c.Key] {
+		return fmt.Errorf("condition key %s is not written by any node", c.Key)
+	}
+	if c.Equals == "" && c.Contains == "" && c.Matches == "" {
+		return fmt.Errorf("condition on %s needs equals, contains or matches", c.Key)
+	}
+	if c.Matches != "" {
+		re, err := regexp.Compile("(?i)" + c.Matches)
+		if err != nil {
+			return fmt.Errorf("condition on %s: %w", c.Key, err)
+		}
+		c.matches = re
+	}
+
+	return nil
+}
+
+func (c *Condition) Match(state map[string]string) bool {
+	value := normalize(state[c.Key])
+	if c.Equals != "" && value != normalize(c.Equals) {
+		return false
+	}
+	if c.Contains != "" && !strings.Contains(value, normalize(c.Contains)) {
+		return false
+	}
+	if c.matches != nil && !c.matches.MatchString(state[c.Key]) {
+		return false
+	}
+	return true
+}
+
+func normalize(value string) string {
+	return strings.Trim(strings.ToLower(strings.TrimSpace(value)), ".!?:; \t\n\r")
+}
+
+// Next picks the branch target for the state.
+func (b *BranchSpec) Next(state map[string]string) string {
+	for i := range b.Cases {
+		if b.Cases[i].When.Match(state) {
+			return b.Cases[i].To
+		}
+	}
+	return b.Default
+}
+
+// RenderPrompt fills the node's prompt template from the graph state.
+func (n *NodeSpec) RenderPrompt(state map[string]string) (string, error) {
+	var sb strings.Builder
+	if err := n.prompt.Execute(&sb, state); err != nil {
+		return "", fmt.Errorf("render prompt of node %s: %w", n.Name, err)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// Topology describes the graph and its teams for printing.
+func (s *Spec) Topology() string {
+	var sb strings.Builder
+	sb.WriteString("Graph topology:\n")
+	for _, edge := range s.Edges {
+		if edge.Branch == nil {
+			fmt.Fprintf(&sb, "  %s -> %s\n", edge.From, edge.To)
+			continue
+		}
+		for _, c := range edge.Branch.Cases {
+			fmt.Fprintf(&sb, "  %s -> %s (when %s)\n", edge.From, c.To, c.When)
+		}
+		fmt.Fprintf(&sb, "  %s -> %s (otherwise)\n", edge.From, edge.Branch.Default)
+	}
+
+	for _, name := range sortedKeys(s.Teams) {
+		team := s.Teams[name]
+		separator := " -> "
+		if team.Mode == TeamParallel {
+			separator = " | "
+		}
+		fmt.Fprintf(&sb, "\n%s (%s): %s", name, team.Mode, strings.Join(team.Members, separator))
+		if team.Mode == TeamLoop && team.MaxIterations > 0 {
+			fmt.Fprintf(&sb, " (max %d iterations)", team.MaxIterations)
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+func (c Condition) String() string {
+	var tests []string
+	if c.Equals != "" {
+		tests = append(tests, fmt.Sprintf("%s equals %q", c.Key, c.Equals))
+	}
+	if c.Contains != "" {
+		tests = append(tests, fmt.Sprintf("%s contains %q", c.Key, c.Contains))
+	}
+	if c.Matches != "" {
+		tests = append(tests, fmt.Sprintf("%s matches %q", c.Key, c.Matches))
+	}
+	return strings.Join(tests, " and ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,267 @@
+package agentgraph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goyaml "gopkg.in/yaml.v3"
+)
+
+const validGraph = `
+agents:
+  triage: {instruction: Classify the question.}
+  writer: {instruction: Answer it.}
+  reviewer: {instruction: Review the answer.}
+teams:
+  answer-team: {mode: loop, members: [writer, reviewer], max_iterations: 2}
+nodes:
+  - {name: classify, agent: triage, output: kind}
+  - {name: answer, agent: answer-team, prompt: "{{.question}} ({{.kind}})"}
+edges:
+  - {from: start, to: classify}
+  - from: classify
+    branch:
+      cases:
+        - when: {key: kind, equals: simple}
+          to: end
+      default: answer
+  - {from: answer, to: end}
+`
+
+// parseSpec decodes a graph without validating it.
+func parseSpec(t *testing.T, source string) *Spec {
+	t.Helper()
+	var spec Spec
+	if err := goyaml.Unmarshal([]byte(source), &spec); err != nil {
+		t.Fatalf("%s: %v", source, err)
+	}
+	return &spec
+}
+
+func TestValidate(t *testing.T) {
+	spec := parseSpec(t, validGraph)
+	if err := spec.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Name != "agent-graph" || spec.Input != "question" || spec.Output != "answer" {
+		t.Errorf("defaults = %q, %q, %q", spec.Name, spec.Input, spec.Output)
+	}
+	if node := spec.Nodes[1]; node.Output != "answer" {
+		t.Errorf("node output = %q, want the node name", node.Output)
+	}
+	prompt, err := spec.Nodes[1].RenderPrompt(map[string]string{"question": "Why?", "kind": "hard"})
+	if err != nil || prompt != "Why? (hard)" {
+		t.Errorf("RenderPrompt() = %q, %v", prompt, err)
+	}
+	if prompt, err := spec.Nodes[0].RenderPrompt(map[string]string{"question": " Why? "}); err != nil || prompt != "Why?" {
+		t.Errorf("default prompt = %q, %v", prompt, err)
+	}
+}
+
+func TestValidateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(spec *Spec)
+		err    string
+	}{
+		{
+			name:   "unknown node agent",
+			change: func(spec *Spec) { spec.Nodes[0].Agent = "router" },
+			err:    `node classify references unknown agent or team "router"`,
+		},
+		{
+			name:   "unknown edge target",
+			change: func(spec *Spec) { spec.Edges[2].To = "publish" },
+			err:    `edge from answer goes to unknown node "publish"`,
+		},
+		{
+			name:   "unknown edge source",
+			change: func(spec *Spec) { spec.Edges[2].From = "publish" },
+			err:    `edge 3 starts at unknown node "publish"`,
+		},
+		{
+			name:   "unknown branch target",
+			change: func(spec *Spec) { spec.Edges[1].Branch.Cases[0].To = "publish" },
+			err:    `edge from classify goes to unknown node "publish"`,
+		},
+		{
+			name: "team cycle",
+			change: func(spec *Spec) {
+				spec.Teams["review-team"] = TeamSpec{Mode: TeamSequential, Members: []string{"reviewer", "answer-team"}}
+				spec.Teams["answer-team"] = TeamSpec{Mode: TeamLoop, Members: []string{"writer", "review-team"}}
+			},
+			err: "teams contain themselves: answer-team -> review-team -> answer-team",
+		},
+		{
+			name: "team member",
+			change: func(spec *Spec) {
+				spec.Teams["answer-team"] = TeamSpec{Mode: "round-robin", Members: []string{"editor"}}
+			},
+			err: `team answer-team has mode "round-robin", want sequential, parallel or loop` + "\n" + "team answer-team references unknown agent or team editor",
+		},
+		{
+			name:   "unwritten condition key",
+			change: func(spec *Spec) { spec.Edges[1].Branch.Cases[0].When.Key = "category" },
+			err:    "branch from classify case 1: condition key category is not written by any node",
+		},
+		{
+			name:   "bad regex",
+			change: func(spec *Spec) { spec.Edges[1].Branch.Cases[0].When = Condition{Key: "kind", Matches: "(simple"} },
+			err:    "branch from classify case 1: condition on kind: error parsing regexp",
+		},
+		{
+			name:   "empty condition",
+			change: func(spec *Spec) { spec.Edges[1].Branch.Cases[0].When = Condition{Key: "kind"} },
+			err:    "condition on kind needs equals, contains or matches",
+		},
+		{
+			name:   "branch without default",
+			change: func(spec *Spec) { spec.Edges[1].Branch.Default = "" },
+			err:    "branch from classify has no default\nnode answer is unreachable",
+		},
+		{
+			name: "two edges from a node",
+			change: func(spec *Spec) {
+				spec.Edges[1] = EdgeSpec{From: "classify", To: "answer"}
+				spec.Edges = append(spec.Edges, EdgeSpec{From: "classify", To: End})
+			},
+			err: "node classify has 2 outgoing edges",
+		},
+		{
+			name: "reserved and duplicate nodes",
+			change: func(spec *Spec) {
+				spec.Nodes = append(spec.Nodes, NodeSpec{Name: End, Agent: "writer"}, NodeSpec{Name: "answer", Agent: "writer"})
+			},
+			err: "node name end is reserved\nduplicate node answer",
+		},
+		{
+			name:   "missing output",
+			change: func(spec *Spec) { spec.Output = "summary" },
+			err:    "no node writes the output key summary",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec := parseSpec(t, validGraph)
+			test.change(spec)
+			if err := spec.Validate(); err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Validate() error = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestTeamCycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		teams map[string][]string
+		want  string
+	}{
+		{name: "nested teams", teams: map[string][]string{"a": {"b", "writer"}, "b": {"writer"}}},
+		{name: "shared member", teams: map[string][]string{"a": {"c"}, "b": {"c"}, "c": {"writer"}}},
+		{name: "self", teams: map[string][]string{"a": {"a"}}, want: "a -> a"},
+		{name: "indirect", teams: map[string][]string{"a": {"writer", "b"}, "b": {"c"}, "c": {"a"}}, want: "a -> b -> c -> a"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec := &Spec{Teams: map[string]TeamSpec{}}
+			for name, members := range test.teams {
+				spec.Teams[name] = TeamSpec{Mode: TeamSequential, Members: members}
+			}
+			if got := spec.teamCycle(); got != test.want {
+				t.Errorf("teamCycle() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestConditionMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		when  Condition
+		value string
+		want  bool
+	}{
+		{name: "equals ignores case and punctuation", when: Condition{Equals: "Simple"}, value: " simple.\n", want: true},
+		{name: "equals", when: Condition{Equals: "simple"}, value: "not simple"},
+		{name: "contains", when: Condition{Contains: "REFUND"}, value: "It is a refund request!", want: true},
+		{name: "matches ignores case", when: Condition{Matches: `^order #\d+$`}, value: "Order #42", want: true},
+		{name: "matches", when: Condition{Matches: `^order #\d+$`}, value: "order 42"},
+		{name: "all tests", when: Condition{Contains: "refund", Matches: `urgent`}, value: "refund, soon"},
+		{name: "missing key", when: Condition{Contains: "x"}, value: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.when.Key = "kind"
+			if err := test.when.compile(map[string]bool{"kind": true}); err != nil {
+				t.Fatal(err)
+			}
+			state := map[string]string{}
+			if test.value != "" {
+				state["kind"] = test.value
+			}
+			if got := test.when.Match(state); got != test.want {
+				t.Errorf("Match(%q) = %t, want %t", test.value, got, test.want)
+			}
+		})
+	}
+
+	if err := (&Condition{Equals: "x"}).compile(nil); err == nil || err.Error() != "condition has no key" {
+		t.Errorf("compile() without a key error = %v", err)
+	}
+}
+
+func TestBranchNext(t *testing.T) {
+	spec := parseSpec(t, validGraph)
+	spec.Edges[1].Branch.Cases = append(spec.Edges[1].Branch.Cases,
+		CaseSpec{When: Condition{Key: "kind", Contains: "simple"}, To: "answer"},
+		CaseSpec{When: Condition{Key: "question", Matches: "refund"}, To: "answer"},
+	)
+	if err := spec.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	branch := spec.Edges[1].Branch
+
+	tests := []struct {
+		state map[string]string
+		want  string
+	}{
+		// The first matching case wins.
+		{state: map[string]string{"kind": "Simple"}, want: End},
+		{state: map[string]string{"kind": "not simple"}, want: "answer"},
+		{state: map[string]string{"kind": "hard", "question": "A refund?"}, want: "answer"},
+		// Without a match the branch falls through to the default.
+		{state: map[string]string{"kind": "hard"}, want: "answer"},
+		{state: map[string]string{}, want: "answer"},
+	}
+	for _, test := range tests {
+		if got := branch.Next(test.state); got != test.want {
+			t.Errorf("Next(%v) = %s, want %s", test.state, got, test.want)
+		}
+	}
+
+	branch.Default = "classify"
+	if got := branch.Next(map[string]string{"kind": "hard"}); got != "classify" {
+		t.Errorf("Next() without a match = %s, want the default", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "graph.yaml")
+	if err := os.WriteFile(path, []byte(validGraph), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(validGraph+"retries: 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "field retries not found") {
+		t.Errorf("Load() with an unknown field error = %v", err)
+	}
+}
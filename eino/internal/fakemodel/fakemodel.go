@@ -0,0 +1,260 @@
+// Package fakemodel provides a chat model that replays a script of canned
+// responses and tool calls instead of calling a provider, so the agent
+// commands can run deterministically and without network.
+package fakemodel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	goyaml "gopkg.in/yaml.v3"
+)
+
+type Script struct {
+	Turns []Turn `yaml:"turns"`
+}
+
+// Turn is one model response. Every turn is used once: a request gets the
+// first unused turn whose conditions match it, so turns for agents that run
+// in parallel can be told apart by their system prompt.
+type Turn struct {
+	// System has to be part of the system prompt, which holds the agent's
+	// instruction.
+	System string `yaml:"system"`
+	// Input has to be part of the last message of the request.
+	Input string `yaml:"input"`
+
+	Content string `yaml:"content"`
+	// Chunks are streamed instead of Content when set.
+	Chunks    []string   `yaml:"chunks"`
+	ToolCalls []ToolCall `yaml:"tool_calls"`
+	// Error fails the request instead of answering.
+	Error string `yaml:"error"`
+	// Delay holds the response back, which orders the output of agents that
+	// run in parallel.
+	Delay time.Duration `yaml:"delay"`
+	Usage *Usage        `yaml:"usage"`
+}
+
+type ToolCall struct {
+	ID        string         `yaml:"id"`
+	Name      string         `yaml:"name"`
+	Arguments map[string]any `yaml:"arguments"`
+}
+
+type Usage struct {
+	Prompt     int `yaml:"prompt"`
+	Completion int `yaml:"completion"`
+}
+
+// Model is safe for concurrent use. It implements model.ToolCallingChatModel;
+// scripted tool calls have to name a tool the request offers.
+type Model struct {
+	mu    sync.Mutex
+	turns []Turn
+	used  []bool
+	calls int
+}
+
+func New(script Script) *Model {
+	return &Model{turns: script.Turns, used: make([]bool, len(script.Turns))}
+}
+
+// Load reads a YAML or JSON script.
+func Load(path string) (*Model, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read model script: %w", err)
+	}
+
+	var script Script
+	decoder := goyaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&script); err != nil {
+		return nil, fmt.Errorf("parse model script %s: %w", path, err)
+	}
+	for i, turn := range script.Turns {
+		if turn.Content == "" && len(turn.Chunks) == 0 && len(turn.ToolCalls) == 0 && turn.Error == "" {
+			return nil, fmt.Errorf("model script %s: turn %d has no content, chunks, tool calls or error", path, i+1)
+		}
+	}
+
+	return New(script), nil
+}
+
+// Remaining reports how many turns were not used.
+func (m *Model) Remaining() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	remaining := 0
+	for _, used := range m.used {
+		if !used {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+func (m *Model) WithTools(tools []*schema.ToolInfo) (model.ToolCallingChatModel, error) {
+	_ = tools
+	return m, nil
+}
+
+func (m *Model) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	turn, err := m.next(ctx, input, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	message := schema.AssistantMessage(turn.content(), turn.toolCalls())
+	message.ResponseMeta = turn.responseMeta()
+	return message, nil
+}
+
+func (m *Model) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	turn, err := m.next(ctx, input, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := turn.Chunks
+	if len(chunks) == 0 && turn.Content != "" {
+		chunks = []string{turn.Content}
+	}
+	frames := make([]*schema.Message, 0, len(chunks)+1)
+	for _, chunk := range chunks {
+		frames = append(frames, schema.AssistantMessage(chunk, nil))
+	}
+	last := schema.AssistantMessage("", turn.toolCalls())
+	last.ResponseMeta = turn.responseMeta()
+	frames = append(frames, last)
+
+	return schema.StreamReaderFromArray(frames), nil
+}
+
+func (m *Model) next(ctx context.Context, input []*schema.Message, opts []model.Option) (Turn, error) {
+	turn, err := m.take(input, opts)
+	if err != nil {
+		return Turn{}, err
+	}
+
+	if turn.Delay > 0 {
+		select {
+		case <-ctx.Done():
+			return Turn{}, ctx.Err()
+		case <-time.After(turn.Delay):
+		}
+	}
+	if turn.Error != "" {
+		return Turn{}, errors.New(turn.Error)
+	}
+	return turn, nil
+}
+
+func (m *Model) take(input []*schema.Message, opts []model.Option) (Turn, error) {
+	system, last := "", ""
+	for _, message := range input {
+		if message.Role == schema.System {
+			system += message.Content + "\n"
+		}
+	}
+	if len(input) > 0 {
+		last = input[len(input)-1].Content
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls++
+	for i, turn := range m.turns {
+		if m.used[i] || !strings.Contains(system, turn.System) || !strings.Contains(last, turn.Input) {
+			continue
+		}
+		if err := checkTools(turn, model.GetCommonOptions(nil, opts...).Tools); err != nil {
+			return Turn{}, fmt.Errorf("scripted turn %d: %w", i+1, err)
+		}
+		m.used[i] = true
+		return turn, nil
+	}
+
+	return Turn{}, fmt.Errorf("no scripted turn left for request %d (last message %q)", m.calls, abbreviate(last))
+}
+
+func checkTools(turn Turn, tools []*schema.ToolInfo) error {
+	for _, call := range turn.ToolCalls {
+		found := false
+		for _, tool := range tools {
+			found = found || tool.Name == call.Name
+		}
+		if !found {
+			return fmt.Errorf("calls tool %s, which the request does not offer", call.Name)
+		}
+	}
+	return nil
+}
+
+func (t Turn) content() string {
+	if len(t.Chunks) > 0 {
+		return strings.Join(t.Chunks, "")
+	}
+	return t.Content
+}
+
+func (t Turn) toolCalls() []schema.ToolCall {
+	if len(t.ToolCalls) == 0 {
+		return nil
+	}
+
+	calls := make([]schema.ToolCall, 0, len(t.ToolCalls))
+	for i, call := range t.ToolCalls {
+		arguments, err := json.Marshal(call.Arguments)
+		if err != nil || call.Arguments == nil {
+			arguments = []byte("{}")
+		}
+		id := call.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", i+1)
+		}
+		index := i
+		calls = append(calls, schema.ToolCall{
+			Index:    &index,
+			ID:       id,
+			Type:     "function",
+			Function: schema.FunctionCall{Name: call.Name, Arguments: string(arguments)},
+		})
+	}
+	return calls
+}
+
+func (t Turn) responseMeta() *schema.ResponseMeta {
+	meta := &schema.ResponseMeta{FinishReason: "stop"}
+	if len(t.ToolCalls) > 0 {
+		meta.FinishReason = "tool_calls"
+	}
+	if t.Usage != nil {
+		meta.Usage = &schema.TokenUsage{
+			PromptTokens:     t.Usage.Prompt,
+			CompletionTokens: t.Usage.Completion,
+			TotalTokens:      t.Usage.Prompt + t.Usage.Completion,
+		}
+	}
+	return meta
+}
+
+func abbreviate(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) > 80 {
+		return text[:77] + "..."
+	}
+	return text
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const DefaultDir = ".eino-sessions"
+
+// FileStore keeps one JSON file per session. Files are replaced atomically,
+// so a crash never leaves a half-written session behind.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if dir == "" {
+		dir = DefaultDir
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create session directory: %w", err)
+	}
+
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return "", fmt.Errorf("invalid session ID %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+func (s *FileStore) Load(_ context.Context, id string) (*Session, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read session %s: %w", id, err)
+	}
+	return decode(encoded)
+}
+
+func (s *FileStore) Save(_ context.Context, session *Session) error {
+	path, err := s.path(session.ID)
+	if err != nil {
+		return err
+	}
+	encoded, err := encode(session)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, encoded, 0o644); err != nil {
+		return fmt.Errorf("write session %s: %w", session.ID, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write session %s: %w", session.ID, err)
+	}
+	return nil
+}
+
+func (s *FileStore) List(ctx context.Context) ([]Info, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read session directory: %w", err)
+	}
+
+	infos := make([]Info, 0, len(entries))
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		session, err := s.Load(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info(session))
+	}
+	sortInfos(infos)
+	return infos, nil
+}
+
+func (s *FileStore) Close() error {
+	return nil
+}
+
+func encode(session *Session) ([]byte, error) {
+	encoded, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode session %s: %w", session.ID, err)
+	}
+	return encoded, nil
+}
+
+func decode(encoded []byte) (*Session, error) {
+	var session Session
+	if err := json.Unmarshal(encoded, &session); err != nil {
+		return nil, fmt.Errorf("decode session: %w", err)
+	}
+	return &session, nil
+}
@@ -0,0 +1,156 @@
+// Package memory keeps multi-turn conversations between runs. A session holds
+// the recent messages verbatim and a summary of everything older, and is
+// stored in memory, in a directory of JSON files or in SQLite.
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+var ErrNotFound = errors.New("session not found")
+
+type Session struct {
+	ID string `json:"id"`
+	// Summary condenses the messages that were dropped from Messages.
+	Summary  string            `json:"summary,omitempty"`
+	Messages []*schema.Message `json:"messages"`
+	// Turns counts the answered turns, including summarized ones.
+	Turns     int       `json:"turns"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func NewSession() *Session {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	now := time.Now()
+	return &Session{
+		ID:        now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Input is what the agent gets for the next turn: the summary, if there is
+// one, followed by the recent messages.
+func (s *Session) Input() []*schema.Message {
+	input := make([]*schema.Message, 0, len(s.Messages)+1)
+	if s.Summary != "" {
+		input = append(input, schema.SystemMessage("Summary of the earlier conversation:\n"+s.Summary))
+	}
+	return append(input, s.Messages...)
+}
+
+type Info struct {
+	ID        string
+	Messages  int
+	UpdatedAt time.Time
+}
+
+type Store interface {
+	// Load returns ErrNotFound for an unknown session.
+	Load(ctx context.Context, id string) (*Session, error)
+	Save(ctx context.Context, session *Session) error
+	// List returns the sessions, most recently updated first.
+	List(ctx context.Context) ([]Info, error)
+	Close() error
+}
+
+const (
+	KindMemory = "memory"
+	KindFile   = "file"
+	KindSQLite = "sqlite"
+)
+
+// Open creates a store by kind. path is the directory of a file store and
+// the database file of a SQLite store.
+func Open(kind, path string) (Store, error) {
+	switch kind {
+	case KindMemory:
+		return NewInMemoryStore(), nil
+	case KindFile:
+		return NewFileStore(path)
+	case KindSQLite:
+		return NewSQLiteStore(path)
+	default:
+		return nil, fmt.Errorf("unknown memory store %q, want memory, file or sqlite", kind)
+	}
+}
+
+// InMemoryStore forgets everything when the process exits; it is useful for
+// a REPL that does not need to be resumed.
+type InMemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string][]byte
+}
+
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{sessions: map[string][]byte{}}
+}
+
+func (s *InMemoryStore) Load(_ context.Context, id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encoded, ok := s.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return decode(encoded)
+}
+
+// Save stores a copy, so later changes to the session are not visible until
+// it is saved again.
+func (s *InMemoryStore) Save(_ context.Context, session *Session) error {
+	encoded, err := encode(session)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[session.ID] = encoded
+	return nil
+}
+
+func (s *InMemoryStore) List(_ context.Context) ([]Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	infos := make([]Info, 0, len(s.sessions))
+	for _, encoded := range s.sessions {
+		session, err := decode(encoded)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info(session))
+	}
+	sortInfos(infos)
+	return infos, nil
+}
+
+func (s *InMemoryStore) Close() error {
+	return nil
+}
+
+func info(session *Session) Info {
+	return Info{ID: session.ID, Messages: len(session.Messages), UpdatedAt: session.UpdatedAt}
+}
+
+func sortInfos(infos []Info) {
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].UpdatedAt.Equal(infos[j].UpdatedAt) {
+			return infos[i].ID > infos[j].ID
+		}
+		return infos[i].UpdatedAt.After(infos[j].UpdatedAt)
+	})
+}
@@ -0,0 +1,153 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// openStore opens a store of the given kind in a fresh directory. The SQLite
+// store is skipped in a build without cgo.
+func openStore(t *testing.T, kind, dir string) Store {
+	t.Helper()
+	path := dir
+	if kind == KindSQLite {
+		path = filepath.Join(dir, "sessions.db")
+	}
+	store, err := Open(kind, path)
+	if kind == KindSQLite && err != nil && strings.Contains(err.Error(), "cgo") {
+		t.Skip("the SQLite driver needs cgo")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestStores(t *testing.T) {
+	created := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	for _, kind := range []string{KindMemory, KindFile, KindSQLite} {
+		t.Run(kind, func(t *testing.T) {
+			dir := t.TempDir()
+			store := openStore(t, kind, dir)
+			defer func() { store.Close() }()
+
+			if _, err := store.Load(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Load(missing) error = %v, want ErrNotFound", err)
+			}
+			if infos, err := store.List(ctx); err != nil || len(infos) != 0 {
+				t.Errorf("List() of an empty store = %v, %v", infos, err)
+			}
+
+			session := &Session{
+				ID:      "s1",
+				Summary: "Ada maintains billing.",
+				Messages: []*schema.Message{
+					schema.UserMessage("weather in Hangzhou?"),
+					schema.AssistantMessage("", []schema.ToolCall{{ID: "call_1", Function: schema.FunctionCall{Name: "lookup_weather", Arguments: `{"city":"Hangzhou"}`}}}),
+					schema.ToolMessage("Light rain", "call_1"),
+					schema.AssistantMessage("Take an umbrella.", nil),
+				},
+				Turns:     3,
+				CreatedAt: created,
+				UpdatedAt: created.Add(time.Minute),
+			}
+			if err := store.Save(ctx, session); err != nil {
+				t.Fatal(err)
+			}
+			// Later changes to the session must not leak into the store.
+			session.Messages[3].Content = "changed"
+
+			loaded, err := store.Load(ctx, "s1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.ID != "s1" || loaded.Summary != "Ada maintains billing." || loaded.Turns != 3 ||
+				!loaded.CreatedAt.Equal(created) || !loaded.UpdatedAt.Equal(created.Add(time.Minute)) {
+				t.Errorf("Load() = %+v", loaded)
+			}
+			if len(loaded.Messages) != 4 {
+				t.Fatalf("loaded %d messages, want 4", len(loaded.Messages))
+			}
+			if call := loaded.Messages[1].ToolCalls; len(call) != 1 || call[0].ID != "call_1" || call[0].Function.Arguments != `{"city":"Hangzhou"}` {
+				t.Errorf("tool calls = %+v", call)
+			}
+			if tool := loaded.Messages[2]; tool.Role != schema.Tool || tool.ToolCallID != "call_1" {
+				t.Errorf("tool message = %+v", tool)
+			}
+			if got := loaded.Messages[3].Content; got != "Take an umbrella." {
+				t.Errorf("last message = %q", got)
+			}
+
+			// Saving again replaces the session; List orders by update time,
+			// then by ID, both descending.
+			loaded.Messages = loaded.Messages[:2]
+			loaded.UpdatedAt = created.Add(3 * time.Minute)
+			for _, s := range []*Session{
+				loaded,
+				{ID: "s2", UpdatedAt: created.Add(2 * time.Minute)},
+				{ID: "s3", UpdatedAt: created.Add(3 * time.Minute)},
+				{ID: "s0", UpdatedAt: created},
+			} {
+				if err := store.Save(ctx, s); err != nil {
+					t.Fatal(err)
+				}
+			}
+			infos, err := store.List(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, info := range infos {
+				ids = append(ids, info.ID)
+			}
+			if want := []string{"s3", "s1", "s2", "s0"}; !slices.Equal(ids, want) {
+				t.Errorf("List() = %v, want %v", ids, want)
+			}
+			if infos[1].Messages != 2 || !infos[1].UpdatedAt.Equal(created.Add(3*time.Minute)) {
+				t.Errorf("List() entry for s1 = %+v", infos[1])
+			}
+
+			if kind == KindMemory {
+				return
+			}
+			// The persistent stores keep the sessions across a reopen.
+			if err := store.Close(); err != nil {
+				t.Fatal(err)
+			}
+			store = openStore(t, kind, dir)
+			if reopened, err := store.Load(ctx, "s1"); err != nil || len(reopened.Messages) != 2 {
+				t.Errorf("Load() after reopening = %v, %v", reopened, err)
+			}
+		})
+	}
+}
+
+func TestFileStoreRejectsPaths(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"", "../s1", `a\b`, ".hidden"} {
+		if err := store.Save(context.Background(), &Session{ID: id}); err == nil || !strings.Contains(err.Error(), "invalid session ID") {
+			t.Errorf("Save(%q) error = %v", id, err)
+		}
+		if _, err := store.Load(context.Background(), id); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Load(%q) error = %v, want an invalid ID", id, err)
+		}
+	}
+}
+
+func TestOpenUnknownKind(t *testing.T) {
+	if _, err := Open("redis", ""); err == nil || !strings.Contains(err.Error(), `unknown memory store "redis"`) {
+		t.Errorf("Open(redis) error = %v", err)
+	}
+}
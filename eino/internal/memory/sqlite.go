@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const DefaultSQLiteFile = ".eino-sessions.db"
+
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS sessions (
+	id         TEXT PRIMARY KEY,
+	messages   INTEGER NOT NULL,
+	updated_at INTEGER NOT NULL,
+	data       BLOB NOT NULL
+)`
+
+// SQLiteStore keeps every session as one JSON row, with the message count and
+// update time in their own columns so listing does not decode the sessions.
+//
+// The driver, mattn/go-sqlite3, is a cgo package: the store needs a binary
+// built with CGO_ENABLED=1 and a C compiler. Without cgo the driver is a stub
+// and NewSQLiteStore fails; use the file store there.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	if path == "" {
+		path = DefaultSQLiteFile
+	}
+
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, fmt.Errorf("open session database: %w", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return nil, errors.Join(fmt.Errorf("create session table: %w", err), db.Close())
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Load(ctx context.Context, id string) (*Session, error) {
+	var encoded []byte
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM sessions WHERE id = ?`, id).Scan(&encoded)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load session %s: %w", id, err)
+	}
+	return decode(encoded)
+}
+
+func (s *SQLiteStore) Save(ctx context.Context, session *Session) error {
+	encoded, err := encode(session)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO sessions (id, messages, updated_at, data) VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET messages = excluded.messages, updated_at = excluded.updated_at, data = excluded.data`,
+		session.ID, len(session.Messages), session.UpdatedAt.UnixNano(), encoded)
+	if err != nil {
+		return fmt.Errorf("save session %s: %w", session.ID, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) List(ctx context.Context) ([]Info, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, messages, updated_at FROM sessions ORDER BY updated_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var infos []Info
+	for rows.Next() {
+		var (
+			item    Info
+			updated int64
+		)
+		if err := rows.Scan(&item.ID, &item.Messages, &updated); err != nil {
+			return nil, fmt.Errorf("list sessions: %w", err)
+		}
+		item.UpdatedAt = time.Unix(0, updated)
+		infos = append(infos, item)
+	}
+	return infos, rows.Err()
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
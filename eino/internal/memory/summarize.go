@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+const summaryInstruction = "You condense conversations for your own later reference. Keep facts, decisions, names, numbers and open questions; drop pleasantries. Reply with the summary only."
+
+// Summarizer folds the oldest messages of a session into its summary once the
+// session gets close to the model's context window.
+type Summarizer struct {
+	Model model.BaseChatModel
+	// ContextWindow is the model's context size in tokens.
+	ContextWindow int
+	// Threshold is the share of the context window at which to summarize,
+	// 0.75 by default.
+	Threshold float64
+	// KeepRecent is the number of recent messages kept verbatim, 6 by default.
+	KeepRecent int
+}
+
+// EstimateTokens approximates the prompt size of a session at about four
+// characters per token, which is close enough to decide when to summarize.
+func EstimateTokens(session *Session) int {
+	chars := len(session.Summary)
+	for _, message := range session.Messages {
+		chars += len(message.Content) + len(message.ReasoningContent) + 16
+		for _, call := range message.ToolCalls {
+			chars += len(call.Function.Name) + len(call.Function.Arguments)
+		}
+	}
+	return chars / 4
+}
+
+// Compact summarizes the session when needed and reports whether it did.
+func (s *Summarizer) Compact(ctx context.Context, session *Session) (bool, error) {
+	if s == nil || s.Model == nil || s.ContextWindow <= 0 {
+		return false, nil
+	}
+	threshold := s.Threshold
+	if threshold <= 0 || threshold >= 1 {
+		threshold = 0.75
+	}
+	keep := s.KeepRecent
+	if keep <= 0 {
+		keep = 6
+	}
+	if EstimateTokens(session) < int(float64(s.ContextWindow)*threshold) || len(session.Messages) <= keep {
+		return false, nil
+	}
+
+	// Keep whole turns: the verbatim part starts with a user message.
+	split := len(session.Messages) - keep
+	for split > 0 && session.Messages[split].Role != schema.User {
+		split--
+	}
+	if split == 0 {
+		return false, nil
+	}
+
+	summary, err := s.Model.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(summaryInstruction),
+		schema.UserMessage(transcript(session.Summary, session.Messages[:split])),
+	})
+	if err != nil {
+		return false, fmt.Errorf("summarize session %s: %w", session.ID, err)
+	}
+
+	session.Summary = strings.TrimSpace(summary.Content)
+	session.Messages = append([]*schema.Message(nil), session.Messages[split:]...)
+	return true, nil
+}
+
+func transcript(summary string, messages []*schema.Message) string {
+	var sb strings.Builder
+	if summary != "" {
+		sb.WriteString("Summary so far:\n")
+		sb.WriteString(summary)
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("Conversation to add to the summary:\n")
+	for _, message := range messages {
+		fmt.Fprintf(&sb, "%s: %s\n", message.Role, strings.TrimSpace(message.Content))
+	}
+	return sb.String()
+}
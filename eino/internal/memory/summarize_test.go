@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"einoexamples/internal/fakemodel"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// conversation builds one message per role letter: u for user, a for
+// assistant and t for tool, each with size characters of content.
+func conversation(roles string, size int) []*schema.Message {
+	content := strings.Repeat("x", size)
+	messages := make([]*schema.Message, 0, len(roles))
+	for _, role := range roles {
+		switch role {
+		case 'u':
+			messages = append(messages, schema.UserMessage(content))
+		case 'a':
+			messages = append(messages, schema.AssistantMessage(content, nil))
+		case 't':
+			messages = append(messages, schema.ToolMessage(content, "call_1"))
+		}
+	}
+	return messages
+}
+
+func TestCompact(t *testing.T) {
+	// With a context window of 1000 tokens, the default threshold is 750
+	// tokens, about 3000 characters.
+	const window = 1000
+
+	tests := []struct {
+		name       string
+		roles      string
+		size       int
+		threshold  float64
+		keepRecent int
+		summary    string
+		// input has to be part of the transcript sent to the model.
+		input string
+		// kept is the number of messages left verbatim, 0 if the session is
+		// not compacted.
+		kept int
+	}{
+		{name: "below the default threshold", roles: "uauauaua", size: 100},
+		{name: "default threshold and keep", roles: "uauauauaua", size: 400, kept: 6},
+		{name: "custom threshold", roles: "uauauaua", size: 284, threshold: 0.5, keepRecent: 2, kept: 2},
+		{name: "custom threshold not reached", roles: "uauauaua", size: 200, threshold: 0.5, keepRecent: 2},
+		{name: "invalid threshold uses the default", roles: "uauauaua", size: 284, threshold: 1.5, keepRecent: 2},
+		{name: "fewer messages than kept", roles: "uauaua", size: 2000},
+		// The split moves back from the tool call to the user message that
+		// started the turn.
+		{name: "whole turns", roles: "uauataua", size: 400, keepRecent: 3, kept: 6},
+		{name: "nothing to summarize", roles: "uatatata", size: 400, keepRecent: 2},
+		{
+			name: "earlier summary", roles: "uauauauaua", size: 400, kept: 6,
+			summary: "Ada maintains billing.", input: "Summary so far:\nAda maintains billing.\n\nConversation to add to the summary:\nuser: xxx",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := test.input
+			if input == "" {
+				input = "Conversation to add to the summary:\nuser: xxx"
+			}
+			model := fakemodel.New(fakemodel.Script{Turns: []fakemodel.Turn{
+				{System: "You condense conversations", Input: input, Content: "  Ada asked about billing.\n"},
+			}})
+			summarizer := &Summarizer{Model: model, ContextWindow: window, Threshold: test.threshold, KeepRecent: test.keepRecent}
+
+			messages := conversation(test.roles, test.size)
+			session := &Session{ID: "s1", Summary: test.summary, Messages: messages}
+			compacted, err := summarizer.Compact(context.Background(), session)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.kept == 0 {
+				if compacted || model.Remaining() != 1 || len(session.Messages) != len(messages) || session.Summary != test.summary {
+					t.Errorf("compacted = %t with %d messages and summary %q, want the session unchanged", compacted, len(session.Messages), session.Summary)
+				}
+				return
+			}
+			if !compacted || model.Remaining() != 0 {
+				t.Fatalf("compacted = %t, want the session summarized", compacted)
+			}
+			if session.Summary != "Ada asked about billing." {
+				t.Errorf("Summary = %q", session.Summary)
+			}
+			if len(session.Messages) != test.kept {
+				t.Fatalf("kept %d messages, want %d", len(session.Messages), test.kept)
+			}
+			if session.Messages[0].Role != schema.User {
+				t.Errorf("kept messages start with %s, want a user message", session.Messages[0].Role)
+			}
+			for i, message := range session.Messages {
+				if message != messages[len(messages)-test.kept+i] {
+					t.Errorf("kept message %d is not one of the recent messages", i)
+				}
+			}
+		})
+	}
+}
+
+func TestCompactDisabled(t *testing.T) {
+	session := &Session{ID: "s1", Messages: conversation("uauauauaua", 4000)}
+	for name, summarizer := range map[string]*Summarizer{
+		"nil":            nil,
+		"no model":       {ContextWindow: 1000},
+		"no window size": {Model: fakemodel.New(fakemodel.Script{})},
+	} {
+		if compacted, err := summarizer.Compact(context.Background(), session); compacted || err != nil {
+			t.Errorf("%s: Compact() = %t, %v", name, compacted, err)
+		}
+	}
+}
+
+func TestCompactModelError(t *testing.T) {
+	model := fakemodel.New(fakemodel.Script{Turns: []fakemodel.Turn{{Error: "rate limited"}}})
+	summarizer := &Summarizer{Model: model, ContextWindow: 1000}
+	messages := conversation("uauauauaua", 400)
+	session := &Session{ID: "s1", Messages: messages}
+
+	_, err := summarizer.Compact(context.Background(), session)
+	if err == nil || !strings.Contains(err.Error(), "summarize session s1: rate limited") {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if len(session.Messages) != len(messages) || session.Summary != "" {
+		t.Error("a failed summary changed the session")
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	session := &Session{
+		Summary: strings.Repeat("s", 40),
+		Messages: []*schema.Message{
+			schema.UserMessage(strings.Repeat("u", 24)),
+			schema.AssistantMessage("", []schema.ToolCall{{Function: schema.FunctionCall{Name: "lookup", Arguments: `{"city":"x"}`}}}),
+		},
+	}
+	// 40 summary + 24+16 user + 16+6+12 tool call characters.
+	if got, want := EstimateTokens(session), (40+40+34)/4; got != want {
+		t.Errorf("EstimateTokens() = %d, want %d", got, want)
+	}
+}
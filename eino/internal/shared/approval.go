@@ -0,0 +1,126 @@
+package shared
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+// ApprovalInfo is the interrupt payload of a tool that waits for a human.
+type ApprovalInfo struct {
+	ToolName        string `json:"tool_name"`
+	ArgumentsInJSON string `json:"arguments"`
+	ToolCallID      string `json:"tool_call_id"`
+}
+
+func (i *ApprovalInfo) String() string {
+	return fmt.Sprintf("approval needed for %s(%s)", i.ToolName, i.ArgumentsInJSON)
+}
+
+type ApprovalResult struct {
+	Approved bool
+	Reason   string
+}
+
+func init() {
+	schema.RegisterName[*ApprovalInfo]("einoexamples_approval_info")
+	schema.RegisterName[*ApprovalResult]("einoexamples_approval_result")
+}
+
+// Interrupt is one pending interrupt of a paused agent. Approval is set when a
+// RequireApproval tool raised it.
+type Interrupt struct {
+	ID       string        `json:"id"`
+	Approval *ApprovalInfo `json:"approval,omitempty"`
+	Info     string        `json:"info,omitempty"`
+}
+
+// Approver answers an interrupt. Returning ErrPaused leaves it pending so the
+// run can be resumed later.
+type Approver func(ctx context.Context, interrupt Interrupt) (*ApprovalResult, error)
+
+type approvalTool struct {
+	tool.InvokableTool
+}
+
+// RequireApproval wraps t so every call interrupts the run until a human
+// approves or rejects it. The arguments are kept in the interrupt state, so the
+// approved call runs with exactly what was shown for approval.
+func RequireApproval(t tool.InvokableTool) tool.InvokableTool {
+	return &approvalTool{InvokableTool: t}
+}
+
+func (t *approvalTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts ...tool.Option) (string, error) {
+	info, err := t.Info(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	wasInterrupted, _, storedArguments := tool.GetInterruptState[string](ctx)
+	if !wasInterrupted {
+		return "", tool.StatefulInterrupt(ctx, &ApprovalInfo{
+			ToolName:        info.Name,
+			ArgumentsInJSON: argumentsInJSON,
+			ToolCallID:      compose.GetToolCallID(ctx),
+		}, argumentsInJSON)
+	}
+
+	isResumeTarget, hasData, result := tool.GetResumeContext[*ApprovalResult](ctx)
+	if !isResumeTarget {
+		return "", tool.StatefulInterrupt(ctx, &ApprovalInfo{
+			ToolName:        info.Name,
+			ArgumentsInJSON: storedArguments,
+			ToolCallID:      compose.GetToolCallID(ctx),
+		}, storedArguments)
+	}
+	if !hasData || result == nil {
+		return "", fmt.Errorf("tool %s resumed without an approval decision", info.Name)
+	}
+	if !result.Approved {
+		if result.Reason != "" {
+			return fmt.Sprintf("The user rejected the %s call: %s", info.Name, result.Reason), nil
+		}
+		return fmt.Sprintf("The user rejected the %s call.", info.Name), nil
+	}
+
+	return t.InvokableTool.InvokableRun(ctx, storedArguments, opts...)
+}
+
+// TerminalApprover asks on out and reads the answer from lines: y approves,
+// n [reason] rejects, and p or end of input pauses the run.
+func TerminalApprover(lines *bufio.Scanner, out io.Writer) Approver {
+	return func(ctx context.Context, interrupt Interrupt) (*ApprovalResult, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		question := interrupt.Info
+		if interrupt.Approval != nil {
+			question = interrupt.Approval.String()
+		}
+		for {
+			fmt.Fprintf(out, "%s\n[y]es / [n]o [reason] / [p]ause? ", question)
+			if !lines.Scan() {
+				fmt.Fprintln(out)
+				return nil, ErrPaused
+			}
+
+			answer := strings.TrimSpace(lines.Text())
+			command, reason, _ := strings.Cut(answer, " ")
+			switch strings.ToLower(command) {
+			case "y", "yes":
+				return &ApprovalResult{Approved: true}, nil
+			case "n", "no":
+				return &ApprovalResult{Reason: strings.TrimSpace(reason)}, nil
+			case "p", "pause":
+				return nil, ErrPaused
+			}
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package shared
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/adk"
+)
+
+const DefaultCheckpointDir = ".eino-runs"
+
+// ErrPaused is returned when a run stops at an interrupt so a human can
+// answer it later with -resume.
+var ErrPaused = errors.New("run paused")
+
+// CheckpointStore keeps the adk checkpoints of a run as files, one directory
+// per run. Checkpoint IDs have the form <run-id>/<agent>.
+type CheckpointStore struct {
+	dir string
+}
+
+func NewCheckpointStore(dir string) (*CheckpointStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create checkpoint directory: %w", err)
+	}
+
+	return &CheckpointStore{dir: dir}, nil
+}
+
+func (s *CheckpointStore) Get(_ context.Context, checkPointID string) ([]byte, bool, error) {
+	path, err := s.path(checkPointID, ".ckpt")
+	if err != nil {
+		return nil, false, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("read checkpoint %s: %w", checkPointID, err)
+	}
+
+	return data, true, nil
+}
+
+func (s *CheckpointStore) Set(_ context.Context, checkPointID string, checkPoint []byte) error {
+	path, err := s.path(checkPointID, ".ckpt")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, checkPoint)
+}
+
+func (s *CheckpointStore) path(checkPointID, suffix string) (string, error) {
+	runID, name, ok := strings.Cut(checkPointID, "/")
+	if !ok || !validPathSegment(runID) || !validPathSegment(name) {
+		return "", fmt.Errorf("invalid checkpoint id %q", checkPointID)
+	}
+
+	return filepath.Join(s.dir, runID, name+suffix), nil
+}
+
+func (s *CheckpointStore) load(checkPointID, suffix string, v any) (bool, error) {
+	path, err := s.path(checkPointID, suffix)
+	if err != nil {
+		return false, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	return true, nil
+}
+
+func (s *CheckpointStore) save(checkPointID, suffix string, v any) error {
+	path, err := s.path(checkPointID, suffix)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", path, err)
+	}
+
+	return writeFileAtomic(path, data)
+}
+
+func (s *CheckpointStore) remove(checkPointID, suffix string) error {
+	path, err := s.path(checkPointID, suffix)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func validPathSegment(segment string) bool {
+	return segment != "" && segment != "." && segment != ".." && !strings.ContainsAny(segment, `/\`)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Run is one resumable command invocation. Every agent it executes gets its
+// own checkpoint, and finished agent answers are journaled so a resumed run
+// skips the work that already completed.
+type Run struct {
+	ID      string
+	Resumed bool
+	Store   *CheckpointStore
+	Approve Approver
+}
+
+// OpenRun starts a new run, or continues resumeID when it is set.
+func OpenRun(dir, resumeID string, approve Approver) (*Run, error) {
+	store, err := NewCheckpointStore(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	resumeID = strings.TrimSpace(resumeID)
+	if resumeID == "" {
+		return &Run{ID: newRunID(), Store: store, Approve: approve}, nil
+	}
+	if !validPathSegment(resumeID) {
+		return nil, fmt.Errorf("invalid run id %q", resumeID)
+	}
+	if _, err := os.Stat(filepath.Join(dir, resumeID)); err != nil {
+		return nil, fmt.Errorf("unknown run %s in %s", resumeID, dir)
+	}
+
+	return &Run{ID: resumeID, Resumed: true, Store: store, Approve: approve}, nil
+}
+
+func newRunID() string {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// NewRunner creates a streaming runner that checkpoints into the run's store.
+func (r *Run) NewRunner(ctx context.Context, agent adk.Agent) *adk.Runner {
+	return adk.NewRunner(ctx, adk.RunnerConfig{
+		Agent:           agent,
+		EnableStreaming: true,
+		CheckPointStore: r.Store,
+	})
+}
+
+// Save journals v under key as JSON.
+func (r *Run) Save(key string, v any) error {
+	return r.Store.save(r.ID+"/"+key, ".json", v)
+}
+
+// Load reads what Save journaled under key and reports whether it existed.
+func (r *Run) Load(key string, v any) (bool, error) {
+	return r.Store.load(r.ID+"/"+key, ".json", v)
+}
+
+// Keep saves v under key on a new run and loads it back on a resumed one, so
+// the resumed run works on the same input.
+func (r *Run) Keep(key string, v any) error {
+	if !r.Resumed {
+		return r.Save(key, v)
+	}
+
+	found, err := r.Load(key, v)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("run %s has no saved %s", r.ID, key)
+	}
+
+	return nil
+}
+
+// Execute runs one agent of the run. An agent that finished before returns
+// its journaled answer, and one that stopped at an interrupt is resumed from
+// its checkpoint once the pending interrupts are answered.
+func (r *Run) Execute(ctx context.Context, runner *adk.Runner, name string, messages []adk.Message, printEvents func(*adk.AsyncIterator[*adk.AgentEvent]) (string, error)) (string, error) {
+	checkPointID := r.ID + "/" + name
+
+	var answer string
+	found, err := r.Store.load(checkPointID, ".answer.json", &answer)
+	if err != nil {
+		return "", err
+	}
+	if found {
+		fmt.Printf("[%s restored from run %s]\n%s\n", name, r.ID, answer)
+		return answer, nil
+	}
+
+	var pending []Interrupt
+	found, err = r.Store.load(checkPointID, ".pending.json", &pending)
+	if err != nil {
+		return "", err
+	}
+
+	var events *adk.AsyncIterator[*adk.AgentEvent]
+	if found {
+		if events, err = r.resume(ctx, runner, checkPointID, pending); err != nil {
+			return "", err
+		}
+	} else {
+		events = runner.Run(ctx, messages, adk.WithCheckPointID(checkPointID))
+	}
+
+	for {
+		answer, err = printEvents(events)
+		var interrupted *InterruptedError
+		if !errors.As(err, &interrupted) {
+			break
+		}
+		if err := r.Store.save(checkPointID, ".pending.json", interrupted.Interrupts); err != nil {
+			return "", err
+		}
+		if events, err = r.resume(ctx, runner, checkPointID, interrupted.Interrupts); err != nil {
+			return "", err
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err := r.Store.remove(checkPointID, ".pending.json"); err != nil {
+		return "", err
+	}
+	if err := r.Store.save(checkPointID, ".answer.json", answer); err != nil {
+		return "", err
+	}
+
+	return answer, nil
+}
+
+func (r *Run) resume(ctx context.Context, runner *adk.Runner, checkPointID string, pending []Interrupt) (*adk.AsyncIterator[*adk.AgentEvent], error) {
+	targets := make(map[string]any, len(pending))
+	for _, interrupt := range pending {
+		if r.Approve == nil {
+			return nil, fmt.Errorf("%w: continue with -resume %s", ErrPaused, r.ID)
+		}
+
+		result, err := r.Approve(ctx, interrupt)
+		if errors.Is(err, ErrPaused) {
+			return nil, fmt.Errorf("%w: continue with -resume %s", ErrPaused, r.ID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		targets[interrupt.ID] = result
+	}
+
+	return runner.ResumeWithParams(ctx, checkPointID, &adk.ResumeParams{Targets: targets})
+}
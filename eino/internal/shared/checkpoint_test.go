@@ -0,0 +1,189 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"einoexamples/internal/fakemodel"
+
+	"github.com/cloudwego/eino/adk"
+	toolcomp "github.com/cloudwego/eino/components/tool"
+	"github.com/cloudwego/eino/components/tool/utils"
+	"github.com/cloudwego/eino/compose"
+	"github.com/cloudwego/eino/schema"
+)
+
+type pageInput struct {
+	Team string `json:"team"`
+}
+
+// pagingAgent is an agent whose page tool needs approval; pages records the
+// teams the tool actually paged.
+func pagingAgent(t *testing.T, pages *[]string, turns ...fakemodel.Turn) adk.Agent {
+	t.Helper()
+	page, err := utils.InferTool("page", "Page a team.", func(_ context.Context, input *pageInput) (string, error) {
+		*pages = append(*pages, input.Team)
+		return "paged " + input.Team, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agent, err := adk.NewChatModelAgent(t.Context(), &adk.ChatModelAgentConfig{
+		Name:        "pager",
+		Description: "Pages teams.",
+		Instruction: "Page the team that owns the incident.",
+		Model:       fakemodel.New(fakemodel.Script{Turns: turns}),
+		ToolsConfig: adk.ToolsConfig{
+			ToolsNodeConfig: compose.ToolsNodeConfig{
+				Tools: []toolcomp.BaseTool{RequireApproval(page)},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return agent
+}
+
+var pageCall = fakemodel.Turn{ToolCalls: []fakemodel.ToolCall{{Name: "page", Arguments: map[string]any{"team": "payments"}}}}
+
+// answer returns an approver that gives result and records what it was asked.
+func answer(result *ApprovalResult, err error, asked *[]Interrupt) Approver {
+	return func(_ context.Context, interrupt Interrupt) (*ApprovalResult, error) {
+		*asked = append(*asked, interrupt)
+		return result, err
+	}
+}
+
+func execute(t *testing.T, run *Run, agent adk.Agent) (string, error) {
+	t.Helper()
+	ctx := t.Context()
+	return run.Execute(ctx, run.NewRunner(ctx, agent), "pager", []adk.Message{schema.UserMessage("Checkout is down.")}, PrintAgentEvents)
+}
+
+func TestExecuteResume(t *testing.T) {
+	tests := []struct {
+		name     string
+		decision *ApprovalResult
+		// input has to be part of the tool result the model sees after the
+		// decision.
+		input string
+		pages []string
+	}{
+		{name: "approve", decision: &ApprovalResult{Approved: true}, input: "paged payments", pages: []string{"payments"}},
+		{name: "deny", decision: &ApprovalResult{Reason: "it is a drill"}, input: "The user rejected the page call: it is a drill"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var pages []string
+			var asked []Interrupt
+
+			// The first invocation stops at the approval and leaves it pending.
+			run, err := OpenRun(dir, "", answer(nil, ErrPaused, &asked))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = execute(t, run, pagingAgent(t, &pages, pageCall))
+			if !errors.Is(err, ErrPaused) || !strings.Contains(err.Error(), "continue with -resume "+run.ID) {
+				t.Fatalf("Execute() error = %v, want %v", err, ErrPaused)
+			}
+			if len(asked) != 1 || asked[0].Approval == nil || asked[0].Approval.ToolName != "page" || asked[0].Approval.ArgumentsInJSON != `{"team":"payments"}` {
+				t.Fatalf("approver was asked %+v", asked)
+			}
+			var pending []Interrupt
+			if found, err := run.Store.load(run.ID+"/pager", ".pending.json", &pending); !found || err != nil || len(pending) != 1 || pending[0].ID != asked[0].ID {
+				t.Fatalf("pending interrupts = %+v, %t, %v", pending, found, err)
+			}
+			if len(pages) != 0 {
+				t.Fatalf("paged %v before the approval", pages)
+			}
+
+			// The resumed run answers the pending interrupt from the file and
+			// continues from the checkpoint, without asking the model again
+			// for the tool call.
+			asked = nil
+			resumed, err := OpenRun(dir, run.ID, answer(test.decision, nil, &asked))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !resumed.Resumed {
+				t.Error("the run is not marked as resumed")
+			}
+			final := fakemodel.Turn{Input: test.input, Content: "Done."}
+			got, err := execute(t, resumed, pagingAgent(t, &pages, final))
+			if err != nil || got != "Done." {
+				t.Fatalf("resumed Execute() = %q, %v", got, err)
+			}
+			if len(asked) != 1 || asked[0].ID != pending[0].ID {
+				t.Errorf("approver was asked %+v, want the pending interrupt", asked)
+			}
+			if strings.Join(pages, ",") != strings.Join(test.pages, ",") {
+				t.Errorf("paged %v, want %v", pages, test.pages)
+			}
+			if _, err := os.Stat(filepath.Join(dir, run.ID, "pager.pending.json")); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("the pending interrupts were not removed: %v", err)
+			}
+
+			// A finished agent is restored from the journal.
+			again, err := OpenRun(dir, run.ID, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := execute(t, again, pagingAgent(t, &pages)); err != nil || got != "Done." {
+				t.Errorf("Execute() of a finished agent = %q, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestExecuteWithoutApprover(t *testing.T) {
+	var pages []string
+	run, err := OpenRun(t.TempDir(), "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := execute(t, run, pagingAgent(t, &pages, pageCall)); !errors.Is(err, ErrPaused) {
+		t.Errorf("Execute() error = %v, want %v", err, ErrPaused)
+	}
+}
+
+func TestOpenRun(t *testing.T) {
+	dir := t.TempDir()
+	run, err := OpenRun(dir, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Resumed || !validPathSegment(run.ID) {
+		t.Errorf("new run %+v", run)
+	}
+	question := "Why?"
+	if err := run.Keep("question", &question); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := OpenRun(dir, " "+run.ID+" ", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	question = "a different question"
+	if err := resumed.Keep("question", &question); err != nil || question != "Why?" {
+		t.Errorf("Keep() on a resumed run = %q, %v, want the saved question", question, err)
+	}
+	var answer string
+	if err := resumed.Keep("answer", &answer); err == nil {
+		t.Error("Keep() of a key the run never saved succeeded")
+	}
+
+	if _, err := OpenRun(dir, "20260101-000000-abcdef", nil); err == nil || !strings.Contains(err.Error(), "unknown run 20260101-000000-abcdef") {
+		t.Errorf("OpenRun() of an unknown run error = %v", err)
+	}
+	if _, err := OpenRun(dir, "..", nil); err == nil || !strings.Contains(err.Error(), "invalid run id") {
+		t.Errorf("OpenRun() of an invalid run id error = %v", err)
+	}
+}
@@ -10,6 +10,17 @@ import (
 	"github.com/cloudwego/eino/schema"
 )
 
+// InterruptedError reports that the run stopped at one or more interrupts
+// instead of finishing. The checkpointed run can be resumed once they are
+// answered.
+type InterruptedError struct {
+	Interrupts []Interrupt
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("run interrupted with %d pending interrupt(s)", len(e.Interrupts))
+}
+
 func PrintAgentEvents(events *adk.AsyncIterator[*adk.AgentEvent]) (string, error) {
 	return printAgentEvents(events, nil)
 }
@@ -22,26 +33,52 @@ func PrintQueryAgentEvents(query string, events *adk.AsyncIterator[*adk.AgentEve
 	return printAgentEvents(events, trace)
 }
 
-func printAgentEvents(events *adk.AsyncIterator[*adk.AgentEvent], trace *conversationTrace) (string, error) {
+// QueryEventPrinter prints like PrintQueryAgentEvents but keeps one trace for
+// all calls, so a run resumed after an interrupt continues the trace instead
+// of starting over.
+func QueryEventPrinter(query string) func(*adk.AsyncIterator[*adk.AgentEvent]) (string, error) {
+	trace := newConversationTrace(query)
+	started := false
+	return func(events *adk.AsyncIterator[*adk.AgentEvent]) (string, error) {
+		if !started {
+			trace.printInitialRequest()
+			started = true
+		}
+		return printAgentEvents(events, trace)
+	}
+}
+
+func printAgentEvents(events *adk.AsyncIterator[*adk.AgentEvent], trace *conversationTrace) (answer string, err error) {
+	spans := newAgentSpans()
+	defer func() { spans.finish(err) }()
+
 	var assistantText strings.Builder
+	var interrupts []Interrupt
 
 	for {
 		event, ok := events.Next()
 		if !ok {
+			if len(interrupts) > 0 {
+				return strings.TrimSpace(assistantText.String()), &InterruptedError{Interrupts: interrupts}
+			}
 			return strings.TrimSpace(assistantText.String()), nil
 		}
+		spans.observe(event)
 		if event.Err != nil {
 			return "", event.Err
 		}
 
 		if event.Output != nil {
-			if err := printAgentOutput(event, &assistantText, trace); err != nil {
+			if err := printAgentOutput(event, &assistantText, trace, spans); err != nil {
 				return "", err
 			}
 		}
 
 		if event.Action != nil {
 			printAgentAction(event)
+			if event.Action.Interrupted != nil {
+				interrupts = append(interrupts, pendingInterrupts(event.Action.Interrupted)...)
+			}
 		}
 	}
 }
@@ -150,13 +187,13 @@ func (t *conversationTrace) recordAssistantFinal(message *schema.Message) {
 	t.printAssistantResponseBlock(message, true)
 }
 
-func printAgentOutput(event *adk.AgentEvent, assistantText *strings.Builder, trace *conversationTrace) error {
+func printAgentOutput(event *adk.AgentEvent, assistantText *strings.Builder, trace *conversationTrace, spans *agentSpans) error {
 	if event.Output == nil {
 		return nil
 	}
 
 	if event.Output.MessageOutput != nil {
-		if err := printMessageOutput(event, event.Output.MessageOutput, assistantText, trace); err != nil {
+		if err := printMessageOutput(event, event.Output.MessageOutput, assistantText, trace, spans); err != nil {
 			return err
 		}
 	}
@@ -168,19 +205,19 @@ func printAgentOutput(event *adk.AgentEvent, assistantText *strings.Builder, tra
 	return nil
 }
 
-func printMessageOutput(event *adk.AgentEvent, messageOutput *adk.MessageVariant, assistantText *strings.Builder, trace *conversationTrace) error {
+func printMessageOutput(event *adk.AgentEvent, messageOutput *adk.MessageVariant, assistantText *strings.Builder, trace *conversationTrace, spans *agentSpans) error {
 	if messageOutput == nil {
 		return nil
 	}
 
 	if messageOutput.IsStreaming {
-		return printStreamingMessageOutput(event, messageOutput, assistantText, trace)
+		return printStreamingMessageOutput(event, messageOutput, assistantText, trace, spans)
 	}
 
-	return printFinalMessage(event, messageOutput, messageOutput.Message, assistantText, false, trace)
+	return printFinalMessage(event, messageOutput, messageOutput.Message, assistantText, false, trace, spans)
 }
 
-func printStreamingMessageOutput(event *adk.AgentEvent, messageOutput *adk.MessageVariant, assistantText *strings.Builder, trace *conversationTrace) error {
+func printStreamingMessageOutput(event *adk.AgentEvent, messageOutput *adk.MessageVariant, assistantText *strings.Builder, trace *conversationTrace, spans *agentSpans) error {
 	messageOutput.MessageStream.SetAutomaticClose()
 
 	frames := make([]*schema.Message, 0, 8)
@@ -222,14 +259,16 @@ func printStreamingMessageOutput(event *adk.AgentEvent, messageOutput *adk.Messa
 		return err
 	}
 
-	return printFinalMessage(event, messageOutput, message, assistantText, true, trace)
+	return printFinalMessage(event, messageOutput, message, assistantText, true, trace, spans)
 }
 
-func printFinalMessage(event *adk.AgentEvent, messageOutput *adk.MessageVariant, message *schema.Message, assistantText *strings.Builder, contentAlreadyPrinted bool, trace *conversationTrace) error {
+func printFinalMessage(event *adk.AgentEvent, messageOutput *adk.MessageVariant, message *schema.Message, assistantText *strings.Builder, contentAlreadyPrinted bool, trace *conversationTrace, spans *agentSpans) error {
 	if message == nil {
 		return nil
 	}
 
+	spans.recordMessage(event, messageOutput.Role, messageOutput.ToolName, message)
+
 	if messageOutput.Role == schema.Assistant && !contentAlreadyPrinted && message.Content != "" {
 		assistantText.WriteString(message.Content)
 	}
@@ -295,7 +334,13 @@ func printAgentAction(event *adk.AgentEvent) {
 		fmt.Printf("%s %s\n", eventLabel(event, "transfer"), action.TransferToAgent.DestAgentName)
 	}
 	if action.Interrupted != nil {
-		fmt.Printf("%s %v\n", eventLabel(event, "interrupt"), action.Interrupted.Data)
+		for _, interrupt := range pendingInterrupts(action.Interrupted) {
+			if interrupt.Approval != nil {
+				fmt.Printf("%s %s\n", eventLabel(event, "interrupt"), interrupt.Approval)
+				continue
+			}
+			fmt.Printf("%s %s\n", eventLabel(event, "interrupt"), interrupt.Info)
+		}
 	}
 	if action.BreakLoop != nil {
 		fmt.Printf("%s from=%s iteration=%d\n", eventLabel(event, "break-loop"), action.BreakLoop.From, action.BreakLoop.CurrentIterations)
@@ -308,6 +353,30 @@ func printAgentAction(event *adk.AgentEvent) {
 	}
 }
 
+// pendingInterrupts keeps the root causes only; their parents are the agents
+// and graph nodes the interrupt bubbled up through.
+func pendingInterrupts(info *adk.InterruptInfo) []Interrupt {
+	interrupts := make([]Interrupt, 0, len(info.InterruptContexts))
+	for _, interruptCtx := range info.InterruptContexts {
+		if interruptCtx == nil || !interruptCtx.IsRootCause {
+			continue
+		}
+
+		interrupt := Interrupt{ID: interruptCtx.ID}
+		if approval, ok := interruptCtx.Info.(*ApprovalInfo); ok {
+			interrupt.Approval = approval
+		} else {
+			interrupt.Info = fmt.Sprint(interruptCtx.Info)
+		}
+		interrupts = append(interrupts, interrupt)
+	}
+	if len(interrupts) == 0 && info.Data != nil {
+		interrupts = append(interrupts, Interrupt{Info: fmt.Sprint(info.Data)})
+	}
+
+	return interrupts
+}
+
 func toolResultLabel(messageOutput *adk.MessageVariant, message *schema.Message) string {
 	toolName := strings.TrimSpace(message.ToolName)
 	if toolName == "" {
@@ -364,7 +433,8 @@ func formatTraceMessage(message *schema.Message) string {
 	if len(message.ToolCalls) > 0 {
 		sb.WriteString("\ntool_calls:\n")
 		for _, toolCall := range message.ToolCalls {
-			sb.WriteString(fmt.Sprintf("%+v\n", toolCall))
+			// ToolCall.Index is a pointer, so %+v would print an address.
+			sb.WriteString(fmt.Sprintf("id=%s type=%s %s(%s)\n", toolCall.ID, toolCall.Type, toolCall.Function.Name, toolCall.Function.Arguments))
 		}
 	}
 	if message.ToolCallID != "" {
@@ -2,30 +2,32 @@ package shared
 
 import (
 	"context"
-	"fmt"
-	"os"
-	"strings"
 
 	openai "github.com/cloudwego/eino-ext/components/model/openai"
 	"github.com/cloudwego/eino/components/model"
 )
 
+// NewChatModel returns the default model of the configuration LoadModels
+// finds.
 func NewChatModel(ctx context.Context) (model.BaseChatModel, error) {
-	apiKey := strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
-	modelName := strings.TrimSpace(os.Getenv("OPENAI_MODEL"))
-	if apiKey == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY is required")
-	}
-	if modelName == "" {
-		return nil, fmt.Errorf("OPENAI_MODEL is required")
+	models, err := LoadModels()
+	if err != nil {
+		return nil, err
 	}
 
+	return models.Get(ctx, "")
+}
+
+func newOpenAICompatibleModel(ctx context.Context, config ModelConfig, apiKey string) (model.BaseChatModel, error) {
 	chatModel, err := openai.NewChatModel(ctx, &openai.ChatModelConfig{
-		APIKey:     apiKey,
-		Model:      modelName,
-		BaseURL:    strings.TrimSpace(os.Getenv("OPENAI_BASE_URL")),
-		APIVersion: strings.TrimSpace(os.Getenv("OPENAI_API_VERSION")),
-		ByAzure:    strings.EqualFold(strings.TrimSpace(os.Getenv("OPENAI_BY_AZURE")), "true"),
+		APIKey:      apiKey,
+		Model:       config.Model,
+		BaseURL:     config.BaseURL,
+		APIVersion:  config.APIVersion,
+		ByAzure:     config.ByAzure,
+		Temperature: config.Temperature,
+		MaxTokens:   config.MaxTokens,
+		Timeout:     config.Timeout,
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,338 @@
+package shared
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"einoexamples/internal/fakemodel"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	goyaml "gopkg.in/yaml.v3"
+)
+
+// ModelsFileEnv names a YAML file that defines the available models. Without
+// it there is a single default model configured from the OPENAI_* variables.
+const ModelsFileEnv = "EINO_MODELS_FILE"
+
+const DefaultModel = "default"
+
+type Provider string
+
+const (
+	// ProviderOpenAI is OpenAI, Azure OpenAI with by_azure, or any server with
+	// an OpenAI-compatible chat completions API.
+	ProviderOpenAI    Provider = "openai"
+	ProviderAnthropic Provider = "anthropic"
+	ProviderGemini    Provider = "gemini"
+	ProviderOllama    Provider = "ollama"
+	// ProviderScript replays a fakemodel script; see internal/fakemodel.
+	ProviderScript Provider = "script"
+)
+
+// providerDefaults are the base URL and API key variable used when a model
+// does not set them. Anthropic, Gemini and Ollama are reached through their
+// OpenAI-compatible endpoints. For Anthropic that endpoint ignores
+// cache_control and does not return thinking blocks, so Claude models run
+// without prompt caching and extended thinking here; use the eino-ext claude
+// component directly when an agent needs them.
+var providerDefaults = map[Provider]struct {
+	baseURL   string
+	apiKeyEnv string
+}{
+	ProviderOpenAI:    {apiKeyEnv: "OPENAI_API_KEY"},
+	ProviderAnthropic: {baseURL: "https://api.anthropic.com/v1/", apiKeyEnv: "ANTHROPIC_API_KEY"},
+	ProviderGemini:    {baseURL: "https://generativelanguage.googleapis.com/v1beta/openai/", apiKeyEnv: "GEMINI_API_KEY"},
+	ProviderOllama:    {baseURL: "http://localhost:11434/v1"},
+	ProviderScript:    {},
+}
+
+type ModelConfig struct {
+	Provider Provider `yaml:"provider"`
+	Model    string   `yaml:"model"`
+	BaseURL  string   `yaml:"base_url"`
+	// APIKeyEnv names the variable holding the API key, so keys stay out of
+	// the file.
+	APIKeyEnv   string        `yaml:"api_key_env"`
+	APIVersion  string        `yaml:"api_version"`
+	ByAzure     bool          `yaml:"by_azure"`
+	Temperature *float32      `yaml:"temperature"`
+	MaxTokens   *int          `yaml:"max_tokens"`
+	Timeout     time.Duration `yaml:"timeout"`
+	// Script is the file a script model replays.
+	Script string `yaml:"script"`
+	// Fallback names the models tried in order when this one fails,
+	// followed by their own fallbacks.
+	Fallback []string `yaml:"fallback"`
+}
+
+type ModelsConfig struct {
+	Default string                 `yaml:"default"`
+	Models  map[string]ModelConfig `yaml:"models"`
+	// Agents maps agent names to models and wins over the model an agent
+	// asks for.
+	Agents map[string]string `yaml:"agents"`
+}
+
+// Models builds chat models by name, each wrapped in its fallback chain. Built
+// models are shared, so every agent using the same model uses one client.
+type Models struct {
+	config ModelsConfig
+
+	mu    sync.Mutex
+	built map[string]model.BaseChatModel
+}
+
+// LoadModels reads the file named by EINO_MODELS_FILE, or configures the
+// default model from the environment.
+func LoadModels() (*Models, error) {
+	if path := strings.TrimSpace(os.Getenv(ModelsFileEnv)); path != "" {
+		return LoadModelsFile(path)
+	}
+
+	return NewModels(ModelsConfig{
+		Models: map[string]ModelConfig{
+			DefaultModel: {
+				Provider:   ProviderOpenAI,
+				Model:      strings.TrimSpace(os.Getenv("OPENAI_MODEL")),
+				BaseURL:    strings.TrimSpace(os.Getenv("OPENAI_BASE_URL")),
+				APIVersion: strings.TrimSpace(os.Getenv("OPENAI_API_VERSION")),
+				ByAzure:    strings.EqualFold(strings.TrimSpace(os.Getenv("OPENAI_BY_AZURE")), "true"),
+			},
+		},
+	})
+}
+
+// LoadModelsFile reads a models file. ${VAR} references are expanded from the
+// environment before parsing.
+func LoadModelsFile(path string) (*Models, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read models file: %w", err)
+	}
+
+	var config ModelsConfig
+	decoder := goyaml.NewDecoder(bytes.NewReader([]byte(os.ExpandEnv(string(content)))))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("parse models file %s: %w", path, err)
+	}
+	// Scripts are found relative to the models file.
+	for name, c := range config.Models {
+		if c.Script != "" && !filepath.IsAbs(c.Script) {
+			c.Script = filepath.Join(filepath.Dir(path), c.Script)
+			config.Models[name] = c
+		}
+	}
+
+	models, err := NewModels(config)
+	if err != nil {
+		return nil, fmt.Errorf("models file %s: %w", path, err)
+	}
+	return models, nil
+}
+
+func NewModels(config ModelsConfig) (*Models, error) {
+	if config.Default == "" {
+		config.Default = DefaultModel
+	}
+
+	var errs []error
+	if _, ok := config.Models[config.Default]; !ok {
+		errs = append(errs, fmt.Errorf("default model %s is not defined", config.Default))
+	}
+	for _, name := range sortedNames(config.Models) {
+		c := config.Models[name]
+		if _, ok := providerDefaults[c.Provider]; !ok {
+			errs = append(errs, fmt.Errorf("model %s has provider %q, want openai, anthropic, gemini, ollama or script", name, c.Provider))
+		}
+		for _, fallback := range c.Fallback {
+			if _, ok := config.Models[fallback]; !ok || fallback == name {
+				errs = append(errs, fmt.Errorf("model %s falls back to unknown model %s", name, fallback))
+			}
+		}
+	}
+	for _, agent := range sortedNames(config.Agents) {
+		if _, ok := config.Models[config.Agents[agent]]; !ok {
+			errs = append(errs, fmt.Errorf("agent %s uses unknown model %s", agent, config.Agents[agent]))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return &Models{config: config, built: map[string]model.BaseChatModel{}}, nil
+}
+
+// ForAgent returns the model configured for the agent, else the requested
+// model, else the default one.
+func (m *Models) ForAgent(ctx context.Context, agent, name string) (model.BaseChatModel, error) {
+	if override, ok := m.config.Agents[agent]; ok {
+		name = override
+	}
+
+	return m.Get(ctx, name)
+}
+
+// Get returns a model by name; the empty name is the default model.
+func (m *Models) Get(ctx context.Context, name string) (model.BaseChatModel, error) {
+	if name == "" {
+		name = m.config.Default
+	}
+	if _, ok := m.config.Models[name]; !ok {
+		return nil, fmt.Errorf("unknown model %s", name)
+	}
+
+	chain := m.chain(name, nil)
+	models := make([]model.BaseChatModel, 0, len(chain))
+	for _, link := range chain {
+		chatModel, err := m.build(ctx, link)
+		if err != nil {
+			return nil, fmt.Errorf("model %s: %w", link, err)
+		}
+		models = append(models, chatModel)
+	}
+	if len(models) == 1 {
+		return models[0], nil
+	}
+
+	return &fallbackModel{names: chain, models: models}, nil
+}
+
+// chain lists the model and its fallbacks, depth first, each model once.
+func (m *Models) chain(name string, chain []string) []string {
+	if slices.Contains(chain, name) {
+		return chain
+	}
+	chain = append(chain, name)
+	for _, fallback := range m.config.Models[name].Fallback {
+		chain = m.chain(fallback, chain)
+	}
+	return chain
+}
+
+func (m *Models) build(ctx context.Context, name string) (model.BaseChatModel, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if chatModel, ok := m.built[name]; ok {
+		return chatModel, nil
+	}
+
+	config := m.config.Models[name]
+	if config.Provider == ProviderScript {
+		if config.Script == "" {
+			return nil, fmt.Errorf("no script configured")
+		}
+		scripted, err := fakemodel.Load(config.Script)
+		if err != nil {
+			return nil, err
+		}
+		m.built[name] = scripted
+		return scripted, nil
+	}
+
+	defaults := providerDefaults[config.Provider]
+	if config.BaseURL == "" {
+		config.BaseURL = defaults.baseURL
+	}
+	if config.APIKeyEnv == "" {
+		config.APIKeyEnv = defaults.apiKeyEnv
+	}
+	apiKey := ""
+	if config.APIKeyEnv != "" {
+		apiKey = strings.TrimSpace(os.Getenv(config.APIKeyEnv))
+		if apiKey == "" {
+			return nil, fmt.Errorf("%s is required", config.APIKeyEnv)
+		}
+	}
+	if config.Model == "" {
+		if config.Provider == ProviderOpenAI && name == DefaultModel {
+			return nil, fmt.Errorf("OPENAI_MODEL is required")
+		}
+		return nil, fmt.Errorf("no model name configured")
+	}
+	if config.Provider == ProviderOllama && apiKey == "" {
+		// Ollama ignores the key, but the client sends one anyway.
+		apiKey = "ollama"
+	}
+	if config.Provider == ProviderAnthropic && config.MaxTokens == nil {
+		// Anthropic requires max_tokens on every request.
+		maxTokens := 4096
+		config.MaxTokens = &maxTokens
+	}
+
+	chatModel, err := newOpenAICompatibleModel(ctx, config, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	m.built[name] = chatModel
+	return chatModel, nil
+}
+
+// fallbackModel tries its models in order until one accepts the request.
+// Only errors before the first streamed chunk fall back; a stream that fails
+// halfway returns its error as is.
+type fallbackModel struct {
+	names  []string
+	models []model.BaseChatModel
+}
+
+func (f *fallbackModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	var errs []error
+	for i, chatModel := range f.models {
+		message, err := chatModel.Generate(ctx, input, opts...)
+		if err == nil {
+			return message, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", f.names[i], err))
+		f.fellBack(i, err)
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+func (f *fallbackModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	var errs []error
+	for i, chatModel := range f.models {
+		stream, err := chatModel.Stream(ctx, input, opts...)
+		if err == nil {
+			return stream, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", f.names[i], err))
+		f.fellBack(i, err)
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+func (f *fallbackModel) fellBack(i int, err error) {
+	if i+1 < len(f.names) {
+		log.Printf("[models] %s failed, falling back to %s: %v", f.names[i], f.names[i+1], err)
+	}
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,246 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestNewModelsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ModelsConfig
+		err    string
+	}{
+		{
+			name:   "missing default",
+			config: ModelsConfig{Models: map[string]ModelConfig{"large": {Provider: ProviderOpenAI}}},
+			err:    "default model default is not defined",
+		},
+		{
+			name:   "named default",
+			config: ModelsConfig{Default: "small", Models: map[string]ModelConfig{"large": {Provider: ProviderOpenAI}}},
+			err:    "default model small is not defined",
+		},
+		{
+			name:   "provider",
+			config: ModelsConfig{Models: map[string]ModelConfig{"default": {Provider: "bedrock"}}},
+			err:    `model default has provider "bedrock", want openai, anthropic, gemini, ollama or script`,
+		},
+		{
+			name:   "unknown fallback",
+			config: ModelsConfig{Models: map[string]ModelConfig{"default": {Provider: ProviderOpenAI, Fallback: []string{"backup"}}}},
+			err:    "model default falls back to unknown model backup",
+		},
+		{
+			name:   "fallback to itself",
+			config: ModelsConfig{Models: map[string]ModelConfig{"default": {Provider: ProviderOpenAI, Fallback: []string{"default"}}}},
+			err:    "model default falls back to unknown model default",
+		},
+		{
+			name:   "agent override",
+			config: ModelsConfig{Models: map[string]ModelConfig{"default": {Provider: ProviderOpenAI}}, Agents: map[string]string{"writer": "large"}},
+			err:    "agent writer uses unknown model large",
+		},
+		{
+			name: "all problems",
+			config: ModelsConfig{
+				Models: map[string]ModelConfig{"b": {Fallback: []string{"c"}}, "a": {Provider: ProviderGemini}},
+				Agents: map[string]string{"writer": "d"},
+			},
+			err: "default model default is not defined\n" +
+				`model b has provider "", want openai, anthropic, gemini, ollama or script` + "\n" +
+				"model b falls back to unknown model c\n" +
+				"agent writer uses unknown model d",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewModels(test.config); err == nil || err.Error() != test.err {
+				t.Errorf("NewModels() error = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+// writeModels writes a models file and a script for every scripted model,
+// answering with its name or failing when the name starts with "down".
+func writeModels(t *testing.T, config string, scripts ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range scripts {
+		turn := "content: " + name
+		if strings.HasPrefix(name, "down") {
+			turn = "error: " + name + " is unavailable"
+		}
+		script := "turns:\n  - " + turn + "\n  - " + turn + "\n"
+		if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(script), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "models.yaml")
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func generate(t *testing.T, chatModel model.BaseChatModel) (string, error) {
+	t.Helper()
+	message, err := chatModel.Generate(t.Context(), []*schema.Message{schema.UserMessage("hi")})
+	if err != nil {
+		return "", err
+	}
+	return message.Content, nil
+}
+
+func TestModelsForAgent(t *testing.T) {
+	t.Setenv("SMALL_SCRIPT", "small.yaml")
+	path := writeModels(t, `
+default: large
+models:
+  large: {provider: script, script: large.yaml}
+  small: {provider: script, script: "${SMALL_SCRIPT}"}
+agents:
+  router: small
+`, "large", "small")
+	models, err := LoadModelsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		agent, name, want string
+	}{
+		{agent: "writer", want: "large"},
+		{agent: "writer", name: "small", want: "small"},
+		// The override wins over the model the agent asks for.
+		{agent: "router", name: "large", want: "small"},
+	}
+	for _, test := range tests {
+		chatModel, err := models.ForAgent(t.Context(), test.agent, test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := generate(t, chatModel); err != nil || got != test.want {
+			t.Errorf("ForAgent(%s, %q) answered %q, %v, want %s", test.agent, test.name, got, err, test.want)
+		}
+	}
+
+	// Built models are shared.
+	first, _ := models.Get(t.Context(), "small")
+	second, _ := models.ForAgent(t.Context(), "router", "")
+	if first != second {
+		t.Error("the same model was built twice")
+	}
+	if _, err := models.Get(t.Context(), "medium"); err == nil || err.Error() != "unknown model medium" {
+		t.Errorf("Get() of an unknown model error = %v", err)
+	}
+}
+
+func TestLoadModelsFileErrors(t *testing.T) {
+	if _, err := LoadModelsFile(writeModels(t, "models:\n  default: {provider: openai, retries: 3}\n")); err == nil || !strings.Contains(err.Error(), "field retries not found") {
+		t.Errorf("LoadModelsFile() with an unknown field error = %v", err)
+	}
+	if _, err := LoadModelsFile(writeModels(t, "models:\n  default: {provider: bedrock}\n")); err == nil || !strings.HasPrefix(err.Error(), "models file ") {
+		t.Errorf("LoadModelsFile() of an invalid config error = %v", err)
+	}
+
+	t.Setenv("EINO_TEST_KEY", "")
+	models, err := LoadModelsFile(writeModels(t, "models:\n  default: {provider: anthropic, model: claude, api_key_env: EINO_TEST_KEY}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := models.Get(t.Context(), ""); err == nil || err.Error() != "model default: EINO_TEST_KEY is required" {
+		t.Errorf("Get() without an API key error = %v", err)
+	}
+}
+
+func TestFallbackOrder(t *testing.T) {
+	path := writeModels(t, `
+default: down-a
+models:
+  down-a: {provider: script, script: down-a.yaml, fallback: [down-b, c]}
+  down-b: {provider: script, script: down-b.yaml, fallback: [d, down-a]}
+  c: {provider: script, script: c.yaml}
+  d: {provider: script, script: d.yaml}
+  down-e: {provider: script, script: down-e.yaml, fallback: [down-f]}
+  down-f: {provider: script, script: down-f.yaml, fallback: [down-e]}
+`, "down-a", "down-b", "c", "d", "down-e", "down-f")
+	models, err := LoadModelsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fallbacks are tried depth first, so down-b's own fallback d comes before
+	// c, and down-a is not tried twice.
+	if chain := models.chain("down-a", nil); strings.Join(chain, ",") != "down-a,down-b,d,c" {
+		t.Errorf("chain = %v", chain)
+	}
+	chatModel, err := models.Get(t.Context(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := generate(t, chatModel); err != nil || got != "d" {
+		t.Errorf("Generate() = %q, %v, want the answer of d", got, err)
+	}
+	stream, err := chatModel.Stream(t.Context(), []*schema.Message{schema.UserMessage("hi")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := schema.ConcatMessageStream(stream)
+	if err != nil || message.Content != "d" {
+		t.Errorf("Stream() = %v, %v, want the answer of d", message, err)
+	}
+
+	chatModel, err = models.Get(t.Context(), "down-e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := generate(t, chatModel); err == nil || err.Error() != "down-e: down-e is unavailable\ndown-f: down-f is unavailable" {
+		t.Errorf("Generate() error = %v, want the errors of every model", err)
+	}
+}
+
+// cancellingModel fails and cancels the request context on the way.
+type cancellingModel struct {
+	cancel context.CancelFunc
+	calls  int
+}
+
+func (m *cancellingModel) Generate(context.Context, []*schema.Message, ...model.Option) (*schema.Message, error) {
+	m.calls++
+	m.cancel()
+	return nil, context.Canceled
+}
+
+func (m *cancellingModel) Stream(context.Context, []*schema.Message, ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	m.calls++
+	m.cancel()
+	return nil, context.Canceled
+}
+
+func TestFallbackCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	first := &cancellingModel{cancel: cancel}
+	second := &cancellingModel{cancel: cancel}
+	fallback := &fallbackModel{names: []string{"first", "second"}, models: []model.BaseChatModel{first, second}}
+
+	// A cancelled request is not retried with the next model.
+	if _, err := fallback.Generate(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Generate() error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := fallback.Stream(ctx, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Stream() error = %v, want %v", err, context.Canceled)
+	}
+	if first.calls != 2 || second.calls != 0 {
+		t.Errorf("models were called %d and %d times, want 2 and 0", first.calls, second.calls)
+	}
+}
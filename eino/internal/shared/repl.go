@@ -0,0 +1,185 @@
+package shared
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"einoexamples/internal/memory"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+type SessionFlags struct {
+	Session       *string
+	Memory        *string
+	MemoryPath    *string
+	ContextWindow *int
+	List          *bool
+}
+
+// AddSessionFlags registers the flags of the conversation REPL.
+func AddSessionFlags() *SessionFlags {
+	return &SessionFlags{
+		Session:       flag.String("session", "", "ID of a saved conversation to continue"),
+		Memory:        flag.String("memory", memory.KindFile, "Where conversations are kept: memory, file or sqlite (sqlite needs a cgo build)"),
+		MemoryPath:    flag.String("memory-path", "", "Session directory for -memory file, database file for -memory sqlite"),
+		ContextWindow: flag.Int("context-window", 32000, "Context window of the model in tokens; older turns are summarized before it fills up, 0 disables summarizing"),
+		List:          flag.Bool("sessions", false, "List the saved conversations and exit"),
+	}
+}
+
+func (f *SessionFlags) OpenStore() (memory.Store, error) {
+	return memory.Open(*f.Memory, *f.MemoryPath)
+}
+
+func (f *SessionFlags) Summarizer(chatModel model.BaseChatModel) *memory.Summarizer {
+	return &memory.Summarizer{Model: chatModel, ContextWindow: *f.ContextWindow}
+}
+
+// PrintSessions lists the saved conversations.
+func (f *SessionFlags) PrintSessions(ctx context.Context) error {
+	store, err := f.OpenStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	infos, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+	if len(infos) == 0 {
+		fmt.Println("No saved sessions.")
+		return nil
+	}
+	for _, info := range infos {
+		fmt.Printf("%s  %3d message(s)  %s\n", info.ID, info.Messages, info.UpdatedAt.Format(time.DateTime))
+	}
+	return nil
+}
+
+// REPL runs a conversation on the terminal. The session is saved before and
+// after every turn, so a session that was cut off answers its pending
+// question first when it is resumed.
+type REPL struct {
+	Runner *adk.Runner
+	// Run, when set, checkpoints every turn so interrupts can be approved or
+	// paused and resumed.
+	Run        *Run
+	Store      memory.Store
+	Summarizer *memory.Summarizer
+	Scanner    *bufio.Scanner
+}
+
+// Open loads a saved session, or starts a new one when id is empty. A new
+// session is stored with its first turn. A resumed Run continues the session
+// it was started for.
+func (r *REPL) Open(ctx context.Context, id string) (*memory.Session, error) {
+	if r.Run != nil && r.Run.Resumed {
+		if err := r.Run.Keep("session", &id); err != nil {
+			return nil, err
+		}
+	}
+
+	var session *memory.Session
+	if id == "" {
+		session = memory.NewSession()
+	} else {
+		var err error
+		session, err = r.Store.Load(ctx, id)
+		if errors.Is(err, memory.ErrNotFound) {
+			return nil, fmt.Errorf("%w; start without -session for a new one", err)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.Run != nil && !r.Run.Resumed {
+		if err := r.Run.Save("session", session.ID); err != nil {
+			return nil, err
+		}
+	}
+	return session, nil
+}
+
+func (r *REPL) Start(ctx context.Context, session *memory.Session) error {
+	if len(session.Messages) > 0 || session.Summary != "" {
+		fmt.Printf("Resumed session %s with %d message(s).\n", session.ID, len(session.Messages))
+
+		// The last turn was cut off before the agent answered.
+		if n := len(session.Messages); n > 0 && session.Messages[n-1].Role == schema.User {
+			if err := r.turn(ctx, session); err != nil {
+				return err
+			}
+		}
+	} else {
+		fmt.Printf("Session: %s\n", session.ID)
+	}
+
+	fmt.Println("Ask something. Submit an empty line to exit.")
+	for {
+		fmt.Print("you> ")
+		if !r.Scanner.Scan() {
+			break
+		}
+
+		line := strings.TrimSpace(r.Scanner.Text())
+		if line == "" {
+			break
+		}
+
+		session.Messages = append(session.Messages, schema.UserMessage(line))
+		if err := r.turn(ctx, session); err != nil {
+			return err
+		}
+	}
+
+	return r.Scanner.Err()
+}
+
+func (r *REPL) turn(ctx context.Context, session *memory.Session) error {
+	if err := r.save(ctx, session); err != nil {
+		return err
+	}
+
+	query := session.Messages[len(session.Messages)-1].Content
+	var (
+		answer string
+		err    error
+	)
+	if r.Run != nil {
+		name := fmt.Sprintf("%s-turn-%d", session.ID, session.Turns+1)
+		answer, err = r.Run.Execute(ctx, r.Runner, name, session.Input(), QueryEventPrinter(query))
+	} else {
+		answer, err = PrintQueryAgentEvents(query, r.Runner.Run(ctx, session.Input()))
+	}
+	if err != nil {
+		return err
+	}
+
+	session.Turns++
+	session.Messages = append(session.Messages, schema.AssistantMessage(answer, nil))
+	before := len(session.Messages)
+	compacted, err := r.Summarizer.Compact(ctx, session)
+	if err != nil {
+		return err
+	}
+	if compacted {
+		fmt.Printf("[memory] summarized %d earlier message(s)\n", before-len(session.Messages))
+	}
+
+	return r.save(ctx, session)
+}
+
+func (r *REPL) save(ctx context.Context, session *memory.Session) error {
+	session.UpdatedAt = time.Now()
+	return r.Store.Save(ctx, session)
+}
@@ -0,0 +1,91 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+
+	"einoexamples/internal/skills"
+
+	"github.com/cloudwego/eino/adk/middlewares/skill"
+	"github.com/cloudwego/eino/components/tool"
+	toolutils "github.com/cloudwego/eino/components/tool/utils"
+	goyaml "gopkg.in/yaml.v3"
+)
+
+// SkillBackend serves the skill middleware from an indexed skills directory.
+// Lookups never touch the disk; the index reloads itself when the directory
+// changes.
+type SkillBackend struct {
+	index *skills.Index
+}
+
+func NewSkillBackend(index *skills.Index) *SkillBackend {
+	return &SkillBackend{index: index}
+}
+
+func (b *SkillBackend) List(ctx context.Context) ([]skill.FrontMatter, error) {
+	_ = ctx
+
+	list := b.index.List()
+	matters := make([]skill.FrontMatter, 0, len(list))
+	for _, sk := range list {
+		matter, err := frontMatter(sk)
+		if err != nil {
+			return nil, err
+		}
+		matters = append(matters, matter)
+	}
+
+	return matters, nil
+}
+
+func (b *SkillBackend) Get(ctx context.Context, name string) (skill.Skill, error) {
+	_ = ctx
+
+	sk, err := b.index.Get(name)
+	if err != nil {
+		return skill.Skill{}, err
+	}
+	matter, err := frontMatter(sk)
+	if err != nil {
+		return skill.Skill{}, err
+	}
+
+	return skill.Skill{
+		FrontMatter:   matter,
+		Content:       sk.ContentWithResources(),
+		BaseDirectory: sk.Dir,
+	}, nil
+}
+
+func frontMatter(sk *skills.Skill) (skill.FrontMatter, error) {
+	var matter skill.FrontMatter
+	if err := goyaml.Unmarshal(sk.FrontMatter, &matter); err != nil {
+		return skill.FrontMatter{}, fmt.Errorf("unmarshal front matter for %s: %w", sk.Path, err)
+	}
+
+	return matter, nil
+}
+
+type resourceInput struct {
+	Skill string `json:"skill" jsonschema:"required" jsonschema_description:"Skill name, optionally name@version"`
+	Path  string `json:"path" jsonschema:"required" jsonschema_description:"Resource path relative to the skill directory"`
+}
+
+type resourceOutput struct {
+	Content string `json:"content"`
+}
+
+// ResourceTool lets the model load the files bundled with a skill.
+func (b *SkillBackend) ResourceTool() (tool.InvokableTool, error) {
+	return toolutils.InferTool("skill_resource", "Load a file or script bundled with a skill. Use the paths listed under Bundled resources in the loaded skill.", b.readResource)
+}
+
+func (b *SkillBackend) readResource(_ context.Context, input *resourceInput) (*resourceOutput, error) {
+	content, err := b.index.ReadResource(input.Skill, input.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &resourceOutput{Content: content}, nil
+}
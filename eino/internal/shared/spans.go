@@ -0,0 +1,520 @@
+package shared
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/adk"
+	"github.com/cloudwego/eino/schema"
+)
+
+const (
+	spanKindInternal = 1
+	spanKindClient   = 3
+	statusCodeError  = 2
+)
+
+// Tracer collects an OpenTelemetry span tree for every agent run printed by
+// PrintAgentEvents: agent -> model call -> tool call, with token usage on the
+// model calls and summed up on the agents. The spans are written as OTLP/JSON
+// when the tracer shuts down.
+type Tracer struct {
+	service  string
+	traceID  string
+	file     string
+	endpoint string
+	headers  map[string]string
+
+	mu    sync.Mutex
+	root  *span
+	spans []*span
+}
+
+type span struct {
+	id     string
+	parent *span
+	name   string
+	kind   int
+	start  time.Time
+	end    time.Time
+	attrs  map[string]any
+	agent  string
+	usage  schema.TokenUsage
+	err    string
+}
+
+var (
+	activeTracerMu sync.Mutex
+	activeTracer   *Tracer
+)
+
+// StartTracing starts a trace for the command when EINO_TRACE_FILE or an OTLP
+// endpoint (OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT)
+// is configured and returns nil otherwise. EINO_TRACE_FILE receives one OTLP
+// JSON export request per line, the format of the collector's file exporter.
+func StartTracing(service string) (*Tracer, error) {
+	file := strings.TrimSpace(os.Getenv("EINO_TRACE_FILE"))
+	endpoint := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"))
+	if endpoint == "" {
+		if base := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")); base != "" {
+			endpoint = strings.TrimRight(base, "/") + "/v1/traces"
+		}
+	}
+	if file == "" && endpoint == "" {
+		return nil, nil
+	}
+
+	if name := strings.TrimSpace(os.Getenv("OTEL_SERVICE_NAME")); name != "" {
+		service = name
+	}
+	headers, err := parseOTLPHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	if err != nil {
+		return nil, err
+	}
+
+	tracer := &Tracer{
+		service:  service,
+		traceID:  randomHex(16),
+		file:     file,
+		endpoint: endpoint,
+		headers:  headers,
+	}
+	tracer.root = tracer.startSpan(nil, service, spanKindInternal, time.Now())
+
+	activeTracerMu.Lock()
+	activeTracer = tracer
+	activeTracerMu.Unlock()
+
+	return tracer, nil
+}
+
+func parseOTLPHeaders(value string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS entry %q", pair)
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+
+	return headers, nil
+}
+
+func currentTracer() *Tracer {
+	activeTracerMu.Lock()
+	defer activeTracerMu.Unlock()
+	return activeTracer
+}
+
+// Shutdown ends the trace, prints the token usage per agent and exports the
+// spans. It is safe to call on a nil tracer.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	activeTracerMu.Lock()
+	if activeTracer == t {
+		activeTracer = nil
+	}
+	activeTracerMu.Unlock()
+
+	t.mu.Lock()
+	t.root.end = time.Now()
+	request := t.exportRequest()
+	t.printUsage()
+	t.mu.Unlock()
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("encode trace: %w", err)
+	}
+
+	var errs []error
+	if t.file != "" {
+		errs = append(errs, appendLine(t.file, payload))
+	}
+	if t.endpoint != "" {
+		errs = append(errs, t.post(ctx, payload))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (t *Tracer) startSpan(parent *span, name string, kind int, start time.Time) *span {
+	s := &span{
+		id:     randomHex(8),
+		parent: parent,
+		name:   name,
+		kind:   kind,
+		start:  start,
+		end:    start,
+		attrs:  map[string]any{},
+	}
+
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+
+	return s
+}
+
+func (t *Tracer) printUsage() {
+	totals := map[string]*schema.TokenUsage{}
+	for _, s := range t.spans {
+		if s.agent == "" {
+			continue
+		}
+		total, ok := totals[s.agent]
+		if !ok {
+			total = &schema.TokenUsage{}
+			totals[s.agent] = total
+		}
+		// Parent agents include their sub-agents, so only count the calls
+		// made by the agent itself.
+		own := s.usage
+		for _, child := range t.spans {
+			if child.parent == s && child.agent != "" {
+				own.PromptTokens -= child.usage.PromptTokens
+				own.CompletionTokens -= child.usage.CompletionTokens
+				own.TotalTokens -= child.usage.TotalTokens
+			}
+		}
+		total.PromptTokens += own.PromptTokens
+		total.CompletionTokens += own.CompletionTokens
+		total.TotalTokens += own.TotalTokens
+	}
+	if len(totals) == 0 {
+		return
+	}
+
+	agents := make([]string, 0, len(totals))
+	for agent := range totals {
+		agents = append(agents, agent)
+	}
+	sort.Strings(agents)
+
+	fmt.Printf("\n[token usage] trace_id=%s\n", t.traceID)
+	for _, agent := range agents {
+		usage := totals[agent]
+		fmt.Printf("  %s: prompt_tokens=%d, completion_tokens=%d, total_tokens=%d\n", agent, usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens)
+	}
+}
+
+func (t *Tracer) post(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("export trace to %s: %w", t.endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("export trace to %s: %s: %s", t.endpoint, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+func appendLine(path string, payload []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open trace file: %w", err)
+	}
+	if _, err := file.Write(append(payload, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("write trace file: %w", err)
+	}
+
+	return file.Close()
+}
+
+func randomHex(size int) string {
+	b := make([]byte, size)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// The OTLP/JSON encoding of an ExportTraceServiceRequest. IDs are hex strings
+// and 64-bit integers are encoded as decimal strings.
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func (t *Tracer) exportRequest() otlpExportRequest {
+	spans := make([]otlpSpan, 0, len(t.spans))
+	for _, s := range t.spans {
+		attrs := s.attrs
+		if s.agent != "" || s.usage.TotalTokens > 0 {
+			attrs["gen_ai.usage.input_tokens"] = s.usage.PromptTokens
+			attrs["gen_ai.usage.output_tokens"] = s.usage.CompletionTokens
+			attrs["gen_ai.usage.total_tokens"] = s.usage.TotalTokens
+		}
+
+		exported := otlpSpan{
+			TraceID:           t.traceID,
+			SpanID:            s.id,
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(attrs),
+		}
+		if s.parent != nil {
+			exported.ParentSpanID = s.parent.id
+		}
+		if s.err != "" {
+			exported.Status = &otlpStatus{Code: statusCodeError, Message: s.err}
+		}
+		spans = append(spans, exported)
+	}
+
+	return otlpExportRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: otlpAttributes(map[string]any{"service.name": t.service})},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "einoexamples/internal/shared"},
+			Spans: spans,
+		}},
+	}}}
+}
+
+func otlpAttributes(attrs map[string]any) []otlpAttribute {
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	converted := make([]otlpAttribute, 0, len(keys))
+	for _, key := range keys {
+		var value map[string]any
+		switch v := attrs[key].(type) {
+		case int:
+			value = map[string]any{"intValue": strconv.Itoa(v)}
+		case bool:
+			value = map[string]any{"boolValue": v}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(v)}
+		}
+		converted = append(converted, otlpAttribute{Key: key, Value: value})
+	}
+
+	return converted
+}
+
+// agentSpans turns the events of one printAgentEvents call into spans. Model
+// call spans start at the previous activity of the same agent, because the
+// events only show when a response arrives.
+type agentSpans struct {
+	tracer       *Tracer
+	agents       map[string]*span
+	lastActivity map[string]time.Time
+	tools        map[string]*span
+}
+
+func newAgentSpans() *agentSpans {
+	tracer := currentTracer()
+	if tracer == nil {
+		return nil
+	}
+
+	return &agentSpans{
+		tracer:       tracer,
+		agents:       map[string]*span{},
+		lastActivity: map[string]time.Time{},
+		tools:        map[string]*span{},
+	}
+}
+
+func (a *agentSpans) agentFor(event *adk.AgentEvent, now time.Time) (*span, string) {
+	steps := make([]string, 0, len(event.RunPath))
+	for _, step := range event.RunPath {
+		if name := strings.TrimSpace(step.String()); name != "" {
+			steps = append(steps, name)
+		}
+	}
+	if len(steps) == 0 {
+		steps = append(steps, strings.TrimSpace(event.AgentName))
+	}
+
+	parent := a.tracer.root
+	key := ""
+	for index, step := range steps {
+		key = strings.Join(steps[:index+1], " > ")
+		agent, ok := a.agents[key]
+		if !ok {
+			agent = a.tracer.startSpan(parent, "invoke_agent "+step, spanKindInternal, now)
+			agent.agent = step
+			agent.attrs["gen_ai.operation.name"] = "invoke_agent"
+			agent.attrs["gen_ai.agent.name"] = step
+			a.agents[key] = agent
+			a.lastActivity[key] = now
+		}
+		agent.end = now
+		parent = agent
+	}
+
+	return parent, key
+}
+
+func (a *agentSpans) observe(event *adk.AgentEvent) {
+	if a == nil || event == nil {
+		return
+	}
+
+	agent, _ := a.agentFor(event, time.Now())
+	if event.Err != nil {
+		agent.err = event.Err.Error()
+	}
+	if event.Action != nil && event.Action.TransferToAgent != nil {
+		agent.attrs["eino.transfer_to_agent"] = event.Action.TransferToAgent.DestAgentName
+	}
+	if event.Action != nil && event.Action.Interrupted != nil {
+		agent.attrs["eino.interrupted"] = true
+	}
+}
+
+func (a *agentSpans) recordMessage(event *adk.AgentEvent, role schema.RoleType, toolName string, message *schema.Message) {
+	if a == nil || message == nil {
+		return
+	}
+
+	now := time.Now()
+	agent, key := a.agentFor(event, now)
+	defer func() { a.lastActivity[key] = now }()
+
+	switch role {
+	case schema.Assistant:
+		call := a.tracer.startSpan(agent, "chat", spanKindClient, a.lastActivity[key])
+		call.end = now
+		call.attrs["gen_ai.operation.name"] = "chat"
+		call.attrs["gen_ai.agent.name"] = agent.agent
+		if message.ResponseMeta != nil {
+			call.attrs["gen_ai.response.finish_reasons"] = message.ResponseMeta.FinishReason
+			if usage := message.ResponseMeta.Usage; usage != nil {
+				call.usage = *usage
+				call.attrs["gen_ai.usage.cached_input_tokens"] = usage.PromptTokenDetails.CachedTokens
+				call.attrs["gen_ai.usage.reasoning_tokens"] = usage.CompletionTokensDetails.ReasoningTokens
+				for parent := agent; parent != nil && parent.agent != ""; parent = parent.parent {
+					parent.usage.PromptTokens += usage.PromptTokens
+					parent.usage.CompletionTokens += usage.CompletionTokens
+					parent.usage.TotalTokens += usage.TotalTokens
+				}
+			}
+		}
+		for _, toolCall := range message.ToolCalls {
+			tool := a.tracer.startSpan(agent, "execute_tool "+toolCall.Function.Name, spanKindInternal, now)
+			tool.attrs["gen_ai.operation.name"] = "execute_tool"
+			tool.attrs["gen_ai.tool.name"] = toolCall.Function.Name
+			tool.attrs["gen_ai.tool.call.id"] = toolCall.ID
+			tool.attrs["gen_ai.tool.call.arguments"] = toolCall.Function.Arguments
+			a.tools[toolCall.ID] = tool
+		}
+	case schema.Tool:
+		tool, ok := a.tools[message.ToolCallID]
+		if !ok {
+			if toolName == "" {
+				toolName = message.ToolName
+			}
+			tool = a.tracer.startSpan(agent, "execute_tool "+toolName, spanKindInternal, a.lastActivity[key])
+			tool.attrs["gen_ai.operation.name"] = "execute_tool"
+			tool.attrs["gen_ai.tool.name"] = toolName
+			tool.attrs["gen_ai.tool.call.id"] = message.ToolCallID
+		}
+		tool.end = now
+		delete(a.tools, message.ToolCallID)
+	}
+}
+
+// finish closes the tool calls that never got a result and marks the run's
+// agents as failed on err. An interrupted run is not a failure; its pending
+// tool calls continue in the resumed run.
+func (a *agentSpans) finish(err error) {
+	if a == nil {
+		return
+	}
+
+	var interrupted *InterruptedError
+	if errors.As(err, &interrupted) {
+		return
+	}
+
+	now := time.Now()
+	for _, tool := range a.tools {
+		tool.end = now
+		tool.err = "no tool result"
+	}
+	if err == nil {
+		return
+	}
+	for _, agent := range a.agents {
+		if agent.parent == a.tracer.root && agent.err == "" {
+			agent.err = err.Error()
+		}
+	}
+}
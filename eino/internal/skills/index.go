@@ -0,0 +1,392 @@
+// Package skills indexes a directory of SKILL.md files. Every skill is parsed
+// and validated once, skills can exist in several versions, and bundled
+// resource files referenced from the skill body are tracked so they can be
+// served to the agent and checked by the linter.
+package skills
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const SkillFile = "SKILL.md"
+
+type Skill struct {
+	Name        string
+	Description string
+	Version     string
+	// FrontMatter is the raw YAML front matter, kept so callers can decode it
+	// into their own types.
+	FrontMatter []byte
+	Body        string
+	Dir         string
+	Path        string
+	Resources   []Resource
+}
+
+// ID is name@version, or just the name for an unversioned skill.
+func (s *Skill) ID() string {
+	if s.Version == "" {
+		return s.Name
+	}
+	return s.Name + "@" + s.Version
+}
+
+// Resource is a file bundled with a skill and referenced from its body.
+type Resource struct {
+	// Path is relative to the skill directory and uses forward slashes.
+	Path   string
+	Abs    string
+	Script bool
+}
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+type Problem struct {
+	Path     string
+	Severity Severity
+	Message  string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.Path, p.Severity, p.Message)
+}
+
+// Index is safe for concurrent use. A reload swaps in a new snapshot, so
+// readers always see a consistent set of skills.
+type Index struct {
+	dir  string
+	pins map[string]string
+
+	mu          sync.RWMutex
+	skills      map[string][]*Skill
+	problems    []Problem
+	fingerprint string
+}
+
+// Open indexes dir. Invalid skills are left out of the index and reported by
+// Problems; only an unreadable directory is an error.
+func Open(dir string, pins map[string]string) (*Index, error) {
+	index := &Index{dir: dir, pins: pins}
+	if _, err := index.Reload(); err != nil {
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// ParsePins parses name@version pins separated by commas.
+func ParsePins(value string) (map[string]string, error) {
+	pins := map[string]string{}
+	for _, pin := range strings.Split(value, ",") {
+		pin = strings.TrimSpace(pin)
+		if pin == "" {
+			continue
+		}
+		name, version, ok := strings.Cut(pin, "@")
+		if !ok || name == "" || version == "" {
+			return nil, fmt.Errorf("invalid skill pin %q, want name@version", pin)
+		}
+		pins[name] = version
+	}
+
+	return pins, nil
+}
+
+func (x *Index) Dir() string {
+	return x.dir
+}
+
+func (x *Index) Problems() []Problem {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return append([]Problem(nil), x.problems...)
+}
+
+// List returns the selected version of every skill, sorted by name.
+func (x *Index) List() []*Skill {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	list := make([]*Skill, 0, len(x.skills))
+	for name, versions := range x.skills {
+		if selected := x.selectVersion(name, versions, ""); selected != nil {
+			list = append(list, selected)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list
+}
+
+// Get looks up a skill by name, or a specific version by name@version.
+// Without a version the pinned version is used, else the newest one.
+func (x *Index) Get(id string) (*Skill, error) {
+	name, version, _ := strings.Cut(strings.TrimSpace(id), "@")
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	versions, ok := x.skills[name]
+	if !ok {
+		return nil, fmt.Errorf("skill not found: %s", name)
+	}
+	selected := x.selectVersion(name, versions, version)
+	if selected == nil {
+		return nil, fmt.Errorf("skill %s has no version %s", name, version)
+	}
+
+	return selected, nil
+}
+
+// Versions lists the indexed versions of a skill, newest first.
+func (x *Index) Versions(name string) []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	versions := make([]string, 0, len(x.skills[name]))
+	for _, sk := range x.skills[name] {
+		versions = append(versions, sk.Version)
+	}
+
+	return versions
+}
+
+func (x *Index) selectVersion(name string, versions []*Skill, version string) *Skill {
+	if version == "" {
+		version = x.pins[name]
+	}
+	if version == "" {
+		return versions[0]
+	}
+	for _, sk := range versions {
+		if sk.Version == version {
+			return sk
+		}
+	}
+
+	return nil
+}
+
+// Reload re-indexes the directory when any file in it changed since the last
+// load and reports whether it did.
+func (x *Index) Reload() (bool, error) {
+	fingerprint, err := fingerprintDir(x.dir)
+	if err != nil {
+		return false, err
+	}
+
+	x.mu.RLock()
+	unchanged := fingerprint == x.fingerprint
+	x.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	skills, problems, err := scan(x.dir)
+	if err != nil {
+		return false, err
+	}
+	for name, version := range x.pins {
+		if !hasVersion(skills[name], version) {
+			problems = append(problems, Problem{Path: x.dir, Severity: SeverityError, Message: fmt.Sprintf("pinned skill %s@%s does not exist", name, version)})
+		}
+	}
+
+	x.mu.Lock()
+	x.skills = skills
+	x.problems = problems
+	x.fingerprint = fingerprint
+	x.mu.Unlock()
+
+	return true, nil
+}
+
+func hasVersion(versions []*Skill, version string) bool {
+	for _, sk := range versions {
+		if sk.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch polls the directory every interval until ctx is done and reloads the
+// index when something changed. onReload, when set, is called after every
+// reload attempt that found changes or failed.
+func (x *Index) Watch(ctx context.Context, interval time.Duration, onReload func(problems []Problem, err error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := x.Reload()
+			if onReload != nil && (reloaded || err != nil) {
+				onReload(x.Problems(), err)
+			}
+		}
+	}
+}
+
+// fingerprintDir summarizes the names, sizes and modification times of all
+// files below dir, which is enough to notice edits without reading them.
+func fingerprintDir(dir string) (string, error) {
+	var sb strings.Builder
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&sb, "%s|%d|%d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("read skills directory: %w", err)
+	}
+
+	return sb.String(), nil
+}
+
+// scan finds every SKILL.md below dir. A skill directory may contain nested
+// directories with further skills, which is how several versions of a skill
+// are usually laid out (incident-triage/v1/SKILL.md, incident-triage/v2/...).
+func scan(dir string) (map[string][]*Skill, []Problem, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && entry.Name() == SkillFile {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("read skills directory: %w", err)
+	}
+	sort.Strings(paths)
+
+	skills := map[string][]*Skill{}
+	var problems []Problem
+	for _, path := range paths {
+		sk, skillProblems, err := Load(path)
+		problems = append(problems, skillProblems...)
+		if errors.Is(err, errInvalidSkill) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if hasVersion(skills[sk.Name], sk.Version) {
+			problems = append(problems, Problem{Path: path, Severity: SeverityError, Message: fmt.Sprintf("duplicate skill %s, already defined in another directory", sk.ID())})
+			continue
+		}
+		skills[sk.Name] = append(skills[sk.Name], sk)
+	}
+	for _, versions := range skills {
+		sort.SliceStable(versions, func(i, j int) bool {
+			return compareVersions(versions[i].Version, versions[j].Version) > 0
+		})
+	}
+
+	return skills, problems, nil
+}
+
+var errInvalidSkill = errors.New("invalid skill")
+
+// Load parses and validates one SKILL.md. Errors in the file are returned as
+// problems together with errInvalidSkill; warnings do not make a skill
+// invalid.
+func Load(path string) (*Skill, []Problem, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	problems := []Problem{}
+	invalid := func(message string) (*Skill, []Problem, error) {
+		problems = append(problems, Problem{Path: path, Severity: SeverityError, Message: message})
+		return nil, problems, errInvalidSkill
+	}
+
+	frontMatter, body, err := splitFrontMatter(string(content))
+	if err != nil {
+		return invalid(err.Error())
+	}
+	matter, schemaErrors, err := validateFrontMatter([]byte(frontMatter))
+	if err != nil {
+		return invalid(err.Error())
+	}
+	if len(schemaErrors) > 0 {
+		for _, message := range schemaErrors {
+			problems = append(problems, Problem{Path: path, Severity: SeverityError, Message: message})
+		}
+		return nil, problems, errInvalidSkill
+	}
+
+	sk := &Skill{
+		Name:        matter.Name,
+		Description: strings.TrimSpace(matter.Description),
+		Version:     matter.Version,
+		FrontMatter: []byte(frontMatter),
+		Body:        strings.TrimSpace(body),
+		Dir:         filepath.Dir(path),
+		Path:        path,
+	}
+	if dirName := filepath.Base(sk.Dir); dirName != sk.Name && !isVersionDir(dirName, sk.Version) {
+		problems = append(problems, Problem{Path: path, Severity: SeverityWarning, Message: fmt.Sprintf("directory %s does not match skill name %s", dirName, sk.Name)})
+	}
+
+	resources, resourceProblems := findResources(sk)
+	sk.Resources = resources
+	problems = append(problems, resourceProblems...)
+	for _, problem := range resourceProblems {
+		if problem.Severity == SeverityError {
+			return nil, problems, errInvalidSkill
+		}
+	}
+
+	return sk, problems, nil
+}
+
+func isVersionDir(dirName, version string) bool {
+	return version != "" && (dirName == version || dirName == "v"+version || strings.HasPrefix("v"+version, dirName+"."))
+}
+
+func splitFrontMatter(content string) (string, string, error) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(content, "---\n") {
+		return "", "", errors.New("missing YAML front matter")
+	}
+
+	rest := strings.TrimPrefix(content, "---\n")
+	before, after, ok := strings.Cut(rest, "\n---\n")
+	if !ok {
+		return "", "", errors.New("missing closing front matter delimiter")
+	}
+
+	return before, after, nil
+}
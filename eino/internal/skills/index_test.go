@@ -0,0 +1,180 @@
+package skills
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeFile writes content to rel below dir, creating its directories.
+func writeFile(t *testing.T, dir, rel, content string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func skillFile(frontMatter, body string) string {
+	return "---\n" + frontMatter + "\n---\n" + body + "\n"
+}
+
+// problemMessages returns the problems as "severity: message" with the path
+// relative to dir.
+func problemMessages(dir string, problems []Problem) []string {
+	var messages []string
+	for _, problem := range problems {
+		rel, _ := filepath.Rel(dir, problem.Path)
+		messages = append(messages, filepath.ToSlash(rel)+": "+string(problem.Severity)+": "+problem.Message)
+	}
+	return messages
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "release-notes/SKILL.md", skillFile("name: release-notes\ndescription: Write release notes.", "Use [the template](template.md)."))
+	writeFile(t, dir, "release-notes/template.md", "# Release")
+	writeFile(t, dir, "incident-triage/v1/SKILL.md", skillFile("name: incident-triage\ndescription: Old triage.\nversion: 1.0.0", "Page someone."))
+	writeFile(t, dir, "incident-triage/v2/SKILL.md", skillFile("name: incident-triage\ndescription: Triage.\nversion: 2.0.0", "Check the dashboard."))
+	writeFile(t, dir, "incident-triage/v2-rc/SKILL.md", skillFile("name: incident-triage\ndescription: Next triage.\nversion: 2.0.0-rc.1", "Try this."))
+
+	index, err := Open(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := index.Problems(); len(problems) != 1 || !strings.Contains(problems[0].Message, "directory v2-rc does not match") {
+		t.Errorf("Problems() = %v, want the directory warning", problemMessages(dir, problems))
+	}
+
+	var names []string
+	for _, sk := range index.List() {
+		names = append(names, sk.ID())
+	}
+	if want := []string{"incident-triage@2.0.0", "release-notes"}; !slices.Equal(names, want) {
+		t.Errorf("List() = %v, want %v", names, want)
+	}
+	if versions := index.Versions("incident-triage"); !slices.Equal(versions, []string{"2.0.0", "2.0.0-rc.1", "1.0.0"}) {
+		t.Errorf("Versions() = %v", versions)
+	}
+
+	sk, err := index.Get(" incident-triage@1.0.0 ")
+	if err != nil || sk.Body != "Page someone." || sk.Description != "Old triage." {
+		t.Errorf("Get(incident-triage@1.0.0) = %+v, %v", sk, err)
+	}
+	if _, err := index.Get("incident-triage@3.0.0"); err == nil || !strings.Contains(err.Error(), "has no version 3.0.0") {
+		t.Errorf("Get() of a missing version error = %v", err)
+	}
+	if _, err := index.Get("deploy"); err == nil || !strings.Contains(err.Error(), "skill not found") {
+		t.Errorf("Get() of a missing skill error = %v", err)
+	}
+
+	// A pin selects the version used without one.
+	pinned, err := Open(dir, map[string]string{"incident-triage": "1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sk, err := pinned.Get("incident-triage"); err != nil || sk.Version != "1.0.0" {
+		t.Errorf("Get() with a pin = %+v, %v", sk, err)
+	}
+	missingPin, err := Open(dir, map[string]string{"incident-triage": "9.9.9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messages := problemMessages(dir, missingPin.Problems()); !slices.Contains(messages, ".: error: pinned skill incident-triage@9.9.9 does not exist") {
+		t.Errorf("Problems() with a missing pin = %v", messages)
+	}
+
+	if _, err := Open(filepath.Join(dir, "missing"), nil); err == nil {
+		t.Error("Open() of a missing directory succeeded")
+	}
+}
+
+// TestLintProblems covers what skills lint reports for a directory with one
+// valid skill and one of every kind of problem.
+func TestLintProblems(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "valid/SKILL.md", skillFile("name: valid\ndescription: A valid skill.\nversion: 1.2.3", "Run [the check](scripts/check.sh)."))
+	writeFile(t, dir, "valid/scripts/check.sh", "#!/bin/sh\n")
+	writeFile(t, dir, "bad-version/SKILL.md", skillFile("name: bad-version\ndescription: Bad version.\nversion: v1", "Body."))
+	writeFile(t, dir, "escape/SKILL.md", skillFile("name: escape\ndescription: Escapes.", "Read [secrets](../valid/scripts/check.sh)."))
+	writeFile(t, dir, "missing-resource/SKILL.md", skillFile("name: missing-resource\ndescription: Missing.", "See [guide](guide.md)."))
+	writeFile(t, dir, "unreferenced/SKILL.md", skillFile("name: unreferenced\ndescription: Has an extra file.", "Nothing to see."))
+	writeFile(t, dir, "unreferenced/notes.txt", "forgotten")
+	writeFile(t, dir, "no-front-matter/SKILL.md", "# Just markdown\n")
+	writeFile(t, dir, "copy/SKILL.md", skillFile("name: valid\ndescription: A copy.\nversion: 1.2.3", "Body."))
+	writeFile(t, dir, "renamed/SKILL.md", skillFile("name: other-name\ndescription: Lives elsewhere.", "Body."))
+
+	index, err := Open(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"bad-version/SKILL.md: error: front matter: validating /properties/version: pattern: ",
+		"copy/SKILL.md: warning: directory copy does not match skill name valid",
+		"escape/SKILL.md: error: resource ../valid/scripts/check.sh points outside the skill directory",
+		"missing-resource/SKILL.md: error: resource guide.md does not exist",
+		"no-front-matter/SKILL.md: error: missing YAML front matter",
+		"renamed/SKILL.md: warning: directory renamed does not match skill name other-name",
+		"unreferenced/SKILL.md: warning: bundled file notes.txt is not referenced from the skill body",
+		"valid/SKILL.md: error: duplicate skill valid@1.2.3, already defined in another directory",
+	}
+	got := problemMessages(dir, index.Problems())
+	if len(got) != len(want) {
+		t.Fatalf("Problems() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("problem %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+
+	// Skills with errors are left out; warnings keep a skill.
+	var names []string
+	for _, sk := range index.List() {
+		names = append(names, sk.Name)
+	}
+	if want := []string{"other-name", "unreferenced", "valid"}; !slices.Equal(names, want) {
+		t.Errorf("List() = %v, want %v", names, want)
+	}
+	if sk, _ := index.Get("valid"); sk.Description != "A copy." {
+		t.Errorf("the first skill in path order wins a duplicate, got %q", sk.Description)
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "alpha/SKILL.md", skillFile("name: alpha\ndescription: First.", "Body."))
+	index, err := Open(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := index.Reload(); reloaded || err != nil {
+		t.Errorf("Reload() of an unchanged directory = %t, %v", reloaded, err)
+	}
+
+	writeFile(t, dir, "beta/SKILL.md", skillFile("name: beta\ndescription: Second.", "Body."))
+	if reloaded, err := index.Reload(); !reloaded || err != nil {
+		t.Fatalf("Reload() after adding a skill = %t, %v", reloaded, err)
+	}
+	if _, err := index.Get("beta"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParsePins(t *testing.T) {
+	pins, err := ParsePins(" alpha@1.0.0, beta@2.1.0-rc.1 ,")
+	if err != nil || len(pins) != 2 || pins["alpha"] != "1.0.0" || pins["beta"] != "2.1.0-rc.1" {
+		t.Errorf("ParsePins() = %v, %v", pins, err)
+	}
+	for _, value := range []string{"alpha", "alpha@", "@1.0.0"} {
+		if _, err := ParsePins(value); err == nil {
+			t.Errorf("ParsePins(%q) succeeded", value)
+		}
+	}
+}
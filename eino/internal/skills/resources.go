@@ -0,0 +1,156 @@
+package skills
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// MaxResourceSize caps what ReadResource returns to the model.
+const MaxResourceSize = 64 * 1024
+
+// markdownLink matches [text](target) and ![alt](target); the target ends at
+// the first space so titles like (file.md "Title") are ignored.
+var markdownLink = regexp.MustCompile(`!?\[[^\]]*\]\(<?([^)\s>]+)>?(?:\s+"[^"]*")?\)`)
+
+// findResources collects the local files the skill body links to. Links must
+// stay inside the skill directory; a missing target is an error, a bundled
+// file nothing links to only a warning.
+func findResources(sk *Skill) ([]Resource, []Problem) {
+	var (
+		resources []Resource
+		problems  []Problem
+		seen      = map[string]bool{}
+	)
+	for _, match := range markdownLink.FindAllStringSubmatch(sk.Body, -1) {
+		target := match[1]
+		if isExternalLink(target) {
+			continue
+		}
+		target, _, _ = strings.Cut(target, "#")
+		if target == "" {
+			continue
+		}
+
+		rel := path.Clean(strings.TrimPrefix(target, "./"))
+		if path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+			problems = append(problems, Problem{Path: sk.Path, Severity: SeverityError, Message: fmt.Sprintf("resource %s points outside the skill directory", target)})
+			continue
+		}
+		if seen[rel] {
+			continue
+		}
+		seen[rel] = true
+
+		abs := filepath.Join(sk.Dir, filepath.FromSlash(rel))
+		info, err := os.Stat(abs)
+		if err != nil {
+			problems = append(problems, Problem{Path: sk.Path, Severity: SeverityError, Message: fmt.Sprintf("resource %s does not exist", target)})
+			continue
+		}
+		if info.IsDir() {
+			problems = append(problems, Problem{Path: sk.Path, Severity: SeverityError, Message: fmt.Sprintf("resource %s is a directory", target)})
+			continue
+		}
+		resources = append(resources, Resource{
+			Path:   rel,
+			Abs:    abs,
+			Script: strings.HasPrefix(rel, "scripts/") || info.Mode()&0o111 != 0,
+		})
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].Path < resources[j].Path })
+
+	for _, rel := range bundledFiles(sk.Dir) {
+		if !seen[rel] {
+			problems = append(problems, Problem{Path: sk.Path, Severity: SeverityWarning, Message: fmt.Sprintf("bundled file %s is not referenced from the skill body", rel)})
+		}
+	}
+
+	return resources, problems
+}
+
+func isExternalLink(target string) bool {
+	return strings.HasPrefix(target, "#") || strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:")
+}
+
+// bundledFiles lists the files of a skill directory except SKILL.md, skipping
+// nested skills, which own their files.
+func bundledFiles(dir string) []string {
+	var files []string
+	_ = filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if p != dir {
+				if _, err := os.Stat(filepath.Join(p, SkillFile)); err == nil {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") || p == filepath.Join(dir, SkillFile) {
+			return nil
+		}
+		if rel, err := filepath.Rel(dir, p); err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+
+	return files
+}
+
+// ReadResource returns a resource the skill body references. Scripts are
+// returned as text; they are never executed.
+func (x *Index) ReadResource(id, resourcePath string) (string, error) {
+	sk, err := x.Get(id)
+	if err != nil {
+		return "", err
+	}
+
+	rel := path.Clean(strings.TrimPrefix(strings.TrimSpace(resourcePath), "./"))
+	for _, resource := range sk.Resources {
+		if resource.Path != rel {
+			continue
+		}
+
+		content, err := os.ReadFile(resource.Abs)
+		if err != nil {
+			return "", fmt.Errorf("read resource %s of skill %s: %w", rel, sk.ID(), err)
+		}
+		if len(content) > MaxResourceSize {
+			return string(content[:MaxResourceSize]) + "\n[truncated]", nil
+		}
+		return string(content), nil
+	}
+
+	return "", fmt.Errorf("skill %s has no resource %s", sk.ID(), resourcePath)
+}
+
+// ContentWithResources appends the list of bundled resources to the skill
+// body so the model knows what it can load.
+func (s *Skill) ContentWithResources() string {
+	if len(s.Resources) == 0 {
+		return s.Body
+	}
+
+	var sb strings.Builder
+	sb.WriteString(s.Body)
+	sb.WriteString("\n\n## Bundled resources\n\n")
+	sb.WriteString("Load these with the skill_resource tool when the instructions above need them.\n\n")
+	for _, resource := range s.Resources {
+		kind := "file"
+		if resource.Script {
+			kind = "script"
+		}
+		fmt.Fprintf(&sb, "- %s (%s)\n", resource.Path, kind)
+	}
+
+	return strings.TrimSpace(sb.String())
+}
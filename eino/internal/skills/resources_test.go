@@ -0,0 +1,113 @@
+package skills
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindResources(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		files    []string
+		want     []Resource
+		problems []string
+	}{
+		{
+			name:  "linked files",
+			body:  "Fill in [the template](./template.md#usage), run ![chart](scripts/plot.py \"Plot\") and read [it again](template.md).",
+			files: []string{"template.md", "scripts/plot.py"},
+			want:  []Resource{{Path: "scripts/plot.py", Script: true}, {Path: "template.md"}},
+		},
+		{
+			name: "external links",
+			body: "See [docs](https://example.com/a.md), [mail](mailto:ops@example.com) and [below](#steps).",
+		},
+		{
+			name:     "escaping links",
+			body:     "Read [up](../other/SKILL.md), [root](/etc/passwd) and [sneaky](docs/../../x.md).",
+			problems: []string{"error: resource ../other/SKILL.md points outside the skill directory", "error: resource /etc/passwd points outside the skill directory", "error: resource docs/../../x.md points outside the skill directory"},
+		},
+		{
+			name:     "missing resource",
+			body:     "See [guide](guide.md).",
+			problems: []string{"error: resource guide.md does not exist"},
+		},
+		{
+			name:     "directory",
+			body:     "See [docs](docs).",
+			files:    []string{"docs/a.md"},
+			problems: []string{"error: resource docs is a directory", "warning: bundled file docs/a.md is not referenced from the skill body"},
+		},
+		{
+			name:     "unreferenced bundled file",
+			body:     "Nothing linked.",
+			files:    []string{"notes.txt", ".hidden", "nested/SKILL.md", "nested/own.md"},
+			problems: []string{"warning: bundled file notes.txt is not referenced from the skill body"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, file := range test.files {
+				writeFile(t, dir, file, file)
+			}
+			sk := &Skill{Name: "test", Body: test.body, Dir: dir, Path: filepath.Join(dir, SkillFile)}
+
+			resources, problems := findResources(sk)
+			for i := range resources {
+				if resources[i].Abs != filepath.Join(dir, filepath.FromSlash(resources[i].Path)) {
+					t.Errorf("resource %s at %s", resources[i].Path, resources[i].Abs)
+				}
+				resources[i].Abs = ""
+			}
+			if !reflect.DeepEqual(resources, test.want) {
+				t.Errorf("resources = %+v, want %+v", resources, test.want)
+			}
+			var got []string
+			for _, problem := range problems {
+				got = append(got, string(problem.Severity)+": "+problem.Message)
+			}
+			if !reflect.DeepEqual(got, test.problems) {
+				t.Errorf("problems = %q, want %q", got, test.problems)
+			}
+		})
+	}
+}
+
+func TestReadResource(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "deploy/SKILL.md", skillFile("name: deploy\ndescription: Deploy.", "Run [the script](scripts/run.sh) with [the big log](big.log)."))
+	writeFile(t, dir, "deploy/scripts/run.sh", "echo deploy")
+	writeFile(t, dir, "deploy/big.log", strings.Repeat("x", MaxResourceSize+10))
+	index, err := Open(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if content, err := index.ReadResource("deploy", " ./scripts/run.sh"); err != nil || content != "echo deploy" {
+		t.Errorf("ReadResource() = %q, %v", content, err)
+	}
+	if content, err := index.ReadResource("deploy", "big.log"); err != nil || len(content) != MaxResourceSize+len("\n[truncated]") {
+		t.Errorf("ReadResource() of a large file = %d bytes, %v", len(content), err)
+	}
+	if _, err := index.ReadResource("deploy", "SKILL.md"); err == nil || err.Error() != "skill deploy has no resource SKILL.md" {
+		t.Errorf("ReadResource() of an unlinked file error = %v", err)
+	}
+	if _, err := index.ReadResource("deploy", "../deploy/scripts/run.sh"); err == nil {
+		t.Error("ReadResource() outside the skill directory succeeded")
+	}
+
+	sk, err := index.Get("deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sk.Body + "\n\n## Bundled resources\n\n" +
+		"Load these with the skill_resource tool when the instructions above need them.\n\n" +
+		"- big.log (file)\n- scripts/run.sh (script)"
+	if got := sk.ContentWithResources(); got != want {
+		t.Errorf("ContentWithResources() =\n%s\nwant\n%s", got, want)
+	}
+}
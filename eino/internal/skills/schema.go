@@ -0,0 +1,132 @@
+package skills
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	goyaml "gopkg.in/yaml.v3"
+)
+
+// frontMatterSchema is the contract every SKILL.md front matter has to meet.
+// Unknown keys are allowed so skills can carry metadata for other tools.
+const frontMatterSchema = `{
+  "type": "object",
+  "required": ["name", "description"],
+  "properties": {
+    "name": {
+      "type": "string",
+      "pattern": "^[a-z0-9]+(-[a-z0-9]+)*$",
+      "maxLength": 64
+    },
+    "description": {
+      "type": "string",
+      "minLength": 1,
+      "maxLength": 1024
+    },
+    "version": {
+      "type": "string",
+      "pattern": "^[0-9]+\\.[0-9]+\\.[0-9]+(-[0-9A-Za-z.-]+)?$"
+    },
+    "tags": {
+      "type": "array",
+      "items": {"type": "string"}
+    },
+    "deprecated": {"type": "boolean"}
+  }
+}`
+
+var resolvedFrontMatterSchema = mustResolveSchema(frontMatterSchema)
+
+func mustResolveSchema(source string) *jsonschema.Resolved {
+	var schema jsonschema.Schema
+	if err := json.Unmarshal([]byte(source), &schema); err != nil {
+		panic(fmt.Sprintf("parse front matter schema: %v", err))
+	}
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		panic(fmt.Sprintf("resolve front matter schema: %v", err))
+	}
+	return resolved
+}
+
+type frontMatter struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+// validateFrontMatter checks the YAML against the schema. Schema violations
+// are returned as messages, malformed YAML as an error.
+func validateFrontMatter(source []byte) (frontMatter, []string, error) {
+	var raw any
+	if err := goyaml.Unmarshal(source, &raw); err != nil {
+		return frontMatter{}, nil, fmt.Errorf("front matter is not valid YAML: %w", err)
+	}
+	if raw == nil {
+		raw = map[string]any{}
+	}
+
+	// Round-trip through JSON so the validator sees JSON types instead of the
+	// ints and times YAML decodes to.
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return frontMatter{}, nil, fmt.Errorf("front matter cannot be represented as JSON: %w", err)
+	}
+	var instance any
+	if err := json.Unmarshal(encoded, &instance); err != nil {
+		return frontMatter{}, nil, err
+	}
+	if err := resolvedFrontMatterSchema.Validate(instance); err != nil {
+		return frontMatter{}, []string{"front matter: " + strings.TrimPrefix(err.Error(), "validating root: ")}, nil
+	}
+
+	var matter frontMatter
+	if err := json.Unmarshal(encoded, &matter); err != nil {
+		return frontMatter{}, nil, err
+	}
+
+	return matter, nil, nil
+}
+
+// compareVersions orders semantic versions; an empty version sorts before
+// every other one and a pre-release before its release.
+func compareVersions(a, b string) int {
+	if a == b {
+		return 0
+	}
+	if a == "" {
+		return -1
+	}
+	if b == "" {
+		return 1
+	}
+
+	coreA, preA, _ := strings.Cut(a, "-")
+	coreB, preB, _ := strings.Cut(b, "-")
+	partsA := strings.Split(coreA, ".")
+	partsB := strings.Split(coreB, ".")
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		numA, _ := strconv.Atoi(partsA[i])
+		numB, _ := strconv.Atoi(partsB[i])
+		if numA != numB {
+			if numA < numB {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	default:
+		return strings.Compare(preA, preB)
+	}
+}
@@ -0,0 +1,62 @@
+package skills
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateFrontMatter(t *testing.T) {
+	tests := []struct {
+		name, source string
+		want         frontMatter
+		problem      string
+		err          string
+	}{
+		{name: "valid", source: "name: pdf-forms\ndescription: Fill PDF forms.\nversion: 1.2.0-beta.1", want: frontMatter{Name: "pdf-forms", Description: "Fill PDF forms.", Version: "1.2.0-beta.1"}},
+		{name: "unversioned", source: "name: pdf\ndescription: PDFs.\nlicense: MIT", want: frontMatter{Name: "pdf", Description: "PDFs."}},
+		{name: "bad version", source: "name: pdf\ndescription: PDFs.\nversion: 1.x", problem: "front matter: validating /properties/version: pattern:"},
+		{name: "numeric version", source: "name: pdf\ndescription: PDFs.\nversion: 1.2", problem: "front matter: validating /properties/version: type:"},
+		{name: "bad name", source: "name: PDF Forms\ndescription: PDFs.", problem: "front matter: validating /properties/name: pattern:"},
+		{name: "missing description", source: "name: pdf", problem: "description"},
+		{name: "empty", source: "", problem: "front matter: "},
+		{name: "malformed YAML", source: "name: [pdf", err: "front matter is not valid YAML"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, problems, err := validateFrontMatter([]byte(test.source))
+			switch {
+			case test.err != "":
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("validateFrontMatter() error = %v, want %q", err, test.err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case test.problem != "":
+				if len(problems) != 1 || !strings.Contains(problems[0], test.problem) {
+					t.Errorf("validateFrontMatter() problems = %q, want %q", problems, test.problem)
+				}
+			case len(problems) != 0 || got != test.want:
+				t.Errorf("validateFrontMatter() = %+v, %q, want %+v", got, problems, test.want)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"", "0.0.1", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0-rc.1", "2.0.0", -1},
+		{"2.0.0-beta", "2.0.0-alpha", 1},
+		{"1.0.0", "", 1},
+	}
+	for _, test := range tests {
+		if got := compareVersions(test.a, test.b); got != test.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"errgroupdemo/pipeline"
+)
+
+type order struct {
+	id     int
+	region string
+	amount float64
+}
+
+type charge struct {
+	order
+	fee float64
+}
+
+var regions = []string{"eu", "us", "apac"}
+
+func main() {
+	log.SetFlags(0)
+
+	items := flag.Int("items", 200, "number of orders to produce, 0 for no limit")
+	workers := flag.Int("workers", 4, "workers of the enrich stage")
+	buffer := flag.Int("buffer", 8, "capacity of the channels between stages")
+	stopAfter := flag.Duration("stop-after", 0, "start draining after this duration, 0 to run until done or interrupted")
+	drainTimeout := flag.Duration("drain-timeout", 2*time.Second, "how long in-flight orders may take after the drain started")
+	failAt := flag.Int("fail-at", 0, "fail the charge stage at this order id, 0 to never fail")
+	flag.Parse()
+
+	// Ctrl-C drains the pipeline: no new orders, but the produced ones are
+	// finished.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if *stopAfter > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *stopAfter)
+		defer cancel()
+	}
+
+	p := pipeline.New(ctx, pipeline.Options{DrainTimeout: *drainTimeout})
+
+	orders := pipeline.Source(p, "produce", *buffer, func(ctx context.Context, emit func(order) error) error {
+		for id := 1; *items == 0 || id <= *items; id++ {
+			select {
+			case <-time.After(2 * time.Millisecond):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			o := order{id: id, region: regions[rand.IntN(len(regions))], amount: float64(rand.IntN(20000)) / 100}
+			if err := emit(o); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	enriched := pipeline.Map(p, "enrich", *workers, *buffer, orders, func(ctx context.Context, o order) (order, error) {
+		if err := sleep(ctx, time.Duration(10+rand.IntN(30))*time.Millisecond); err != nil {
+			return order{}, err
+		}
+		if o.amount < 5 {
+			return order{}, pipeline.ErrSkip
+		}
+		return o, nil
+	})
+
+	// Two workers for the slowest stage make it the bottleneck; its queue
+	// fills up and holds back the stages before it.
+	charged := pipeline.Map(p, "charge", 2, *buffer, enriched, func(ctx context.Context, o order) (charge, error) {
+		if o.id == *failAt {
+			return charge{}, fmt.Errorf("payment provider rejected order %d", o.id)
+		}
+		if err := sleep(ctx, time.Duration(15+rand.IntN(20))*time.Millisecond); err != nil {
+			return charge{}, err
+		}
+		return charge{order: o, fee: o.amount * 0.029}, nil
+	})
+
+	totals := map[string]float64{}
+	count := 0
+	pipeline.Sink(p, "aggregate", charged, func(ctx context.Context, c charge) error {
+		totals[c.region] += c.amount - c.fee
+		count++
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- p.Wait() }()
+
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	var err error
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			printStats(p)
+		case err = <-done:
+			break loop
+		}
+	}
+
+	fmt.Println("final:")
+	printStats(p)
+
+	switch {
+	case errors.Is(err, pipeline.ErrDrainTimeout):
+		log.Printf("drain timed out after %v, some orders were abandoned", *drainTimeout)
+	case err != nil:
+		log.Printf("pipeline failed: %v", err)
+	case ctx.Err() != nil:
+		log.Println("pipeline drained")
+	default:
+		log.Println("pipeline completed")
+	}
+
+	fmt.Printf("charged %d orders\n", count)
+	for _, region := range slices.Sorted(maps.Keys(totals)) {
+		fmt.Printf("  %-5s %10.2f\n", region, totals[region])
+	}
+}
+
+func printStats(p *pipeline.Pipeline) {
+	for _, s := range p.Stats() {
+		fmt.Println(" ", s)
+	}
+	fmt.Println()
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,182 @@
+// Package fanout runs a function for every item of a slice concurrently. It
+// wraps errgroup with typed results, concurrency limits, per-task timeouts and
+// panic recovery.
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// ErrTaskTimeout marks errors of tasks that ran out of their TaskTimeout.
+var ErrTaskTimeout = errors.New("task timed out")
+
+// Mode decides what happens to the other tasks when a task fails.
+type Mode int
+
+const (
+	// FailFast cancels the remaining tasks on the first error and returns
+	// that error. Tasks that have not started yet are skipped.
+	FailFast Mode = iota
+	// CollectAll runs every task and returns all errors joined.
+	CollectAll
+)
+
+func (m Mode) String() string {
+	switch m {
+	case FailFast:
+		return "fail-fast"
+	case CollectAll:
+		return "collect-all"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// Options configures Map, ForEach and Run. The zero value runs all tasks at
+// once in FailFast mode without timeouts.
+type Options struct {
+	// Limit is the maximum number of tasks running at once, unlimited when
+	// zero.
+	Limit int
+	// Mode is FailFast by default.
+	Mode Mode
+	// TaskTimeout limits each task, no limit when zero. A task that runs out
+	// of time fails with an error wrapping ErrTaskTimeout.
+	TaskTimeout time.Duration
+}
+
+// TaskError is the error of the task for Items[Index].
+type TaskError struct {
+	Index int
+	Err   error
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("task %d: %v", e.Index, e.Err)
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is the error of a task that panicked.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Result is the outcome of one task.
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+// Map calls fn for every item and returns the values in the order of items.
+// Values of failed or skipped tasks are the zero value. The error wraps a
+// *TaskError per failed task.
+func Map[In, Out any](ctx context.Context, items []In, opts Options, fn func(context.Context, In) (Out, error)) ([]Out, error) {
+	results, first := run(ctx, items, opts, fn)
+
+	values := make([]Out, len(results))
+	var errs []error
+	for i, result := range results {
+		values[i] = result.Value
+		if result.Err != nil {
+			errs = append(errs, &TaskError{Index: i, Err: result.Err})
+		}
+	}
+
+	if len(errs) == 0 {
+		return values, nil
+	}
+	if opts.Mode == FailFast && first != nil {
+		return values, first
+	}
+	return values, errors.Join(errs...)
+}
+
+// ForEach is Map for functions without a result.
+func ForEach[In any](ctx context.Context, items []In, opts Options, fn func(context.Context, In) error) error {
+	_, err := Map(ctx, items, opts, func(ctx context.Context, item In) (struct{}, error) {
+		return struct{}{}, fn(ctx, item)
+	})
+	return err
+}
+
+// Run calls fn for every item and returns the result of each task in the
+// order of items. In FailFast mode, tasks skipped after the first error have
+// the context's error.
+func Run[In, Out any](ctx context.Context, items []In, opts Options, fn func(context.Context, In) (Out, error)) []Result[Out] {
+	results, _ := run(ctx, items, opts, fn)
+	return results
+}
+
+// run also returns the first *TaskError, which in FailFast mode cancelled the
+// other tasks.
+func run[In, Out any](ctx context.Context, items []In, opts Options, fn func(context.Context, In) (Out, error)) ([]Result[Out], error) {
+	results := make([]Result[Out], len(items))
+
+	var g *errgroup.Group
+	groupCtx := ctx
+	if opts.Mode == FailFast {
+		g, groupCtx = errgroup.WithContext(ctx)
+	} else {
+		g = &errgroup.Group{}
+	}
+	if opts.Limit > 0 {
+		g.SetLimit(opts.Limit)
+	}
+
+	for i, item := range items {
+		if err := groupCtx.Err(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		// Go blocks while Limit tasks are running, so the context is checked
+		// again once the task gets its turn.
+		g.Go(func() error {
+			if err := groupCtx.Err(); err != nil {
+				results[i].Err = err
+				return nil
+			}
+			value, err := runTask(groupCtx, item, opts.TaskTimeout, fn)
+			results[i] = Result[Out]{Value: value, Err: err}
+			if err != nil {
+				return &TaskError{Index: i, Err: err}
+			}
+			return nil
+		})
+	}
+	first := g.Wait()
+
+	return results, first
+}
+
+func runTask[In, Out any](ctx context.Context, item In, timeout time.Duration, fn func(context.Context, In) (Out, error)) (value Out, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	if timeout <= 0 {
+		return fn(ctx, item)
+	}
+
+	taskCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	value, err = fn(taskCtx, item)
+	if err != nil && ctx.Err() == nil && errors.Is(taskCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("%w after %v: %w", ErrTaskTimeout, timeout, err)
+	}
+	return value, err
+}
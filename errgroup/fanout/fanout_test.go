@@ -0,0 +1,237 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errTask = errors.New("task failed")
+
+func items(n int) []int {
+	values := make([]int, n)
+	for i := range values {
+		values[i] = i
+	}
+	return values
+}
+
+func TestMapOrder(t *testing.T) {
+	// Later items finish first.
+	values, err := Map(context.Background(), items(20), Options{Limit: 5}, func(ctx context.Context, item int) (string, error) {
+		time.Sleep(time.Duration(20-item) * time.Millisecond)
+		return fmt.Sprint(item * item), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, value := range values {
+		if want := fmt.Sprint(i * i); value != want {
+			t.Errorf("values[%d] = %q, want %q", i, value, want)
+		}
+	}
+}
+
+func TestLimit(t *testing.T) {
+	for _, limit := range []int{1, 3, 8} {
+		t.Run(fmt.Sprint(limit), func(t *testing.T) {
+			var running, peak atomic.Int64
+			err := ForEach(context.Background(), items(30), Options{Limit: limit}, func(ctx context.Context, item int) error {
+				current := running.Add(1)
+				defer running.Add(-1)
+				for {
+					old := peak.Load()
+					if current <= old || peak.CompareAndSwap(old, current) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := peak.Load(); got > int64(limit) {
+				t.Errorf("%d tasks ran at once, limit is %d", got, limit)
+			}
+			if got := peak.Load(); got < int64(limit) {
+				t.Errorf("at most %d tasks ran at once, want %d", got, limit)
+			}
+		})
+	}
+}
+
+func TestFailFast(t *testing.T) {
+	var started atomic.Int64
+	results := Run(context.Background(), items(50), Options{Limit: 2, Mode: FailFast}, func(ctx context.Context, item int) (int, error) {
+		started.Add(1)
+		if item == 3 {
+			return 0, errTask
+		}
+		select {
+		case <-time.After(10 * time.Millisecond):
+			return item, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	})
+
+	if !errors.Is(results[3].Err, errTask) {
+		t.Errorf("results[3].Err = %v, want %v", results[3].Err, errTask)
+	}
+	if got := started.Load(); got >= 50 {
+		t.Errorf("all %d tasks started after the failure", got)
+	}
+	skipped := 0
+	for i, result := range results[4:] {
+		if result.Err == nil {
+			continue
+		}
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("results[%d].Err = %v, want %v", i+4, result.Err, context.Canceled)
+		}
+		skipped++
+	}
+	if skipped < 40 {
+		t.Errorf("%d tasks after the failure were skipped, want most of the 46", skipped)
+	}
+}
+
+func TestMapFailFastReturnsFirstError(t *testing.T) {
+	_, err := Map(context.Background(), items(10), Options{}, func(ctx context.Context, item int) (int, error) {
+		if item == 7 {
+			return 0, errTask
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+
+	var taskErr *TaskError
+	if !errors.As(err, &taskErr) || taskErr.Index != 7 || !errors.Is(err, errTask) {
+		t.Fatalf("Map() error = %v, want task 7 failing with %v", err, errTask)
+	}
+	if strings.Contains(err.Error(), "canceled") {
+		t.Errorf("error %q includes the cancelled tasks", err)
+	}
+}
+
+func TestCollectAll(t *testing.T) {
+	failing := []int{2, 5, 9}
+	values, err := Map(context.Background(), items(10), Options{Limit: 3, Mode: CollectAll}, func(ctx context.Context, item int) (int, error) {
+		if slices.Contains(failing, item) {
+			return 0, fmt.Errorf("item %d: %w", item, errTask)
+		}
+		return item + 100, nil
+	})
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("Map() error = %T, want errors.Join", err)
+	}
+	errs := joined.Unwrap()
+	if len(errs) != len(failing) {
+		t.Fatalf("got %d errors, want %d: %v", len(errs), len(failing), err)
+	}
+	for i, err := range errs {
+		var taskErr *TaskError
+		if !errors.As(err, &taskErr) || taskErr.Index != failing[i] || !errors.Is(err, errTask) {
+			t.Errorf("errs[%d] = %v, want task %d", i, err, failing[i])
+		}
+	}
+	for i, value := range values {
+		want := i + 100
+		if slices.Contains(failing, i) {
+			want = 0
+		}
+		if value != want {
+			t.Errorf("values[%d] = %d, want %d", i, value, want)
+		}
+	}
+}
+
+func TestTaskTimeout(t *testing.T) {
+	results := Run(context.Background(), items(4), Options{Mode: CollectAll, TaskTimeout: 20 * time.Millisecond}, func(ctx context.Context, item int) (int, error) {
+		if item%2 == 0 {
+			return item, nil
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+
+	for i, result := range results {
+		if i%2 == 0 {
+			if result.Err != nil || result.Value != i {
+				t.Errorf("results[%d] = %v, %v; want %d", i, result.Value, result.Err, i)
+			}
+			continue
+		}
+		if !errors.Is(result.Err, ErrTaskTimeout) || !errors.Is(result.Err, context.DeadlineExceeded) {
+			t.Errorf("results[%d].Err = %v, want %v", i, result.Err, ErrTaskTimeout)
+		}
+	}
+}
+
+func TestTimeoutOfParentIsNotTaskTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := ForEach(ctx, items(1), Options{TaskTimeout: time.Hour}, func(ctx context.Context, item int) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if errors.Is(err, ErrTaskTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ForEach() error = %v, want only %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestPanicRecovery(t *testing.T) {
+	results := Run(context.Background(), items(3), Options{Mode: CollectAll}, func(ctx context.Context, item int) (int, error) {
+		if item == 1 {
+			panic("boom")
+		}
+		return item, nil
+	})
+
+	var panicErr *PanicError
+	if !errors.As(results[1].Err, &panicErr) {
+		t.Fatalf("results[1].Err = %v, want a *PanicError", results[1].Err)
+	}
+	if panicErr.Value != "boom" || !strings.Contains(string(panicErr.Stack), "TestPanicRecovery") {
+		t.Errorf("PanicError = %v with stack:\n%s", panicErr.Value, panicErr.Stack)
+	}
+	if results[0].Err != nil || results[2].Err != nil {
+		t.Errorf("other tasks failed: %v, %v", results[0].Err, results[2].Err)
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var started atomic.Int64
+	results := Run(ctx, items(5), Options{Mode: CollectAll}, func(ctx context.Context, item int) (int, error) {
+		started.Add(1)
+		return item, nil
+	})
+	if started.Load() != 0 {
+		t.Errorf("%d tasks started with a cancelled context", started.Load())
+	}
+	for i, result := range results {
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("results[%d].Err = %v, want %v", i, result.Err, context.Canceled)
+		}
+	}
+}
+
+func TestModeString(t *testing.T) {
+	for mode, want := range map[Mode]string{FailFast: "fail-fast", CollectAll: "collect-all", Mode(7): "Mode(7)"} {
+		if got := mode.String(); got != want {
+			t.Errorf("Mode(%d).String() = %q, want %q", int(mode), got, want)
+		}
+	}
+}
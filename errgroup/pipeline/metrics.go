@@ -0,0 +1,129 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds of the latency histogram buckets.
+var DefaultBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// Histogram counts durations in fixed buckets. It is safe for concurrent use.
+type Histogram struct {
+	bounds []time.Duration
+	counts []atomic.Int64 // one more than bounds for the overflow bucket
+	sum    atomic.Int64
+}
+
+func NewHistogram(bounds []time.Duration) *Histogram {
+	return &Histogram{bounds: bounds, counts: make([]atomic.Int64, len(bounds)+1)}
+}
+
+func (h *Histogram) Observe(d time.Duration) {
+	i := 0
+	for i < len(h.bounds) && d > h.bounds[i] {
+		i++
+	}
+	h.counts[i].Add(1)
+	h.sum.Add(int64(d))
+}
+
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	s := HistogramSnapshot{Bounds: h.bounds, Counts: make([]int64, len(h.counts))}
+	for i := range h.counts {
+		s.Counts[i] = h.counts[i].Load()
+		s.Count += s.Counts[i]
+	}
+	s.Sum = time.Duration(h.sum.Load())
+	return s
+}
+
+type HistogramSnapshot struct {
+	Bounds []time.Duration
+	// Counts has one entry per bound plus one for larger durations.
+	Counts []int64
+	Count  int64
+	Sum    time.Duration
+}
+
+func (s HistogramSnapshot) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// Quantile returns the upper bound of the bucket that holds quantile q, so
+// it overestimates by at most one bucket. Durations beyond the last bound
+// report the last bound.
+func (s HistogramSnapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 || len(s.Bounds) == 0 {
+		return 0
+	}
+	rank := int64(q * float64(s.Count))
+	var seen int64
+	for i, count := range s.Counts[:len(s.Bounds)] {
+		seen += count
+		if seen > rank {
+			return s.Bounds[i]
+		}
+	}
+	return s.Bounds[len(s.Bounds)-1]
+}
+
+type stageMetrics struct {
+	inFlight  atomic.Int64
+	processed atomic.Int64
+	skipped   atomic.Int64
+	failed    atomic.Int64
+	latency   *Histogram
+}
+
+// StageStats is a snapshot of the metrics of one stage.
+type StageStats struct {
+	Name    string
+	Workers int
+	// InFlight is the number of items being processed.
+	InFlight int64
+	// Queued is the number of items waiting in the stage's input buffer of
+	// size Capacity. A full buffer means the stage is the bottleneck and
+	// applies backpressure to the stage before it.
+	Queued    int
+	Capacity  int
+	Processed int64
+	Skipped   int64
+	Failed    int64
+	Latency   HistogramSnapshot
+}
+
+func (s StageStats) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-10s workers=%d in-flight=%d", s.Name, s.Workers, s.InFlight)
+	if s.Capacity > 0 {
+		fmt.Fprintf(&b, " queued=%d/%d", s.Queued, s.Capacity)
+	}
+	fmt.Fprintf(&b, " processed=%d", s.Processed)
+	if s.Skipped > 0 {
+		fmt.Fprintf(&b, " skipped=%d", s.Skipped)
+	}
+	if s.Failed > 0 {
+		fmt.Fprintf(&b, " failed=%d", s.Failed)
+	}
+	if s.Latency.Count > 0 {
+		fmt.Fprintf(&b, " mean=%v p50<=%v p99<=%v", s.Latency.Mean().Round(time.Microsecond), s.Latency.Quantile(0.5), s.Latency.Quantile(0.99))
+	}
+	return b.String()
+}
@@ -0,0 +1,268 @@
+// Package pipeline builds multi-stage pipelines on top of errgroup: a source
+// produces items, worker pool stages transform them and a sink aggregates
+// them. Stages are connected by bounded channels, so a slow stage blocks the
+// stages before it instead of buffering without limit.
+//
+// Close, or cancelling the context passed to New, drains the pipeline: the
+// source stops producing and the other stages finish the items already
+// produced. An error in any stage cancels all stages at once.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	// ErrSkip returned by a stage function drops the item without failing
+	// the pipeline.
+	ErrSkip = errors.New("skip item")
+	// ErrDrainTimeout is the error of a pipeline that did not drain within
+	// its DrainTimeout.
+	ErrDrainTimeout = errors.New("pipeline did not drain in time")
+)
+
+type Options struct {
+	// DrainTimeout limits how long the stages may take to finish their
+	// items after the drain started. Then they are cancelled. No limit when
+	// zero.
+	DrainTimeout time.Duration
+	// Buckets of the latency histograms, DefaultBuckets when nil.
+	Buckets []time.Duration
+}
+
+type Pipeline struct {
+	g          *errgroup.Group
+	drainCtx   context.Context
+	startDrain context.CancelFunc
+	// workCtx is cancelled on the first error or when draining times out.
+	workCtx    context.Context
+	cancelWork context.CancelCauseFunc
+	stopDrain  func() bool
+	buckets    []time.Duration
+
+	mu     sync.Mutex
+	stages []*stage
+}
+
+type stage struct {
+	name    string
+	workers int
+	queue   func() (int, int)
+	metrics stageMetrics
+}
+
+// New creates a pipeline. Cancelling ctx starts the drain, like Close.
+func New(ctx context.Context, opts Options) *Pipeline {
+	workBase, cancelWork := context.WithCancelCause(context.WithoutCancel(ctx))
+	g, workCtx := errgroup.WithContext(workBase)
+	drainCtx, startDrain := context.WithCancel(ctx)
+
+	p := &Pipeline{
+		g:          g,
+		drainCtx:   drainCtx,
+		startDrain: startDrain,
+		workCtx:    workCtx,
+		cancelWork: cancelWork,
+		buckets:    opts.Buckets,
+		stopDrain:  func() bool { return false },
+	}
+	if p.buckets == nil {
+		p.buckets = DefaultBuckets
+	}
+	if opts.DrainTimeout > 0 {
+		p.stopDrain = context.AfterFunc(drainCtx, func() {
+			time.AfterFunc(opts.DrainTimeout, func() { cancelWork(ErrDrainTimeout) })
+		})
+	}
+	return p
+}
+
+func (p *Pipeline) addStage(name string, workers int, queue func() (int, int)) *stage {
+	s := &stage{name: name, workers: workers, queue: queue}
+	s.metrics.latency = NewHistogram(p.buckets)
+
+	p.mu.Lock()
+	p.stages = append(p.stages, s)
+	p.mu.Unlock()
+	return s
+}
+
+// Close starts the drain and returns at once; Wait waits for it to finish.
+func (p *Pipeline) Close() {
+	p.startDrain()
+}
+
+// Wait waits for all stages to finish. It returns nil when the pipeline
+// completed or drained, and the first error of a stage otherwise.
+func (p *Pipeline) Wait() error {
+	err := p.g.Wait()
+	p.stopDrain()
+	p.startDrain()
+	cause := context.Cause(p.workCtx)
+	p.cancelWork(nil)
+
+	if errors.Is(cause, ErrDrainTimeout) {
+		return ErrDrainTimeout
+	}
+	return err
+}
+
+// Stats returns a snapshot of the metrics of all stages in the order they
+// were added.
+func (p *Pipeline) Stats() []StageStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]StageStats, len(p.stages))
+	for i, s := range p.stages {
+		stats[i] = StageStats{
+			Name:      s.name,
+			Workers:   s.workers,
+			InFlight:  s.metrics.inFlight.Load(),
+			Processed: s.metrics.processed.Load(),
+			Skipped:   s.metrics.skipped.Load(),
+			Failed:    s.metrics.failed.Load(),
+			Latency:   s.metrics.latency.Snapshot(),
+		}
+		if s.queue != nil {
+			stats[i].Queued, stats[i].Capacity = s.queue()
+		}
+	}
+	return stats
+}
+
+// Source adds the first stage. produce calls emit for every item; emit blocks
+// while the next stage is busy and fails once the pipeline drains or fails,
+// which produce should return. Its ctx is cancelled at the same time. emit
+// must be called from produce's goroutine.
+func Source[T any](p *Pipeline, name string, buffer int, produce func(ctx context.Context, emit func(T) error) error) <-chan T {
+	out := make(chan T, buffer)
+	s := p.addStage(name, 1, nil)
+
+	p.g.Go(func() error {
+		defer close(out)
+
+		ctx, cancel := context.WithCancel(p.workCtx)
+		defer cancel()
+		stop := context.AfterFunc(p.drainCtx, cancel)
+		defer stop()
+
+		last := time.Now()
+		emit := func(item T) error {
+			s.metrics.latency.Observe(time.Since(last))
+			select {
+			case out <- item:
+				s.metrics.processed.Add(1)
+				last = time.Now()
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		err := produce(ctx, emit)
+		switch {
+		case err == nil:
+			return nil
+		case p.workCtx.Err() != nil:
+			// Cancelled because of another stage's error.
+			return context.Cause(p.workCtx)
+		case p.drainCtx.Err() != nil && errors.Is(err, context.Canceled):
+			// Stopped by the drain.
+			return nil
+		default:
+			s.metrics.failed.Add(1)
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	})
+	return out
+}
+
+// Map adds a stage with workers goroutines that call fn for every item of in
+// and send the results to the returned channel with room for buffer items.
+// The order of items is not preserved.
+func Map[In, Out any](p *Pipeline, name string, workers, buffer int, in <-chan In, fn func(context.Context, In) (Out, error)) <-chan Out {
+	out := make(chan Out, buffer)
+	s := p.addStage(name, workers, func() (int, int) { return len(in), cap(in) })
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		p.g.Go(func() error {
+			defer wg.Done()
+			return consume(p, s, in, func(item In) error {
+				start := time.Now()
+				result, err := fn(p.workCtx, item)
+				s.metrics.latency.Observe(time.Since(start))
+				if err != nil {
+					return err
+				}
+				select {
+				case out <- result:
+					return nil
+				case <-p.workCtx.Done():
+					return context.Cause(p.workCtx)
+				}
+			})
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Sink adds the last stage, which calls fn for every item of in from a single
+// goroutine, so fn can aggregate without locking.
+func Sink[T any](p *Pipeline, name string, in <-chan T, fn func(context.Context, T) error) {
+	s := p.addStage(name, 1, func() (int, int) { return len(in), cap(in) })
+	p.g.Go(func() error {
+		return consume(p, s, in, func(item T) error {
+			start := time.Now()
+			defer func() { s.metrics.latency.Observe(time.Since(start)) }()
+			return fn(p.workCtx, item)
+		})
+	})
+}
+
+// consume reads in until it is closed and drained, or the pipeline fails.
+// Latency is observed by process, so time spent waiting for the next stage
+// does not count.
+func consume[T any](p *Pipeline, s *stage, in <-chan T, process func(T) error) error {
+	for {
+		var item T
+		select {
+		case <-p.workCtx.Done():
+			return context.Cause(p.workCtx)
+		case next, ok := <-in:
+			if !ok {
+				return nil
+			}
+			item = next
+		}
+
+		s.metrics.inFlight.Add(1)
+		err := process(item)
+		s.metrics.inFlight.Add(-1)
+
+		switch {
+		case err == nil:
+			s.metrics.processed.Add(1)
+		case errors.Is(err, ErrSkip):
+			s.metrics.skipped.Add(1)
+		case p.workCtx.Err() != nil:
+			// Cancelled because of another stage's error.
+			return context.Cause(p.workCtx)
+		default:
+			s.metrics.failed.Add(1)
+			return fmt.Errorf("%s: %w", s.name, err)
+		}
+	}
+}
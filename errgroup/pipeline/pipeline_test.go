@@ -0,0 +1,325 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errStage = errors.New("stage failed")
+
+// count emits the numbers from 0 until n, or forever if n is negative.
+func count(n int, emitted *atomic.Int64) func(context.Context, func(int) error) error {
+	return func(ctx context.Context, emit func(int) error) error {
+		for i := 0; n < 0 || i < n; i++ {
+			if err := emit(i); err != nil {
+				return err
+			}
+			if emitted != nil {
+				emitted.Add(1)
+			}
+		}
+		return nil
+	}
+}
+
+func double(ctx context.Context, n int) (int, error) {
+	return 2 * n, nil
+}
+
+func stats(t *testing.T, p *Pipeline, name string) StageStats {
+	t.Helper()
+	for _, s := range p.Stats() {
+		if s.Name == name {
+			return s
+		}
+	}
+	t.Fatalf("no stage %q", name)
+	return StageStats{}
+}
+
+func TestPipeline(t *testing.T) {
+	p := New(context.Background(), Options{})
+	numbers := Source(p, "numbers", 4, count(100, nil))
+	doubled := Map(p, "double", 3, 4, numbers, double)
+	sum := 0
+	Sink(p, "sum", doubled, func(ctx context.Context, n int) error {
+		sum += n
+		return nil
+	})
+
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if sum != 9900 {
+		t.Errorf("sum = %d, want 9900", sum)
+	}
+
+	want := []StageStats{
+		{Name: "numbers", Workers: 1, Processed: 100},
+		{Name: "double", Workers: 3, Capacity: 4, Processed: 100},
+		{Name: "sum", Workers: 1, Capacity: 4, Processed: 100},
+	}
+	got := p.Stats()
+	if len(got) != len(want) {
+		t.Fatalf("got %d stages, want %d", len(got), len(want))
+	}
+	for i, s := range got {
+		if s.Name != want[i].Name || s.Workers != want[i].Workers || s.Capacity != want[i].Capacity ||
+			s.Processed != want[i].Processed || s.InFlight != 0 || s.Queued != 0 || s.Skipped != 0 || s.Failed != 0 {
+			t.Errorf("stage %d = %+v, want %+v", i, s, want[i])
+		}
+		if s.Latency.Count != 100 {
+			t.Errorf("%s observed %d latencies, want 100", s.Name, s.Latency.Count)
+		}
+	}
+}
+
+func TestBackpressure(t *testing.T) {
+	const (
+		sourceBuffer = 2
+		workers      = 3
+		mapBuffer    = 4
+	)
+	p := New(context.Background(), Options{})
+	var emitted, sunk atomic.Int64
+	numbers := Source(p, "numbers", sourceBuffer, count(100, &emitted))
+	doubled := Map(p, "double", workers, mapBuffer, numbers, double)
+	release := make(chan struct{})
+	Sink(p, "sink", doubled, func(ctx context.Context, n int) error {
+		<-release
+		sunk.Add(1)
+		return nil
+	})
+
+	// With the sink stuck, every buffer and worker fills up and the source
+	// blocks: one item in the sink, a full map output buffer, one item per
+	// worker and a full source buffer.
+	const held = 1 + mapBuffer + workers + sourceBuffer
+	deadline := time.Now().Add(time.Second)
+	for emitted.Load() < held && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := emitted.Load(); got != held {
+		t.Errorf("source emitted %d items while the sink was stuck, want %d", got, held)
+	}
+	if s := stats(t, p, "sink"); s.Queued != s.Capacity || s.InFlight != 1 {
+		t.Errorf("sink queued %d/%d with %d in flight, want a full buffer and 1", s.Queued, s.Capacity, s.InFlight)
+	}
+	if s := stats(t, p, "double"); s.Queued != s.Capacity {
+		t.Errorf("double queued %d/%d, want a full buffer", s.Queued, s.Capacity)
+	}
+
+	close(release)
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if got := sunk.Load(); got != 100 {
+		t.Errorf("sink processed %d items, want 100", got)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name  string
+		drain func(p *Pipeline, cancel context.CancelFunc)
+	}{
+		{name: "Close", drain: func(p *Pipeline, cancel context.CancelFunc) { p.Close() }},
+		{name: "cancel", drain: func(p *Pipeline, cancel context.CancelFunc) { cancel() }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			p := New(ctx, Options{DrainTimeout: time.Minute})
+			var emitted atomic.Int64
+			numbers := Source(p, "numbers", 8, count(-1, &emitted))
+			doubled := Map(p, "double", 4, 8, numbers, func(ctx context.Context, n int) (int, error) {
+				time.Sleep(time.Millisecond)
+				return 2 * n, nil
+			})
+			var sunk int64
+			Sink(p, "sink", doubled, func(ctx context.Context, n int) error {
+				sunk++
+				if sunk == 20 {
+					test.drain(p, cancel)
+				}
+				return nil
+			})
+
+			if err := p.Wait(); err != nil {
+				t.Fatalf("Wait() = %v, want a clean drain", err)
+			}
+			// Every item the source emitted went through the pipeline.
+			if sunk < 20 || sunk != emitted.Load() {
+				t.Errorf("sink processed %d of %d emitted items", sunk, emitted.Load())
+			}
+			if s := stats(t, p, "numbers"); s.Failed != 0 {
+				t.Errorf("source failed: %+v", s)
+			}
+		})
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	p := New(context.Background(), Options{DrainTimeout: 50 * time.Millisecond})
+	numbers := Source(p, "numbers", 1, count(-1, nil))
+	// The sink never finishes its first item on its own.
+	Sink(p, "stuck", numbers, func(ctx context.Context, n int) error {
+		p.Close()
+		<-ctx.Done()
+		return context.Cause(ctx)
+	})
+
+	start := time.Now()
+	if err := p.Wait(); !errors.Is(err, ErrDrainTimeout) {
+		t.Fatalf("Wait() = %v, want %v", err, ErrDrainTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("drain took %v", elapsed)
+	}
+}
+
+func TestErrorCancelsAllStages(t *testing.T) {
+	p := New(context.Background(), Options{})
+	var sourceErr atomic.Value
+	numbers := Source(p, "numbers", 4, func(ctx context.Context, emit func(int) error) error {
+		err := count(-1, nil)(ctx, emit)
+		sourceErr.Store(err)
+		return err
+	})
+	doubled := Map(p, "double", 3, 4, numbers, func(ctx context.Context, n int) (int, error) {
+		if n == 5 {
+			return 0, errStage
+		}
+		if n > 5 {
+			// Blocks until the pipeline is cancelled.
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return 2 * n, nil
+	})
+	Sink(p, "sink", doubled, func(ctx context.Context, n int) error {
+		return nil
+	})
+
+	err := p.Wait()
+	if !errors.Is(err, errStage) || !strings.HasPrefix(err.Error(), "double: ") {
+		t.Fatalf("Wait() = %v, want double: %v", err, errStage)
+	}
+	if err, _ := sourceErr.Load().(error); !errors.Is(err, context.Canceled) {
+		t.Errorf("source returned %v, want %v", err, context.Canceled)
+	}
+	if s := stats(t, p, "double"); s.Failed != 1 {
+		t.Errorf("double failed %d items, want 1", s.Failed)
+	}
+	if s := stats(t, p, "numbers"); s.Failed != 0 {
+		t.Errorf("cancelled source counted as failed: %+v", s)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	p := New(context.Background(), Options{})
+	numbers := Source(p, "numbers", 4, count(100, nil))
+	evens := Map(p, "evens", 2, 4, numbers, func(ctx context.Context, n int) (int, error) {
+		if n%2 != 0 {
+			return 0, ErrSkip
+		}
+		return n, nil
+	})
+	var got []int
+	Sink(p, "sink", evens, func(ctx context.Context, n int) error {
+		if n%4 != 0 {
+			return ErrSkip
+		}
+		got = append(got, n)
+		return nil
+	})
+
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 25 {
+		t.Errorf("sink kept %d items, want 25", len(got))
+	}
+	for _, n := range got {
+		if n%4 != 0 {
+			t.Errorf("sink kept %d", n)
+		}
+	}
+	if s := stats(t, p, "evens"); s.Processed != 50 || s.Skipped != 50 || s.Failed != 0 {
+		t.Errorf("evens = %+v, want 50 processed and 50 skipped", s)
+	}
+	if s := stats(t, p, "sink"); s.Processed != 25 || s.Skipped != 25 || s.Failed != 0 {
+		t.Errorf("sink = %+v, want 25 processed and 25 skipped", s)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	ms := time.Millisecond
+	h := NewHistogram([]time.Duration{ms, 10 * ms, 100 * ms})
+	if got := h.Snapshot(); got.Quantile(0.5) != 0 || got.Mean() != 0 {
+		t.Errorf("empty histogram: p50 = %v, mean = %v", got.Quantile(0.5), got.Mean())
+	}
+
+	// 50 fast, 40 medium, 9 slow and 1 beyond the last bound.
+	for range 50 {
+		h.Observe(ms / 2)
+	}
+	for range 40 {
+		h.Observe(5 * ms)
+	}
+	for range 9 {
+		h.Observe(50 * ms)
+	}
+	h.Observe(time.Second)
+	s := h.Snapshot()
+
+	if s.Count != 100 {
+		t.Errorf("Count = %d, want 100", s.Count)
+	}
+	if want := []int64{50, 40, 9, 1}; !slices.Equal(s.Counts, want) {
+		t.Errorf("Counts = %v, want %v", s.Counts, want)
+	}
+	if want := 25*ms + 200*ms + 450*ms + time.Second; s.Sum != want {
+		t.Errorf("Sum = %v, want %v", s.Sum, want)
+	}
+	if want := (25*ms + 200*ms + 450*ms + time.Second) / 100; s.Mean() != want {
+		t.Errorf("Mean() = %v, want %v", s.Mean(), want)
+	}
+
+	tests := []struct {
+		q    float64
+		want time.Duration
+	}{
+		{q: 0, want: ms},
+		{q: 0.25, want: ms},
+		{q: 0.49, want: ms},
+		{q: 0.5, want: 10 * ms},
+		{q: 0.89, want: 10 * ms},
+		{q: 0.9, want: 100 * ms},
+		{q: 0.98, want: 100 * ms},
+		// The overflow bucket reports the last bound.
+		{q: 0.99, want: 100 * ms},
+		{q: 1, want: 100 * ms},
+	}
+	for _, test := range tests {
+		if got := s.Quantile(test.q); got != test.want {
+			t.Errorf("Quantile(%v) = %v, want %v", test.q, got, test.want)
+		}
+	}
+}
+
+func TestHistogramBoundsAreInclusive(t *testing.T) {
+	h := NewHistogram([]time.Duration{time.Millisecond, time.Second})
+	h.Observe(time.Millisecond)
+	if got := h.Snapshot().Counts; !slices.Equal(got, []int64{1, 0, 0}) {
+		t.Errorf("Counts = %v, want the first bucket", got)
+	}
+}
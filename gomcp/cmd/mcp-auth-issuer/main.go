@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"gomcpexample/internal"
+)
+
+func main() {
+	config, err := internal.IssuerConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	issuer, err := internal.NewIssuer(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	addr := os.Getenv(internal.IssuerAddrEnv)
+	if addr == "" {
+		addr = ":9000"
+	}
+	srv := &http.Server{
+		Addr:         addr,
+		Handler:      issuer.Handler(),
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+	}
+
+	log.Printf("Starting development OAuth issuer %s on %s", config.URL, addr)
+	if err := srv.ListenAndServe(); err != nil {
+		log.Fatalf("Issuer failed: %v", err)
+	}
+}
@@ -5,9 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"net/http"
 	"time"
 
+	"gomcpexample/internal"
+
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -19,11 +20,15 @@ func main() {
 		Version: "1.0.0",
 	}, nil)
 
+	serverURL := internal.ServerURLFromEnv()
+	httpClient, err := internal.NewAuthHTTPClient(ctx, serverURL)
+	if err != nil {
+		log.Fatalf("Failed to set up authentication: %v", err)
+	}
+
 	transport := &mcp.StreamableClientTransport{
-		Endpoint: "http://localhost:8080",
-		HTTPClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		Endpoint:   serverURL,
+		HTTPClient: httpClient,
 	}
 
 	session, err := client.Connect(ctx, transport, nil)
@@ -35,7 +40,9 @@ func main() {
 	demonstrateListTools(ctx, session)
 	demonstrateToolsIterator(ctx, session)
 	demonstrateCallTool(ctx, session)
+	demonstrateCallForecastTools(ctx, session)
 	demonstrateListResources(ctx, session)
+	demonstrateListResourceTemplates(ctx, session)
 	demonstrateListPrompts(ctx, session)
 }
 
@@ -98,6 +105,36 @@ func demonstrateCallTool(ctx context.Context, session *mcp.ClientSession) {
 
 }
 
+func demonstrateCallForecastTools(ctx context.Context, session *mcp.ClientSession) {
+	forecast, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name: "get_forecast",
+		Arguments: map[string]any{
+			"city": "Zurich",
+			"days": 3,
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to call tool: %v", err)
+	} else {
+		displayToolResult(forecast)
+	}
+
+	lastWeek := time.Now().AddDate(0, 0, -7)
+	history, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name: "get_historical_weather",
+		Arguments: map[string]any{
+			"city":       "Zurich",
+			"start_date": lastWeek.Format(time.DateOnly),
+			"end_date":   lastWeek.AddDate(0, 0, 2).Format(time.DateOnly),
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to call tool: %v", err)
+	} else {
+		displayToolResult(history)
+	}
+}
+
 func displayToolResult(result *mcp.CallToolResult) {
 	if result.IsError {
 		fmt.Println("Tool returned error")
@@ -165,6 +202,30 @@ func demonstrateListResources(ctx context.Context, session *mcp.ClientSession) {
 	fmt.Println()
 }
 
+func demonstrateListResourceTemplates(ctx context.Context, session *mcp.ClientSession) {
+	result, err := session.ListResourceTemplates(ctx, nil)
+	if err != nil {
+		log.Printf("Failed to list resource templates: %v", err)
+		return
+	}
+
+	fmt.Printf("Found %d resource template(s):\n", len(result.ResourceTemplates))
+	for i, template := range result.ResourceTemplates {
+		fmt.Printf("  %d. Name: %s\n", i+1, template.Name)
+		fmt.Printf("     URI Template: %s\n", template.URITemplate)
+		fmt.Printf("     Description: %s\n", template.Description)
+	}
+	fmt.Println()
+}
+
+// promptExamples fills in the arguments of the prompts this client knows.
+var promptExamples = map[string]string{
+	"city":       "Zurich",
+	"days":       "3",
+	"activities": "hiking, museums",
+	"cities":     "Zurich, Rome, Athens",
+}
+
 func demonstrateListPrompts(ctx context.Context, session *mcp.ClientSession) {
 	fmt.Println("=== Demonstrating ListPrompts ===")
 
@@ -195,9 +256,15 @@ func demonstrateListPrompts(ctx context.Context, session *mcp.ClientSession) {
 
 		if len(result.Prompts) > 0 {
 			fmt.Println("\n  Demonstrating GetPrompt:")
+			arguments := map[string]string{}
+			for _, arg := range result.Prompts[0].Arguments {
+				if value, ok := promptExamples[arg.Name]; ok {
+					arguments[arg.Name] = value
+				}
+			}
 			promptResult, err := session.GetPrompt(ctx, &mcp.GetPromptParams{
 				Name:      result.Prompts[0].Name,
-				Arguments: map[string]string{},
+				Arguments: arguments,
 			})
 			if err != nil {
 				log.Printf("  Failed to get prompt: %v", err)
@@ -206,8 +273,11 @@ func demonstrateListPrompts(ctx context.Context, session *mcp.ClientSession) {
 				fmt.Printf("  Messages:\n")
 				for _, msg := range promptResult.Messages {
 					fmt.Printf("    Role: %s\n", msg.Role)
-					if text, ok := msg.Content.(*mcp.TextContent); ok {
-						fmt.Printf("    Content: %s\n", text.Text)
+					switch content := msg.Content.(type) {
+					case *mcp.TextContent:
+						fmt.Printf("    Content: %s\n", content.Text)
+					case *mcp.ResourceLink:
+						fmt.Printf("    Resource: %s\n", content.URI)
 					}
 				}
 			}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"gomcpexample/internal"
+	"gomcpexample/internal/gateway"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func main() {
+	configPath := os.Getenv("MCP_GATEWAY_CONFIG")
+	if configPath == "" {
+		configPath = "gateway.json"
+	}
+	config, err := gateway.LoadConfig(configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	authConfig, err := internal.AuthConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !authConfig.Enabled() {
+		log.Printf("Warning: %s and %s are not set, the gateway accepts unauthenticated requests", internal.AuthIssuerEnv, internal.APIKeysEnv)
+	}
+
+	gw := gateway.New()
+	defer gw.Close()
+
+	connected := 0
+	for _, server := range config.Servers {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := gw.Connect(ctx, server)
+		cancel()
+		if err != nil {
+			log.Printf("Skipping %s: %v", server.Name, err)
+			continue
+		}
+		connected++
+	}
+	if connected == 0 {
+		log.Fatal("Could not connect to any downstream server")
+	}
+
+	handler := mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server {
+		return gw.Server()
+	}, nil)
+
+	addr := os.Getenv("MCP_GATEWAY_ADDR")
+	if addr == "" {
+		addr = ":8090"
+	}
+	srv := &http.Server{
+		Addr:        addr,
+		Handler:     authConfig.Protect(handler),
+		ReadTimeout: 15 * time.Second,
+		IdleTimeout: 120 * time.Second,
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		log.Printf("Starting MCP gateway on %s with %d server(s)", addr, connected)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Gateway failed: %v", err)
+		}
+	}()
+
+	<-quit
+	log.Println("Shutting down gateway...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("Gateway forced to shutdown: %v", err)
+	} else {
+		log.Println("Gateway exited gracefully")
+	}
+}
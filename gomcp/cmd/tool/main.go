@@ -25,6 +25,11 @@ func main() {
 		log.Fatal("GEMINI_API_KEY environment variable is required")
 	}
 
+	provider, err := internal.NewWeatherProviderFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	ctx := context.Background()
 
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
@@ -37,7 +42,7 @@ func main() {
 
 	weatherFunc := &genai.FunctionDeclaration{
 		Name:        "get_weather",
-		Description: "Get current weather information for a location. You can provide either latitude/longitude coordinates OR city/country ISO-3166-1 alpha2 code. If you know the coordinates, provide them directly. If you only have the city name, you must provide the city name together with the country ISO-3166-1 alpha2 code.",
+		Description: "Get current weather information for a location. You can provide either latitude/longitude coordinates OR a city name, optionally with its country ISO-3166-1 alpha2 code. If you know the coordinates, provide them directly. Add the country code when the city name is ambiguous.",
 		Parameters: &genai.Schema{
 			Type: genai.TypeObject,
 			Properties: map[string]*genai.Schema{
@@ -55,7 +60,7 @@ func main() {
 				},
 				"country": {
 					Type:        genai.TypeString,
-					Description: "Country ISO-3166-1 alpha2 code (optional if latitude/longitude provided, mandatory if city name is provided)",
+					Description: "Country ISO-3166-1 alpha2 code (optional, narrows down the city)",
 				},
 			},
 		},
@@ -87,7 +92,7 @@ func main() {
 			continue
 		}
 
-		if err := processResponse(ctx, client, userMessage, result, config); err != nil {
+		if err := processResponse(ctx, client, provider, userMessage, result, config); err != nil {
 			log.Printf("Error processing response: %v", err)
 		}
 
@@ -96,7 +101,7 @@ func main() {
 
 }
 
-func processResponse(ctx context.Context, client *genai.Client, userMessage []*genai.Content, result *genai.GenerateContentResponse, config *genai.GenerateContentConfig) error {
+func processResponse(ctx context.Context, client *genai.Client, provider internal.WeatherProvider, userMessage []*genai.Content, result *genai.GenerateContentResponse, config *genai.GenerateContentConfig) error {
 	const maxLoops = 3
 	conversationHistory := slices.Clone(userMessage)
 	currentResult := result
@@ -142,17 +147,19 @@ func processResponse(ctx context.Context, client *genai.Client, userMessage []*g
 						args.Country = &country
 					}
 
-					weatherData, err := internal.ExecuteWeatherFunction(args)
+					// Errors go back to the model so it can correct the call.
+					var weatherResponse map[string]any
+					weatherData, err := internal.ExecuteWeatherFunction(ctx, provider, args)
 					if err != nil {
-						return fmt.Errorf("function execution failed: %w", err)
-					}
-
-					weatherResponse := map[string]any{
-						"temperature":         weatherData.Temperature,
-						"wind_speed":          weatherData.WindSpeed,
-						"wind_direction":      weatherData.WindDirection,
-						"weather_description": weatherData.WeatherDescription,
-						"time":                weatherData.Time,
+						weatherResponse = map[string]any{"error": err.Error()}
+					} else {
+						weatherResponse = map[string]any{
+							"temperature":         weatherData.Temperature,
+							"wind_speed":          weatherData.WindSpeed,
+							"wind_direction":      weatherData.WindDirection,
+							"weather_description": weatherData.WeatherDescription,
+							"time":                weatherData.Time,
+						}
 					}
 
 					functionResponseParts = append(functionResponseParts, &genai.Part{
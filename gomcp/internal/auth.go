@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/modelcontextprotocol/go-sdk/oauthex"
+)
+
+const (
+	ResourceURLEnv = "MCP_RESOURCE_URL"
+	AuthIssuerEnv  = "MCP_AUTH_ISSUER"
+	AuthJWKSEnv    = "MCP_AUTH_JWKS_URL"
+	AuthScopesEnv  = "MCP_AUTH_SCOPES"
+	APIKeysEnv     = "MCP_API_KEYS"
+	RateLimitEnv   = "MCP_RATE_LIMIT"
+
+	// APIKeyHeader carries an API key for clients that do not send it as a
+	// bearer token.
+	APIKeyHeader = "X-API-Key"
+
+	ProtectedResourceMetadataPath = "/.well-known/oauth-protected-resource"
+)
+
+type AuthConfig struct {
+	// ResourceURL is the public URL of the MCP endpoint. Access tokens must
+	// name it as their audience.
+	ResourceURL string
+	// Issuer enables OAuth bearer tokens signed by this authorization server.
+	Issuer string
+	// JWKSURL overrides the key set URL from the issuer's metadata.
+	JWKSURL string
+	// Scopes are required from every OAuth token.
+	Scopes []string
+	// APIKeys maps client names to API keys.
+	APIKeys map[string]string
+	// RateLimit is the number of requests per minute and client, 0 for no
+	// limit.
+	RateLimit int
+}
+
+// AuthConfigFromEnv reads the MCP_* variables. MCP_API_KEYS is a
+// comma-separated list of name:key pairs.
+func AuthConfigFromEnv() (AuthConfig, error) {
+	config := AuthConfig{
+		ResourceURL: os.Getenv(ResourceURLEnv),
+		Issuer:      strings.TrimSuffix(os.Getenv(AuthIssuerEnv), "/"),
+		JWKSURL:     os.Getenv(AuthJWKSEnv),
+		Scopes:      strings.Fields(os.Getenv(AuthScopesEnv)),
+		RateLimit:   60,
+	}
+	if config.ResourceURL == "" {
+		config.ResourceURL = "http://localhost:8080"
+	}
+	if _, ok := os.LookupEnv(AuthScopesEnv); !ok {
+		config.Scopes = []string{"weather:read"}
+	}
+
+	if keys := strings.TrimSpace(os.Getenv(APIKeysEnv)); keys != "" {
+		config.APIKeys = map[string]string{}
+		for entry := range strings.SplitSeq(keys, ",") {
+			name, key, ok := strings.Cut(strings.TrimSpace(entry), ":")
+			if !ok || name == "" || len(key) < 16 {
+				return AuthConfig{}, fmt.Errorf("%s entry %q must be name:key with a key of at least 16 characters", APIKeysEnv, entry)
+			}
+			config.APIKeys[name] = key
+		}
+	}
+
+	if limit := os.Getenv(RateLimitEnv); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return AuthConfig{}, fmt.Errorf("%s must be a number of requests per minute, got %q", RateLimitEnv, limit)
+		}
+		config.RateLimit = n
+	}
+
+	return config, nil
+}
+
+func (c AuthConfig) Enabled() bool {
+	return c.Issuer != "" || len(c.APIKeys) > 0
+}
+
+// Protect puts handler behind bearer token authentication and the rate limit,
+// and serves the protected resource metadata that tells OAuth clients where to
+// get a token. Without an issuer or API keys, only the rate limit applies.
+//
+// The rate limit applies twice: per remote address in front of the
+// authentication, so failed tokens and guessed API keys are throttled too, and
+// per user behind it.
+func (c AuthConfig) Protect(handler http.Handler) http.Handler {
+	if !c.Enabled() {
+		return c.limit(handler)
+	}
+
+	var jwks *JWKSVerifier
+	if c.Issuer != "" {
+		jwks = NewJWKSVerifier(c.Issuer, c.JWKSURL, c.ResourceURL)
+	}
+	metadataURL := strings.TrimSuffix(c.ResourceURL, "/") + ProtectedResourceMetadataPath
+	handler = auth.RequireBearerToken(c.verifier(jwks), &auth.RequireBearerTokenOptions{
+		ResourceMetadataURL: metadataURL,
+		Scopes:              c.Scopes,
+	})(c.limit(handler))
+
+	mux := http.NewServeMux()
+	mux.Handle(ProtectedResourceMetadataPath, auth.ProtectedResourceMetadataHandler(c.metadata()))
+	mux.Handle("/", apiKeyAsBearer(handler))
+	return c.limit(mux)
+}
+
+// limit wraps handler in a rate limiter of its own, keyed by user when
+// handler runs behind the authentication and by remote address otherwise.
+func (c AuthConfig) limit(handler http.Handler) http.Handler {
+	if c.RateLimit <= 0 {
+		return handler
+	}
+	return NewRateLimiter(c.RateLimit).Middleware(handler)
+}
+
+func (c AuthConfig) metadata() *oauthex.ProtectedResourceMetadata {
+	metadata := &oauthex.ProtectedResourceMetadata{
+		Resource:               c.ResourceURL,
+		ScopesSupported:        c.Scopes,
+		BearerMethodsSupported: []string{"header"},
+		ResourceName:           "Weather MCP server",
+	}
+	if c.Issuer != "" {
+		metadata.AuthorizationServers = []string{c.Issuer}
+	}
+	return metadata
+}
+
+// verifier accepts API keys and, with an issuer, JWT access tokens. API keys
+// are granted the required scopes.
+func (c AuthConfig) verifier(jwks *JWKSVerifier) auth.TokenVerifier {
+	keys := make(map[string][32]byte, len(c.APIKeys))
+	for name, key := range c.APIKeys {
+		keys[name] = sha256.Sum256([]byte(key))
+	}
+
+	return func(ctx context.Context, token string, req *http.Request) (*auth.TokenInfo, error) {
+		// Compare hashes so every comparison takes the same time.
+		sum := sha256.Sum256([]byte(token))
+		for name, key := range keys {
+			if subtle.ConstantTimeCompare(sum[:], key[:]) == 1 {
+				return &auth.TokenInfo{
+					Scopes:     c.Scopes,
+					Expiration: time.Now().Add(time.Hour),
+					UserID:     "api-key:" + name,
+				}, nil
+			}
+		}
+
+		if jwks == nil || strings.Count(token, ".") != 2 {
+			return nil, fmt.Errorf("%w: unknown API key", auth.ErrInvalidToken)
+		}
+		return jwks.Verify(ctx, token, req)
+	}
+}
+
+// apiKeyAsBearer lets clients send an API key in X-API-Key instead of the
+// Authorization header.
+func apiKeyAsBearer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get(APIKeyHeader); key != "" && r.Header.Get("Authorization") == "" {
+			r = r.Clone(r.Context())
+			r.Header.Set("Authorization", "Bearer "+key)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
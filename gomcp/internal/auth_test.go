@@ -0,0 +1,196 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/auth"
+)
+
+const testAPIKey = "0123456789abcdef"
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// serve sends one request from addr to handler, with key as the bearer token
+// unless it is empty.
+func serve(handler http.Handler, addr, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	req.RemoteAddr = addr
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestProtectRateLimits(t *testing.T) {
+	config := AuthConfig{
+		ResourceURL: "http://localhost:8080",
+		APIKeys:     map[string]string{"alice": testAPIKey},
+		RateLimit:   3,
+	}
+
+	t.Run("failed authentication is limited per address", func(t *testing.T) {
+		handler := config.Protect(okHandler())
+		for i := range 3 {
+			if rec := serve(handler, "192.0.2.1:1000", "wrong-key-wrong-key"); rec.Code != http.StatusUnauthorized {
+				t.Fatalf("request %d: status %d, want 401", i+1, rec.Code)
+			}
+		}
+		rec := serve(handler, "192.0.2.1:1001", testAPIKey)
+		if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+			t.Errorf("status %d with Retry-After %q, want 429 for the guessing address", rec.Code, rec.Header().Get("Retry-After"))
+		}
+		if rec := serve(handler, "192.0.2.2:1000", testAPIKey); rec.Code != http.StatusOK {
+			t.Errorf("status %d, want 200 from another address", rec.Code)
+		}
+	})
+
+	t.Run("authenticated users are limited across addresses", func(t *testing.T) {
+		handler := config.Protect(okHandler())
+		addrs := []string{"192.0.2.1:1000", "192.0.2.2:1000", "192.0.2.3:1000", "192.0.2.4:1000"}
+		for i, addr := range addrs {
+			want := http.StatusOK
+			if i == 3 {
+				want = http.StatusTooManyRequests
+			}
+			if rec := serve(handler, addr, testAPIKey); rec.Code != want {
+				t.Errorf("request %d: status %d, want %d", i+1, rec.Code, want)
+			}
+		}
+	})
+
+	t.Run("without authentication", func(t *testing.T) {
+		handler := AuthConfig{RateLimit: 1}.Protect(okHandler())
+		if rec := serve(handler, "192.0.2.1:1000", ""); rec.Code != http.StatusOK {
+			t.Errorf("status %d, want 200", rec.Code)
+		}
+		if rec := serve(handler, "192.0.2.1:1000", ""); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("status %d, want 429", rec.Code)
+		}
+	})
+}
+
+func TestAPIKeys(t *testing.T) {
+	config := AuthConfig{
+		ResourceURL: "http://localhost:8080",
+		APIKeys:     map[string]string{"alice": testAPIKey, "bob": "fedcba9876543210"},
+		Scopes:      []string{"weather:read"},
+	}
+	verify := config.verifier(nil)
+
+	tests := []struct {
+		name, token, user string
+	}{
+		{name: "first key", token: testAPIKey, user: "api-key:alice"},
+		{name: "second key", token: "fedcba9876543210", user: "api-key:bob"},
+		{name: "wrong key", token: "0123456789abcdeX"},
+		{name: "prefix", token: testAPIKey[:15]},
+		{name: "longer", token: testAPIKey + "0"},
+		{name: "empty", token: ""},
+		// Without an issuer, a JWT can only match an API key.
+		{name: "JWT without issuer", token: "eyJhbGciOiJub25lIn0.eyJzdWIiOiJhbGljZSJ9."},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			info, err := verify(context.Background(), test.token, nil)
+			if test.user == "" {
+				if !errors.Is(err, auth.ErrInvalidToken) {
+					t.Errorf("verify() = %+v, %v, want ErrInvalidToken", info, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.UserID != test.user || !slices.Equal(info.Scopes, config.Scopes) {
+				t.Errorf("verify() = %+v, want user %s with the required scopes", info, test.user)
+			}
+		})
+	}
+}
+
+func TestProtectAPIKeyHeader(t *testing.T) {
+	handler := AuthConfig{
+		ResourceURL: "http://localhost:8080",
+		APIKeys:     map[string]string{"alice": testAPIKey},
+	}.Protect(okHandler())
+
+	tests := []struct {
+		name          string
+		authorization string
+		apiKey        string
+		status        int
+	}{
+		{name: "X-API-Key", apiKey: testAPIKey, status: http.StatusOK},
+		{name: "bearer", authorization: "Bearer " + testAPIKey, status: http.StatusOK},
+		// The Authorization header wins over X-API-Key.
+		{name: "both", authorization: "Bearer wrong-key-wrong-key", apiKey: testAPIKey, status: http.StatusUnauthorized},
+		{name: "wrong X-API-Key", apiKey: "wrong-key-wrong-key", status: http.StatusUnauthorized},
+		{name: "none", status: http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			if test.apiKey != "" {
+				req.Header.Set(APIKeyHeader, test.apiKey)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != test.status {
+				t.Errorf("status %d, want %d", rec.Code, test.status)
+			}
+			if rec.Code == http.StatusUnauthorized && !strings.Contains(rec.Header().Get("WWW-Authenticate"), ProtectedResourceMetadataPath) {
+				t.Errorf("WWW-Authenticate %q does not point to the resource metadata", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestAuthConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name, keys, limit string
+		want              map[string]string
+		rateLimit         int
+		err               bool
+	}{
+		{name: "defaults", rateLimit: 60},
+		{name: "keys", keys: "alice:" + testAPIKey + ", bob:fedcba9876543210", limit: "10", rateLimit: 10, want: map[string]string{"alice": testAPIKey, "bob": "fedcba9876543210"}},
+		{name: "short key", keys: "alice:short", err: true},
+		{name: "no name", keys: ":" + testAPIKey, err: true},
+		{name: "bad limit", limit: "-1", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(APIKeysEnv, test.keys)
+			t.Setenv(RateLimitEnv, test.limit)
+			config, err := AuthConfigFromEnv()
+			if test.err {
+				if err == nil {
+					t.Errorf("AuthConfigFromEnv() = %+v, want an error", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(config.APIKeys, test.want) || config.RateLimit != test.rateLimit {
+				t.Errorf("AuthConfigFromEnv() = %+v", config)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package bridge
+
+import (
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// OpenAITool is a function tool of the OpenAI chat completions API. It
+// marshals to the JSON the API expects in the tools array.
+type OpenAITool struct {
+	Type     string         `json:"type"`
+	Function OpenAIFunction `json:"function"`
+}
+
+type OpenAIFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// AnthropicTool is a client tool of the Anthropic messages API.
+type AnthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+func OpenAITools(tools []*mcp.Tool) ([]OpenAITool, error) {
+	var out []OpenAITool
+	for _, tool := range tools {
+		schema, err := ResolveSchema(tool)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, OpenAITool{
+			Type: "function",
+			Function: OpenAIFunction{
+				Name:        tool.Name,
+				Description: description(tool),
+				Parameters:  schema,
+			},
+		})
+	}
+	return out, nil
+}
+
+func AnthropicTools(tools []*mcp.Tool) ([]AnthropicTool, error) {
+	var out []AnthropicTool
+	for _, tool := range tools {
+		schema, err := ResolveSchema(tool)
+		if err != nil {
+			return nil, err
+		}
+		// Anthropic requires an object schema at the top level.
+		if _, ok := schema["type"]; !ok {
+			schema["type"] = "object"
+		}
+		out = append(out, AnthropicTool{
+			Name:        tool.Name,
+			Description: description(tool),
+			InputSchema: schema,
+		})
+	}
+	return out, nil
+}
+
+// description falls back to the tool title, since some providers reject
+// declarations without a description.
+func description(tool *mcp.Tool) string {
+	if tool.Description != "" {
+		return tool.Description
+	}
+	if tool.Title != "" {
+		return tool.Title
+	}
+	return tool.Name
+}
@@ -0,0 +1,69 @@
+package bridge
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"google.golang.org/genai"
+)
+
+func TestDeclarations(t *testing.T) {
+	tools := []*mcp.Tool{
+		{
+			Name:        "get_forecast",
+			Description: "Forecast for a city",
+			InputSchema: decode(t, `{"type": "object", "properties": {"city": {"$ref": "#/$defs/city"}}, "$defs": {"city": {"type": "string"}}}`),
+		},
+		// Without a type, a description or a schema at all.
+		{Name: "ping", Title: "Ping the server", InputSchema: decode(t, `{"properties": {}}`)},
+		{Name: "noop"},
+	}
+	citySchema := map[string]any{"type": "object", "properties": map[string]any{"city": map[string]any{"type": "string"}}}
+
+	openAI, err := OpenAITools(tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := openAI[0]; got.Type != "function" || got.Function.Description != "Forecast for a city" || !reflect.DeepEqual(got.Function.Parameters, citySchema) {
+		t.Errorf("OpenAITools()[0] = %+v", got)
+	}
+	if got := openAI[1].Function; got.Description != "Ping the server" || got.Parameters["type"] != nil {
+		t.Errorf("OpenAITools()[1] = %+v", got)
+	}
+
+	anthropic, err := AnthropicTools(tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := anthropic[0]; !reflect.DeepEqual(got.InputSchema, citySchema) {
+		t.Errorf("AnthropicTools()[0] = %+v", got)
+	}
+	for _, got := range anthropic[1:] {
+		if got.InputSchema["type"] != "object" {
+			t.Errorf("AnthropicTools() input schema of %s = %v, want an object", got.Name, got.InputSchema)
+		}
+	}
+	if got := anthropic[2].Description; got != "noop" {
+		t.Errorf("description without description and title = %q, want the name", got)
+	}
+
+	gemini, err := GeminiDeclarations(tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gemini[0].Parameters; got.Type != genai.TypeObject || got.Properties["city"].Type != genai.TypeString {
+		t.Errorf("GeminiDeclarations()[0] parameters = %+v", got)
+	}
+
+	bad := []*mcp.Tool{{Name: "bad", InputSchema: decode(t, `{"$ref": "#/$defs/missing"}`)}}
+	if _, err := OpenAITools(bad); err == nil {
+		t.Error("OpenAITools() accepted a broken reference")
+	}
+	if _, err := AnthropicTools(bad); err == nil {
+		t.Error("AnthropicTools() accepted a broken reference")
+	}
+	if _, err := GeminiDeclarations(bad); err == nil {
+		t.Error("GeminiDeclarations() accepted a broken reference")
+	}
+}
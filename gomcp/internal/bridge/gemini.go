@@ -0,0 +1,277 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"google.golang.org/genai"
+)
+
+func GeminiDeclarations(tools []*mcp.Tool) ([]*genai.FunctionDeclaration, error) {
+	var out []*genai.FunctionDeclaration
+	for _, tool := range tools {
+		schema, err := ResolveSchema(tool)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, &genai.FunctionDeclaration{
+			Name:        tool.Name,
+			Description: description(tool),
+			Parameters:  GeminiSchema(schema),
+		})
+	}
+	return out, nil
+}
+
+// GeminiSchema converts a resolved JSON schema to the OpenAPI subset Gemini
+// accepts. Nullable types become Nullable and keywords without an equivalent,
+// like additionalProperties, are dropped.
+func GeminiSchema(schema map[string]any) *genai.Schema {
+	out := &genai.Schema{}
+
+	switch t := schema["type"].(type) {
+	case string:
+		out.Type = geminiType(t)
+	case []any:
+		for _, t := range t {
+			if t == "null" {
+				out.Nullable = genai.Ptr(true)
+			} else if name, ok := t.(string); ok && out.Type == "" {
+				out.Type = geminiType(name)
+			}
+		}
+	}
+
+	out.Title, _ = schema["title"].(string)
+	out.Description, _ = schema["description"].(string)
+	out.Pattern, _ = schema["pattern"].(string)
+	if format, ok := schema["format"].(string); ok && geminiFormat(out.Type, format) {
+		out.Format = format
+	}
+	out.Default = schema["default"]
+
+	if enum, ok := schema["enum"]; ok {
+		out.Enum = stringSlice(enum)
+	} else if value, ok := schema["const"]; ok {
+		out.Enum = stringSlice([]any{value})
+	}
+	if out.Enum != nil && out.Type == "" {
+		out.Type = genai.TypeString
+	}
+	if out.Enum != nil && out.Type == genai.TypeString {
+		out.Format = "enum"
+	}
+
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		out.Properties = make(map[string]*genai.Schema, len(properties))
+		for _, name := range sortedKeys(properties) {
+			if property, ok := properties[name].(map[string]any); ok {
+				out.Properties[name] = GeminiSchema(property)
+				out.PropertyOrdering = append(out.PropertyOrdering, name)
+			}
+		}
+		if out.Type == "" {
+			out.Type = genai.TypeObject
+		}
+	}
+	out.Required = stringSlice(schema["required"])
+
+	if items, ok := schema["items"].(map[string]any); ok {
+		out.Items = GeminiSchema(items)
+		if out.Type == "" {
+			out.Type = genai.TypeArray
+		}
+	}
+
+	out.Minimum = floatValue(schema["minimum"])
+	out.Maximum = floatValue(schema["maximum"])
+	out.MinItems = intValue(schema["minItems"])
+	out.MaxItems = intValue(schema["maxItems"])
+	out.MinLength = intValue(schema["minLength"])
+	out.MaxLength = intValue(schema["maxLength"])
+
+	// Branches are merged last, so the schema's own keywords win over theirs.
+	if branches, ok := schema["anyOf"].([]any); ok {
+		out.AnyOf = geminiBranches(out, branches)
+	} else if branches, ok := schema["oneOf"].([]any); ok {
+		out.AnyOf = geminiBranches(out, branches)
+	}
+	if branches, ok := schema["allOf"].([]any); ok {
+		// All branches must hold, which for objects means all their
+		// properties are merged.
+		for _, branch := range branches {
+			if branch, ok := branch.(map[string]any); ok {
+				mergeGeminiSchema(out, GeminiSchema(branch))
+			}
+		}
+	}
+	return out
+}
+
+// geminiBranches converts anyOf/oneOf. A null branch makes the schema
+// nullable, and a single remaining branch is merged into the schema itself.
+func geminiBranches(out *genai.Schema, branches []any) []*genai.Schema {
+	var schemas []*genai.Schema
+	for _, branch := range branches {
+		branch, ok := branch.(map[string]any)
+		if !ok {
+			continue
+		}
+		if branch["type"] == "null" {
+			out.Nullable = genai.Ptr(true)
+			continue
+		}
+		schemas = append(schemas, GeminiSchema(branch))
+	}
+	if len(schemas) == 1 {
+		mergeGeminiSchema(out, schemas[0])
+		return nil
+	}
+	return schemas
+}
+
+func mergeGeminiSchema(dst, src *genai.Schema) {
+	if dst.Type == "" {
+		dst.Type = src.Type
+	}
+	if dst.Description == "" {
+		dst.Description = src.Description
+	}
+	if dst.Format == "" {
+		dst.Format = src.Format
+	}
+	if dst.Enum == nil {
+		dst.Enum = src.Enum
+	}
+	if dst.Items == nil {
+		dst.Items = src.Items
+	}
+	if dst.Nullable == nil {
+		dst.Nullable = src.Nullable
+	}
+	for name, property := range src.Properties {
+		if dst.Properties == nil {
+			dst.Properties = map[string]*genai.Schema{}
+		}
+		if _, ok := dst.Properties[name]; !ok {
+			dst.Properties[name] = property
+			dst.PropertyOrdering = append(dst.PropertyOrdering, name)
+		}
+	}
+	dst.Required = append(dst.Required, src.Required...)
+}
+
+func geminiType(t string) genai.Type {
+	switch t {
+	case "object":
+		return genai.TypeObject
+	case "string":
+		return genai.TypeString
+	case "number":
+		return genai.TypeNumber
+	case "integer":
+		return genai.TypeInteger
+	case "boolean":
+		return genai.TypeBoolean
+	case "array":
+		return genai.TypeArray
+	case "null":
+		return genai.TypeNULL
+	}
+	return genai.TypeUnspecified
+}
+
+// geminiFormat reports whether Gemini accepts format for t; it rejects
+// declarations with other formats.
+func geminiFormat(t genai.Type, format string) bool {
+	switch t {
+	case genai.TypeString:
+		return format == "date-time"
+	case genai.TypeNumber:
+		return format == "float" || format == "double"
+	case genai.TypeInteger:
+		return format == "int32" || format == "int64"
+	}
+	return false
+}
+
+func floatValue(value any) *float64 {
+	if n, ok := value.(float64); ok {
+		return &n
+	}
+	return nil
+}
+
+func intValue(value any) *int64 {
+	if n, ok := value.(float64); ok {
+		i := int64(n)
+		return &i
+	}
+	return nil
+}
+
+// GeminiChat is a Chat with a Gemini model. Add the user's message with
+// AddText before running it in a Loop; History holds the conversation.
+type GeminiChat struct {
+	Client  *genai.Client
+	Model   string
+	Config  *genai.GenerateContentConfig
+	History []*genai.Content
+}
+
+func (c *GeminiChat) AddText(text string) {
+	c.History = append(c.History, genai.NewContentFromText(text, genai.RoleUser))
+}
+
+func (c *GeminiChat) Generate(ctx context.Context, tools []*mcp.Tool, results []ToolResult) (string, []ToolCall, error) {
+	if len(results) > 0 {
+		var parts []*genai.Part
+		for _, result := range results {
+			parts = append(parts, &genai.Part{FunctionResponse: &genai.FunctionResponse{
+				ID:       result.Call.ID,
+				Name:     result.Call.Name,
+				Response: result.Response,
+			}})
+		}
+		c.History = append(c.History, genai.NewContentFromParts(parts, genai.RoleUser))
+	}
+
+	declarations, err := GeminiDeclarations(tools)
+	if err != nil {
+		return "", nil, err
+	}
+	config := &genai.GenerateContentConfig{}
+	if c.Config != nil {
+		*config = *c.Config
+	}
+	config.Tools = append(config.Tools[:len(config.Tools):len(config.Tools)], &genai.Tool{FunctionDeclarations: declarations})
+
+	resp, err := c.Client.Models.GenerateContent(ctx, c.Model, c.History, config)
+	if err != nil {
+		return "", nil, fmt.Errorf("generate content: %w", err)
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return "", nil, fmt.Errorf("model returned no candidate")
+	}
+
+	content := resp.Candidates[0].Content
+	c.History = append(c.History, content)
+
+	var text strings.Builder
+	var calls []ToolCall
+	for _, part := range content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			calls = append(calls, ToolCall{
+				ID:   part.FunctionCall.ID,
+				Name: part.FunctionCall.Name,
+				Args: part.FunctionCall.Args,
+			})
+		case part.Text != "" && !part.Thought:
+			text.WriteString(part.Text)
+		}
+	}
+	return text.String(), calls, nil
+}
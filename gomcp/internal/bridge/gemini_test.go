@@ -0,0 +1,100 @@
+package bridge
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestGeminiSchema(t *testing.T) {
+	tests := []struct {
+		name, schema string
+		want         *genai.Schema
+	}{
+		{
+			name:   "nested objects",
+			schema: `{"type": "object", "properties": {"location": {"type": "object", "properties": {"lon": {"type": "number", "minimum": -180, "maximum": 180}, "lat": {"type": "number"}}, "required": ["lat", "lon"], "additionalProperties": false}}}`,
+			want: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"location": {
+						Type: genai.TypeObject,
+						Properties: map[string]*genai.Schema{
+							"lat": {Type: genai.TypeNumber},
+							"lon": {Type: genai.TypeNumber, Minimum: genai.Ptr(-180.0), Maximum: genai.Ptr(180.0)},
+						},
+						PropertyOrdering: []string{"lat", "lon"},
+						Required:         []string{"lat", "lon"},
+					},
+				},
+				PropertyOrdering: []string{"location"},
+			},
+		},
+		{
+			name:   "enum",
+			schema: `{"enum": ["metric", "imperial"], "default": "metric"}`,
+			want:   &genai.Schema{Type: genai.TypeString, Format: "enum", Enum: []string{"metric", "imperial"}, Default: "metric"},
+		},
+		{
+			name:   "const",
+			schema: `{"const": 3}`,
+			want:   &genai.Schema{Type: genai.TypeString, Format: "enum", Enum: []string{"3"}},
+		},
+		{
+			name:   "nullable type",
+			schema: `{"type": ["null", "integer"], "format": "int64"}`,
+			want:   &genai.Schema{Type: genai.TypeInteger, Format: "int64", Nullable: genai.Ptr(true)},
+		},
+		{
+			name:   "unsupported format",
+			schema: `{"type": "string", "format": "email", "minLength": 3}`,
+			want:   &genai.Schema{Type: genai.TypeString, MinLength: genai.Ptr[int64](3)},
+		},
+		{
+			name:   "array of objects",
+			schema: `{"items": {"properties": {"city": {"type": "string", "format": "date-time"}}}, "maxItems": 5}`,
+			want: &genai.Schema{
+				Type: genai.TypeArray,
+				Items: &genai.Schema{
+					Type:             genai.TypeObject,
+					Properties:       map[string]*genai.Schema{"city": {Type: genai.TypeString, Format: "date-time"}},
+					PropertyOrdering: []string{"city"},
+				},
+				MaxItems: genai.Ptr[int64](5),
+			},
+		},
+		{
+			// The schema's own description wins over the merged branch's.
+			name:   "anyOf with null",
+			schema: `{"description": "Units", "anyOf": [{"enum": ["metric", "imperial"], "description": "A system"}, {"type": "null"}]}`,
+			want:   &genai.Schema{Type: genai.TypeString, Format: "enum", Enum: []string{"metric", "imperial"}, Description: "Units", Nullable: genai.Ptr(true)},
+		},
+		{
+			name:   "oneOf",
+			schema: `{"oneOf": [{"type": "string"}, {"type": "integer"}]}`,
+			want:   &genai.Schema{AnyOf: []*genai.Schema{{Type: genai.TypeString}, {Type: genai.TypeInteger}}},
+		},
+		{
+			name:   "allOf",
+			schema: `{"properties": {"a": {"type": "string"}}, "required": ["a"], "allOf": [{"properties": {"b": {"type": "integer"}}, "required": ["b"]}, {"properties": {"a": {"type": "integer"}}}]}`,
+			want: &genai.Schema{
+				Type:             genai.TypeObject,
+				Properties:       map[string]*genai.Schema{"a": {Type: genai.TypeString}, "b": {Type: genai.TypeInteger}},
+				PropertyOrdering: []string{"a", "b"},
+				Required:         []string{"a", "b"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := GeminiSchema(decode(t, test.schema))
+			if !reflect.DeepEqual(got, test.want) {
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(test.want)
+				t.Errorf("GeminiSchema() = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ErrMaxTurns is returned when the model still calls tools after the last
+// turn of a Loop.
+var ErrMaxTurns = errors.New("maximum number of tool turns reached")
+
+type ToolCall struct {
+	// ID correlates the result with the call for providers that need it.
+	ID   string
+	Name string
+	Args map[string]any
+}
+
+type ToolResult struct {
+	Call     ToolCall
+	Response map[string]any
+	IsError  bool
+}
+
+// Chat is a conversation with a model that can call tools.
+type Chat interface {
+	// Generate hands the model the results of its previous tool calls, none
+	// on the first turn, and returns its answer and the tools it wants to
+	// call next. tools is the current tool list of the MCP session.
+	Generate(ctx context.Context, tools []*mcp.Tool, results []ToolResult) (string, []ToolCall, error)
+}
+
+// Loop runs the tool calls of a model on an MCP session until the model
+// answers without calling a tool.
+type Loop struct {
+	Session *mcp.ClientSession
+	Tools   *ToolSet
+	// MaxTurns limits the number of model requests, 5 by default.
+	MaxTurns int
+	// Timeout limits the whole run, 2 minutes by default.
+	Timeout time.Duration
+	// ToolTimeout limits each tool call, 30 seconds by default.
+	ToolTimeout time.Duration
+	// OnToolCall, if set, is called before each tool call.
+	OnToolCall func(turn int, call ToolCall)
+}
+
+// Run returns the model's final answer. With ErrMaxTurns, the answer is the
+// text of the last turn.
+func (l *Loop) Run(ctx context.Context, chat Chat) (string, error) {
+	maxTurns := l.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = 5
+	}
+	timeout := l.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var (
+		text    string
+		results []ToolResult
+	)
+	for turn := 1; turn <= maxTurns; turn++ {
+		tools, _ := l.Tools.Tools()
+
+		var calls []ToolCall
+		var err error
+		text, calls, err = chat.Generate(ctx, tools, results)
+		if err != nil {
+			return "", fmt.Errorf("turn %d: %w", turn, err)
+		}
+		if len(calls) == 0 {
+			return text, nil
+		}
+
+		results = results[:0]
+		for _, call := range calls {
+			if l.OnToolCall != nil {
+				l.OnToolCall(turn, call)
+			}
+			result, err := l.call(ctx, call)
+			if err != nil {
+				return "", fmt.Errorf("turn %d: %w", turn, err)
+			}
+			results = append(results, result)
+		}
+	}
+	return text, ErrMaxTurns
+}
+
+// call executes one tool call. Failures the model can react to, like unknown
+// tools or invalid arguments, become error results; only the end of the run
+// is returned as an error.
+func (l *Loop) call(ctx context.Context, call ToolCall) (ToolResult, error) {
+	if _, ok := l.Tools.Lookup(call.Name); !ok {
+		return errorResult(call, fmt.Sprintf("unknown tool %q", call.Name)), nil
+	}
+
+	toolTimeout := l.ToolTimeout
+	if toolTimeout <= 0 {
+		toolTimeout = 30 * time.Second
+	}
+	callCtx, cancel := context.WithTimeout(ctx, toolTimeout)
+	defer cancel()
+
+	res, err := l.Session.CallTool(callCtx, &mcp.CallToolParams{
+		Name:      call.Name,
+		Arguments: call.Args,
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return ToolResult{}, ctx.Err()
+		}
+		return errorResult(call, err.Error()), nil
+	}
+
+	text := resultText(res)
+	if res.IsError {
+		return errorResult(call, text), nil
+	}
+	if structured, ok := res.StructuredContent.(map[string]any); ok {
+		return ToolResult{Call: call, Response: structured}, nil
+	}
+	return ToolResult{Call: call, Response: map[string]any{"result": text}}, nil
+}
+
+func errorResult(call ToolCall, message string) ToolResult {
+	return ToolResult{Call: call, Response: map[string]any{"error": message}, IsError: true}
+}
+
+func resultText(res *mcp.CallToolResult) string {
+	var texts []string
+	for _, content := range res.Content {
+		switch content := content.(type) {
+		case *mcp.TextContent:
+			texts = append(texts, content.Text)
+		case *mcp.ResourceLink:
+			texts = append(texts, content.URI)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
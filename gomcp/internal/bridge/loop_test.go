@@ -0,0 +1,197 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// scriptedChat answers with its turns in order and records the tool results
+// it is handed.
+type scriptedChat struct {
+	turns   []scriptedTurn
+	results [][]ToolResult
+}
+
+type scriptedTurn struct {
+	text  string
+	calls []ToolCall
+}
+
+func (c *scriptedChat) Generate(_ context.Context, tools []*mcp.Tool, results []ToolResult) (string, []ToolCall, error) {
+	if len(tools) == 0 {
+		return "", nil, errors.New("no tools")
+	}
+	c.results = append(c.results, append([]ToolResult(nil), results...))
+	if len(c.results) > len(c.turns) {
+		return "", nil, errors.New("out of turns")
+	}
+	turn := c.turns[len(c.results)-1]
+	return turn.text, turn.calls, nil
+}
+
+// newTestLoop connects to an in-memory server with an add tool, a failing
+// tool and a tool that blocks until it is cancelled.
+func newTestLoop(t *testing.T) *Loop {
+	t.Helper()
+	ctx := t.Context()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	type addArgs struct {
+		A int `json:"a"`
+		B int `json:"b"`
+	}
+	type sum struct {
+		Sum int `json:"sum"`
+	}
+	mcp.AddTool(server, &mcp.Tool{Name: "add"}, func(_ context.Context, _ *mcp.CallToolRequest, args addArgs) (*mcp.CallToolResult, sum, error) {
+		return nil, sum{Sum: args.A + args.B}, nil
+	})
+	mcp.AddTool(server, &mcp.Tool{Name: "fail"}, func(context.Context, *mcp.CallToolRequest, any) (*mcp.CallToolResult, any, error) {
+		return nil, nil, errors.New("station offline")
+	})
+	mcp.AddTool(server, &mcp.Tool{Name: "block"}, func(ctx context.Context, _ *mcp.CallToolRequest, _ any) (*mcp.CallToolResult, any, error) {
+		<-ctx.Done()
+		return nil, nil, ctx.Err()
+	})
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatal(err)
+	}
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { session.Close() })
+
+	tools := NewToolSet()
+	if err := tools.Load(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	return &Loop{Session: session, Tools: tools}
+}
+
+func TestLoop(t *testing.T) {
+	add := ToolCall{ID: "1", Name: "add", Args: map[string]any{"a": 2, "b": 3}}
+	unknown := ToolCall{ID: "2", Name: "subtract"}
+	fail := ToolCall{ID: "3", Name: "fail"}
+	badArgs := ToolCall{ID: "4", Name: "add", Args: map[string]any{"a": "two"}}
+
+	loop := newTestLoop(t)
+	var called []string
+	loop.OnToolCall = func(turn int, call ToolCall) {
+		called = append(called, call.Name)
+	}
+	chat := &scriptedChat{turns: []scriptedTurn{
+		{calls: []ToolCall{add, unknown}},
+		{text: "Let me retry.", calls: []ToolCall{fail, badArgs}},
+		{text: "2 + 3 = 5"},
+	}}
+	answer, err := loop.Run(t.Context(), chat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer != "2 + 3 = 5" {
+		t.Errorf("answer %q", answer)
+	}
+	if want := []string{"add", "subtract", "fail", "add"}; !reflect.DeepEqual(called, want) {
+		t.Errorf("OnToolCall got %v, want %v", called, want)
+	}
+
+	// Structured content is handed to the model as is, and failures the model
+	// can react to become error results.
+	if len(chat.results[0]) != 0 {
+		t.Errorf("first turn got results %+v", chat.results[0])
+	}
+	second := chat.results[1]
+	if len(second) != 2 || second[0].Call.ID != "1" || second[0].IsError || !reflect.DeepEqual(second[0].Response, map[string]any{"sum": 5.0}) {
+		t.Errorf("second turn results %+v", second)
+	}
+	if second[1].Call.ID != "2" || !second[1].IsError || second[1].Response["error"] != `unknown tool "subtract"` {
+		t.Errorf("unknown tool result %+v", second[1])
+	}
+	third := chat.results[2]
+	if len(third) != 2 || !third[0].IsError || third[0].Response["error"] != "station offline" {
+		t.Errorf("failed tool result %+v", third)
+	}
+	if !third[1].IsError || !strings.Contains(third[1].Response["error"].(string), `validating "arguments"`) {
+		t.Errorf("invalid arguments result %+v", third[1])
+	}
+}
+
+func TestLoopGuards(t *testing.T) {
+	add := ToolCall{Name: "add", Args: map[string]any{"a": 1, "b": 1}}
+	block := ToolCall{Name: "block"}
+
+	t.Run("max turns", func(t *testing.T) {
+		loop := newTestLoop(t)
+		loop.MaxTurns = 2
+		chat := &scriptedChat{turns: []scriptedTurn{
+			{text: "one", calls: []ToolCall{add}},
+			{text: "two", calls: []ToolCall{add}},
+			{text: "never"},
+		}}
+		answer, err := loop.Run(t.Context(), chat)
+		if !errors.Is(err, ErrMaxTurns) || answer != "two" {
+			t.Errorf("Run() = %q, %v, want the last text with ErrMaxTurns", answer, err)
+		}
+		if len(chat.results) != 2 {
+			t.Errorf("%d model requests, want 2", len(chat.results))
+		}
+	})
+
+	t.Run("default max turns", func(t *testing.T) {
+		loop := newTestLoop(t)
+		chat := &scriptedChat{}
+		for range 10 {
+			chat.turns = append(chat.turns, scriptedTurn{calls: []ToolCall{add}})
+		}
+		if _, err := loop.Run(t.Context(), chat); !errors.Is(err, ErrMaxTurns) || len(chat.results) != 5 {
+			t.Errorf("Run() error = %v after %d requests, want ErrMaxTurns after 5", err, len(chat.results))
+		}
+	})
+
+	t.Run("tool timeout", func(t *testing.T) {
+		loop := newTestLoop(t)
+		loop.ToolTimeout = 50 * time.Millisecond
+		chat := &scriptedChat{turns: []scriptedTurn{
+			{calls: []ToolCall{block}},
+			{text: "gave up"},
+		}}
+		answer, err := loop.Run(t.Context(), chat)
+		if err != nil || answer != "gave up" {
+			t.Fatalf("Run() = %q, %v, want the answer after the timed out call", answer, err)
+		}
+		if result := chat.results[1][0]; !result.IsError {
+			t.Errorf("timed out call result %+v, want an error", result)
+		}
+	})
+
+	t.Run("run timeout", func(t *testing.T) {
+		loop := newTestLoop(t)
+		loop.Timeout = 50 * time.Millisecond
+		chat := &scriptedChat{turns: []scriptedTurn{{calls: []ToolCall{block}}}}
+		start := time.Now()
+		_, err := loop.Run(t.Context(), chat)
+		if !errors.Is(err, context.DeadlineExceeded) || !strings.HasPrefix(err.Error(), "turn 1: ") {
+			t.Errorf("Run() error = %v, want the deadline of turn 1", err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("Run() took %s", elapsed)
+		}
+	})
+
+	t.Run("model error", func(t *testing.T) {
+		loop := newTestLoop(t)
+		chat := &scriptedChat{turns: []scriptedTurn{{calls: []ToolCall{add}}}}
+		if _, err := loop.Run(t.Context(), chat); err == nil || err.Error() != "turn 2: out of turns" {
+			t.Errorf("Run() error = %v, want the model's error", err)
+		}
+	})
+}
@@ -0,0 +1,178 @@
+// Package bridge connects MCP tools to LLM function calling: it converts tool
+// input schemas to the declarations of the model providers and runs the loop
+// that executes the model's tool calls on an MCP session.
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxRefDepth stops the expansion of recursive schemas. Function declarations
+// cannot express recursion, so deeper levels become plain objects.
+const maxRefDepth = 3
+
+// ResolveSchema returns a tool's input schema as a self-contained JSON schema:
+// $ref pointers into $defs or definitions are inlined, so providers that do
+// not support references can use it.
+func ResolveSchema(tool *mcp.Tool) (map[string]any, error) {
+	if tool.InputSchema == nil {
+		return map[string]any{"type": "object"}, nil
+	}
+
+	var root map[string]any
+	switch schema := tool.InputSchema.(type) {
+	case map[string]any:
+		root = schema
+	default:
+		// Server-side tools hold a *jsonschema.Schema.
+		data, err := json.Marshal(schema)
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: marshal input schema: %w", tool.Name, err)
+		}
+		if err := json.Unmarshal(data, &root); err != nil {
+			return nil, fmt.Errorf("tool %s: input schema is not an object: %w", tool.Name, err)
+		}
+	}
+
+	r := resolver{root: root}
+	resolved, err := r.resolve(root, 0)
+	if err != nil {
+		return nil, fmt.Errorf("tool %s: %w", tool.Name, err)
+	}
+	return resolved, nil
+}
+
+type resolver struct {
+	root map[string]any
+}
+
+func (r resolver) resolve(schema map[string]any, depth int) (map[string]any, error) {
+	if ref, ok := schema["$ref"].(string); ok {
+		if depth >= maxRefDepth {
+			return map[string]any{"type": "object", "description": "(nested too deeply)"}, nil
+		}
+		target, err := r.lookup(ref)
+		if err != nil {
+			return nil, err
+		}
+		// Keywords next to $ref, usually a description, override the target.
+		merged := maps.Clone(target)
+		for key, value := range schema {
+			if key != "$ref" {
+				merged[key] = value
+			}
+		}
+		return r.resolve(merged, depth+1)
+	}
+
+	out := make(map[string]any, len(schema))
+	for key, value := range schema {
+		switch key {
+		case "$defs", "definitions", "$schema", "$id":
+			continue
+		case "properties":
+			properties, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("properties must be an object")
+			}
+			resolved := make(map[string]any, len(properties))
+			for name, property := range properties {
+				child, ok := property.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("property %s must be a schema object", name)
+				}
+				var err error
+				if resolved[name], err = r.resolve(child, depth); err != nil {
+					return nil, fmt.Errorf("property %s: %w", name, err)
+				}
+			}
+			out[key] = resolved
+		case "items":
+			child, ok := value.(map[string]any)
+			if !ok {
+				// Tuple validation has no equivalent in function declarations.
+				continue
+			}
+			resolved, err := r.resolve(child, depth)
+			if err != nil {
+				return nil, fmt.Errorf("items: %w", err)
+			}
+			out[key] = resolved
+		case "anyOf", "oneOf", "allOf":
+			branches, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s must be an array", key)
+			}
+			resolved := make([]any, len(branches))
+			for i, branch := range branches {
+				child, ok := branch.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("%s[%d] must be a schema object", key, i)
+				}
+				var err error
+				if resolved[i], err = r.resolve(child, depth); err != nil {
+					return nil, fmt.Errorf("%s[%d]: %w", key, i, err)
+				}
+			}
+			out[key] = resolved
+		default:
+			out[key] = value
+		}
+	}
+	return out, nil
+}
+
+func (r resolver) lookup(ref string) (map[string]any, error) {
+	pointer, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil, fmt.Errorf("unsupported $ref %q: only local references are resolved", ref)
+	}
+
+	var node any = r.root
+	for token := range strings.SplitSeq(pointer, "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		object, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("$ref %q does not point into an object", ref)
+		}
+		if node, ok = object[token]; !ok {
+			return nil, fmt.Errorf("$ref %q not found", ref)
+		}
+	}
+
+	target, ok := node.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("$ref %q is not a schema object", ref)
+	}
+	return target, nil
+}
+
+// stringSlice converts the []any of decoded JSON arrays like enum and
+// required.
+func stringSlice(value any) []string {
+	if values, ok := value.([]string); ok {
+		return values
+	}
+	items, _ := value.([]any)
+	var out []string
+	for _, item := range items {
+		switch item := item.(type) {
+		case string:
+			out = append(out, item)
+		case nil:
+		default:
+			out = append(out, fmt.Sprint(item))
+		}
+	}
+	return out
+}
+
+func sortedKeys(m map[string]any) []string {
+	return slices.Sorted(maps.Keys(m))
+}
@@ -0,0 +1,131 @@
+package bridge
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// decode parses a JSON schema literal of a test case.
+func decode(t *testing.T, data string) map[string]any {
+	t.Helper()
+	var schema map[string]any
+	if err := json.Unmarshal([]byte(data), &schema); err != nil {
+		t.Fatalf("%s: %v", data, err)
+	}
+	return schema
+}
+
+func TestResolveSchema(t *testing.T) {
+	tests := []struct {
+		name, schema, want string
+	}{
+		{
+			name:   "nested objects and enums",
+			schema: `{"$schema": "https://json-schema.org/draft/2020-12/schema", "type": "object", "properties": {"location": {"type": "object", "properties": {"city": {"type": "string"}, "units": {"enum": ["metric", "imperial"]}}, "required": ["city"]}}}`,
+			want:   `{"type": "object", "properties": {"location": {"type": "object", "properties": {"city": {"type": "string"}, "units": {"enum": ["metric", "imperial"]}}, "required": ["city"]}}}`,
+		},
+		{
+			name:   "$defs",
+			schema: `{"type": "object", "properties": {"from": {"$ref": "#/$defs/date"}, "to": {"$ref": "#/$defs/date", "description": "Last day"}}, "$defs": {"date": {"type": "string", "description": "A day"}}}`,
+			want:   `{"type": "object", "properties": {"from": {"type": "string", "description": "A day"}, "to": {"type": "string", "description": "Last day"}}}`,
+		},
+		{
+			name:   "definitions",
+			schema: `{"type": "object", "properties": {"cities": {"type": "array", "items": {"$ref": "#/definitions/city"}}}, "definitions": {"city": {"type": "string"}}}`,
+			want:   `{"type": "object", "properties": {"cities": {"type": "array", "items": {"type": "string"}}}}`,
+		},
+		{
+			name:   "root $ref",
+			schema: `{"$ref": "#/$defs/query", "$defs": {"query": {"type": "object", "properties": {"q": {"type": "string"}}}}}`,
+			want:   `{"type": "object", "properties": {"q": {"type": "string"}}}`,
+		},
+		{
+			name:   "references in branches",
+			schema: `{"anyOf": [{"$ref": "#/$defs/city"}, {"type": "null"}], "$defs": {"city": {"type": "string"}}}`,
+			want:   `{"anyOf": [{"type": "string"}, {"type": "null"}]}`,
+		},
+		{
+			name:   "escaped pointer",
+			schema: `{"properties": {"a": {"$ref": "#/$defs/a~1b"}}, "$defs": {"a/b": {"type": "integer"}}}`,
+			want:   `{"properties": {"a": {"type": "integer"}}}`,
+		},
+		{
+			name:   "tuple items are dropped",
+			schema: `{"type": "array", "items": [{"type": "string"}]}`,
+			want:   `{"type": "array"}`,
+		},
+		{
+			// Recursion stops after maxRefDepth references on a path.
+			name:   "recursive reference",
+			schema: `{"$ref": "#/$defs/node", "$defs": {"node": {"type": "object", "properties": {"children": {"type": "array", "items": {"$ref": "#/$defs/node"}}}}}}`,
+			want: `{"type": "object", "properties": {"children": {"type": "array", "items":
+				{"type": "object", "properties": {"children": {"type": "array", "items":
+					{"type": "object", "properties": {"children": {"type": "array", "items":
+						{"type": "object", "description": "(nested too deeply)"}}}}}}}}}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ResolveSchema(&mcp.Tool{Name: "test", InputSchema: decode(t, test.schema)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := decode(t, test.want); !reflect.DeepEqual(got, want) {
+				data, _ := json.Marshal(got)
+				t.Errorf("ResolveSchema() = %s, want %s", data, test.want)
+			}
+		})
+	}
+}
+
+func TestResolveSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name, schema, err string
+	}{
+		{name: "remote reference", schema: `{"$ref": "https://example.com/schema.json"}`, err: "only local references"},
+		{name: "missing definition", schema: `{"properties": {"a": {"$ref": "#/$defs/missing"}}}`, err: `property a: $ref "#/$defs/missing" not found`},
+		{name: "reference to a value", schema: `{"properties": {"a": {"$ref": "#/$defs/a"}}, "$defs": {"a": true}}`, err: "is not a schema object"},
+		{name: "properties array", schema: `{"properties": []}`, err: "properties must be an object"},
+		{name: "property value", schema: `{"properties": {"a": "string"}}`, err: "property a must be a schema object"},
+		{name: "anyOf object", schema: `{"anyOf": {}}`, err: "anyOf must be an array"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ResolveSchema(&mcp.Tool{Name: "test", InputSchema: decode(t, test.schema)})
+			if err == nil || !strings.Contains(err.Error(), test.err) || !strings.HasPrefix(err.Error(), "tool test: ") {
+				t.Errorf("ResolveSchema() error = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestResolveSchemaServerTool(t *testing.T) {
+	// Tools of an in-process server hold a *jsonschema.Schema.
+	tool := &mcp.Tool{Name: "test", InputSchema: &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"city": {Ref: "#/$defs/city"},
+		},
+		Defs: map[string]*jsonschema.Schema{
+			"city": {Type: "string", MinLength: jsonschema.Ptr(1)},
+		},
+	}}
+	got, err := ResolveSchema(tool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := decode(t, `{"type": "object", "properties": {"city": {"type": "string", "minLength": 1}}}`)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveSchema() = %v, want %v", got, want)
+	}
+
+	got, err = ResolveSchema(&mcp.Tool{Name: "test"})
+	if err != nil || !reflect.DeepEqual(got, map[string]any{"type": "object"}) {
+		t.Errorf("ResolveSchema() without schema = %v, %v", got, err)
+	}
+}
@@ -0,0 +1,92 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ToolSet keeps the tool list of an MCP session current. Servers that load
+// their tools after the handshake announce them with a tools/list_changed
+// notification; install OnListChanged in the client options to follow them.
+type ToolSet struct {
+	mu      sync.Mutex
+	tools   []*mcp.Tool
+	version int
+	changed chan struct{}
+}
+
+func NewToolSet() *ToolSet {
+	return &ToolSet{changed: make(chan struct{})}
+}
+
+// OnListChanged is a ClientOptions.ToolListChangedHandler.
+func (s *ToolSet) OnListChanged(ctx context.Context, req *mcp.ToolListChangedRequest) {
+	if err := s.Load(ctx, req.Session); err != nil {
+		log.Printf("Failed to reload tools: %v", err)
+	}
+}
+
+// Load lists all tools of session, following pagination.
+func (s *ToolSet) Load(ctx context.Context, session *mcp.ClientSession) error {
+	var tools []*mcp.Tool
+	for tool, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			return fmt.Errorf("list tools: %w", err)
+		}
+		tools = append(tools, tool)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tools = tools
+	s.version++
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return nil
+}
+
+// Tools returns the current tools and a version that changes with every load.
+func (s *ToolSet) Tools() ([]*mcp.Tool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tools, s.version
+}
+
+// Lookup returns the current tool called name.
+func (s *ToolSet) Lookup(name string) (*mcp.Tool, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tool := range s.tools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return nil, false
+}
+
+// Wait loads the tools of session and, while there are none, waits for the
+// server to announce some. It replaces sleeping after connecting to servers
+// that start up slowly.
+func (s *ToolSet) Wait(ctx context.Context, session *mcp.ClientSession) ([]*mcp.Tool, error) {
+	if err := s.Load(ctx, session); err != nil {
+		return nil, err
+	}
+	for {
+		s.mu.Lock()
+		tools, changed := s.tools, s.changed
+		s.mu.Unlock()
+		if len(tools) > 0 {
+			return tools, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for tools: %w", ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTLCache(t *testing.T) {
+	start := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	now := start
+	cache := newTTLCache[int](time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.put("a", 1)
+	now = start.Add(30 * time.Second)
+	cache.put("b", 2)
+
+	steps := []struct {
+		at   time.Duration
+		key  string
+		want int
+		ok   bool
+	}{
+		{at: 59 * time.Second, key: "a", want: 1, ok: true},
+		{at: time.Minute, key: "a"},
+		{at: time.Minute, key: "b", want: 2, ok: true},
+		{at: 90 * time.Second, key: "b"},
+		{at: 0, key: "missing"},
+	}
+	for _, step := range steps {
+		now = start.Add(step.at)
+		if got, ok := cache.get(step.key); got != step.want || ok != step.ok {
+			t.Errorf("get(%s) at %s = %d, %t, want %d, %t", step.key, step.at, got, ok, step.want, step.ok)
+		}
+	}
+}
+
+func TestTTLCacheEvictsExpiredEntries(t *testing.T) {
+	start := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	now := start
+	cache := newTTLCache[int](time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.put("a", 1)
+	cache.put("b", 2)
+	now = start.Add(time.Minute)
+	cache.put("c", 3)
+	if len(cache.entries) != 1 {
+		t.Errorf("%d entries after adding one to expired ones, want 1", len(cache.entries))
+	}
+
+	// Putting a key again restarts its time to live.
+	now = start.Add(90 * time.Second)
+	cache.put("c", 4)
+	now = start.Add(2*time.Minute + 30*time.Second - time.Nanosecond)
+	if got, ok := cache.get("c"); got != 4 || !ok {
+		t.Errorf("get(c) = %d, %t, want 4, true", got, ok)
+	}
+}
+
+func TestTTLCacheDisabled(t *testing.T) {
+	cache := newTTLCache[int](0)
+	cache.put("a", 1)
+	if _, ok := cache.get("a"); ok || len(cache.entries) != 0 {
+		t.Error("a cache without TTL kept an entry")
+	}
+}
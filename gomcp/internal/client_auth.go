@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/oauthex"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const (
+	ServerURLEnv    = "MCP_SERVER_URL"
+	APIKeyEnv       = "MCP_API_KEY"
+	BearerTokenEnv  = "MCP_BEARER_TOKEN"
+	ClientIDEnv     = "MCP_CLIENT_ID"
+	ClientSecretEnv = "MCP_CLIENT_SECRET"
+	ClientScopesEnv = "MCP_CLIENT_SCOPES"
+)
+
+// ServerURLFromEnv returns MCP_SERVER_URL, http://localhost:8080 by default.
+func ServerURLFromEnv() string {
+	if url := os.Getenv(ServerURLEnv); url != "" {
+		return url
+	}
+	return "http://localhost:8080"
+}
+
+// NewAuthHTTPClient returns the HTTP client for connecting to serverURL with
+// the credentials from the environment, in order of preference:
+//
+//   - MCP_CLIENT_ID and MCP_CLIENT_SECRET: OAuth client credentials. The token
+//     endpoint is discovered from the server's protected resource metadata.
+//   - MCP_BEARER_TOKEN: a token obtained elsewhere.
+//   - MCP_API_KEY: sent in the X-API-Key header.
+//
+// Without credentials the client connects anonymously.
+func NewAuthHTTPClient(ctx context.Context, serverURL string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = 10 * time.Second
+	// Only connecting is bounded. A client timeout would also cut the body:
+	// the SSE stream of the streamable transport and every tool call that
+	// runs longer. The server flushes the headers of a call with its first
+	// message, so a response header timeout would cut long calls as well.
+	base := &http.Client{Transport: transport}
+
+	if clientID := os.Getenv(ClientIDEnv); clientID != "" {
+		tokenURL, err := discoverTokenEndpoint(ctx, base, serverURL)
+		if err != nil {
+			return nil, err
+		}
+		config := &clientcredentials.Config{
+			ClientID:       clientID,
+			ClientSecret:   os.Getenv(ClientSecretEnv),
+			TokenURL:       tokenURL,
+			Scopes:         strings.Fields(os.Getenv(ClientScopesEnv)),
+			EndpointParams: map[string][]string{"resource": {serverURL}},
+		}
+		// The token source caches the token and fetches a new one when it
+		// expires.
+		return oauth2.NewClient(context.WithValue(ctx, oauth2.HTTPClient, base), config.TokenSource(ctx)), nil
+	}
+
+	if token := os.Getenv(BearerTokenEnv); token != "" {
+		base.Transport = headerTransport{base: transport, name: "Authorization", value: "Bearer " + token}
+		return base, nil
+	}
+	if key := os.Getenv(APIKeyEnv); key != "" {
+		base.Transport = headerTransport{base: transport, name: APIKeyHeader, value: key}
+		return base, nil
+	}
+	return base, nil
+}
+
+// discoverTokenEndpoint follows the MCP authorization spec: the protected
+// resource metadata names the authorization server, whose metadata names the
+// token endpoint.
+func discoverTokenEndpoint(ctx context.Context, client *http.Client, serverURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	metadataURL := strings.TrimSuffix(serverURL, "/") + ProtectedResourceMetadataPath
+	resource, err := oauthex.GetProtectedResourceMetadata(ctx, metadataURL, serverURL, client)
+	if err != nil {
+		return "", fmt.Errorf("get protected resource metadata: %w", err)
+	}
+	if len(resource.AuthorizationServers) == 0 {
+		return "", fmt.Errorf("%s names no authorization server", metadataURL)
+	}
+
+	issuer := resource.AuthorizationServers[0]
+	var meta oauthex.AuthServerMeta
+	if err := getJSONDocument(ctx, client, AuthServerMetadataURL(issuer), &meta); err != nil {
+		return "", fmt.Errorf("get authorization server metadata: %w", err)
+	}
+	if meta.TokenEndpoint == "" {
+		return "", fmt.Errorf("authorization server %s publishes no token_endpoint", issuer)
+	}
+	return meta.TokenEndpoint, nil
+}
+
+type headerTransport struct {
+	base        http.RoundTripper
+	name, value string
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(t.name, t.value)
+	return t.base.RoundTrip(req)
+}
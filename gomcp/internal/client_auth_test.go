@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewAuthHTTPClient(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		header, want string
+	}{
+		{name: "anonymous", header: "Authorization"},
+		{name: "bearer token", env: map[string]string{BearerTokenEnv: "token"}, header: "Authorization", want: "Bearer token"},
+		{name: "API key", env: map[string]string{APIKeyEnv: testAPIKey}, header: APIKeyHeader, want: testAPIKey},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, name := range []string{ClientIDEnv, BearerTokenEnv, APIKeyEnv} {
+				t.Setenv(name, test.env[name])
+			}
+
+			// The response body arrives after a pause, as a slow tool call
+			// or a quiet SSE stream would; the client must wait for it.
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Seen", r.Header.Get(test.header))
+				w.WriteHeader(http.StatusOK)
+				w.(http.Flusher).Flush()
+				time.Sleep(50 * time.Millisecond)
+				w.Write([]byte("done"))
+			}))
+			defer server.Close()
+
+			client, err := NewAuthHTTPClient(context.Background(), server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if client.Timeout != 0 {
+				t.Errorf("client timeout %s would cut long responses", client.Timeout)
+			}
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got := resp.Header.Get("X-Seen"); got != test.want {
+				t.Errorf("server saw %s %q, want %q", test.header, got, test.want)
+			}
+		})
+	}
+}
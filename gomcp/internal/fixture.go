@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+//go:embed fixtures/weather.json
+var defaultFixtures []byte
+
+type fixtureLocation struct {
+	Location
+	Current CurrentWeather `json:"current"`
+	Daily   []DailyWeather `json:"daily"`
+}
+
+type fixtureFile struct {
+	Locations []fixtureLocation `json:"locations"`
+}
+
+// FixtureProvider answers from a JSON file instead of the network, so the
+// servers and clients can run offline and give the same answers every time.
+type FixtureProvider struct {
+	locations []fixtureLocation
+}
+
+// NewFixtureProvider loads a fixture file, or the built-in fixtures when path
+// is empty.
+func NewFixtureProvider(path string) (*FixtureProvider, error) {
+	data := defaultFixtures
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read weather fixtures: %w", err)
+		}
+	}
+
+	var fixtures fixtureFile
+	if err := json.Unmarshal(data, &fixtures); err != nil {
+		return nil, fmt.Errorf("failed to parse weather fixtures: %w", err)
+	}
+	if len(fixtures.Locations) == 0 {
+		return nil, fmt.Errorf("weather fixtures contain no locations")
+	}
+
+	return &FixtureProvider{locations: fixtures.Locations}, nil
+}
+
+func (p *FixtureProvider) Geocode(_ context.Context, city, country string) (*Location, error) {
+	for _, fixture := range p.locations {
+		if strings.EqualFold(fixture.Name, city) && (country == "" || strings.EqualFold(fixture.Country, country)) {
+			location := fixture.Location
+			return &location, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrLocationNotFound, formatPlace(city, country))
+}
+
+// CurrentWeather returns the weather of the nearest fixture location within
+// half a degree.
+func (p *FixtureProvider) CurrentWeather(_ context.Context, latitude, longitude float64) (*CurrentWeather, error) {
+	fixture, err := p.nearest(latitude, longitude)
+	if err != nil {
+		return nil, err
+	}
+
+	weather := fixture.Current
+	return &weather, nil
+}
+
+// Forecast treats the day of the current weather as today.
+func (p *FixtureProvider) Forecast(_ context.Context, latitude, longitude float64, days int) ([]DailyWeather, error) {
+	fixture, err := p.nearest(latitude, longitude)
+	if err != nil {
+		return nil, err
+	}
+
+	today, _, _ := strings.Cut(fixture.Current.Time, "T")
+	var forecast []DailyWeather
+	for _, day := range fixture.Daily {
+		if day.Date >= today && len(forecast) < days {
+			forecast = append(forecast, day)
+		}
+	}
+	if len(forecast) == 0 {
+		return nil, fmt.Errorf("no fixture forecast for %s", fixture.Name)
+	}
+	return forecast, nil
+}
+
+func (p *FixtureProvider) History(_ context.Context, latitude, longitude float64, start, end time.Time) ([]DailyWeather, error) {
+	fixture, err := p.nearest(latitude, longitude)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := start.Format(DateLayout), end.Format(DateLayout)
+	var history []DailyWeather
+	for _, day := range fixture.Daily {
+		if day.Date >= from && day.Date <= to {
+			history = append(history, day)
+		}
+	}
+	if len(history) == 0 {
+		return nil, fmt.Errorf("no fixture history for %s between %s and %s", fixture.Name, from, to)
+	}
+	return history, nil
+}
+
+func (p *FixtureProvider) nearest(latitude, longitude float64) (*fixtureLocation, error) {
+	nearest, distance := -1, 0.5
+	for i, fixture := range p.locations {
+		if d := math.Hypot(fixture.Latitude-latitude, fixture.Longitude-longitude); d <= distance {
+			nearest, distance = i, d
+		}
+	}
+	if nearest < 0 {
+		return nil, fmt.Errorf("%w: no fixture weather near %.4f,%.4f", ErrLocationNotFound, latitude, longitude)
+	}
+	return &p.locations[nearest], nil
+}
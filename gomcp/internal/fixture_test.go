@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestFixtureProvider(t *testing.T) *FixtureProvider {
+	t.Helper()
+	provider, err := NewFixtureProvider("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+func TestFixtureGeocode(t *testing.T) {
+	provider := newTestFixtureProvider(t)
+
+	tests := []struct {
+		city, country string
+		want          string
+	}{
+		{city: "Rome", want: "Rome, IT"},
+		{city: "rome", want: "Rome, IT"},
+		{city: "Paris", country: "fr", want: "Paris, FR"},
+		{city: "Paris", country: "US"},
+		{city: "Atlantis"},
+	}
+	for _, test := range tests {
+		location, err := provider.Geocode(context.Background(), test.city, test.country)
+		if test.want == "" {
+			if !errors.Is(err, ErrLocationNotFound) {
+				t.Errorf("Geocode(%q, %q) error = %v, want ErrLocationNotFound", test.city, test.country, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := location.String(); got != test.want {
+			t.Errorf("Geocode(%q, %q) = %s, want %s", test.city, test.country, got, test.want)
+		}
+	}
+}
+
+func TestFixtureWeather(t *testing.T) {
+	provider := newTestFixtureProvider(t)
+	ctx := context.Background()
+
+	// Coordinates within half a degree get the nearest fixture.
+	weather, err := provider.CurrentWeather(ctx, 41.9, 12.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weather.Temperature != 24.3 || weather.Description() != "Mainly clear" {
+		t.Errorf("CurrentWeather() = %+v", weather)
+	}
+	if _, err := provider.CurrentWeather(ctx, 0, 0); !errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("CurrentWeather(0, 0) error = %v, want ErrLocationNotFound", err)
+	}
+
+	// The fixtures' today is the day of the current weather, 2026-06-15.
+	forecast, err := provider.Forecast(ctx, 41.9, 12.5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dates := dailyDates(forecast); dates != "2026-06-15 2026-06-16 2026-06-17" {
+		t.Errorf("Forecast() dates = %s", dates)
+	}
+
+	start, end := time.Date(2026, 6, 7, 0, 0, 0, 0, time.UTC), time.Date(2026, 6, 9, 0, 0, 0, 0, time.UTC)
+	history, err := provider.History(ctx, 41.9, 12.5, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dates := dailyDates(history); dates != "2026-06-08 2026-06-09" {
+		t.Errorf("History() dates = %s", dates)
+	}
+	if _, err := provider.History(ctx, 41.9, 12.5, start.AddDate(-1, 0, 0), end.AddDate(-1, 0, 0)); err == nil {
+		t.Error("History() before the fixtures succeeded")
+	}
+}
+
+func TestNewFixtureProviderErrors(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"broken.json": `{"locations": [`,
+		"empty.json":  `{"locations": []}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"broken.json", "empty.json", "missing.json"} {
+		if _, err := NewFixtureProvider(filepath.Join(dir, name)); err == nil {
+			t.Errorf("NewFixtureProvider(%s) succeeded", name)
+		}
+	}
+}
+
+func dailyDates(days []DailyWeather) string {
+	dates := make([]string, 0, len(days))
+	for _, day := range days {
+		dates = append(dates, day.Date)
+	}
+	return strings.Join(dates, " ")
+}
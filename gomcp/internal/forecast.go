@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const archiveBaseURL = "https://archive-api.open-meteo.com/v1/archive"
+
+const dailyVariables = "weathercode,temperature_2m_max,temperature_2m_min,precipitation_sum,windspeed_10m_max"
+
+type dailyResponse struct {
+	Daily struct {
+		Time           []string  `json:"time"`
+		WeatherCode    []int     `json:"weathercode"`
+		TemperatureMax []float64 `json:"temperature_2m_max"`
+		TemperatureMin []float64 `json:"temperature_2m_min"`
+		Precipitation  []float64 `json:"precipitation_sum"`
+		WindSpeedMax   []float64 `json:"windspeed_10m_max"`
+	} `json:"daily"`
+}
+
+func (r *dailyResponse) days() []DailyWeather {
+	daily := r.Daily
+	days := make([]DailyWeather, 0, len(daily.Time))
+	for i, date := range daily.Time {
+		day := DailyWeather{Date: date}
+		if i < len(daily.WeatherCode) {
+			day.WeatherCode = daily.WeatherCode[i]
+		}
+		if i < len(daily.TemperatureMax) {
+			day.TemperatureMax = daily.TemperatureMax[i]
+		}
+		if i < len(daily.TemperatureMin) {
+			day.TemperatureMin = daily.TemperatureMin[i]
+		}
+		if i < len(daily.Precipitation) {
+			day.Precipitation = daily.Precipitation[i]
+		}
+		if i < len(daily.WindSpeedMax) {
+			day.WindSpeedMax = daily.WindSpeedMax[i]
+		}
+		days = append(days, day)
+	}
+	return days
+}
+
+func dailyParams(latitude, longitude float64) url.Values {
+	params := url.Values{}
+	params.Add("latitude", fmt.Sprintf("%.6f", latitude))
+	params.Add("longitude", fmt.Sprintf("%.6f", longitude))
+	params.Add("daily", dailyVariables)
+	params.Add("timezone", "auto")
+	return params
+}
+
+func (o *OpenMeteo) Forecast(ctx context.Context, latitude, longitude float64, days int) ([]DailyWeather, error) {
+	key := fmt.Sprintf("%.2f,%.2f,%d", latitude, longitude, days)
+	if forecast, ok := o.forecasts.get(key); ok {
+		return forecast, nil
+	}
+
+	params := dailyParams(latitude, longitude)
+	params.Add("forecast_days", strconv.Itoa(days))
+
+	var resp dailyResponse
+	if err := o.getJSON(ctx, "forecast", o.forecastURL, params, &resp); err != nil {
+		return nil, err
+	}
+
+	forecast := resp.days()
+	o.forecasts.put(key, forecast)
+	return forecast, nil
+}
+
+func (o *OpenMeteo) History(ctx context.Context, latitude, longitude float64, start, end time.Time) ([]DailyWeather, error) {
+	key := fmt.Sprintf("%.2f,%.2f,%s,%s", latitude, longitude, start.Format(DateLayout), end.Format(DateLayout))
+	if history, ok := o.history.get(key); ok {
+		return history, nil
+	}
+
+	params := dailyParams(latitude, longitude)
+	params.Add("start_date", start.Format(DateLayout))
+	params.Add("end_date", end.Format(DateLayout))
+
+	var resp dailyResponse
+	if err := o.getJSON(ctx, "archive", o.archiveURL, params, &resp); err != nil {
+		return nil, err
+	}
+
+	history := resp.days()
+	o.history.put(key, history)
+	return history, nil
+}
@@ -0,0 +1,83 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// Separator joins a server name and a tool or prompt name. It is accepted in
+// function names by all model providers, unlike "." or "/".
+const Separator = "__"
+
+// Server names become part of tool names and URI schemes.
+var serverNamePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+type ServerConfig struct {
+	// Name namespaces the server's tools, prompts and resources.
+	Name string `json:"name"`
+
+	// Command and Args start a server that speaks MCP over stdio.
+	Command string            `json:"command,omitempty"`
+	Args    []string          `json:"args,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Dir     string            `json:"dir,omitempty"`
+
+	// URL is the endpoint of a streamable HTTP server.
+	URL string `json:"url,omitempty"`
+	// Headers are sent with every HTTP request. Values are expanded with
+	// environment variables, so secrets can stay out of the file.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type Config struct {
+	Servers []ServerConfig `json:"servers"`
+}
+
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("read gateway config: %w", err)
+	}
+
+	var config Config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return Config{}, fmt.Errorf("parse gateway config %s: %w", path, err)
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, fmt.Errorf("gateway config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+func (c Config) Validate() error {
+	if len(c.Servers) == 0 {
+		return fmt.Errorf("no servers configured")
+	}
+
+	seen := map[string]bool{}
+	for i, server := range c.Servers {
+		if !serverNamePattern.MatchString(server.Name) {
+			return fmt.Errorf("servers[%d]: name %q must be lowercase letters, digits and dashes", i, server.Name)
+		}
+		if seen[server.Name] {
+			return fmt.Errorf("servers[%d]: duplicate name %q", i, server.Name)
+		}
+		seen[server.Name] = true
+
+		if (server.Command == "") == (server.URL == "") {
+			return fmt.Errorf("server %s: set either command or url", server.Name)
+		}
+		if server.URL != "" && (len(server.Args) > 0 || len(server.Env) > 0 || server.Dir != "") {
+			return fmt.Errorf("server %s: args, env and dir only apply to command servers", server.Name)
+		}
+		if server.Command != "" && len(server.Headers) > 0 {
+			return fmt.Errorf("server %s: headers only apply to url servers", server.Name)
+		}
+	}
+	return nil
+}
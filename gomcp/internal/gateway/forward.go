@@ -0,0 +1,237 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// progressGrace is how long progress notifications are still forwarded after
+// the call they belong to has returned.
+const progressGrace = time.Second
+
+func (g *Gateway) syncTools(ctx context.Context, d *downstream) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	session := d.client()
+	if session == nil {
+		// Still connecting; Connect loads the lists afterwards.
+		return nil
+	}
+
+	var names []string
+	for tool, err := range session.Tools(ctx, nil) {
+		if err != nil {
+			return fmt.Errorf("list tools: %w", err)
+		}
+		if !objectSchema(tool.InputSchema) {
+			log.Printf("Skipping tool %s of %s: input schema is not an object", tool.Name, d.config.Name)
+			continue
+		}
+
+		exported := *tool
+		exported.Name = d.name(tool.Name)
+		if exported.OutputSchema != nil && !objectSchema(exported.OutputSchema) {
+			exported.OutputSchema = nil
+		}
+		g.server.AddTool(&exported, g.callTool(d, tool.Name))
+		names = append(names, exported.Name)
+	}
+
+	g.server.RemoveTools(removed(d.tools, names)...)
+	d.tools = names
+	return nil
+}
+
+func (g *Gateway) syncPrompts(ctx context.Context, d *downstream) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	session := d.client()
+	if session == nil {
+		// Still connecting; Connect loads the lists afterwards.
+		return nil
+	}
+
+	var names []string
+	for prompt, err := range session.Prompts(ctx, nil) {
+		if err != nil {
+			return fmt.Errorf("list prompts: %w", err)
+		}
+		exported := *prompt
+		exported.Name = d.name(prompt.Name)
+		g.server.AddPrompt(&exported, g.getPrompt(d, prompt.Name))
+		names = append(names, exported.Name)
+	}
+
+	g.server.RemovePrompts(removed(d.prompts, names)...)
+	d.prompts = names
+	return nil
+}
+
+func (g *Gateway) syncResources(ctx context.Context, d *downstream) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	session := d.client()
+	if session == nil {
+		// Still connecting; Connect loads the lists afterwards.
+		return nil
+	}
+
+	var uris []string
+	for resource, err := range session.Resources(ctx, nil) {
+		if err != nil {
+			return fmt.Errorf("list resources: %w", err)
+		}
+		exported := *resource
+		exported.URI = d.uri(resource.URI)
+		g.server.AddResource(&exported, g.readResource(d))
+		uris = append(uris, exported.URI)
+	}
+
+	var templates []string
+	for template, err := range session.ResourceTemplates(ctx, nil) {
+		if err != nil {
+			return fmt.Errorf("list resource templates: %w", err)
+		}
+		exported := *template
+		exported.URITemplate = d.uri(template.URITemplate)
+		g.server.AddResourceTemplate(&exported, g.readResource(d))
+		templates = append(templates, exported.URITemplate)
+	}
+
+	g.server.RemoveResources(removed(d.resources, uris)...)
+	g.server.RemoveResourceTemplates(removed(d.templates, templates)...)
+	d.resources, d.templates = uris, templates
+	return nil
+}
+
+func (g *Gateway) callTool(d *downstream, name string) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		params := &mcp.CallToolParams{Name: name}
+		if len(req.Params.Arguments) > 0 {
+			params.Arguments = req.Params.Arguments
+		}
+		defer g.trackProgress(req.Session, req.Params.GetProgressToken(), params.SetProgressToken)()
+
+		// When the client cancels, ctx is cancelled and the SDK sends the
+		// cancellation on to the downstream server.
+		start := time.Now()
+		res, err := d.client().CallTool(ctx, params)
+		logCall("tools/call", req.Params.Name, d, start, err == nil && res.IsError, err)
+		if err != nil {
+			return nil, err
+		}
+		d.exportContent(res.Content)
+		return res, nil
+	}
+}
+
+func (g *Gateway) getPrompt(d *downstream, name string) mcp.PromptHandler {
+	return func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		params := &mcp.GetPromptParams{Name: name, Arguments: req.Params.Arguments}
+		defer g.trackProgress(req.Session, req.Params.GetProgressToken(), params.SetProgressToken)()
+
+		start := time.Now()
+		res, err := d.client().GetPrompt(ctx, params)
+		logCall("prompts/get", req.Params.Name, d, start, false, err)
+		if err != nil {
+			return nil, err
+		}
+		for _, message := range res.Messages {
+			d.exportContent([]mcp.Content{message.Content})
+		}
+		return res, nil
+	}
+}
+
+func (g *Gateway) readResource(d *downstream) mcp.ResourceHandler {
+	return func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		params := &mcp.ReadResourceParams{URI: d.originalURI(req.Params.URI)}
+		defer g.trackProgress(req.Session, req.Params.GetProgressToken(), params.SetProgressToken)()
+
+		start := time.Now()
+		res, err := d.client().ReadResource(ctx, params)
+		logCall("resources/read", req.Params.URI, d, start, false, err)
+		if err != nil {
+			return nil, err
+		}
+		for _, contents := range res.Contents {
+			contents.URI = d.uri(contents.URI)
+		}
+		return res, nil
+	}
+}
+
+// exportContent rewrites the URIs of resource links and embedded resources so
+// clients can read them through the gateway.
+func (d *downstream) exportContent(contents []mcp.Content) {
+	for _, content := range contents {
+		switch content := content.(type) {
+		case *mcp.ResourceLink:
+			content.URI = d.uri(content.URI)
+		case *mcp.EmbeddedResource:
+			if content.Resource != nil {
+				content.Resource.URI = d.uri(content.Resource.URI)
+			}
+		}
+	}
+}
+
+// trackProgress gives a forwarded request its own progress token when the
+// client asked for progress, so notifications from the downstream server can
+// be routed back. Tokens of different clients may collide, so they are not
+// passed through. The returned function forgets the token.
+func (g *Gateway) trackProgress(session *mcp.ServerSession, token any, setToken func(any)) func() {
+	if token == nil {
+		return func() {}
+	}
+
+	g.mu.Lock()
+	g.nextToken++
+	forwarded := fmt.Sprintf("gateway-%d", g.nextToken)
+	g.progress[forwarded] = progressTarget{session: session, token: token}
+	g.mu.Unlock()
+
+	setToken(forwarded)
+	return func() {
+		// The SDK reads the response to a call before it has handled the
+		// notifications that came just ahead of it, so the token is kept a
+		// little longer for those to be forwarded.
+		time.AfterFunc(progressGrace, func() {
+			g.mu.Lock()
+			delete(g.progress, forwarded)
+			g.mu.Unlock()
+		})
+	}
+}
+
+func (g *Gateway) forwardProgress(ctx context.Context, req *mcp.ProgressNotificationClientRequest) {
+	forwarded, _ := req.Params.ProgressToken.(string)
+
+	g.mu.Lock()
+	target, ok := g.progress[forwarded]
+	g.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	err := target.session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+		ProgressToken: target.token,
+		Message:       req.Params.Message,
+		Progress:      req.Params.Progress,
+		Total:         req.Params.Total,
+	})
+	if err != nil {
+		log.Printf("Failed to forward progress: %v", err)
+	}
+}
+
+// objectSchema reports whether schema has type "object", which the SDK
+// requires of tool schemas.
+func objectSchema(schema any) bool {
+	m, ok := schema.(map[string]any)
+	return ok && m["type"] == "object"
+}
@@ -0,0 +1,243 @@
+// Package gateway aggregates several MCP servers behind one server. Tools and
+// prompts are re-exported as <server>__<name>, and resource URIs get the
+// server name as a scheme prefix: weather://Rome/current from the server
+// "weather" becomes weather+weather://Rome/current.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type Gateway struct {
+	server *mcp.Server
+
+	mu          sync.Mutex
+	downstreams []*downstream
+	progress    map[string]progressTarget
+	nextToken   int
+}
+
+// progressTarget is the upstream request a downstream progress token
+// belongs to.
+type progressTarget struct {
+	session *mcp.ServerSession
+	token   any
+}
+
+type downstream struct {
+	config ServerConfig
+
+	// sessionMu guards session on its own, so forwarded calls do not wait
+	// for a list update.
+	sessionMu sync.Mutex
+	session   *mcp.ClientSession
+
+	// mu serializes list updates, which replace the registered names.
+	mu        sync.Mutex
+	tools     []string
+	prompts   []string
+	resources []string
+	templates []string
+}
+
+func New() *Gateway {
+	g := &Gateway{progress: map[string]progressTarget{}}
+	g.server = mcp.NewServer(&mcp.Implementation{
+		Name:    "mcp-gateway",
+		Version: "1.0.0",
+	}, &mcp.ServerOptions{
+		Instructions: "Gateway to several MCP servers. Tool and prompt names are prefixed with the name of the server that provides them, followed by " + Separator + ".",
+	})
+	return g
+}
+
+// Server is the aggregated server to serve to clients.
+func (g *Gateway) Server() *mcp.Server {
+	return g.server
+}
+
+// Connect connects to a downstream server and re-exports its tools, prompts
+// and resources. Later changes to its lists are followed.
+func (g *Gateway) Connect(ctx context.Context, config ServerConfig) error {
+	return g.connect(ctx, config, newTransport(config))
+}
+
+func (g *Gateway) connect(ctx context.Context, config ServerConfig, transport mcp.Transport) error {
+	d := &downstream{config: config}
+
+	client := mcp.NewClient(&mcp.Implementation{
+		Name:    "mcp-gateway",
+		Version: "1.0.0",
+	}, &mcp.ClientOptions{
+		// Updates list the downstream tools again, which must not block the
+		// session's notification handling.
+		ToolListChangedHandler: func(ctx context.Context, req *mcp.ToolListChangedRequest) {
+			g.resync(ctx, d, g.syncTools)
+		},
+		PromptListChangedHandler: func(ctx context.Context, req *mcp.PromptListChangedRequest) {
+			g.resync(ctx, d, g.syncPrompts)
+		},
+		ResourceListChangedHandler: func(ctx context.Context, req *mcp.ResourceListChangedRequest) {
+			g.resync(ctx, d, g.syncResources)
+		},
+		ProgressNotificationHandler: g.forwardProgress,
+	})
+
+	session, err := client.Connect(ctx, transport, nil)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", config.Name, err)
+	}
+	d.sessionMu.Lock()
+	d.session = session
+	d.sessionMu.Unlock()
+
+	capabilities := session.InitializeResult().Capabilities
+	if capabilities.Tools != nil {
+		err = errors.Join(err, g.syncTools(ctx, d))
+	}
+	if capabilities.Prompts != nil {
+		err = errors.Join(err, g.syncPrompts(ctx, d))
+	}
+	if capabilities.Resources != nil {
+		err = errors.Join(err, g.syncResources(ctx, d))
+	}
+	if err != nil {
+		session.Close()
+		return fmt.Errorf("server %s: %w", config.Name, err)
+	}
+
+	g.mu.Lock()
+	g.downstreams = append(g.downstreams, d)
+	g.mu.Unlock()
+
+	info := session.InitializeResult().ServerInfo
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	log.Printf("Connected to %s (%s %s): %d tools, %d prompts, %d resources, %d resource templates",
+		config.Name, info.Name, info.Version, len(d.tools), len(d.prompts), len(d.resources), len(d.templates))
+	return nil
+}
+
+// Close disconnects from all downstream servers.
+func (g *Gateway) Close() error {
+	// Closing a session waits for its handlers, which may need g.mu to
+	// forward progress.
+	g.mu.Lock()
+	downstreams := g.downstreams
+	g.downstreams = nil
+	g.mu.Unlock()
+
+	var errs []error
+	for _, d := range downstreams {
+		if err := d.client().Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close %s: %w", d.config.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func newTransport(config ServerConfig) mcp.Transport {
+	if config.Command != "" {
+		cmd := exec.Command(config.Command, config.Args...)
+		cmd.Dir = config.Dir
+		cmd.Env = os.Environ()
+		for key, value := range config.Env {
+			cmd.Env = append(cmd.Env, key+"="+os.ExpandEnv(value))
+		}
+		// Let the server's log output through.
+		cmd.Stderr = os.Stderr
+		return &mcp.CommandTransport{Command: cmd}
+	}
+
+	headers := http.Header{}
+	for key, value := range config.Headers {
+		// Leave out headers whose variables are not set.
+		if value = os.ExpandEnv(value); value != "" {
+			headers.Set(key, value)
+		}
+	}
+	return &mcp.StreamableClientTransport{
+		Endpoint: config.URL,
+		HTTPClient: &http.Client{
+			Transport: headerTransport{headers: headers},
+		},
+	}
+}
+
+type headerTransport struct {
+	headers http.Header
+}
+
+func (t headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if len(t.headers) > 0 {
+		req = req.Clone(req.Context())
+		for key, values := range t.headers {
+			req.Header[key] = values
+		}
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (g *Gateway) resync(ctx context.Context, d *downstream, sync func(context.Context, *downstream) error) {
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := sync(ctx, d); err != nil {
+			log.Printf("Failed to update the lists of %s: %v", d.config.Name, err)
+		}
+	}()
+}
+
+// client returns the session with the downstream server, nil while Connect
+// has not established it.
+func (d *downstream) client() *mcp.ClientSession {
+	d.sessionMu.Lock()
+	defer d.sessionMu.Unlock()
+	return d.session
+}
+
+func (d *downstream) name(name string) string {
+	return d.config.Name + Separator + name
+}
+
+func (d *downstream) uri(uri string) string {
+	return d.config.Name + "+" + uri
+}
+
+func (d *downstream) originalURI(uri string) string {
+	return strings.TrimPrefix(uri, d.config.Name+"+")
+}
+
+// removed returns the names in old that are not in current.
+func removed(old, current []string) []string {
+	var names []string
+	for _, name := range old {
+		if !slices.Contains(current, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// logCall logs a forwarded request with its outcome.
+func logCall(method, name string, d *downstream, start time.Time, failed bool, err error) {
+	outcome := "ok"
+	switch {
+	case err != nil:
+		outcome = "error: " + err.Error()
+	case failed:
+		outcome = "tool error"
+	}
+	log.Printf("%s %s -> %s in %v: %s", method, name, d.config.Name, time.Since(start).Round(time.Millisecond), outcome)
+}
@@ -0,0 +1,307 @@
+package gateway
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// testDownstream is a downstream server with one of everything the gateway
+// forwards.
+type testDownstream struct {
+	server *mcp.Server
+	// tokens receives the progress token of every progress call.
+	tokens chan any
+	// started and cancelled report the life of a wait call.
+	started, cancelled chan struct{}
+}
+
+func newTestDownstream() *testDownstream {
+	d := &testDownstream{
+		server:    mcp.NewServer(&mcp.Implementation{Name: "weather", Version: "1.0.0"}, nil),
+		tokens:    make(chan any, 10),
+		started:   make(chan struct{}, 1),
+		cancelled: make(chan struct{}, 1),
+	}
+	schema := map[string]any{"type": "object"}
+	text := func(s string) *mcp.CallToolResult {
+		return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: s}}}
+	}
+
+	d.server.AddTool(&mcp.Tool{Name: "echo", InputSchema: schema}, func(_ context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return text(string(req.Params.Arguments)), nil
+	})
+	d.server.AddTool(&mcp.Tool{Name: "link", InputSchema: schema}, func(context.Context, *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{Content: []mcp.Content{
+			&mcp.ResourceLink{URI: "weather://Rome/current", Name: "rome"},
+			&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: "weather://Paris/current", Text: "{}"}},
+		}}, nil
+	})
+	d.server.AddTool(&mcp.Tool{Name: "progress", InputSchema: schema}, func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		token := req.Params.GetProgressToken()
+		d.tokens <- token
+		for i := 1; i <= 2; i++ {
+			req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{ProgressToken: token, Progress: float64(i), Total: 2, Message: "step"})
+		}
+		return text("done"), nil
+	})
+	d.server.AddTool(&mcp.Tool{Name: "wait", InputSchema: schema}, func(ctx context.Context, _ *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		d.started <- struct{}{}
+		select {
+		case <-ctx.Done():
+			d.cancelled <- struct{}{}
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Second):
+			return text("not cancelled"), nil
+		}
+	})
+	d.server.AddPrompt(&mcp.Prompt{Name: "greet"}, func(context.Context, *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		return &mcp.GetPromptResult{Messages: []*mcp.PromptMessage{
+			{Role: "user", Content: &mcp.ResourceLink{URI: "weather://Rome/current", Name: "rome"}},
+		}}, nil
+	})
+
+	read := func(_ context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{{URI: req.Params.URI, Text: "weather of " + req.Params.URI}}}, nil
+	}
+	d.server.AddResource(&mcp.Resource{Name: "rome", URI: "weather://Rome/current"}, read)
+	d.server.AddResourceTemplate(&mcp.ResourceTemplate{Name: "current", URITemplate: "weather://{city}/current"}, read)
+	return d
+}
+
+// connectGateway connects the gateway to the downstream server and a client
+// to the gateway, all in memory.
+func connectGateway(t *testing.T, downstream *testDownstream, opts *mcp.ClientOptions) *mcp.ClientSession {
+	t.Helper()
+	ctx := t.Context()
+
+	downstreamTransport, gatewayTransport := mcp.NewInMemoryTransports()
+	if _, err := downstream.server.Connect(ctx, downstreamTransport, nil); err != nil {
+		t.Fatal(err)
+	}
+	g := New()
+	if err := g.connect(ctx, ServerConfig{Name: "weather"}, gatewayTransport); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { g.Close() })
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := g.Server().Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatal(err)
+	}
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, opts).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func toolNames(t *testing.T, session *mcp.ClientSession) []string {
+	t.Helper()
+	var names []string
+	for tool, err := range session.Tools(t.Context(), nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, tool.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestNamespacing(t *testing.T) {
+	session := connectGateway(t, newTestDownstream(), nil)
+	ctx := t.Context()
+
+	want := []string{"weather__echo", "weather__link", "weather__progress", "weather__wait"}
+	if names := toolNames(t, session); !slices.Equal(names, want) {
+		t.Errorf("tools %v, want %v", names, want)
+	}
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "weather__echo", Arguments: map[string]any{"city": "Rome"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := result.Content[0].(*mcp.TextContent).Text; got != `{"city":"Rome"}` {
+		t.Errorf("echo = %s, want the arguments", got)
+	}
+	if _, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "echo"}); err == nil {
+		t.Error("the downstream tool name is callable without its prefix")
+	}
+
+	prompts, err := session.ListPrompts(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prompts.Prompts) != 1 || prompts.Prompts[0].Name != "weather__greet" {
+		t.Errorf("prompts %+v, want weather__greet", prompts.Prompts)
+	}
+}
+
+func TestURIRewriting(t *testing.T) {
+	session := connectGateway(t, newTestDownstream(), nil)
+	ctx := t.Context()
+
+	resources, err := session.ListResources(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources.Resources) != 1 || resources.Resources[0].URI != "weather+weather://Rome/current" {
+		t.Errorf("resources %+v", resources.Resources)
+	}
+	templates, err := session.ListResourceTemplates(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates.ResourceTemplates) != 1 || templates.ResourceTemplates[0].URITemplate != "weather+weather://{city}/current" {
+		t.Errorf("resource templates %+v", templates.ResourceTemplates)
+	}
+
+	// Listed resources and template matches are read from the downstream
+	// server under their original URI.
+	for _, uri := range []string{"weather+weather://Rome/current", "weather+weather://Paris/current"} {
+		result, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: uri})
+		if err != nil {
+			t.Fatal(err)
+		}
+		original := strings.TrimPrefix(uri, "weather+")
+		if contents := result.Contents[0]; contents.URI != uri || contents.Text != "weather of "+original {
+			t.Errorf("ReadResource(%s) = %s %q", uri, contents.URI, contents.Text)
+		}
+	}
+
+	// Links in tool results and prompts point back to the gateway.
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "weather__link"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link := result.Content[0].(*mcp.ResourceLink); link.URI != "weather+weather://Rome/current" {
+		t.Errorf("resource link %s", link.URI)
+	}
+	if embedded := result.Content[1].(*mcp.EmbeddedResource); embedded.Resource.URI != "weather+weather://Paris/current" {
+		t.Errorf("embedded resource %s", embedded.Resource.URI)
+	}
+	prompt, err := session.GetPrompt(ctx, &mcp.GetPromptParams{Name: "weather__greet"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link := prompt.Messages[0].Content.(*mcp.ResourceLink); link.URI != "weather+weather://Rome/current" {
+		t.Errorf("prompt resource link %s", link.URI)
+	}
+}
+
+func TestProgressTokens(t *testing.T) {
+	downstream := newTestDownstream()
+	var (
+		mu       sync.Mutex
+		progress []*mcp.ProgressNotificationParams
+	)
+	session := connectGateway(t, downstream, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+			mu.Lock()
+			defer mu.Unlock()
+			progress = append(progress, req.Params)
+		},
+	})
+
+	params := &mcp.CallToolParams{Name: "weather__progress"}
+	params.SetProgressToken("client-token")
+	if _, err := session.CallTool(t.Context(), params); err != nil {
+		t.Fatal(err)
+	}
+
+	// The downstream server gets a token of the gateway, and the client gets
+	// its own back.
+	if token, _ := (<-downstream.tokens).(string); !strings.HasPrefix(token, "gateway-") {
+		t.Errorf("downstream progress token %q, want one of the gateway", token)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(progress)
+		mu.Unlock()
+		if n == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(progress) != 2 {
+		t.Fatalf("%d progress notifications, want 2", len(progress))
+	}
+	for i, p := range progress {
+		if p.ProgressToken != "client-token" || p.Progress != float64(i+1) || p.Total != 2 || p.Message != "step" {
+			t.Errorf("progress %d = %+v", i, p)
+		}
+	}
+
+	// Without a token the downstream call gets none either.
+	if _, err := session.CallTool(t.Context(), &mcp.CallToolParams{Name: "weather__progress"}); err != nil {
+		t.Fatal(err)
+	}
+	if token := <-downstream.tokens; token != nil {
+		t.Errorf("downstream progress token %v, want none", token)
+	}
+}
+
+func TestCancellation(t *testing.T) {
+	downstream := newTestDownstream()
+	session := connectGateway(t, downstream, nil)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() {
+		_, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "weather__wait"})
+		done <- err
+	}()
+
+	select {
+	case <-downstream.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the call did not reach the downstream server")
+	}
+	cancel()
+	select {
+	case <-downstream.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the cancellation did not reach the downstream server")
+	}
+	if err := <-done; err == nil {
+		t.Error("the cancelled call succeeded")
+	}
+}
+
+func TestListChanges(t *testing.T) {
+	downstream := newTestDownstream()
+	changed := make(chan struct{}, 10)
+	session := connectGateway(t, downstream, &mcp.ClientOptions{
+		ToolListChangedHandler: func(context.Context, *mcp.ToolListChangedRequest) {
+			changed <- struct{}{}
+		},
+	})
+
+	downstream.server.RemoveTools("wait")
+	downstream.server.AddTool(&mcp.Tool{Name: "new", InputSchema: map[string]any{"type": "object"}}, func(context.Context, *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return &mcp.CallToolResult{}, nil
+	})
+
+	want := []string{"weather__echo", "weather__link", "weather__new", "weather__progress"}
+	deadline := time.After(5 * time.Second)
+	for {
+		if names := toolNames(t, session); slices.Equal(names, want) {
+			return
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			t.Fatalf("tools %v, want %v", toolNames(t, session), want)
+		}
+	}
+}
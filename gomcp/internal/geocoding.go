@@ -1,60 +1,64 @@
 package internal
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
+	"strings"
 )
 
 const geocodingBaseURL = "https://geocoding-api.open-meteo.com/v1/search"
 
 type geocodingResult struct {
-	Name      string  `json:"name"`
-	Latitude  float64 `json:"latitude"`
-	Longitude float64 `json:"longitude"`
-	Country   string  `json:"country"`
+	Name        string  `json:"name"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Country     string  `json:"country"`
+	CountryCode string  `json:"country_code"`
 }
 
 type geocodingResponse struct {
 	Results []geocodingResult `json:"results"`
 }
 
-func getLatLng(city, country string) (*geocodingResult, error) {
+func (o *OpenMeteo) Geocode(ctx context.Context, city, country string) (*Location, error) {
+	key := strings.ToLower(city) + "|" + strings.ToUpper(country)
+	if location, ok := o.locations.get(key); ok {
+		return location, nil
+	}
 
 	params := url.Values{}
 	params.Add("name", city)
 	params.Add("count", "1")
 	params.Add("language", "en")
 	params.Add("format", "json")
-	params.Add("countryCode", country)
-
-	fullURL := fmt.Sprintf("%s?%s", geocodingBaseURL, params.Encode())
-
-	resp, err := http.Get(fullURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make geocoding request: %w", err)
+	if country != "" {
+		params.Add("countryCode", country)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("geocoding API returned status %d", resp.StatusCode)
+	var geoResp geocodingResponse
+	if err := o.getJSON(ctx, "geocoding", o.geocodingURL, params, &geoResp); err != nil {
+		return nil, err
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	if len(geoResp.Results) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrLocationNotFound, formatPlace(city, country))
 	}
 
-	var geoResp geocodingResponse
-	if err := json.Unmarshal(body, &geoResp); err != nil {
-		return nil, fmt.Errorf("failed to parse geocoding response: %w", err)
+	result := geoResp.Results[0]
+	location := &Location{
+		Name:      result.Name,
+		Country:   result.CountryCode,
+		Latitude:  result.Latitude,
+		Longitude: result.Longitude,
 	}
+	o.locations.put(key, location)
+	return location, nil
+}
 
-	if len(geoResp.Results) == 0 {
-		return nil, fmt.Errorf("no results found for location: %s, %s", city, country)
+func formatPlace(city, country string) string {
+	if country == "" {
+		return city
 	}
-
-	return &geoResp.Results[0], nil
+	return city + ", " + country
 }
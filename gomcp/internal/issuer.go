@@ -0,0 +1,205 @@
+package internal
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/modelcontextprotocol/go-sdk/oauthex"
+)
+
+const (
+	IssuerAddrEnv    = "MCP_ISSUER_ADDR"
+	IssuerURLEnv     = "MCP_ISSUER_URL"
+	IssuerClientsEnv = "MCP_ISSUER_CLIENTS"
+)
+
+type IssuerConfig struct {
+	// URL is the issuer identifier and the base URL of its endpoints.
+	URL string
+	// Clients maps client IDs to secrets for the client_credentials grant.
+	Clients map[string]string
+	// Scopes lists the scopes a client may ask for, all of them by default.
+	Scopes []string
+	// TokenTTL is the lifetime of access tokens, 15 minutes by default.
+	TokenTTL time.Duration
+}
+
+// IssuerConfigFromEnv reads MCP_ISSUER_URL and MCP_ISSUER_CLIENTS, a
+// comma-separated list of id:secret pairs.
+func IssuerConfigFromEnv() (IssuerConfig, error) {
+	config := IssuerConfig{
+		URL:     strings.TrimSuffix(os.Getenv(IssuerURLEnv), "/"),
+		Clients: map[string]string{},
+		Scopes:  []string{"weather:read"},
+	}
+	if config.URL == "" {
+		config.URL = "http://localhost:9000"
+	}
+
+	clients := os.Getenv(IssuerClientsEnv)
+	if clients == "" {
+		clients = "weather-client:weather-secret"
+	}
+	for entry := range strings.SplitSeq(clients, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" || secret == "" {
+			return IssuerConfig{}, fmt.Errorf("%s entry %q must be id:secret", IssuerClientsEnv, entry)
+		}
+		config.Clients[id] = secret
+	}
+	return config, nil
+}
+
+// Issuer is a minimal OAuth 2.1 authorization server for local development.
+// It issues RS256 JWT access tokens with the client_credentials grant and
+// publishes its signing key as a JWKS.
+type Issuer struct {
+	config IssuerConfig
+	key    *rsa.PrivateKey
+	kid    string
+}
+
+func NewIssuer(config IssuerConfig) (*Issuer, error) {
+	if config.TokenTTL <= 0 {
+		config.TokenTTL = 15 * time.Minute
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generate signing key: %w", err)
+	}
+	sum := sha256.Sum256(key.N.Bytes())
+
+	return &Issuer{
+		config: config,
+		key:    key,
+		kid:    base64.RawURLEncoding.EncodeToString(sum[:8]),
+	}, nil
+}
+
+func (i *Issuer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/oauth-authorization-server", i.serveMetadata)
+	mux.HandleFunc("GET /jwks.json", i.serveJWKS)
+	mux.HandleFunc("POST /token", i.serveToken)
+	return mux
+}
+
+func (i *Issuer) serveMetadata(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, &oauthex.AuthServerMeta{
+		Issuer:                            i.config.URL,
+		TokenEndpoint:                     i.config.URL + "/token",
+		JWKSURI:                           i.config.URL + "/jwks.json",
+		ScopesSupported:                   i.config.Scopes,
+		GrantTypesSupported:               []string{"client_credentials"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post"},
+	})
+}
+
+func (i *Issuer) serveJWKS(w http.ResponseWriter, _ *http.Request) {
+	e := big.NewInt(int64(i.key.E)).Bytes()
+	writeJSON(w, http.StatusOK, jsonWebKeySet{Keys: []jsonWebKey{{
+		Kty: "RSA",
+		Kid: i.kid,
+		Use: "sig",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(i.key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(e),
+	}}})
+}
+
+func (i *Issuer) serveToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request", "malformed form body")
+		return
+	}
+
+	clientID, secret, ok := r.BasicAuth()
+	if !ok {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	expected, known := i.config.Clients[clientID]
+	if !known || subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+		tokenError(w, http.StatusUnauthorized, "invalid_client", "unknown client or wrong secret")
+		return
+	}
+
+	if grant := r.PostForm.Get("grant_type"); grant != "client_credentials" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type", fmt.Sprintf("grant type %q is not supported", grant))
+		return
+	}
+
+	// RFC 8707: the resource parameter becomes the audience, so the token is
+	// only accepted by the server it was requested for.
+	resource := r.PostForm.Get("resource")
+	if resource == "" {
+		tokenError(w, http.StatusBadRequest, "invalid_target", "the resource parameter is required")
+		return
+	}
+
+	scopes := strings.Fields(r.PostForm.Get("scope"))
+	if len(scopes) == 0 {
+		scopes = i.config.Scopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(i.config.Scopes, scope) {
+			tokenError(w, http.StatusBadRequest, "invalid_scope", fmt.Sprintf("scope %q is not supported", scope))
+			return
+		}
+	}
+
+	now := time.Now()
+	jti := make([]byte, 16)
+	rand.Read(jti)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":       i.config.URL,
+		"sub":       clientID,
+		"aud":       resource,
+		"iat":       now.Unix(),
+		"exp":       now.Add(i.config.TokenTTL).Unix(),
+		"jti":       base64.RawURLEncoding.EncodeToString(jti),
+		"scope":     strings.Join(scopes, " "),
+		"client_id": clientID,
+	})
+	token.Header["kid"] = i.kid
+
+	signed, err := token.SignedString(i.key)
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error", "could not sign the token")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": signed,
+		"token_type":   "Bearer",
+		"expires_in":   int(i.config.TokenTTL.Seconds()),
+		"scope":        strings.Join(scopes, " "),
+	})
+}
+
+func tokenError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newTestIssuer(t *testing.T) (*httptest.Server, *Issuer) {
+	t.Helper()
+	var handler http.Handler
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	issuer, err := NewIssuer(IssuerConfig{
+		URL:     server.URL,
+		Clients: map[string]string{"weather-client": "weather-secret"},
+		Scopes:  []string{"weather:read", "weather:admin"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler = issuer.Handler()
+	return server, issuer
+}
+
+func TestIssuerToken(t *testing.T) {
+	server, _ := newTestIssuer(t)
+
+	tests := []struct {
+		name       string
+		basic      bool
+		id, secret string
+		form       url.Values
+		status     int
+		// err is the OAuth error code, empty for a token.
+		err    string
+		scopes []string
+	}{
+		{name: "basic auth", basic: true, id: "weather-client", secret: "weather-secret", status: http.StatusOK, scopes: []string{"weather:read", "weather:admin"}},
+		{name: "form credentials", id: "weather-client", secret: "weather-secret", status: http.StatusOK, scopes: []string{"weather:read", "weather:admin"}},
+		{name: "requested scope", basic: true, id: "weather-client", secret: "weather-secret", form: url.Values{"scope": {"weather:read"}}, status: http.StatusOK, scopes: []string{"weather:read"}},
+		{name: "wrong secret", basic: true, id: "weather-client", secret: "guess", status: http.StatusUnauthorized, err: "invalid_client"},
+		{name: "unknown client", id: "other", secret: "weather-secret", status: http.StatusUnauthorized, err: "invalid_client"},
+		{name: "other grant", basic: true, id: "weather-client", secret: "weather-secret", form: url.Values{"grant_type": {"password"}}, status: http.StatusBadRequest, err: "unsupported_grant_type"},
+		{name: "no resource", basic: true, id: "weather-client", secret: "weather-secret", form: url.Values{"resource": {""}}, status: http.StatusBadRequest, err: "invalid_target"},
+		{name: "unknown scope", basic: true, id: "weather-client", secret: "weather-secret", form: url.Values{"scope": {"weather:read weather:write"}}, status: http.StatusBadRequest, err: "invalid_scope"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			form := url.Values{"grant_type": {"client_credentials"}, "resource": {testAudience}}
+			for key, values := range test.form {
+				form[key] = values
+			}
+			if !test.basic {
+				form.Set("client_id", test.id)
+				form.Set("client_secret", test.secret)
+			}
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/token", strings.NewReader(form.Encode()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if test.basic {
+				req.SetBasicAuth(test.id, test.secret)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			var body struct {
+				AccessToken string `json:"access_token"`
+				Error       string `json:"error"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != test.status || body.Error != test.err {
+				t.Fatalf("status %d with error %q, want %d with %q", resp.StatusCode, body.Error, test.status, test.err)
+			}
+			if test.err != "" {
+				return
+			}
+
+			// The token is accepted by the resource it was issued for, with
+			// the keys discovered from the issuer's metadata.
+			info, err := NewJWKSVerifier(server.URL, "", testAudience).Verify(context.Background(), body.AccessToken, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.UserID != "weather-client" || !slices.Equal(info.Scopes, test.scopes) {
+				t.Errorf("token info = %+v, want scopes %v", info, test.scopes)
+			}
+			if _, err := NewJWKSVerifier(server.URL, "", "http://localhost:9999").Verify(context.Background(), body.AccessToken, nil); err == nil {
+				t.Error("another resource accepted the token")
+			}
+		})
+	}
+}
+
+func TestIssuerConfigFromEnv(t *testing.T) {
+	t.Setenv(IssuerURLEnv, "http://issuer.test/")
+	t.Setenv(IssuerClientsEnv, "a:secret-a, b:secret-b")
+	config, err := IssuerConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.URL != "http://issuer.test" || len(config.Clients) != 2 || config.Clients["b"] != "secret-b" {
+		t.Errorf("IssuerConfigFromEnv() = %+v", config)
+	}
+
+	t.Setenv(IssuerClientsEnv, "a:secret-a,b")
+	if _, err := IssuerConfigFromEnv(); err == nil {
+		t.Error("IssuerConfigFromEnv() accepted a client without secret")
+	}
+}
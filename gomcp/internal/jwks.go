@@ -0,0 +1,213 @@
+package internal
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/modelcontextprotocol/go-sdk/oauthex"
+)
+
+// jwksRefreshInterval limits how often an unknown key ID triggers a new
+// fetch of the key set.
+const jwksRefreshInterval = 30 * time.Second
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// JWKSVerifier validates JWT access tokens signed by an OAuth authorization
+// server. Keys are fetched from the server's JWKS on first use and again when
+// a token names a key that is not known yet.
+type JWKSVerifier struct {
+	issuer   string
+	audience string
+	client   *http.Client
+
+	mu      sync.Mutex
+	jwksURL string
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewJWKSVerifier accepts tokens issued by issuer for audience, usually the
+// URL of this resource server. jwksURL is discovered from the issuer's
+// metadata when empty.
+func NewJWKSVerifier(issuer, jwksURL, audience string) *JWKSVerifier {
+	return &JWKSVerifier{
+		issuer:   issuer,
+		audience: audience,
+		jwksURL:  jwksURL,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Verify is an auth.TokenVerifier.
+func (v *JWKSVerifier) Verify(ctx context.Context, token string, _ *http.Request) (*auth.TokenInfo, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", auth.ErrInvalidToken, err)
+	}
+
+	expires, err := claims.GetExpirationTime()
+	if err != nil || expires == nil {
+		return nil, fmt.Errorf("%w: no expiration", auth.ErrInvalidToken)
+	}
+	subject, _ := claims.GetSubject()
+	scope, _ := claims["scope"].(string)
+
+	return &auth.TokenInfo{
+		Scopes:     strings.Fields(scope),
+		Expiration: expires.Time,
+		UserID:     subject,
+		Extra:      map[string]any{"client_id": claims["client_id"]},
+	}, nil
+}
+
+func (v *JWKSVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(v.fetched) < jwksRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if err := v.refresh(ctx); err != nil {
+		return nil, err
+	}
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (v *JWKSVerifier) refresh(ctx context.Context) error {
+	v.fetched = time.Now()
+
+	if v.jwksURL == "" {
+		var meta oauthex.AuthServerMeta
+		if err := getJSONDocument(ctx, v.client, AuthServerMetadataURL(v.issuer), &meta); err != nil {
+			return fmt.Errorf("discover JWKS of %s: %w", v.issuer, err)
+		}
+		if meta.Issuer != v.issuer {
+			return fmt.Errorf("metadata issuer %q does not match %q", meta.Issuer, v.issuer)
+		}
+		if meta.JWKSURI == "" {
+			return fmt.Errorf("authorization server %s publishes no jwks_uri", v.issuer)
+		}
+		v.jwksURL = meta.JWKSURI
+	}
+
+	var set jsonWebKeySet
+	if err := getJSONDocument(ctx, v.client, v.jwksURL, &set); err != nil {
+		return fmt.Errorf("fetch JWKS: %w", err)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return fmt.Errorf("key %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	v.keys = keys
+	return nil
+}
+
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode n: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode e: %w", err)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decode x: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decode y: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// AuthServerMetadataURL is where an issuer publishes its RFC 8414 metadata.
+func AuthServerMetadataURL(issuer string) string {
+	return strings.TrimSuffix(issuer, "/") + "/.well-known/oauth-authorization-server"
+}
+
+func getJSONDocument(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("parse %s: %w", url, err)
+	}
+	return nil
+}
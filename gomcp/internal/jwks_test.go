@@ -0,0 +1,207 @@
+package internal
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/modelcontextprotocol/go-sdk/oauthex"
+)
+
+const testAudience = "http://localhost:8080"
+
+// testAuthServer publishes the metadata and JWKS of an authorization server
+// with one RSA and one P-256 signing key, and counts the JWKS fetches.
+type testAuthServer struct {
+	*httptest.Server
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	fetches atomic.Int32
+}
+
+func newTestAuthServer(t *testing.T) *testAuthServer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testAuthServer{rsaKey: rsaKey, ecKey: ecKey}
+
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/oauth-authorization-server", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, &oauthex.AuthServerMeta{Issuer: s.URL, JWKSURI: s.URL + "/jwks.json"})
+	})
+	mux.HandleFunc("GET /jwks.json", func(w http.ResponseWriter, _ *http.Request) {
+		s.fetches.Add(1)
+		writeJSON(w, http.StatusOK, jsonWebKeySet{Keys: []jsonWebKey{
+			{Kty: "RSA", Kid: "rsa", Use: "sig", N: encode(rsaKey.N.Bytes()), E: encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{Kty: "EC", Kid: "ec", Crv: "P-256", X: encode(ecKey.X.Bytes()), Y: encode(ecKey.Y.Bytes())},
+			// Encryption keys are not used to verify signatures.
+			{Kty: "RSA", Kid: "enc", Use: "enc", N: encode(rsaKey.N.Bytes()), E: encode(big.NewInt(int64(rsaKey.E)).Bytes())},
+		}})
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// claims are valid claims for the server, which a test case may change.
+func (s *testAuthServer) claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":       s.URL,
+		"sub":       "weather-client",
+		"aud":       testAudience,
+		"exp":       time.Now().Add(time.Minute).Unix(),
+		"scope":     "weather:read weather:write",
+		"client_id": "weather-client",
+	}
+}
+
+func (s *testAuthServer) sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	var key any
+	switch method {
+	case jwt.SigningMethodES256:
+		key = s.ecKey
+	case jwt.SigningMethodHS256:
+		// The public modulus is known to everybody, which is why an HMAC
+		// token must never be checked against an RSA key.
+		key = s.rsaKey.N.Bytes()
+	case jwt.SigningMethodNone:
+		key = jwt.UnsafeAllowNoneSignatureType
+	default:
+		key = s.rsaKey
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestJWKSVerifier(t *testing.T) {
+	server := newTestAuthServer(t)
+
+	tests := []struct {
+		name   string
+		method jwt.SigningMethod
+		kid    string
+		change func(jwt.MapClaims)
+		// err is part of the error message, empty for a valid token.
+		err string
+	}{
+		{name: "RS256", method: jwt.SigningMethodRS256, kid: "rsa"},
+		{name: "ES256", method: jwt.SigningMethodES256, kid: "ec"},
+		{name: "audience in a list", method: jwt.SigningMethodRS256, kid: "rsa", change: func(c jwt.MapClaims) { c["aud"] = []string{"other", testAudience} }},
+		{name: "expired within the leeway", method: jwt.SigningMethodRS256, kid: "rsa", change: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-10 * time.Second).Unix() }},
+		{name: "bad audience", method: jwt.SigningMethodRS256, kid: "rsa", change: func(c jwt.MapClaims) { c["aud"] = "http://localhost:9999" }, err: "token has invalid audience"},
+		{name: "no audience", method: jwt.SigningMethodRS256, kid: "rsa", change: func(c jwt.MapClaims) { delete(c, "aud") }, err: "aud claim is required"},
+		{name: "bad issuer", method: jwt.SigningMethodRS256, kid: "rsa", change: func(c jwt.MapClaims) { c["iss"] = "http://evil.example" }, err: "token has invalid issuer"},
+		{name: "missing exp", method: jwt.SigningMethodRS256, kid: "rsa", change: func(c jwt.MapClaims) { delete(c, "exp") }, err: "exp claim is required"},
+		{name: "expired", method: jwt.SigningMethodRS256, kid: "rsa", change: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }, err: "token is expired"},
+		{name: "unknown kid", method: jwt.SigningMethodRS256, kid: "other", err: `unknown signing key "other"`},
+		{name: "no kid", method: jwt.SigningMethodRS256, err: `unknown signing key ""`},
+		{name: "encryption key", method: jwt.SigningMethodRS256, kid: "enc", err: `unknown signing key "enc"`},
+		{name: "key of another type", method: jwt.SigningMethodRS256, kid: "ec", err: "key is of invalid type"},
+		{name: "HS256", method: jwt.SigningMethodHS256, kid: "rsa", err: "signing method HS256 is invalid"},
+		{name: "RS384", method: jwt.SigningMethodRS384, kid: "rsa", err: "signing method RS384 is invalid"},
+		{name: "none", method: jwt.SigningMethodNone, kid: "rsa", err: "signing method none is invalid"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verifier := NewJWKSVerifier(server.URL, "", testAudience)
+			claims := server.claims()
+			if test.change != nil {
+				test.change(claims)
+			}
+
+			info, err := verifier.Verify(context.Background(), server.sign(t, test.method, test.kid, claims), nil)
+			if test.err != "" {
+				if !errors.Is(err, auth.ErrInvalidToken) || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("Verify() error = %v, want ErrInvalidToken with %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.UserID != "weather-client" || !slices.Equal(info.Scopes, []string{"weather:read", "weather:write"}) || info.Expiration.IsZero() {
+				t.Errorf("Verify() = %+v", info)
+			}
+		})
+	}
+}
+
+func TestJWKSVerifierRefresh(t *testing.T) {
+	server := newTestAuthServer(t)
+	verifier := NewJWKSVerifier(server.URL, server.URL+"/jwks.json", testAudience)
+	ctx := context.Background()
+
+	valid := server.sign(t, jwt.SigningMethodRS256, "rsa", server.claims())
+	unknown := server.sign(t, jwt.SigningMethodRS256, "rotated", server.claims())
+	for _, token := range []string{valid, valid, unknown, unknown} {
+		verifier.Verify(ctx, token, nil)
+	}
+	// Known keys are cached, and unknown ones do not fetch the key set again
+	// within the refresh interval.
+	if got := server.fetches.Load(); got != 1 {
+		t.Errorf("fetched the key set %d times, want 1", got)
+	}
+}
+
+func TestJWKSVerifierDiscovery(t *testing.T) {
+	server := newTestAuthServer(t)
+	// A metadata document that names another issuer must not be trusted for
+	// its keys.
+	impostor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, &oauthex.AuthServerMeta{Issuer: server.URL, JWKSURI: server.URL + "/jwks.json"})
+	}))
+	defer impostor.Close()
+
+	claims := server.claims()
+	claims["iss"] = impostor.URL
+	verifier := NewJWKSVerifier(impostor.URL, "", testAudience)
+	_, err := verifier.Verify(context.Background(), server.sign(t, jwt.SigningMethodRS256, "rsa", claims), nil)
+	if err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Verify() error = %v, want an issuer mismatch", err)
+	}
+	if got := server.fetches.Load(); got != 0 {
+		t.Errorf("fetched the key set %d times, want 0", got)
+	}
+}
+
+func TestJSONWebKeyErrors(t *testing.T) {
+	for _, key := range []jsonWebKey{
+		{Kty: "oct"},
+		{Kty: "EC", Crv: "P-384"},
+		{Kty: "RSA", N: "not base64!", E: "AQAB"},
+		{Kty: "EC", Crv: "P-256", X: "AA", Y: "***"},
+	} {
+		if _, err := key.publicKey(); err == nil {
+			t.Errorf("publicKey(%+v) succeeded", key)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type OpenMeteoConfig struct {
+	// HTTPClient defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client
+	// Retries is the number of extra attempts after a network error, a 429
+	// or a 5xx response, 2 by default; a negative value disables retries.
+	Retries int
+	// GeocodeTTL caches geocoding results, 24 hours by default.
+	GeocodeTTL time.Duration
+	// ForecastTTL caches current weather and forecasts per location, 10
+	// minutes by default.
+	ForecastTTL time.Duration
+	// HistoryTTL caches historical weather, 24 hours by default.
+	HistoryTTL time.Duration
+}
+
+// OpenMeteo is the WeatherProvider backed by the free open-meteo APIs.
+type OpenMeteo struct {
+	client       *http.Client
+	retries      int
+	geocodingURL string
+	forecastURL  string
+	archiveURL   string
+	locations    *ttlCache[*Location]
+	weather      *ttlCache[*CurrentWeather]
+	forecasts    *ttlCache[[]DailyWeather]
+	history      *ttlCache[[]DailyWeather]
+}
+
+func NewOpenMeteo(config OpenMeteoConfig) *OpenMeteo {
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.Retries == 0 {
+		config.Retries = 2
+	}
+	if config.GeocodeTTL == 0 {
+		config.GeocodeTTL = 24 * time.Hour
+	}
+	if config.ForecastTTL == 0 {
+		config.ForecastTTL = 10 * time.Minute
+	}
+	if config.HistoryTTL == 0 {
+		config.HistoryTTL = 24 * time.Hour
+	}
+
+	return &OpenMeteo{
+		client:       config.HTTPClient,
+		retries:      max(config.Retries, 0),
+		geocodingURL: geocodingBaseURL,
+		forecastURL:  forecastBaseURL,
+		archiveURL:   archiveBaseURL,
+		locations:    newTTLCache[*Location](config.GeocodeTTL),
+		weather:      newTTLCache[*CurrentWeather](config.ForecastTTL),
+		forecasts:    newTTLCache[[]DailyWeather](config.ForecastTTL),
+		history:      newTTLCache[[]DailyWeather](config.HistoryTTL),
+	}
+}
+
+// statusError is a non-200 response of the API.
+type statusError struct {
+	api        string
+	status     int
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s API returned status %d", e.api, e.status)
+}
+
+func (e *statusError) retryable() bool {
+	return e.status == http.StatusTooManyRequests || e.status >= 500
+}
+
+// getJSON fetches and decodes one API response, retrying transient failures
+// with exponential backoff.
+func (o *OpenMeteo) getJSON(ctx context.Context, api, baseURL string, params url.Values, v any) error {
+	fullURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = o.fetch(ctx, api, fullURL, v)
+		if err == nil || attempt >= o.retries || ctx.Err() != nil {
+			return err
+		}
+
+		delay := 250 * time.Millisecond << attempt
+		var statusErr *statusError
+		if errors.As(err, &statusErr) {
+			if !statusErr.retryable() {
+				return err
+			}
+			if statusErr.retryAfter > 0 {
+				delay = min(statusErr.retryAfter, 10*time.Second)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (giving up: %w)", err, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (o *OpenMeteo) fetch(ctx context.Context, api, fullURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", api, err)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make %s request: %w", api, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		statusErr := &statusError{api: api, status: resp.StatusCode}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			statusErr.retryAfter = time.Duration(seconds) * time.Second
+		}
+		return statusErr
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", api, err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// AddWeatherPrompts registers the prompt templates that put the weather tools
+// to use.
+func AddWeatherPrompts(server *mcp.Server) {
+	server.AddPrompt(&mcp.Prompt{
+		Name:        "plan_trip",
+		Title:       "Plan my trip",
+		Description: "Plan the days of a trip around the weather forecast of the destination.",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "city", Description: "Destination city", Required: true},
+			{Name: "days", Description: "Length of the trip in days, 1 to 16 (default 3)"},
+			{Name: "activities", Description: "Activities you have in mind, for example hiking, museums"},
+		},
+	}, planTripPrompt)
+
+	server.AddPrompt(&mcp.Prompt{
+		Name:        "compare_weather",
+		Title:       "Compare the weather",
+		Description: "Compare the current weather of several cities.",
+		Arguments: []*mcp.PromptArgument{
+			{Name: "cities", Description: "Comma-separated list of cities", Required: true},
+		},
+	}, compareWeatherPrompt)
+}
+
+func planTripPrompt(_ context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	city := strings.TrimSpace(req.Params.Arguments["city"])
+	if city == "" {
+		return nil, fmt.Errorf("%w: city is required", ErrInvalidArguments)
+	}
+
+	days := 3
+	if value := strings.TrimSpace(req.Params.Arguments["days"]); value != "" {
+		var err error
+		if days, err = strconv.Atoi(value); err != nil || days < 1 || days > maxForecastDays {
+			return nil, fmt.Errorf("%w: days must be a number between 1 and %d", ErrInvalidArguments, maxForecastDays)
+		}
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "I'm planning a %d-day trip to %s, starting today.", days, city)
+	if activities := strings.TrimSpace(req.Params.Arguments["activities"]); activities != "" {
+		fmt.Fprintf(&text, " I'd like to do: %s.", activities)
+	}
+	fmt.Fprintf(&text, " Use get_forecast with city %q and days %d to get the daily forecast. Then suggest what to do on which day, what to pack, and warn me about days with heavy rain, thunderstorms or strong wind.", city, days)
+
+	return &mcp.GetPromptResult{
+		Description: fmt.Sprintf("Plan a %d-day trip to %s", days, city),
+		Messages: []*mcp.PromptMessage{
+			{Role: "user", Content: &mcp.TextContent{Text: text.String()}},
+			{Role: "user", Content: &mcp.ResourceLink{
+				URI:      CurrentWeatherURI(city),
+				Name:     "current-weather",
+				Title:    "Current weather in " + city,
+				MIMEType: "application/json",
+			}},
+		},
+	}, nil
+}
+
+func compareWeatherPrompt(_ context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	var cities []string
+	for city := range strings.SplitSeq(req.Params.Arguments["cities"], ",") {
+		if city = strings.TrimSpace(city); city != "" {
+			cities = append(cities, city)
+		}
+	}
+	if len(cities) < 2 {
+		return nil, fmt.Errorf("%w: cities needs at least two comma-separated cities", ErrInvalidArguments)
+	}
+
+	text := fmt.Sprintf("Call get_weather for each of these cities: %s. Then compare them: where is it warmest, where is it windiest, and which one has the most pleasant weather right now?", strings.Join(cities, ", "))
+	return &mcp.GetPromptResult{
+		Description: "Compare the current weather of " + strings.Join(cities, ", "),
+		Messages: []*mcp.PromptMessage{
+			{Role: "user", Content: &mcp.TextContent{Text: text}},
+		},
+	}, nil
+}
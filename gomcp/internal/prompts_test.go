@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestWeatherPrompts(t *testing.T) {
+	session := connect(t, newTestFixtureProvider(t), WeatherServerConfig{}, nil)
+	ctx := t.Context()
+
+	prompts, err := session.ListPrompts(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, prompt := range prompts.Prompts {
+		names = append(names, prompt.Name)
+	}
+	slices.Sort(names)
+	if want := []string{"compare_weather", "plan_trip"}; !slices.Equal(names, want) {
+		t.Errorf("prompts %v, want %v", names, want)
+	}
+
+	tests := []struct {
+		name   string
+		prompt string
+		args   map[string]string
+		// want are parts of the first message, err is part of the error.
+		want []string
+		err  string
+	}{
+		{
+			name: "trip", prompt: "plan_trip",
+			args: map[string]string{"city": "Rome"},
+			want: []string{"3-day trip to Rome", `get_forecast with city "Rome" and days 3`},
+		},
+		{
+			name: "trip with days and activities", prompt: "plan_trip",
+			args: map[string]string{"city": " Zurich ", "days": "5", "activities": "hiking, museums"},
+			want: []string{"5-day trip to Zurich", "I'd like to do: hiking, museums.", "days 5"},
+		},
+		{name: "trip without city", prompt: "plan_trip", args: map[string]string{"city": " "}, err: "city is required"},
+		{name: "trip too long", prompt: "plan_trip", args: map[string]string{"city": "Rome", "days": "17"}, err: "days must be a number between 1 and 16"},
+		{name: "trip days not a number", prompt: "plan_trip", args: map[string]string{"city": "Rome", "days": "a week"}, err: "days must be a number between 1 and 16"},
+		{
+			name: "compare", prompt: "compare_weather",
+			args: map[string]string{"cities": "Rome, Athens,,Zurich"},
+			want: []string{"these cities: Rome, Athens, Zurich."},
+		},
+		{name: "compare one city", prompt: "compare_weather", args: map[string]string{"cities": "Rome, "}, err: "at least two"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := session.GetPrompt(ctx, &mcp.GetPromptParams{Name: test.prompt, Arguments: test.args})
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Errorf("GetPrompt() error = %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := result.Messages[0].Content.(*mcp.TextContent).Text
+			for _, want := range test.want {
+				if !strings.Contains(text, want) {
+					t.Errorf("prompt %q does not contain %q", text, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPlanTripLinksTheCurrentWeather(t *testing.T) {
+	session := connect(t, newTestFixtureProvider(t), WeatherServerConfig{}, nil)
+	result, err := session.GetPrompt(t.Context(), &mcp.GetPromptParams{Name: "plan_trip", Arguments: map[string]string{"city": "New York"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, ok := result.Messages[1].Content.(*mcp.ResourceLink)
+	if !ok || link.URI != "weather://New%20York/current" {
+		t.Errorf("second message %+v, want a link to the current weather", result.Messages[1].Content)
+	}
+}
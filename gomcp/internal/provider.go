@@ -0,0 +1,86 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidArguments = errors.New("invalid arguments")
+	ErrLocationNotFound = errors.New("location not found")
+)
+
+type Location struct {
+	Name      string  `json:"name"`
+	Country   string  `json:"country"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// String is the name and country of the location, or its coordinates when it
+// has no name.
+func (l *Location) String() string {
+	if l.Name == "" {
+		return fmt.Sprintf("%.4f,%.4f", l.Latitude, l.Longitude)
+	}
+	return formatPlace(l.Name, l.Country)
+}
+
+type CurrentWeather struct {
+	Temperature   float64 `json:"temperature"`
+	WindSpeed     float64 `json:"wind_speed"`
+	WindDirection int     `json:"wind_direction"`
+	WeatherCode   int     `json:"weather_code"`
+	Time          string  `json:"time"`
+}
+
+func (w *CurrentWeather) Description() string {
+	return getWeatherDescription(w.WeatherCode)
+}
+
+// DailyWeather summarizes one day in the local time zone of the location.
+type DailyWeather struct {
+	Date           string  `json:"date"`
+	TemperatureMax float64 `json:"temperature_max"`
+	TemperatureMin float64 `json:"temperature_min"`
+	Precipitation  float64 `json:"precipitation"`
+	WindSpeedMax   float64 `json:"wind_speed_max"`
+	WeatherCode    int     `json:"weather_code"`
+}
+
+// DateLayout is the format of DailyWeather.Date and of date arguments.
+const DateLayout = time.DateOnly
+
+// WeatherProvider looks up locations and their weather. country is an
+// ISO-3166-1 alpha2 code and may be empty.
+type WeatherProvider interface {
+	Geocode(ctx context.Context, city, country string) (*Location, error)
+	CurrentWeather(ctx context.Context, latitude, longitude float64) (*CurrentWeather, error)
+	// Forecast returns the given number of days, starting today.
+	Forecast(ctx context.Context, latitude, longitude float64, days int) ([]DailyWeather, error)
+	// History returns the days from start to end, both inclusive.
+	History(ctx context.Context, latitude, longitude float64, start, end time.Time) ([]DailyWeather, error)
+}
+
+const (
+	ProviderEnv = "WEATHER_PROVIDER"
+	FixturesEnv = "WEATHER_FIXTURES"
+)
+
+// NewWeatherProviderFromEnv returns the open-meteo provider, or the fixture
+// provider when WEATHER_PROVIDER is "fixture". WEATHER_FIXTURES points to a
+// fixture file other than the built-in one.
+func NewWeatherProviderFromEnv() (WeatherProvider, error) {
+	switch provider := strings.ToLower(strings.TrimSpace(os.Getenv(ProviderEnv))); provider {
+	case "", "open-meteo", "openmeteo":
+		return NewOpenMeteo(OpenMeteoConfig{}), nil
+	case "fixture":
+		return NewFixtureProvider(os.Getenv(FixturesEnv))
+	default:
+		return nil, fmt.Errorf("unknown %s %q, want open-meteo or fixture", ProviderEnv, provider)
+	}
+}
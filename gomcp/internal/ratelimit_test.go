@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiterAllow(t *testing.T) {
+	start := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	type step struct {
+		at   time.Duration
+		key  string
+		ok   bool
+		wait time.Duration
+	}
+	tests := []struct {
+		name      string
+		perMinute int
+		steps     []step
+	}{
+		{
+			name:      "burst then wait",
+			perMinute: 2,
+			steps: []step{
+				{at: 0, key: "a", ok: true},
+				{at: 0, key: "a", ok: true},
+				{at: 0, key: "a", wait: 30 * time.Second},
+				{at: 10 * time.Second, key: "a", wait: 20 * time.Second},
+				{at: 30 * time.Second, key: "a", ok: true},
+				{at: 30 * time.Second, key: "a", wait: 30 * time.Second},
+			},
+		},
+		{
+			name:      "clients have their own buckets",
+			perMinute: 1,
+			steps: []step{
+				{at: 0, key: "a", ok: true},
+				{at: 0, key: "b", ok: true},
+				{at: 0, key: "a", wait: time.Minute},
+			},
+		},
+		{
+			name:      "refill stops at the burst",
+			perMinute: 2,
+			steps: []step{
+				{at: 0, key: "a", ok: true},
+				{at: time.Hour, key: "a", ok: true},
+				{at: time.Hour, key: "a", ok: true},
+				{at: time.Hour, key: "a", wait: 30 * time.Second},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			limiter := NewRateLimiter(test.perMinute)
+			var now time.Time
+			limiter.now = func() time.Time { return now }
+
+			for i, step := range test.steps {
+				now = start.Add(step.at)
+				ok, wait := limiter.Allow(step.key)
+				if ok != step.ok || wait.Round(time.Millisecond) != step.wait {
+					t.Errorf("step %d: Allow(%s) at %s = %t, %s, want %t, %s", i+1, step.key, step.at, ok, wait, step.ok, step.wait)
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	start := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	now := start
+	limiter := NewRateLimiter(60)
+	limiter.now = func() time.Time { return now }
+
+	limiter.Allow("idle")
+	now = start.Add(50 * time.Second)
+	for range 60 {
+		limiter.Allow("busy")
+	}
+
+	// At the next sweep, the idle bucket is full again and is forgotten; the
+	// busy one has refilled 11 tokens and is kept.
+	now = start.Add(61 * time.Second)
+	limiter.Allow("other")
+	if _, ok := limiter.buckets["idle"]; ok {
+		t.Error("the full bucket was not swept")
+	}
+	if _, ok := limiter.buckets["busy"]; !ok {
+		t.Error("the partly empty bucket was swept")
+	}
+}
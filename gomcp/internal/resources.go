@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const CurrentWeatherTemplate = "weather://{city}/current"
+
+func CurrentWeatherURI(city string) string {
+	return "weather://" + url.PathEscape(city) + "/current"
+}
+
+func parseCurrentWeatherURI(uri string) (string, bool) {
+	rest, ok := strings.CutPrefix(uri, "weather://")
+	if !ok {
+		return "", false
+	}
+	escaped, ok := strings.CutSuffix(rest, "/current")
+	if !ok || escaped == "" || strings.Contains(escaped, "/") {
+		return "", false
+	}
+	city, err := url.PathUnescape(escaped)
+	if err != nil || strings.TrimSpace(city) == "" {
+		return "", false
+	}
+	return city, true
+}
+
+type currentWeatherContent struct {
+	Location string `json:"location"`
+	WeatherToolOutput
+}
+
+// readCurrentWeather returns the JSON body of a weather://{city}/current
+// resource.
+func readCurrentWeather(ctx context.Context, provider WeatherProvider, uri string) (string, error) {
+	city, ok := parseCurrentWeatherURI(uri)
+	if !ok {
+		return "", mcp.ResourceNotFoundError(uri)
+	}
+
+	location, err := provider.Geocode(ctx, city, "")
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", uri, err)
+	}
+	weather, err := provider.CurrentWeather(ctx, location.Latitude, location.Longitude)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", uri, err)
+	}
+
+	body, err := json.MarshalIndent(currentWeatherContent{
+		Location: location.String(),
+		WeatherToolOutput: WeatherToolOutput{
+			Temperature:        weather.Temperature,
+			WindSpeed:          weather.WindSpeed,
+			WindDirection:      weather.WindDirection,
+			WeatherDescription: weather.Description(),
+			Time:               weather.Time,
+		},
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// AddWeatherResources registers the weather://{city}/current template and
+// one resource per listed city, so clients that only list resources find
+// some.
+func AddWeatherResources(server *mcp.Server, provider WeatherProvider, cities []string) {
+	handler := func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		body, err := readCurrentWeather(ctx, provider, req.Params.URI)
+		if err != nil {
+			return nil, err
+		}
+		return &mcp.ReadResourceResult{
+			Contents: []*mcp.ResourceContents{{URI: req.Params.URI, MIMEType: "application/json", Text: body}},
+		}, nil
+	}
+
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "current-weather",
+		Title:       "Current weather",
+		Description: "Current weather of a city. Subscribe to be notified when it changes.",
+		MIMEType:    "application/json",
+		URITemplate: CurrentWeatherTemplate,
+	}, handler)
+
+	for _, city := range cities {
+		server.AddResource(&mcp.Resource{
+			Name:        strings.ToLower(strings.ReplaceAll(city, " ", "-")) + "-current",
+			Title:       "Current weather in " + city,
+			Description: "Current weather in " + city + ". Subscribe to be notified when it changes.",
+			MIMEType:    "application/json",
+			URI:         CurrentWeatherURI(city),
+		}, handler)
+	}
+}
+
+type subscription struct {
+	subscribers int
+	last        string
+}
+
+// ResourceWatcher keeps track of subscribed weather resources and notifies
+// the subscribers when a resource reads differently than before.
+type ResourceWatcher struct {
+	provider WeatherProvider
+
+	mu            sync.Mutex
+	subscriptions map[string]*subscription
+}
+
+func NewResourceWatcher(provider WeatherProvider) *ResourceWatcher {
+	return &ResourceWatcher{provider: provider, subscriptions: map[string]*subscription{}}
+}
+
+// Subscribe is the server's SubscribeHandler. It reads the resource once, so
+// unknown cities are rejected and later changes have a baseline.
+func (w *ResourceWatcher) Subscribe(ctx context.Context, req *mcp.SubscribeRequest) error {
+	body, err := readCurrentWeather(ctx, w.provider, req.Params.URI)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sub, ok := w.subscriptions[req.Params.URI]
+	if !ok {
+		sub = &subscription{last: body}
+		w.subscriptions[req.Params.URI] = sub
+	}
+	sub.subscribers++
+	return nil
+}
+
+func (w *ResourceWatcher) Unsubscribe(_ context.Context, req *mcp.UnsubscribeRequest) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if sub, ok := w.subscriptions[req.Params.URI]; ok {
+		if sub.subscribers--; sub.subscribers <= 0 {
+			delete(w.subscriptions, req.Params.URI)
+		}
+	}
+	return nil
+}
+
+// Run checks the subscribed resources every interval until ctx is done.
+func (w *ResourceWatcher) Run(ctx context.Context, server *mcp.Server, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.poll(ctx, server)
+		}
+	}
+}
+
+func (w *ResourceWatcher) poll(ctx context.Context, server *mcp.Server) {
+	w.mu.Lock()
+	uris := make([]string, 0, len(w.subscriptions))
+	for uri := range w.subscriptions {
+		uris = append(uris, uri)
+	}
+	w.mu.Unlock()
+
+	for _, uri := range uris {
+		body, err := readCurrentWeather(ctx, w.provider, uri)
+		if err != nil {
+			log.Printf("Failed to refresh %s: %v", uri, err)
+			continue
+		}
+
+		w.mu.Lock()
+		sub, ok := w.subscriptions[uri]
+		changed := ok && sub.last != body
+		if changed {
+			sub.last = body
+		}
+		w.mu.Unlock()
+
+		if changed {
+			if err := server.ResourceUpdated(ctx, &mcp.ResourceUpdatedNotificationParams{URI: uri}); err != nil {
+				log.Printf("Failed to notify subscribers of %s: %v", uri, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// changingProvider is the fixture provider with a temperature that the test
+// can change, to trigger resource updates.
+type changingProvider struct {
+	*FixtureProvider
+	mu    sync.Mutex
+	delta float64
+}
+
+func (p *changingProvider) CurrentWeather(ctx context.Context, latitude, longitude float64) (*CurrentWeather, error) {
+	weather, err := p.FixtureProvider.CurrentWeather(ctx, latitude, longitude)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	weather.Temperature += p.delta
+	return weather, nil
+}
+
+func (p *changingProvider) warm(delta float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.delta += delta
+}
+
+func TestCurrentWeatherURI(t *testing.T) {
+	tests := []struct {
+		uri  string
+		city string
+	}{
+		{uri: CurrentWeatherURI("Rome"), city: "Rome"},
+		{uri: CurrentWeatherURI("New York"), city: "New York"},
+		{uri: "weather://S%C3%A3o%20Paulo/current", city: "São Paulo"},
+		{uri: "weather://Rome/forecast"},
+		{uri: "weather:///current"},
+		{uri: "weather://a/b/current"},
+		{uri: "weather://%20/current"},
+		{uri: "weather://%zz/current"},
+		{uri: "https://Rome/current"},
+	}
+	for _, test := range tests {
+		city, ok := parseCurrentWeatherURI(test.uri)
+		if city != test.city || ok != (test.city != "") {
+			t.Errorf("parseCurrentWeatherURI(%q) = %q, %t, want %q", test.uri, city, ok, test.city)
+		}
+	}
+	if got := CurrentWeatherURI("New York"); got != "weather://New%20York/current" {
+		t.Errorf("CurrentWeatherURI(New York) = %s", got)
+	}
+}
+
+func TestWeatherResources(t *testing.T) {
+	session := connect(t, newTestFixtureProvider(t), WeatherServerConfig{Cities: []string{"Rome", "Hangzhou"}}, nil)
+	ctx := t.Context()
+
+	resources, err := session.ListResources(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var uris []string
+	for _, resource := range resources.Resources {
+		uris = append(uris, resource.URI)
+	}
+	slices.Sort(uris)
+	if want := []string{"weather://Hangzhou/current", "weather://Rome/current"}; !slices.Equal(uris, want) {
+		t.Errorf("resources %v, want %v", uris, want)
+	}
+
+	templates, err := session.ListResourceTemplates(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates.ResourceTemplates) != 1 || templates.ResourceTemplates[0].URITemplate != CurrentWeatherTemplate {
+		t.Errorf("resource templates %+v", templates.ResourceTemplates)
+	}
+
+	tests := []struct {
+		name, uri, location string
+		temperature         float64
+	}{
+		{name: "listed city", uri: "weather://Rome/current", location: "Rome, IT", temperature: 24.3},
+		{name: "city from the template", uri: "weather://Paris/current", location: "Paris, FR", temperature: 21.4},
+		{name: "unknown city", uri: "weather://Atlantis/current"},
+		{name: "other resource", uri: "weather://Rome/forecast"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: test.uri})
+			if test.location == "" {
+				if err == nil {
+					t.Errorf("ReadResource(%s) succeeded", test.uri)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			contents := result.Contents[0]
+			var body currentWeatherContent
+			if err := json.Unmarshal([]byte(contents.Text), &body); err != nil {
+				t.Fatal(err)
+			}
+			if contents.URI != test.uri || contents.MIMEType != "application/json" || body.Location != test.location || body.Temperature != test.temperature {
+				t.Errorf("ReadResource(%s) = %s %s %+v", test.uri, contents.URI, contents.MIMEType, body)
+			}
+		})
+	}
+}
+
+func TestResourceSubscriptions(t *testing.T) {
+	provider := &changingProvider{FixtureProvider: newTestFixtureProvider(t)}
+	updates := make(chan string, 10)
+	session := connect(t, provider, WeatherServerConfig{PollInterval: 10 * time.Millisecond}, &mcp.ClientOptions{
+		ResourceUpdatedHandler: func(_ context.Context, req *mcp.ResourceUpdatedNotificationRequest) {
+			updates <- req.Params.URI
+		},
+	})
+	ctx := t.Context()
+
+	if err := session.Subscribe(ctx, &mcp.SubscribeParams{URI: "weather://Atlantis/current"}); err == nil {
+		t.Error("subscribing to an unknown city succeeded")
+	}
+	const rome = "weather://Rome/current"
+	if err := session.Subscribe(ctx, &mcp.SubscribeParams{URI: rome}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unchanged weather is not announced.
+	select {
+	case uri := <-updates:
+		t.Fatalf("update of %s before the weather changed", uri)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	provider.warm(1.5)
+	select {
+	case uri := <-updates:
+		if uri != rome {
+			t.Errorf("update of %s, want %s", uri, rome)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update after the weather changed")
+	}
+	// One change is announced once.
+	select {
+	case uri := <-updates:
+		t.Fatalf("second update of %s for one change", uri)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := session.Unsubscribe(ctx, &mcp.UnsubscribeParams{URI: rome}); err != nil {
+		t.Fatal(err)
+	}
+	provider.warm(1.5)
+	select {
+	case uri := <-updates:
+		t.Errorf("update of %s after unsubscribing", uri)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"context"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+var DefaultCities = []string{"Zurich", "Rome", "Athens", "Wellington"}
+
+type WeatherServerConfig struct {
+	// Cities are listed as weather://{city}/current resources, DefaultCities
+	// when nil. Other cities can still be read through the template.
+	Cities []string
+	// PollInterval is how often subscribed resources are checked for
+	// changes, 5 minutes by default.
+	PollInterval time.Duration
+}
+
+// NewWeatherServer creates the weather MCP server with its tools, resources
+// and prompts. Subscribed resources are checked for changes until ctx is done.
+func NewWeatherServer(ctx context.Context, provider WeatherProvider, config WeatherServerConfig) *mcp.Server {
+	if config.Cities == nil {
+		config.Cities = DefaultCities
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 5 * time.Minute
+	}
+
+	watcher := NewResourceWatcher(provider)
+	server := mcp.NewServer(&mcp.Implementation{
+		Name:    "weather-server",
+		Version: "1.1.0",
+	}, &mcp.ServerOptions{
+		Instructions:       "Weather information server. Provides current weather, daily forecasts and historical weather for locations given by latitude/longitude coordinates or city names. The weather://{city}/current resources can be subscribed to for updates.",
+		SubscribeHandler:   watcher.Subscribe,
+		UnsubscribeHandler: watcher.Unsubscribe,
+	})
+
+	AddWeatherTools(server, provider)
+	AddWeatherResources(server, provider, config.Cities)
+	AddWeatherPrompts(server)
+
+	go watcher.Run(ctx, server, config.PollInterval)
+	return server
+}
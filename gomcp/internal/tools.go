@@ -0,0 +1,171 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	maxForecastDays = 16
+	maxHistoryDays  = 92
+)
+
+type WeatherToolInput struct {
+	Latitude  *float64 `json:"latitude,omitempty" jsonschema:"Latitude coordinate (optional if city is provided)"`
+	Longitude *float64 `json:"longitude,omitempty" jsonschema:"Longitude coordinate (optional if city is provided)"`
+	City      *string  `json:"city,omitempty" jsonschema:"City name (optional if latitude/longitude provided)"`
+	Country   *string  `json:"country,omitempty" jsonschema:"Country ISO-3166-1 alpha2 code (optional, narrows down the city)"`
+}
+
+func (i WeatherToolInput) args() WeatherFunctionArgs {
+	return WeatherFunctionArgs{
+		Latitude:  i.Latitude,
+		Longitude: i.Longitude,
+		City:      i.City,
+		Country:   i.Country,
+	}
+}
+
+type WeatherToolOutput struct {
+	Temperature        float64 `json:"temperature" jsonschema:"Temperature in Celsius"`
+	WindSpeed          float64 `json:"wind_speed" jsonschema:"Wind speed in km/h"`
+	WindDirection      int     `json:"wind_direction" jsonschema:"Wind direction in degrees"`
+	WeatherDescription string  `json:"weather_description" jsonschema:"Human-readable weather description"`
+	Time               string  `json:"time" jsonschema:"Time of the weather observation"`
+}
+
+type ForecastToolInput struct {
+	WeatherToolInput
+	Days int `json:"days,omitempty" jsonschema:"Number of days to forecast starting today, 1 to 16 (default 3)"`
+}
+
+type HistoryToolInput struct {
+	WeatherToolInput
+	StartDate string `json:"start_date" jsonschema:"First day, YYYY-MM-DD"`
+	EndDate   string `json:"end_date" jsonschema:"Last day, YYYY-MM-DD, at most 92 days after start_date"`
+}
+
+type DayOutput struct {
+	Date               string  `json:"date" jsonschema:"Local date, YYYY-MM-DD"`
+	TemperatureMax     float64 `json:"temperature_max" jsonschema:"Maximum temperature in Celsius"`
+	TemperatureMin     float64 `json:"temperature_min" jsonschema:"Minimum temperature in Celsius"`
+	Precipitation      float64 `json:"precipitation" jsonschema:"Precipitation sum in mm"`
+	WindSpeedMax       float64 `json:"wind_speed_max" jsonschema:"Maximum wind speed in km/h"`
+	WeatherDescription string  `json:"weather_description" jsonschema:"Human-readable weather description"`
+}
+
+type DailyToolOutput struct {
+	Location string      `json:"location" jsonschema:"The place the days belong to"`
+	Days     []DayOutput `json:"days"`
+}
+
+// AddWeatherTools registers get_weather, get_forecast and
+// get_historical_weather on server. Invalid arguments and unknown places come
+// back as tool results with IsError set, so the model can correct its call.
+func AddWeatherTools(server *mcp.Server, provider WeatherProvider) {
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "get_weather",
+		Description: "Get current weather information for a location. You can provide either latitude/longitude coordinates OR a city name, optionally with its country ISO-3166-1 alpha2 code. If you know the coordinates, provide them directly. Add the country code when the city name is ambiguous.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input WeatherToolInput) (*mcp.CallToolResult, WeatherToolOutput, error) {
+		result, err := ExecuteWeatherFunction(ctx, provider, input.args())
+		if err != nil {
+			return nil, WeatherToolOutput{}, err
+		}
+
+		output := WeatherToolOutput{
+			Temperature:        result.Temperature,
+			WindSpeed:          result.WindSpeed,
+			WindDirection:      result.WindDirection,
+			WeatherDescription: result.WeatherDescription,
+			Time:               result.Time,
+		}
+
+		return nil, output, nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "get_forecast",
+		Description: "Get a daily weather forecast for up to 16 days, starting today. The location is given like for get_weather.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input ForecastToolInput) (*mcp.CallToolResult, DailyToolOutput, error) {
+		days := input.Days
+		if days == 0 {
+			days = 3
+		}
+		if days < 1 || days > maxForecastDays {
+			return nil, DailyToolOutput{}, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidArguments, maxForecastDays)
+		}
+
+		location, err := Locate(ctx, provider, input.args())
+		if err != nil {
+			return nil, DailyToolOutput{}, err
+		}
+
+		forecast, err := provider.Forecast(ctx, location.Latitude, location.Longitude, days)
+		if err != nil {
+			return nil, DailyToolOutput{}, fmt.Errorf("forecast fetch failed: %w", err)
+		}
+
+		return nil, dailyOutput(location, forecast), nil
+	})
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "get_historical_weather",
+		Description: "Get the observed daily weather of past days, up to 92 days per call. The location is given like for get_weather.",
+	}, func(ctx context.Context, req *mcp.CallToolRequest, input HistoryToolInput) (*mcp.CallToolResult, DailyToolOutput, error) {
+		start, end, err := parseDateRange(input.StartDate, input.EndDate)
+		if err != nil {
+			return nil, DailyToolOutput{}, err
+		}
+
+		location, err := Locate(ctx, provider, input.args())
+		if err != nil {
+			return nil, DailyToolOutput{}, err
+		}
+
+		history, err := provider.History(ctx, location.Latitude, location.Longitude, start, end)
+		if err != nil {
+			return nil, DailyToolOutput{}, fmt.Errorf("history fetch failed: %w", err)
+		}
+
+		return nil, dailyOutput(location, history), nil
+	})
+}
+
+func parseDateRange(startDate, endDate string) (time.Time, time.Time, error) {
+	start, err := time.Parse(DateLayout, startDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: start_date %q is not a YYYY-MM-DD date", ErrInvalidArguments, startDate)
+	}
+	end, err := time.Parse(DateLayout, endDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: end_date %q is not a YYYY-MM-DD date", ErrInvalidArguments, endDate)
+	}
+
+	switch {
+	case end.Before(start):
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: end_date is before start_date", ErrInvalidArguments)
+	case end.Sub(start) >= maxHistoryDays*24*time.Hour:
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: at most %d days can be requested at once", ErrInvalidArguments, maxHistoryDays)
+	case end.After(time.Now()):
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: end_date is in the future, use get_forecast instead", ErrInvalidArguments)
+	}
+	return start, end, nil
+}
+
+func dailyOutput(location *Location, days []DailyWeather) DailyToolOutput {
+	output := DailyToolOutput{Location: location.String(), Days: make([]DayOutput, 0, len(days))}
+	for _, day := range days {
+		output.Days = append(output.Days, DayOutput{
+			Date:               day.Date,
+			TemperatureMax:     day.TemperatureMax,
+			TemperatureMin:     day.TemperatureMin,
+			Precipitation:      day.Precipitation,
+			WindSpeedMax:       day.WindSpeedMax,
+			WeatherDescription: getWeatherDescription(day.WeatherCode),
+		})
+	}
+	return output
+}
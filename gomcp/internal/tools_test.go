@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// connect serves the weather server over an in-memory transport and returns
+// the client's session.
+func connect(t *testing.T, provider WeatherProvider, config WeatherServerConfig, opts *mcp.ClientOptions) *mcp.ClientSession {
+	t.Helper()
+	ctx := t.Context()
+	server := NewWeatherServer(ctx, provider, config)
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatal(err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "1.0.0"}, opts)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+// callTool calls a tool and decodes its structured output into out, unless
+// the call failed; then it returns the error text.
+func callTool(t *testing.T, session *mcp.ClientSession, name string, args map[string]any, out any) string {
+	t.Helper()
+	result, err := session.CallTool(t.Context(), &mcp.CallToolParams{Name: name, Arguments: args})
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if result.IsError {
+		return result.Content[0].(*mcp.TextContent).Text
+	}
+	encoded, err := json.Marshal(result.StructuredContent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(encoded, out); err != nil {
+		t.Fatal(err)
+	}
+	return ""
+}
+
+func TestGetWeather(t *testing.T) {
+	session := connect(t, newTestFixtureProvider(t), WeatherServerConfig{}, nil)
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want WeatherToolOutput
+		// err is part of the tool error, empty for a result.
+		err string
+	}{
+		{
+			name: "city without country",
+			args: map[string]any{"city": "rome"},
+			want: WeatherToolOutput{Temperature: 24.3, WindSpeed: 9.4, WindDirection: 230, WeatherDescription: "Mainly clear", Time: "2026-06-15T14:00"},
+		},
+		{
+			name: "city and country",
+			args: map[string]any{"city": " Paris ", "country": "fr"},
+			want: WeatherToolOutput{Temperature: 21.4, WindSpeed: 12.6, WindDirection: 250, WeatherDescription: "Partly cloudy", Time: "2026-06-15T14:00"},
+		},
+		{
+			name: "coordinates",
+			args: map[string]any{"latitude": 47.37, "longitude": 8.55},
+			want: WeatherToolOutput{Temperature: 18.7, WindSpeed: 6.1, WindDirection: 270, WeatherDescription: "Overcast", Time: "2026-06-15T14:00"},
+		},
+		{name: "nothing", args: map[string]any{}, err: "invalid arguments: either latitude/longitude or city must be provided"},
+		{name: "blank city", args: map[string]any{"city": "  "}, err: "invalid arguments: either latitude/longitude or city must be provided"},
+		{name: "latitude only", args: map[string]any{"latitude": 47.37}, err: "invalid arguments: latitude and longitude must be provided together"},
+		{name: "latitude out of range", args: map[string]any{"latitude": 91, "longitude": 8.55}, err: "invalid arguments: latitude 91 is outside -90..90"},
+		{name: "longitude out of range", args: map[string]any{"latitude": 47.37, "longitude": -181}, err: "invalid arguments: longitude -181 is outside -180..180"},
+		{name: "country name", args: map[string]any{"city": "Rome", "country": "Italy"}, err: `invalid arguments: country "Italy" is not an ISO-3166-1 alpha2 code`},
+		{name: "unknown city", args: map[string]any{"city": "Atlantis"}, err: "geocoding failed: location not found: Atlantis"},
+		{name: "city in another country", args: map[string]any{"city": "Paris", "country": "us"}, err: "geocoding failed: location not found: Paris, US"},
+		{name: "no weather there", args: map[string]any{"latitude": 0, "longitude": 0}, err: "weather fetch failed: location not found"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got WeatherToolOutput
+			errText := callTool(t, session, "get_weather", test.args, &got)
+			if test.err != "" {
+				if !strings.Contains(errText, test.err) {
+					t.Errorf("tool error %q, want %q", errText, test.err)
+				}
+				return
+			}
+			if errText != "" {
+				t.Fatal(errText)
+			}
+			if got != test.want {
+				t.Errorf("get_weather = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGetForecast(t *testing.T) {
+	session := connect(t, newTestFixtureProvider(t), WeatherServerConfig{}, nil)
+
+	tests := []struct {
+		name     string
+		args     map[string]any
+		location string
+		dates    string
+		err      string
+	}{
+		{name: "three days by default", args: map[string]any{"city": "Rome"}, location: "Rome, IT", dates: "2026-06-15 2026-06-16 2026-06-17"},
+		{name: "days", args: map[string]any{"city": "Rome", "days": 1}, location: "Rome, IT", dates: "2026-06-15"},
+		{name: "coordinates", args: map[string]any{"latitude": 41.89193, "longitude": 12.51133, "days": 2}, location: "41.8919,12.5113", dates: "2026-06-15 2026-06-16"},
+		{name: "too many days", args: map[string]any{"city": "Rome", "days": 17}, err: "invalid arguments: days must be between 1 and 16"},
+		{name: "negative days", args: map[string]any{"city": "Rome", "days": -1}, err: "invalid arguments: days must be between 1 and 16"},
+		{name: "no location", args: map[string]any{"days": 2}, err: "invalid arguments: either latitude/longitude or city must be provided"},
+		{name: "unknown city", args: map[string]any{"city": "Atlantis"}, err: "geocoding failed"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkDaily(t, session, "get_forecast", test.args, test.location, test.dates, test.err)
+		})
+	}
+}
+
+func TestGetHistoricalWeather(t *testing.T) {
+	session := connect(t, newTestFixtureProvider(t), WeatherServerConfig{}, nil)
+	rome := func(start, end string) map[string]any {
+		return map[string]any{"city": "Rome", "start_date": start, "end_date": end}
+	}
+
+	tests := []struct {
+		name     string
+		args     map[string]any
+		location string
+		dates    string
+		err      string
+	}{
+		{name: "range", args: rome("2026-06-08", "2026-06-10"), location: "Rome, IT", dates: "2026-06-08 2026-06-09 2026-06-10"},
+		{name: "one day", args: rome("2026-06-09", "2026-06-09"), location: "Rome, IT", dates: "2026-06-09"},
+		{name: "bad start date", args: rome("June 8", "2026-06-10"), err: `invalid arguments: start_date "June 8" is not a YYYY-MM-DD date`},
+		{name: "bad end date", args: rome("2026-06-08", ""), err: `invalid arguments: end_date "" is not a YYYY-MM-DD date`},
+		{name: "end before start", args: rome("2026-06-10", "2026-06-08"), err: "invalid arguments: end_date is before start_date"},
+		{name: "92 days", args: rome("2026-03-12", "2026-06-11"), location: "Rome, IT", dates: "2026-06-08 2026-06-09 2026-06-10 2026-06-11"},
+		{name: "too long", args: rome("2026-03-11", "2026-06-11"), err: "invalid arguments: at most 92 days can be requested at once"},
+		{name: "future", args: rome(time.Now().Format(DateLayout), time.Now().AddDate(0, 0, 2).Format(DateLayout)), err: "end_date is in the future, use get_forecast instead"},
+		{name: "no fixture days", args: rome("2025-06-08", "2025-06-10"), err: "history fetch failed"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkDaily(t, session, "get_historical_weather", test.args, test.location, test.dates, test.err)
+		})
+	}
+}
+
+func checkDaily(t *testing.T, session *mcp.ClientSession, tool string, args map[string]any, location, dates, err string) {
+	t.Helper()
+	var got DailyToolOutput
+	errText := callTool(t, session, tool, args, &got)
+	if err != "" {
+		if !strings.Contains(errText, err) {
+			t.Errorf("tool error %q, want %q", errText, err)
+		}
+		return
+	}
+	if errText != "" {
+		t.Fatal(errText)
+	}
+
+	var gotDates []string
+	for _, day := range got.Days {
+		gotDates = append(gotDates, day.Date)
+		if day.WeatherDescription == "" || day.TemperatureMax < day.TemperatureMin {
+			t.Errorf("day %+v", day)
+		}
+	}
+	if got.Location != location || strings.Join(gotDates, " ") != dates {
+		t.Errorf("%s = %s on %v, want %s on %s", tool, got.Location, gotDates, location, dates)
+	}
+}
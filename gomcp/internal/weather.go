@@ -1,22 +1,19 @@
 package internal
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
 )
 
 const forecastBaseURL = "https://api.open-meteo.com/v1/forecast"
 
 type currentWeather struct {
-	Temperature     float64 `json:"temperature_2m"`
-	WindSpeed       float64 `json:"windspeed_10m"`
-	WindDirection   int     `json:"winddirection_10m"`
-	WeatherCode     int     `json:"weathercode"`
-	WeatherCodeText string  `json:"-"`
-	Time            string  `json:"time"`
+	Temperature   float64 `json:"temperature_2m"`
+	WindSpeed     float64 `json:"windspeed_10m"`
+	WindDirection int     `json:"winddirection_10m"`
+	WeatherCode   int     `json:"weathercode"`
+	Time          string  `json:"time"`
 }
 
 type weatherResponse struct {
@@ -26,36 +23,33 @@ type weatherResponse struct {
 	Current   currentWeather `json:"current"`
 }
 
-func getCurrentWeather(latitude, longitude float64) (*currentWeather, error) {
+func (o *OpenMeteo) CurrentWeather(ctx context.Context, latitude, longitude float64) (*CurrentWeather, error) {
+	// Two decimals are about a kilometre, close enough to share a forecast.
+	key := fmt.Sprintf("%.2f,%.2f", latitude, longitude)
+	if weather, ok := o.weather.get(key); ok {
+		return weather, nil
+	}
 
 	params := url.Values{}
 	params.Add("latitude", fmt.Sprintf("%.6f", latitude))
 	params.Add("longitude", fmt.Sprintf("%.6f", longitude))
 	params.Add("current", "temperature_2m,windspeed_10m,winddirection_10m,weathercode")
 
-	fullURL := fmt.Sprintf("%s?%s", forecastBaseURL, params.Encode())
-
-	resp, err := http.Get(fullURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make weather request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("weather API returned status %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	var weatherResp weatherResponse
+	if err := o.getJSON(ctx, "weather", o.forecastURL, params, &weatherResp); err != nil {
+		return nil, err
 	}
 
-	var weatherResp weatherResponse
-	if err := json.Unmarshal(body, &weatherResp); err != nil {
-		return nil, fmt.Errorf("failed to parse weather response: %w", err)
+	current := weatherResp.Current
+	weather := &CurrentWeather{
+		Temperature:   current.Temperature,
+		WindSpeed:     current.WindSpeed,
+		WindDirection: current.WindDirection,
+		WeatherCode:   current.WeatherCode,
+		Time:          current.Time,
 	}
-	weatherResp.Current.WeatherCodeText = getWeatherDescription(weatherResp.Current.WeatherCode)
-	return &weatherResp.Current, nil
+	o.weather.put(key, weather)
+	return weather, nil
 }
 
 func getWeatherDescription(code int) string {
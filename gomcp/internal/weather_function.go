@@ -1,7 +1,9 @@
 package internal
 
 import (
+	"context"
 	"fmt"
+	"strings"
 )
 
 type WeatherFunctionArgs struct {
@@ -19,33 +21,76 @@ type WeatherFunctionResult struct {
 	Time               string
 }
 
-func ExecuteWeatherFunction(args WeatherFunctionArgs) (*WeatherFunctionResult, error) {
-	var latitude, longitude float64
-	var hasCoords bool
-
-	if args.Latitude != nil && args.Longitude != nil {
-		latitude = *args.Latitude
-		longitude = *args.Longitude
-		hasCoords = true
+// Validate checks that the arguments name exactly one location: coordinates
+// in range, or a city with an optional ISO-3166-1 alpha2 country code.
+func (a WeatherFunctionArgs) Validate() error {
+	if (a.Latitude == nil) != (a.Longitude == nil) {
+		return fmt.Errorf("%w: latitude and longitude must be provided together", ErrInvalidArguments)
 	}
 
-	if !hasCoords {
-		city, hasCity := args.City, args.City != nil
-		country := args.Country
+	if a.Latitude != nil {
+		if *a.Latitude < -90 || *a.Latitude > 90 {
+			return fmt.Errorf("%w: latitude %g is outside -90..90", ErrInvalidArguments, *a.Latitude)
+		}
+		if *a.Longitude < -180 || *a.Longitude > 180 {
+			return fmt.Errorf("%w: longitude %g is outside -180..180", ErrInvalidArguments, *a.Longitude)
+		}
+		return nil
+	}
 
-		if !hasCity {
-			return nil, fmt.Errorf("either latitude/longitude or city must be provided")
+	if a.City == nil || strings.TrimSpace(*a.City) == "" {
+		return fmt.Errorf("%w: either latitude/longitude or city must be provided", ErrInvalidArguments)
+	}
+	if a.Country != nil {
+		if country := strings.TrimSpace(*a.Country); country != "" && !isCountryCode(country) {
+			return fmt.Errorf("%w: country %q is not an ISO-3166-1 alpha2 code", ErrInvalidArguments, country)
 		}
-		geoResult, err := getLatLng(*city, *country)
-		if err != nil {
-			return nil, fmt.Errorf("geocoding failed: %w", err)
+	}
+	return nil
+}
+
+func isCountryCode(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
 		}
+	}
+	return true
+}
 
-		latitude = geoResult.Latitude
-		longitude = geoResult.Longitude
+// Locate validates the arguments and resolves them to coordinates. A location
+// given by coordinates has no name.
+func Locate(ctx context.Context, provider WeatherProvider, args WeatherFunctionArgs) (*Location, error) {
+	if err := args.Validate(); err != nil {
+		return nil, err
+	}
+
+	if args.Latitude != nil {
+		return &Location{Latitude: *args.Latitude, Longitude: *args.Longitude}, nil
+	}
+
+	country := ""
+	if args.Country != nil {
+		country = strings.ToUpper(strings.TrimSpace(*args.Country))
+	}
+
+	location, err := provider.Geocode(ctx, strings.TrimSpace(*args.City), country)
+	if err != nil {
+		return nil, fmt.Errorf("geocoding failed: %w", err)
+	}
+	return location, nil
+}
+
+func ExecuteWeatherFunction(ctx context.Context, provider WeatherProvider, args WeatherFunctionArgs) (*WeatherFunctionResult, error) {
+	location, err := Locate(ctx, provider, args)
+	if err != nil {
+		return nil, err
 	}
 
-	weatherInfo, err := getCurrentWeather(latitude, longitude)
+	weatherInfo, err := provider.CurrentWeather(ctx, location.Latitude, location.Longitude)
 	if err != nil {
 		return nil, fmt.Errorf("weather fetch failed: %w", err)
 	}
@@ -54,7 +99,7 @@ func ExecuteWeatherFunction(args WeatherFunctionArgs) (*WeatherFunctionResult, e
 		Temperature:        weatherInfo.Temperature,
 		WindSpeed:          weatherInfo.WindSpeed,
 		WindDirection:      weatherInfo.WindDirection,
-		WeatherDescription: weatherInfo.WeatherCodeText,
+		WeatherDescription: weatherInfo.Description(),
 		Time:               weatherInfo.Time,
 	}, nil
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"resilience-go-demo/policyconfig"
+)
+
+const demoPolicies = `routes:
+  catalog:
+    timeout: 100ms
+    retry:
+      maxAttempts: 3
+      delay: 20ms
+      maxDelay: 80ms
+    cache:
+      ttl: 1m
+  checkout:
+    rateLimiter:
+      maxExecutions: %d
+      period: 1s
+      bursty: true
+`
+
+func demoPolicyConfig() {
+	section("Policies from a config file + hot reload")
+
+	dir, err := os.MkdirTemp("", "policies")
+	if err != nil {
+		fmt.Printf("  temp dir failed: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "policies.yaml")
+	if err := writePolicies(path, fmt.Appendf(nil, demoPolicies, 2)); err != nil {
+		fmt.Printf("  writing policies failed: %v\n", err)
+		return
+	}
+
+	registry, err := policyconfig.Open(path)
+	if err != nil {
+		fmt.Printf("  loading policies failed: %v\n", err)
+		return
+	}
+	fmt.Printf("  loaded routes: %v\n", registry.Routes())
+
+	var catalogCalls atomic.Int32
+	catalog := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if catalogCalls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("12 products"))
+	}))
+	defer catalog.Close()
+
+	client := &http.Client{Transport: registry.RoundTripper("catalog", nil)}
+	for range 2 {
+		status, body := get(client, catalog.URL)
+		fmt.Printf("  catalog: status=%d body=%q upstream calls=%d\n", status, body, catalogCalls.Load())
+	}
+
+	checkout := httptest.NewServer(registry.Handler("checkout", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("order placed"))
+	})))
+	defer checkout.Close()
+
+	placeOrders := func(label string) {
+		for attempt := 1; attempt <= 3; attempt++ {
+			status, _ := get(http.DefaultClient, checkout.URL)
+			fmt.Printf("  %s: order %d status=%d\n", label, attempt, status)
+		}
+	}
+	placeOrders("checkout")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloads := make(chan error)
+	go registry.Watch(ctx, 20*time.Millisecond, func(err error) {
+		select {
+		case reloads <- err:
+		case <-ctx.Done():
+		}
+	})
+
+	reload := func(content []byte) {
+		if err := writePolicies(path, content); err != nil {
+			fmt.Printf("  writing policies failed: %v\n", err)
+			return
+		}
+		select {
+		case err := <-reloads:
+			if err != nil {
+				fmt.Printf("  reload rejected, keeping the current policies: %v\n", err)
+				return
+			}
+			fmt.Println("  reloaded policies")
+		case <-time.After(time.Second):
+			fmt.Println("  policies were not reloaded")
+		}
+	}
+
+	reload(fmt.Appendf(nil, demoPolicies, 10))
+	placeOrders("checkout after raising the limit")
+
+	reload([]byte("routes:\n  checkout:\n    retry:\n      maxAttempts: 0\n"))
+	status, body := get(client, catalog.URL)
+	fmt.Printf("  catalog after the rejected reload: status=%d body=%q upstream calls=%d\n", status, body, catalogCalls.Load())
+}
+
+// writePolicies replaces the file at once, so a reload never sees it half
+// written.
+func writePolicies(path string, content []byte) error {
+	if err := os.WriteFile(path+".tmp", content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func get(client *http.Client, url string) (int, string) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err.Error()
+	}
+	return resp.StatusCode, string(body)
+}
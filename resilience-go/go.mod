@@ -2,7 +2,10 @@ module resilience-go-demo
 
 go 1.26.5
 
-require github.com/failsafe-go/failsafe-go v0.9.6
+require (
+	github.com/failsafe-go/failsafe-go v0.9.6
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/bits-and-blooms/bitset v1.24.6 // indirect
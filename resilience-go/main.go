@@ -9,6 +9,7 @@ func main() {
 	demoRetryCircuitFallback()
 	demoHedgeTimeoutAsync()
 	demoHTTPAdapter()
+	demoPolicyConfig()
 	demoCachePolicy()
 	demoRateLimiter()
 	demoBulkhead()
@@ -0,0 +1,140 @@
+package policyconfig
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// responseCache is a cachepolicy.Cache for HTTP responses. It keeps the
+// bodies in memory and hands out a copy on every hit.
+type responseCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+}
+
+type cachedResponse struct {
+	resp    http.Response
+	body    []byte
+	expires time.Time
+}
+
+func newResponseCache(ttl time.Duration, maxEntries int) *responseCache {
+	return &responseCache{ttl: ttl, maxEntries: maxEntries, entries: map[string]cachedResponse{}}
+}
+
+func (c *responseCache) Get(key string) (*http.Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	resp := entry.resp
+	resp.Header = entry.resp.Header.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(entry.body))
+	return &resp, true
+}
+
+// Set reads the body of resp and replaces it with the buffered copy, so the
+// caller can still read it.
+func (c *responseCache) Set(key string, resp *http.Response) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		// Not cached; the caller gets what was read, then the error.
+		resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), errorReader{err}))
+		return
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry := cachedResponse{resp: *resp, body: body, expires: time.Now().Add(c.ttl)}
+	entry.resp.Header = resp.Header.Clone()
+	entry.resp.Body = nil
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && c.maxEntries > 0 && len(c.entries) >= c.maxEntries {
+		c.evict()
+	}
+	c.entries[key] = entry
+}
+
+// evict removes the expired entries, or the entry that expires first when
+// none has expired.
+func (c *responseCache) evict() {
+	now := time.Now()
+	var first string
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if first == "" || entry.expires.Before(c.entries[first].expires) {
+			first = key
+		}
+	}
+	if len(c.entries) >= c.maxEntries {
+		delete(c.entries, first)
+	}
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+// cacheableRequest reports whether the response to req may come from, and go
+// to, the cache. The key is only the URL, so requests with credentials, whose
+// responses may differ between callers, are left out.
+func cacheableRequest(req *http.Request) bool {
+	if req.Method != http.MethodGet {
+		return false
+	}
+	if req.Header.Get("Authorization") != "" || req.Header.Get("Cookie") != "" {
+		return false
+	}
+	return !cacheControl(req.Header, "no-store")
+}
+
+// cacheableResponse reports whether a response may be served to every caller
+// of its URL.
+func cacheableResponse(resp *http.Response, err error) bool {
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return false
+	}
+	// A response that varies by request header or sets a cookie belongs to
+	// one caller.
+	if resp.Header.Get("Vary") != "" || resp.Header.Get("Set-Cookie") != "" {
+		return false
+	}
+	return !cacheControl(resp.Header, "no-store") && !cacheControl(resp.Header, "private")
+}
+
+// cacheControl reports whether the Cache-Control header has directive.
+func cacheControl(header http.Header, directive string) bool {
+	for _, value := range header.Values("Cache-Control") {
+		for part := range strings.SplitSeq(value, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if strings.EqualFold(name, directive) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package policyconfig
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func response(body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Etag": {body}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestResponseCache(t *testing.T) {
+	cache := newResponseCache(time.Hour, 2)
+
+	resp := response("one")
+	cache.Set("a", resp)
+	if body := readBody(t, resp); body != "one" {
+		t.Errorf("caller body = %q, want one", body)
+	}
+
+	// Every hit gets its own body and header.
+	for range 2 {
+		cached, ok := cache.Get("a")
+		if !ok {
+			t.Fatal("cache miss")
+		}
+		if body := readBody(t, cached); body != "one" {
+			t.Errorf("cached body = %q, want one", body)
+		}
+		cached.Header.Set("Etag", "changed")
+	}
+	if cached, _ := cache.Get("a"); cached.Header.Get("Etag") != "one" {
+		t.Error("changing a hit's header changed the cache")
+	}
+
+	cache.Set("b", response("two"))
+	cache.Set("c", response("three"))
+	if len(cache.entries) != 2 {
+		t.Errorf("cache has %d entries, want 2", len(cache.entries))
+	}
+	if _, ok := cache.Get("c"); !ok {
+		t.Error("newest entry was evicted")
+	}
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	cache := newResponseCache(time.Millisecond, 0)
+	cache.Set("a", response("one"))
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := cache.Get("a"); ok {
+		t.Error("expired entry was returned")
+	}
+}
+
+func TestResponseCacheReadError(t *testing.T) {
+	cache := newResponseCache(time.Hour, 0)
+	errRead := errors.New("connection reset")
+	resp := response("")
+	resp.Body = io.NopCloser(io.MultiReader(strings.NewReader("partial"), iotest.ErrReader(errRead)))
+
+	cache.Set("a", resp)
+	body, err := io.ReadAll(resp.Body)
+	if string(body) != "partial" || !errors.Is(err, errRead) {
+		t.Errorf("caller read %q, %v; want partial, %v", body, err, errRead)
+	}
+	if _, ok := cache.Get("a"); ok {
+		t.Error("incomplete response was cached")
+	}
+}
+
+func TestCacheableRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		header http.Header
+		want   bool
+	}{
+		{name: "GET", method: http.MethodGet, want: true},
+		{name: "max-age", method: http.MethodGet, header: http.Header{"Cache-Control": {"max-age=60"}}, want: true},
+		{name: "POST", method: http.MethodPost},
+		{name: "Authorization", method: http.MethodGet, header: http.Header{"Authorization": {"Bearer secret"}}},
+		{name: "Cookie", method: http.MethodGet, header: http.Header{"Cookie": {"session=1"}}},
+		{name: "no-store", method: http.MethodGet, header: http.Header{"Cache-Control": {"max-age=0, No-Store"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, "http://catalog.test/items", nil)
+			for key, values := range test.header {
+				req.Header[key] = values
+			}
+			if got := cacheableRequest(req); got != test.want {
+				t.Errorf("cacheableRequest() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCacheableResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		err    error
+		want   bool
+	}{
+		{name: "200", status: http.StatusOK, want: true},
+		{name: "public", status: http.StatusOK, header: http.Header{"Cache-Control": {"public, max-age=60"}}, want: true},
+		{name: "404", status: http.StatusNotFound},
+		{name: "error", status: http.StatusOK, err: errors.New("timeout")},
+		{name: "no-store", status: http.StatusOK, header: http.Header{"Cache-Control": {"no-store"}}},
+		{name: "private", status: http.StatusOK, header: http.Header{"Cache-Control": {"max-age=60", `private="Set-Cookie"`}}},
+		{name: "Vary", status: http.StatusOK, header: http.Header{"Vary": {"Authorization"}}},
+		{name: "Set-Cookie", status: http.StatusOK, header: http.Header{"Set-Cookie": {"session=1"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: test.status, Header: http.Header{}}
+			for key, values := range test.header {
+				resp.Header[key] = values
+			}
+			if got := cacheableResponse(resp, test.err); got != test.want {
+				t.Errorf("cacheableResponse() = %t, want %t", got, test.want)
+			}
+		})
+	}
+}
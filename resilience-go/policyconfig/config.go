@@ -0,0 +1,214 @@
+// Package policyconfig builds failsafe-go policies for HTTP clients and
+// servers from a YAML or JSON file, so they can be tuned without a redeploy.
+// The file configures the policies of named routes:
+//
+//	routes:
+//	  inventory:
+//	    timeout: 2s
+//	    retry:
+//	      maxAttempts: 3
+//	      delay: 50ms
+//	      maxDelay: 1s
+//	    circuitBreaker:
+//	      failureThreshold: 5
+//	      delay: 10s
+//
+// A Registry serves the policies of the routes and reloads them when the file
+// changes.
+package policyconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Routes map[string]Route `yaml:"routes"`
+}
+
+// Route configures the policies of one route. Policies that are not set are
+// left out.
+type Route struct {
+	// Timeout limits each attempt, including its hedges.
+	Timeout        time.Duration         `yaml:"timeout"`
+	Retry          *RetryConfig          `yaml:"retry"`
+	CircuitBreaker *CircuitBreakerConfig `yaml:"circuitBreaker"`
+	Hedge          *HedgeConfig          `yaml:"hedge"`
+	Bulkhead       *BulkheadConfig       `yaml:"bulkhead"`
+	RateLimiter    *RateLimiterConfig    `yaml:"rateLimiter"`
+	Cache          *CacheConfig          `yaml:"cache"`
+}
+
+// RetryConfig retries connection errors, 429 and 5xx responses, and honors
+// Retry-After headers.
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"maxAttempts"`
+	Delay       time.Duration `yaml:"delay"`
+	// MaxDelay enables exponential backoff from Delay up to MaxDelay.
+	MaxDelay time.Duration `yaml:"maxDelay"`
+	Jitter   time.Duration `yaml:"jitter"`
+	// MaxDuration limits the time of all attempts together.
+	MaxDuration time.Duration `yaml:"maxDuration"`
+}
+
+// CircuitBreakerConfig counts connection errors and 5xx responses as
+// failures.
+type CircuitBreakerConfig struct {
+	FailureThreshold int `yaml:"failureThreshold"`
+	// FailureExecutions opens the breaker when FailureThreshold of the last
+	// FailureExecutions executions failed. Without it, FailureThreshold
+	// failures in a row open it.
+	FailureExecutions int `yaml:"failureExecutions"`
+	// SuccessThreshold is the number of successful trial executions that
+	// close a half-open breaker, 1 when zero.
+	SuccessThreshold int `yaml:"successThreshold"`
+	// Delay is how long the breaker stays open before it lets trial
+	// executions through.
+	Delay time.Duration `yaml:"delay"`
+}
+
+// HedgeConfig sends up to MaxHedges more requests when a request takes longer
+// than Delay, and uses the first response.
+type HedgeConfig struct {
+	Delay     time.Duration `yaml:"delay"`
+	MaxHedges int           `yaml:"maxHedges"`
+}
+
+type BulkheadConfig struct {
+	MaxConcurrency int `yaml:"maxConcurrency"`
+	// MaxWait is how long a request waits for a free slot before it is
+	// rejected. Requests are rejected at once when zero.
+	MaxWait time.Duration `yaml:"maxWait"`
+}
+
+type RateLimiterConfig struct {
+	MaxExecutions int           `yaml:"maxExecutions"`
+	Period        time.Duration `yaml:"period"`
+	// Bursty lets all MaxExecutions through at the start of a period instead
+	// of spreading them evenly over it.
+	Bursty  bool          `yaml:"bursty"`
+	MaxWait time.Duration `yaml:"maxWait"`
+}
+
+// CacheConfig caches 200 responses to GET requests by URL. Requests with
+// credentials or Cache-Control: no-store are not cached, nor are responses
+// marked no-store or private, or with Vary or Set-Cookie, since the key does
+// not tell callers apart.
+type CacheConfig struct {
+	TTL time.Duration `yaml:"ttl"`
+	// MaxEntries limits the size of the cache, no limit when zero.
+	MaxEntries int `yaml:"maxEntries"`
+}
+
+// LoadConfig reads and validates a YAML or JSON config file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseConfig parses and validates a config in YAML or JSON, which is valid
+// YAML. Unknown fields are rejected.
+func ParseConfig(data []byte) (*Config, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var config Config
+	if err := decoder.Decode(&config); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("empty config")
+		}
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// Validate checks the config for values the policies do not accept.
+func (c *Config) Validate() error {
+	if len(c.Routes) == 0 {
+		return errors.New("no routes configured")
+	}
+
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(c.Routes)) {
+		if name == "" {
+			errs = append(errs, errors.New("route without a name"))
+			continue
+		}
+		for _, err := range c.Routes[name].validate() {
+			errs = append(errs, fmt.Errorf("route %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r Route) validate() []error {
+	var errs []error
+	check := func(ok bool, message string) {
+		if !ok {
+			errs = append(errs, errors.New(message))
+		}
+	}
+
+	check(r.Timeout >= 0, "timeout must not be negative")
+
+	if retry := r.Retry; retry != nil {
+		check(retry.MaxAttempts >= 1, "retry.maxAttempts must be at least 1")
+		check(retry.Delay >= 0, "retry.delay must not be negative")
+		check(retry.MaxDelay == 0 || retry.Delay > 0 && retry.MaxDelay > retry.Delay,
+			"retry.maxDelay needs a retry.delay and must be longer than it")
+		check(retry.Jitter >= 0 && (retry.Jitter == 0 || retry.Jitter < retry.Delay),
+			"retry.jitter must be shorter than retry.delay")
+		check(retry.MaxDuration >= 0, "retry.maxDuration must not be negative")
+	}
+
+	if breaker := r.CircuitBreaker; breaker != nil {
+		check(breaker.FailureThreshold >= 1, "circuitBreaker.failureThreshold must be at least 1")
+		check(breaker.FailureExecutions == 0 || breaker.FailureExecutions >= breaker.FailureThreshold,
+			"circuitBreaker.failureExecutions must not be less than circuitBreaker.failureThreshold")
+		check(breaker.SuccessThreshold >= 0, "circuitBreaker.successThreshold must not be negative")
+		check(breaker.Delay >= 0, "circuitBreaker.delay must not be negative")
+	}
+
+	if hedge := r.Hedge; hedge != nil {
+		check(hedge.Delay > 0, "hedge.delay must be positive")
+		check(hedge.MaxHedges >= 1, "hedge.maxHedges must be at least 1")
+	}
+
+	if bulkhead := r.Bulkhead; bulkhead != nil {
+		check(bulkhead.MaxConcurrency >= 1, "bulkhead.maxConcurrency must be at least 1")
+		check(bulkhead.MaxWait >= 0, "bulkhead.maxWait must not be negative")
+	}
+
+	if limiter := r.RateLimiter; limiter != nil {
+		check(limiter.MaxExecutions >= 1, "rateLimiter.maxExecutions must be at least 1")
+		check(limiter.Period > 0, "rateLimiter.period must be positive")
+		check(limiter.MaxWait >= 0, "rateLimiter.maxWait must not be negative")
+		check(limiter.Bursty || limiter.Period >= time.Duration(limiter.MaxExecutions),
+			"rateLimiter.period is too short for rateLimiter.maxExecutions")
+	}
+
+	if cache := r.Cache; cache != nil {
+		check(cache.TTL > 0, "cache.ttl must be positive")
+		check(cache.MaxEntries >= 0, "cache.maxEntries must not be negative")
+	}
+
+	return errs
+}
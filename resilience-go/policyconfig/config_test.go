@@ -0,0 +1,111 @@
+package policyconfig
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		errors []string
+	}{
+		{
+			name: "yaml",
+			config: `
+routes:
+  inventory:
+    timeout: 2s
+    retry: {maxAttempts: 3, delay: 50ms, maxDelay: 1s, jitter: 10ms}
+    circuitBreaker: {failureThreshold: 5, failureExecutions: 10, delay: 10s}
+    hedge: {delay: 300ms, maxHedges: 1}
+    bulkhead: {maxConcurrency: 20, maxWait: 100ms}
+    rateLimiter: {maxExecutions: 100, period: 1s}
+    cache: {ttl: 30s, maxEntries: 1000}
+`,
+		},
+		{
+			name:   "json",
+			config: `{"routes": {"inventory": {"timeout": "2s", "retry": {"maxAttempts": 2, "delay": "10ms"}}}}`,
+		},
+		{
+			name:   "empty",
+			config: "",
+			errors: []string{"empty config"},
+		},
+		{
+			name:   "no routes",
+			config: "routes: {}",
+			errors: []string{"no routes configured"},
+		},
+		{
+			name:   "unknown field",
+			config: "routes:\n  inventory:\n    retries: {maxAttempts: 3}\n",
+			errors: []string{"field retries not found"},
+		},
+		{
+			name:   "duration without unit",
+			config: "routes:\n  inventory:\n    timeout: 5\n",
+			errors: []string{"into time.Duration"},
+		},
+		{
+			name: "invalid values",
+			config: `
+routes:
+  inventory:
+    timeout: -1s
+    retry: {maxAttempts: 0, delay: 10ms, maxDelay: 5ms, jitter: 20ms}
+    circuitBreaker: {failureThreshold: 5, failureExecutions: 3}
+    hedge: {maxHedges: 0}
+  checkout:
+    bulkhead: {maxConcurrency: 0}
+    rateLimiter: {maxExecutions: 5}
+    cache: {ttl: 0s}
+`,
+			errors: []string{
+				"route checkout: bulkhead.maxConcurrency must be at least 1",
+				"route checkout: rateLimiter.period must be positive",
+				"route checkout: cache.ttl must be positive",
+				"route inventory: timeout must not be negative",
+				"route inventory: retry.maxAttempts must be at least 1",
+				"route inventory: retry.maxDelay needs a retry.delay and must be longer than it",
+				"route inventory: retry.jitter must be shorter than retry.delay",
+				"route inventory: circuitBreaker.failureExecutions must not be less than circuitBreaker.failureThreshold",
+				"route inventory: hedge.delay must be positive",
+				"route inventory: hedge.maxHedges must be at least 1",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := ParseConfig([]byte(test.config))
+			if len(test.errors) == 0 {
+				if err != nil {
+					t.Fatalf("ParseConfig() error = %v", err)
+				}
+				if config.Routes["inventory"].Timeout != 2*time.Second {
+					t.Errorf("timeout = %v, want 2s", config.Routes["inventory"].Timeout)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("ParseConfig() succeeded, want an error")
+			}
+			for _, want := range test.errors {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestExampleConfig(t *testing.T) {
+	if _, err := LoadConfig("../policies.example.yaml"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,134 @@
+package policyconfig
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/failsafe-go/failsafe-go"
+	"github.com/failsafe-go/failsafe-go/bulkhead"
+	"github.com/failsafe-go/failsafe-go/cachepolicy"
+	"github.com/failsafe-go/failsafe-go/circuitbreaker"
+	"github.com/failsafe-go/failsafe-go/failsafehttp"
+	"github.com/failsafe-go/failsafe-go/hedgepolicy"
+	"github.com/failsafe-go/failsafe-go/ratelimiter"
+	"github.com/failsafe-go/failsafe-go/timeout"
+)
+
+// route holds the policies built from the config of a route. They are
+// stateful, so requests of a route share one breaker, limiter and cache.
+type route struct {
+	config Route
+
+	// client is used for outgoing requests, outermost first: a cached
+	// response skips all other policies, every retry passes the rate limiter
+	// and the breaker and gets its own timeout, which covers its hedges, and
+	// hedges need a bulkhead slot like any other request.
+	client []failsafe.Policy[*http.Response]
+	// server leaves out retry and hedge, which would run handlers more than
+	// once.
+	server []failsafe.Policy[*http.Response]
+}
+
+func newRoute(config Route) *route {
+	var cache, retry, limiter, breaker, timeoutPolicy, hedge, gate failsafe.Policy[*http.Response]
+
+	if c := config.Cache; c != nil {
+		cache = cachepolicy.NewBuilder[*http.Response](newResponseCache(c.TTL, c.MaxEntries)).
+			CacheIf(cacheableResponse).
+			Build()
+	}
+
+	if c := config.Retry; c != nil {
+		builder := failsafehttp.NewRetryPolicyBuilder().
+			AbortOnErrors(circuitbreaker.ErrOpen).
+			WithMaxAttempts(c.MaxAttempts)
+		switch {
+		case c.MaxDelay > 0:
+			builder = builder.WithBackoff(c.Delay, c.MaxDelay)
+		case c.Delay > 0:
+			builder = builder.WithDelay(c.Delay)
+		}
+		if c.Jitter > 0 {
+			builder = builder.WithJitter(c.Jitter)
+		}
+		if c.MaxDuration > 0 {
+			builder = builder.WithMaxDuration(c.MaxDuration)
+		}
+		retry = builder.Build()
+	}
+
+	if c := config.RateLimiter; c != nil {
+		builder := ratelimiter.NewSmoothBuilder[*http.Response](uint(c.MaxExecutions), c.Period)
+		if c.Bursty {
+			builder = ratelimiter.NewBurstyBuilder[*http.Response](uint(c.MaxExecutions), c.Period)
+		}
+		limiter = builder.WithMaxWaitTime(c.MaxWait).Build()
+	}
+
+	if c := config.CircuitBreaker; c != nil {
+		builder := circuitbreaker.NewBuilder[*http.Response]().
+			HandleIf(breakerFailure).
+			WithFailureThreshold(uint(c.FailureThreshold))
+		if c.FailureExecutions > 0 {
+			builder = builder.WithFailureThresholdRatio(uint(c.FailureThreshold), uint(c.FailureExecutions))
+		}
+		if c.SuccessThreshold > 0 {
+			builder = builder.WithSuccessThreshold(uint(c.SuccessThreshold))
+		}
+		if c.Delay > 0 {
+			builder = builder.WithDelay(c.Delay)
+		}
+		breaker = builder.Build()
+	}
+
+	if config.Timeout > 0 {
+		timeoutPolicy = timeout.NewBuilder[*http.Response](config.Timeout).Build()
+	}
+
+	if c := config.Hedge; c != nil {
+		hedge = hedgepolicy.NewBuilderWithDelay[*http.Response](c.Delay).
+			WithMaxHedges(c.MaxHedges).
+			Build()
+	}
+
+	if c := config.Bulkhead; c != nil {
+		gate = bulkhead.NewBuilder[*http.Response](uint(c.MaxConcurrency)).
+			WithMaxWaitTime(c.MaxWait).
+			Build()
+	}
+
+	return &route{
+		config: config,
+		client: present(cache, retry, limiter, breaker, timeoutPolicy, hedge, gate),
+		server: present(cache, limiter, breaker, timeoutPolicy, gate),
+	}
+}
+
+// breakerFailure counts errors and server errors, but not the rejections of
+// the route's own rate limiter and bulkhead, which say nothing about the
+// health of the other side.
+func breakerFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, ratelimiter.ErrExceeded) && !errors.Is(err, bulkhead.ErrFull)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+func present(policies ...failsafe.Policy[*http.Response]) []failsafe.Policy[*http.Response] {
+	var result []failsafe.Policy[*http.Response]
+	for _, policy := range policies {
+		if policy != nil {
+			result = append(result, policy)
+		}
+	}
+	return result
+}
+
+// withCacheKey keys cacheable requests by URL for the route's cache. Other
+// requests bypass it.
+func (r *route) withCacheKey(req *http.Request) *http.Request {
+	if r.config.Cache == nil || !cacheableRequest(req) {
+		return req
+	}
+	return req.WithContext(cachepolicy.ContextWithCacheKey(req.Context(), req.URL.String()))
+}
@@ -0,0 +1,153 @@
+package policyconfig
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/failsafe-go/failsafe-go/failsafehttp"
+)
+
+// Registry holds the policies of the routes of a config file. Transports and
+// handlers look up the policies of their route on every request, so a reload
+// applies to requests that start afterwards.
+type Registry struct {
+	path   string
+	routes atomic.Pointer[map[string]*route]
+
+	// mu serializes reloads.
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+}
+
+// Open loads the config file at path.
+func Open(path string) (*Registry, error) {
+	r := &Registry{path: path}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the config file again. Routes whose config did not change keep
+// their policies, and with them the state of their breakers, limiters and
+// caches. When the file is invalid, the current policies stay in place.
+func (r *Registry) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+	// Remembered before reading, so a write during the reload is seen by
+	// Watch, and an invalid file is reported once.
+	r.modTime, r.size = info.ModTime(), info.Size()
+
+	config, err := LoadConfig(r.path)
+	if err != nil {
+		return err
+	}
+
+	var current map[string]*route
+	if loaded := r.routes.Load(); loaded != nil {
+		current = *loaded
+	}
+	routes := make(map[string]*route, len(config.Routes))
+	for name, routeConfig := range config.Routes {
+		if existing, ok := current[name]; ok && reflect.DeepEqual(existing.config, routeConfig) {
+			routes[name] = existing
+			continue
+		}
+		routes[name] = newRoute(routeConfig)
+	}
+	r.routes.Store(&routes)
+	return nil
+}
+
+// Watch checks the config file every interval until ctx is done and reloads
+// it when its modification time or size changed. onReload, if not nil, gets
+// the result of every reload.
+func (r *Registry) Watch(ctx context.Context, interval time.Duration, onReload func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !r.changed() {
+			continue
+		}
+		err := r.Reload()
+		if onReload != nil {
+			onReload(err)
+		}
+	}
+}
+
+func (r *Registry) changed() bool {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		// Editors may replace the file; it is picked up once it is back.
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !info.ModTime().Equal(r.modTime) || info.Size() != r.size
+}
+
+// Routes returns the names of the configured routes.
+func (r *Registry) Routes() []string {
+	return slices.Sorted(maps.Keys(*r.routes.Load()))
+}
+
+func (r *Registry) route(name string) *route {
+	return (*r.routes.Load())[name]
+}
+
+// RoundTripper returns a transport that sends requests through next with the
+// policies of the route. Requests of a route without policies go straight to
+// next, which is http.DefaultTransport when nil.
+func (r *Registry) RoundTripper(name string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		route := r.route(name)
+		if route == nil || len(route.client) == 0 {
+			return next.RoundTrip(req)
+		}
+		return failsafehttp.NewRoundTripper(next, route.client...).RoundTrip(route.withCacheKey(req))
+	})
+}
+
+// Handler returns a handler that serves requests with next under the
+// policies of the route, except retry and hedge.
+func (r *Registry) Handler(name string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		route := r.route(name)
+		if route == nil || len(route.server) == 0 {
+			next.ServeHTTP(w, req)
+			return
+		}
+		failsafehttp.NewHandler(next, route.server...).ServeHTTP(w, route.withCacheKey(req))
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,149 @@
+package policyconfig
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+const testConfig = `
+routes:
+  catalog:
+    retry: {maxAttempts: 3, delay: 10ms}
+  checkout:
+    rateLimiter: {maxExecutions: %d, period: 1s, bursty: true}
+`
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	// Written to a temporary file and renamed, so a reload never sees it half
+	// written.
+	if err := os.WriteFile(path+".tmp", []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testRoutes(maxExecutions int) string {
+	return fmt.Sprintf(testConfig, maxExecutions)
+}
+
+func TestRegistryReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writeConfig(t, path, testRoutes(2))
+
+	registry, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if routes := registry.Routes(); !slices.Equal(routes, []string{"catalog", "checkout"}) {
+		t.Fatalf("Routes() = %v", routes)
+	}
+	catalog, checkout := registry.route("catalog"), registry.route("checkout")
+	if len(catalog.client) != 1 || len(catalog.server) != 0 {
+		t.Errorf("catalog has %d client and %d server policies, want 1 and 0", len(catalog.client), len(catalog.server))
+	}
+
+	writeConfig(t, path, testRoutes(10))
+	if err := registry.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if registry.route("catalog") != catalog {
+		t.Error("unchanged route catalog got new policies")
+	}
+	if registry.route("checkout") == checkout {
+		t.Error("changed route checkout kept its policies")
+	}
+	if got := registry.route("checkout").config.RateLimiter.MaxExecutions; got != 10 {
+		t.Errorf("checkout rateLimiter.maxExecutions = %d, want 10", got)
+	}
+
+	checkout = registry.route("checkout")
+	writeConfig(t, path, testRoutes(0))
+	if err := registry.Reload(); err == nil {
+		t.Fatal("Reload() of an invalid file succeeded")
+	}
+	if registry.route("checkout") != checkout {
+		t.Error("invalid file replaced the policies")
+	}
+}
+
+func TestRegistryWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writeConfig(t, path, testRoutes(2))
+	registry, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloads := make(chan error)
+	go registry.Watch(ctx, 5*time.Millisecond, func(err error) {
+		select {
+		case reloads <- err:
+		case <-ctx.Done():
+		}
+	})
+
+	waitReload := func() error {
+		t.Helper()
+		select {
+		case err := <-reloads:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("file was not reloaded")
+			return nil
+		}
+	}
+
+	writeConfig(t, path, "routes:\n  checkout: {}\n")
+	if err := waitReload(); err != nil {
+		t.Fatal(err)
+	}
+	if routes := registry.Routes(); !slices.Equal(routes, []string{"checkout"}) {
+		t.Errorf("Routes() after reload = %v", routes)
+	}
+
+	writeConfig(t, path, "routes:\n  checkout: {timeout: -1s}\n")
+	if err := waitReload(); err == nil {
+		t.Error("invalid file was accepted")
+	}
+	if routes := registry.Routes(); !slices.Equal(routes, []string{"checkout"}) {
+		t.Errorf("Routes() after the invalid file = %v", routes)
+	}
+}
+
+func TestRegistryPassThrough(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	writeConfig(t, path, "routes:\n  empty: {}\n")
+	registry, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Routes without policies and unknown routes call the handler directly.
+	for _, name := range []string{"empty", "unknown"} {
+		server := httptest.NewServer(registry.Handler(name, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})))
+		client := &http.Client{Transport: registry.RoundTripper(name, nil)}
+		resp, err := client.Get(server.URL)
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusTeapot {
+			t.Errorf("route %s: status = %d, want %d", name, resp.StatusCode, http.StatusTeapot)
+		}
+	}
+}